@@ -0,0 +1,117 @@
+package iocopy
+
+import (
+	"time"
+)
+
+// adaptiveRefSpeed is the speed in bytes per second at which the report interval of [AdaptiveOnEvent] starts to grow.
+// The interval grows linearly with the speed above it.
+const adaptiveRefSpeed = 1024 * 1024
+
+// AdaptiveOnEvent returns an [OnEventFunc] which calls fn with [*EventWritten] at an adaptive interval.
+// The interval tightens to minInterval for slow transfers to make the feedback responsive,
+// and relaxes up to maxInterval for very fast ones to avoid callback spam on local copies.
+// It grows linearly with the average speed above 1 MiB/s.
+// Other events and the last [*EventWritten](copied == total) are always passed to fn.
+// Speed of the passed [*EventWritten] is computed over the time since the previous passed one.
+func AdaptiveOnEvent(fn OnEventFunc, minInterval, maxInterval time.Duration) OnEventFunc {
+	maxInterval = max(minInterval, maxInterval)
+
+	var (
+		reported   bool
+		last       time.Time
+		lastCopied int64
+	)
+
+	return func(e Event) {
+		if fn == nil {
+			return
+		}
+
+		w, ok := e.(*EventWritten)
+		if !ok {
+			fn(e)
+			return
+		}
+
+		now := time.Now()
+		if reported {
+			interval := time.Duration(float64(minInterval) * w.AvgSpeed / adaptiveRefSpeed)
+			interval = min(max(interval, minInterval), maxInterval)
+
+			if now.Sub(last) < interval && w.Copied != w.Total {
+				return
+			}
+
+			if d := now.Sub(last).Seconds(); d > 0 {
+				w.Speed = float64(w.Copied-lastCopied) / d
+			}
+		}
+
+		reported, last, lastCopied = true, now, w.Copied
+		fn(w)
+	}
+}
+
+// MinBytesOnEvent returns an [OnEventFunc] which calls fn with [*EventWritten]
+// only if at least n bytes are copied since the previous passed one.
+// It's independent of the time interval and can be combined with [AdaptiveOnEvent]
+// to avoid flooding the consumers with the events of tiny files.
+// Other events, the first and the last [*EventWritten](copied == total) are always passed to fn.
+// Speed of the passed [*EventWritten] is computed over the time since the previous passed one.
+func MinBytesOnEvent(fn OnEventFunc, n int64) OnEventFunc {
+	var (
+		reported   bool
+		last       time.Time
+		lastCopied int64
+	)
+
+	return func(e Event) {
+		if fn == nil {
+			return
+		}
+
+		w, ok := e.(*EventWritten)
+		if !ok {
+			fn(e)
+			return
+		}
+
+		now := time.Now()
+		if reported {
+			if w.Copied-lastCopied < n && w.Copied != w.Total {
+				return
+			}
+
+			if d := now.Sub(last).Seconds(); d > 0 {
+				w.Speed = float64(w.Copied-lastCopied) / d
+			}
+		}
+
+		reported, last, lastCopied = true, now, w.Copied
+		fn(w)
+	}
+}
+
+// MinBytesOnWritten returns an [OnWrittenFunc] which calls fn
+// only if at least n bytes are copied since the previous call.
+// The first call and the last one(all bytes are copied) are always passed to fn.
+func MinBytesOnWritten(fn OnWrittenFunc, n int64) OnWrittenFunc {
+	var (
+		reported   bool
+		lastCopied int64
+	)
+
+	return func(p ProgressInfo) {
+		if fn == nil {
+			return
+		}
+
+		if reported && p.Copied()-lastCopied < n && p.Copied() != p.Total {
+			return
+		}
+
+		reported, lastCopied = true, p.Copied()
+		fn(p)
+	}
+}
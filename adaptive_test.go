@@ -0,0 +1,118 @@
+package iocopy_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/northbright/iocopy"
+)
+
+func ExampleAdaptiveOnEvent() {
+	// This example copies a local file with a small buffer which makes a lot of written events.
+	// AdaptiveOnEvent reduces the callback calls for the fast copy.
+	dir, err := os.MkdirTemp("", "iocopy")
+	if err != nil {
+		log.Printf("os.MkdirTemp() error: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	src := filepath.Join(dir, "src")
+	if err = os.WriteFile(src, bytes.Repeat([]byte("0123456789abcdef"), 64*1024), 0644); err != nil {
+		log.Printf("os.WriteFile() error: %v", err)
+		return
+	}
+
+	var (
+		n    int
+		last *iocopy.EventWritten
+	)
+	fn := iocopy.AdaptiveOnEvent(func(e iocopy.Event) {
+		if e, ok := e.(*iocopy.EventWritten); ok {
+			n++
+			last = e
+		}
+	}, time.Millisecond*100, time.Second)
+
+	t := iocopy.NewCopyFileTask(filepath.Join(dir, "dst"), src, nil)
+	if err = iocopy.Do(context.Background(), t, make([]byte, 1024), fn); err != nil {
+		log.Printf("iocopy.Do() error: %v", err)
+		return
+	}
+
+	fmt.Printf("less than 10 events reported: %v\n", n < 10)
+	fmt.Printf("last event: %v/%v bytes copied(%.2f%%)\n", last.Copied, last.Total, last.Percent)
+
+	// Output:
+	// less than 10 events reported: true
+	// last event: 1048576/1048576 bytes copied(100.00%)
+}
+
+func ExampleMinBytesOnEvent() {
+	// This example copies a local file with a small buffer which makes a lot of written events.
+	// MinBytesOnEvent passes the written events only if 256 KiB are copied since the previous one.
+	dir, err := os.MkdirTemp("", "iocopy")
+	if err != nil {
+		log.Printf("os.MkdirTemp() error: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	src := filepath.Join(dir, "src")
+	if err = os.WriteFile(src, bytes.Repeat([]byte("0123456789abcdef"), 64*1024), 0644); err != nil {
+		log.Printf("os.WriteFile() error: %v", err)
+		return
+	}
+
+	var copied []int64
+	fn := iocopy.MinBytesOnEvent(func(e iocopy.Event) {
+		if e, ok := e.(*iocopy.EventWritten); ok {
+			copied = append(copied, e.Copied)
+		}
+	}, 256*1024)
+
+	t := iocopy.NewCopyFileTask(filepath.Join(dir, "dst"), src, nil)
+	if err = iocopy.Do(context.Background(), t, make([]byte, 1024), fn); err != nil {
+		log.Printf("iocopy.Do() error: %v", err)
+		return
+	}
+
+	fmt.Printf("written events: %v\n", copied)
+
+	// Output:
+	// written events: [1024 263168 525312 787456 1048576]
+}
+
+func ExampleMinBytesOnWritten() {
+	// This example copies 1 MiB and 100 bytes with a small buffer which makes a lot of progress calls.
+	// MinBytesOnWritten passes the calls only if 256 KiB are copied since the previous one.
+	// The first call and the last one are always passed, so the final 100% is still reported.
+	data := bytes.Repeat([]byte("a"), 1024*1024+100)
+
+	var copied []int64
+	fn := iocopy.MinBytesOnWritten(func(p iocopy.ProgressInfo) {
+		copied = append(copied, p.Copied())
+	}, 256*1024)
+
+	calls := 0
+	countFn := func(p iocopy.ProgressInfo) {
+		calls++
+		fn(p)
+	}
+
+	var dst bytes.Buffer
+	if _, err := iocopy.CopyBufferWithProgress(context.Background(), &dst, bytes.NewReader(data), make([]byte, 1024), int64(len(data)), 0, countFn); err != nil {
+		log.Printf("iocopy.CopyBufferWithProgress() error: %v", err)
+		return
+	}
+
+	fmt.Printf("calls: %v, passed: %v\n", calls, copied)
+
+	// Output:
+	// calls: 1025, passed: [1024 263168 525312 787456 1048676]
+}
@@ -0,0 +1,222 @@
+package iocopy
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+var (
+	// ErrArchiveSeek is returned when seeking or truncating an archive entry to other positions than the written one.
+	// Entries of archives are written sequentially, so tasks writing to archives can't be resumed.
+	ErrArchiveSeek = errors.New("archive entries can't seek or truncate")
+
+	// ErrSizeRequired is returned by [TarFS] when the size of the entry is unknown.
+	ErrSizeRequired = errors.New("size of tar entries is required")
+)
+
+// archiveFile implements [WriteFile] interface to write an archive entry.
+type archiveFile struct {
+	w     io.Writer
+	off   int64
+	close func() error
+}
+
+// Write implements [io.Writer] interface.
+func (f *archiveFile) Write(p []byte) (n int, err error) {
+	n, err = f.w.Write(p)
+	f.off += int64(n)
+	return n, err
+}
+
+// Seek implements [io.Seeker] interface.
+// It only accepts seeking to the written position.
+func (f *archiveFile) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekCurrent:
+		offset += f.off
+	case io.SeekEnd:
+		return f.off, ErrArchiveSeek
+	}
+
+	if offset != f.off {
+		return f.off, ErrArchiveSeek
+	}
+	return f.off, nil
+}
+
+// Truncate implements [WriteFile] interface.
+// It only accepts truncating to the written size.
+func (f *archiveFile) Truncate(size int64) error {
+	if size != f.off {
+		return ErrArchiveSeek
+	}
+	return nil
+}
+
+// Close implements [io.Closer] interface.
+func (f *archiveFile) Close() error {
+	if f.close != nil {
+		return f.close()
+	}
+	return nil
+}
+
+// archiveName converts the name to the slash-separated name used in archives.
+func archiveName(name string) string {
+	return filepath.ToSlash(filepath.Clean(name))
+}
+
+// archiveEntries records the written entries to avoid duplicate ones.
+type archiveEntries map[string]bool
+
+// add adds the entry or returns an error wrapping [fs.ErrExist] if it's been written.
+func (m archiveEntries) add(op, name string) error {
+	if m[name] {
+		return &fs.PathError{Op: op, Path: name, Err: fs.ErrExist}
+	}
+	m[name] = true
+	return nil
+}
+
+// ZipFS implements [WriteFS] interface to write files as entries of a zip archive.
+// It makes "download files straight into a zip" possible without temp files.
+// Entries are written sequentially, so the tasks writing to it can't be resumed.
+// The caller should close the [*zip.Writer] after all tasks are done.
+type ZipFS struct {
+	zw      *zip.Writer
+	entries archiveEntries
+	// Method is the compression method of file entries.
+	// Default is [zip.Deflate].
+	Method uint16
+}
+
+// NewZipFS returns a [*ZipFS] which writes entries to zw.
+func NewZipFS(zw *zip.Writer) *ZipFS {
+	return &ZipFS{zw: zw, entries: archiveEntries{}, Method: zip.Deflate}
+}
+
+// Create implements [WriteFS] interface.
+func (fsys *ZipFS) Create(name string) (WriteFile, error) {
+	return fsys.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+}
+
+// OpenFile implements [WriteFS] interface.
+// It creates a new file entry. flag should contain [os.O_CREATE].
+func (fsys *ZipFS) OpenFile(name string, flag int, perm fs.FileMode) (WriteFile, error) {
+	name = archiveName(name)
+	if flag&os.O_CREATE == 0 {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+
+	if err := fsys.entries.add("open", name); err != nil {
+		return nil, err
+	}
+
+	hdr := &zip.FileHeader{Name: name, Method: fsys.Method, Modified: time.Now()}
+	hdr.SetMode(perm)
+
+	w, err := fsys.zw.CreateHeader(hdr)
+	if err != nil {
+		return nil, err
+	}
+
+	return &archiveFile{w: w}, nil
+}
+
+// Mkdir implements [WriteFS] interface.
+// It creates a directory entry.
+func (fsys *ZipFS) Mkdir(name string, perm fs.FileMode) error {
+	name = archiveName(name) + "/"
+	if err := fsys.entries.add("mkdir", name); err != nil {
+		return err
+	}
+
+	hdr := &zip.FileHeader{Name: name, Method: zip.Store, Modified: time.Now()}
+	hdr.SetMode(fs.ModeDir | perm)
+
+	_, err := fsys.zw.CreateHeader(hdr)
+	return err
+}
+
+// TarFS implements [SizedWriteFS] interface to write files as entries of a tar archive.
+// The size of each entry is written to its header, so the size of the source should be known.
+// Entries are written sequentially, so the tasks writing to it can't be resumed.
+// The caller should close the [*tar.Writer] after all tasks are done.
+type TarFS struct {
+	tw      *tar.Writer
+	entries archiveEntries
+}
+
+// NewTarFS returns a [*TarFS] which writes entries to tw.
+func NewTarFS(tw *tar.Writer) *TarFS {
+	return &TarFS{tw: tw, entries: archiveEntries{}}
+}
+
+// Create implements [WriteFS] interface.
+// It always returns [ErrSizeRequired]. Use CreateSize instead.
+func (fsys *TarFS) Create(name string) (WriteFile, error) {
+	return nil, &fs.PathError{Op: "create", Path: archiveName(name), Err: ErrSizeRequired}
+}
+
+// OpenFile implements [WriteFS] interface.
+// It always returns [ErrSizeRequired]. Use CreateSize instead.
+func (fsys *TarFS) OpenFile(name string, flag int, perm fs.FileMode) (WriteFile, error) {
+	return nil, &fs.PathError{Op: "open", Path: archiveName(name), Err: ErrSizeRequired}
+}
+
+// CreateSize implements [SizedWriteFS] interface.
+// It writes the header of the file entry with the size.
+// Closing the returned file fails if the written bytes are less than size.
+func (fsys *TarFS) CreateSize(name string, size int64, perm fs.FileMode) (WriteFile, error) {
+	name = archiveName(name)
+	if err := fsys.entries.add("create", name); err != nil {
+		return nil, err
+	}
+
+	hdr := &tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Size:     size,
+		Mode:     int64(perm.Perm()),
+		ModTime:  time.Now(),
+	}
+
+	if err := fsys.tw.WriteHeader(hdr); err != nil {
+		return nil, err
+	}
+
+	return &archiveFile{
+		w: fsys.tw,
+		close: func() error {
+			if err := fsys.tw.Flush(); err != nil {
+				return fmt.Errorf("%v: %w", name, err)
+			}
+			return nil
+		},
+	}, nil
+}
+
+// Mkdir implements [WriteFS] interface.
+// It creates a directory entry.
+func (fsys *TarFS) Mkdir(name string, perm fs.FileMode) error {
+	name = archiveName(name) + "/"
+	if err := fsys.entries.add("mkdir", name); err != nil {
+		return err
+	}
+
+	hdr := &tar.Header{
+		Typeflag: tar.TypeDir,
+		Name:     name,
+		Mode:     int64(perm.Perm()),
+		ModTime:  time.Now(),
+	}
+
+	return fsys.tw.WriteHeader(hdr)
+}
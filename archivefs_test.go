@@ -0,0 +1,112 @@
+package iocopy_test
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/northbright/iocopy"
+)
+
+func ExampleZipFS() {
+	// This example downloads remote files straight into a zip archive.
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data := strings.Repeat(r.URL.Path, 1000)
+		http.ServeContent(w, r, r.URL.Path, time.Time{}, strings.NewReader(data))
+	}))
+	defer ts.Close()
+
+	buf := &bytes.Buffer{}
+	zw := zip.NewWriter(buf)
+	fsys := iocopy.NewZipFS(zw)
+
+	for _, name := range []string{"a.txt", "b.txt"} {
+		t := iocopy.NewDownloadTask(filepath.Join("downloads", name), ts.URL+"/"+name, fsys)
+		if err := iocopy.Do(context.Background(), t, nil, nil); err != nil {
+			log.Printf("iocopy.Do() error: %v", err)
+			return
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		log.Printf("zw.Close() error: %v", err)
+		return
+	}
+
+	// List the entries of the zip.
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		log.Printf("zip.NewReader() error: %v", err)
+		return
+	}
+
+	for _, f := range zr.File {
+		fmt.Printf("%v: %v bytes\n", f.Name, f.UncompressedSize64)
+	}
+
+	// Output:
+	// downloads/: 0 bytes
+	// downloads/a.txt: 6000 bytes
+	// downloads/b.txt: 6000 bytes
+}
+
+func ExampleTarFS() {
+	// This example copies files into a tar archive.
+	dir, err := os.MkdirTemp("", "iocopy")
+	if err != nil {
+		log.Printf("os.MkdirTemp() error: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	buf := &bytes.Buffer{}
+	tw := tar.NewWriter(buf)
+	fsys := iocopy.NewTarFS(tw)
+
+	for i, name := range []string{"a.txt", "b.txt"} {
+		src := filepath.Join(dir, name)
+		if err = os.WriteFile(src, bytes.Repeat([]byte("a"), (i+1)*1024), 0644); err != nil {
+			log.Printf("os.WriteFile() error: %v", err)
+			return
+		}
+
+		t := iocopy.NewCopyFileTask(name, src, fsys)
+		if err = iocopy.Do(context.Background(), t, nil, nil); err != nil {
+			log.Printf("iocopy.Do() error: %v", err)
+			return
+		}
+	}
+
+	if err = tw.Close(); err != nil {
+		log.Printf("tw.Close() error: %v", err)
+		return
+	}
+
+	// List the entries of the tar.
+	tr := tar.NewReader(buf)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			log.Printf("tr.Next() error: %v", err)
+			return
+		}
+		fmt.Printf("%v: %v bytes\n", hdr.Name, hdr.Size)
+	}
+
+	// Output:
+	// a.txt: 1024 bytes
+	// b.txt: 2048 bytes
+}
@@ -0,0 +1,129 @@
+package iocopy
+
+import (
+	"context"
+	"sync"
+)
+
+const (
+	// defaultBufSize is the size of the buffer used by [Do] if it's nil, which is the same as [io.Copy].
+	defaultBufSize = 32 * 1024
+	// minBufSize is the min size of the buffers shrunk by the memory budget.
+	minBufSize = 4 * 1024
+)
+
+// memBudget limits the sum of the buffers, prefetch queues and chunks read by the connections of all tasks.
+type memBudget struct {
+	mu    sync.Mutex
+	limit int64
+	used  int64
+	// released is closed and replaced when memory is released to wake up the waiters.
+	released chan struct{}
+}
+
+// budget is the package-level memory budget set by [SetMemoryBudget].
+var budget = &memBudget{released: make(chan struct{})}
+
+// SetMemoryBudget sets the cap of the sum of the buffers used by [Do], the prefetch queues of [PrefetchReader]
+// and the chunks read by the connections of [WithConnections] across all concurrent tasks. A non-positive limit means no cap, which is the default.
+// When many tasks run at once, the buffers are shrunk(down to 4 KiB, or the limit if it's smaller) to stay under the cap,
+// and [Do] waits for the memory released by other tasks if even the smallest buffer does not fit.
+// A [PrefetchReader] or the connections of a download always get at least one chunk, so they may exceed the cap by one chunk.
+func SetMemoryBudget(limit int64) {
+	budget.mu.Lock()
+	defer budget.mu.Unlock()
+
+	budget.limit = limit
+	budget.wake()
+}
+
+// MemoryInUse returns the sum of the buffers and the prefetch queues in use by the tasks.
+func MemoryInUse() int64 {
+	budget.mu.Lock()
+	defer budget.mu.Unlock()
+
+	return budget.used
+}
+
+// wake wakes up the waiters. b.mu should be held.
+func (b *memBudget) wake() {
+	close(b.released)
+	b.released = make(chan struct{})
+}
+
+// grant returns the size to grant between least and want without waiting. ok is false if least does not fit.
+// least is clamped to the limit, so it fits once the memory is released. b.mu should be held.
+func (b *memBudget) grant(want, least int64) (n int64, ok bool) {
+	if b.limit <= 0 {
+		return want, true
+	}
+
+	least = min(least, b.limit)
+	avail := b.limit - b.used
+	if avail < least {
+		return 0, false
+	}
+	return min(want, avail), true
+}
+
+// acquire reserves between least and want bytes and waits if least does not fit until it's released or ctx is done.
+func (b *memBudget) acquire(ctx context.Context, want, least int64) (int64, error) {
+	for {
+		b.mu.Lock()
+		n, ok := b.grant(want, least)
+		if ok {
+			b.used += n
+			b.mu.Unlock()
+			return n, nil
+		}
+		released := b.released
+		b.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		case <-released:
+		}
+	}
+}
+
+// tryAcquire reserves between least and want bytes without waiting. least is always reserved even if it does not fit.
+func (b *memBudget) tryAcquire(want, least int64) int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	n, ok := b.grant(want, least)
+	if !ok || n < least {
+		n = least
+	}
+	b.used += n
+	return n
+}
+
+// release releases n bytes reserved.
+func (b *memBudget) release(n int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.used -= n
+	b.wake()
+}
+
+// budgetBuffer returns the buffer shrunk to fit the memory budget and the function to release it.
+// A buffer is allocated if buf is nil.
+func budgetBuffer(ctx context.Context, buf []byte) ([]byte, func(), error) {
+	want := int64(len(buf))
+	if want == 0 {
+		want = defaultBufSize
+	}
+
+	n, err := budget.acquire(ctx, want, min(want, minBufSize))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(buf) == 0 {
+		buf = make([]byte, n)
+	}
+	return buf[:n], func() { budget.release(n) }, nil
+}
@@ -0,0 +1,155 @@
+package iocopy_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/northbright/iocopy"
+)
+
+func ExampleSetMemoryBudget() {
+	// This example runs 8 copy tasks at once under a memory budget of 64 KiB.
+	// The default buffers(32 KiB) are shrunk to stay under the cap.
+	iocopy.SetMemoryBudget(64 * 1024)
+	defer iocopy.SetMemoryBudget(0)
+
+	dir, err := os.MkdirTemp("", "iocopy")
+	if err != nil {
+		log.Printf("os.MkdirTemp() error: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	data := bytes.Repeat([]byte("0123456789abcdef"), 64*1024)
+	src := filepath.Join(dir, "src")
+	if err = os.WriteFile(src, data, 0644); err != nil {
+		log.Printf("os.WriteFile() error: %v", err)
+		return
+	}
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		maxInUse int64
+	)
+
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			t := iocopy.NewCopyFileTask(filepath.Join(dir, fmt.Sprintf("dst%d", i)), src, nil)
+			if err := iocopy.Do(context.Background(), t, nil, func(e iocopy.Event) {
+				mu.Lock()
+				maxInUse = max(maxInUse, iocopy.MemoryInUse())
+				mu.Unlock()
+			}); err != nil {
+				log.Printf("iocopy.Do() error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	fmt.Printf("max memory in use <= 64 KiB: %v\n", maxInUse <= 64*1024)
+	fmt.Printf("memory in use after tasks are done: %v\n", iocopy.MemoryInUse())
+
+	// Output:
+	// max memory in use <= 64 KiB: true
+	// memory in use after tasks are done: 0
+}
+
+func ExampleSetMemoryBudget_small() {
+	// This example hashes a file with prefetch under a memory budget smaller than the min buffer(4 KiB).
+	// The buffer is shrunk to the limit and the prefetch queue still gets one chunk.
+	iocopy.SetMemoryBudget(1000)
+	defer iocopy.SetMemoryBudget(0)
+
+	dir, err := os.MkdirTemp("", "iocopy")
+	if err != nil {
+		log.Printf("os.MkdirTemp() error: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	src := filepath.Join(dir, "src")
+	if err = os.WriteFile(src, []byte("Hello, World!\n"), 0644); err != nil {
+		log.Printf("os.WriteFile() error: %v", err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	t := iocopy.NewHashTask(src, []string{"sha256"}, iocopy.WithPrefetch(0, 0))
+	if err = iocopy.Do(ctx, t, nil, nil); err != nil {
+		log.Printf("iocopy.Do() error: %v", err)
+		return
+	}
+
+	r := t.ResultValue().(iocopy.HashResult)
+	fmt.Printf("sha256: %v\n", r.Checksums["sha256"])
+
+	// A PrefetchReader alone gets one chunk beyond the cap.
+	pr := iocopy.NewPrefetchReader(ctx, bytes.NewReader(bytes.Repeat([]byte("a"), 100*1024)), 0, 0)
+	defer pr.Close()
+
+	var buf bytes.Buffer
+	n, err := iocopy.Copy(ctx, &buf, pr)
+	if err != nil {
+		log.Printf("iocopy.Copy() error: %v", err)
+		return
+	}
+	fmt.Printf("%v bytes prefetched\n", n)
+
+	// Output:
+	// sha256: c98c24b677eff44860afea6f493bbaec5bb1c4cbb209c6fc2bbb47f66ff2ad31
+	// 102400 bytes prefetched
+}
+
+func ExampleSetMemoryBudget_connections() {
+	// This example downloads a file by 4 connections under a memory budget of 128 KiB.
+	// The buffer of Do takes 32 KiB and the chunks read by the connections share the rest.
+	iocopy.SetMemoryBudget(128 * 1024)
+	defer iocopy.SetMemoryBudget(0)
+
+	data := bytes.Repeat([]byte("0123456789abcdef"), 256*1024)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "file", time.Time{}, bytes.NewReader(data))
+	}))
+	defer ts.Close()
+
+	dir, err := os.MkdirTemp("", "iocopy")
+	if err != nil {
+		log.Printf("os.MkdirTemp() error: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	dst := filepath.Join(dir, "file")
+	maxInUse := int64(0)
+	t := iocopy.NewDownloadTask(dst, ts.URL, nil, iocopy.WithConnections(4))
+	if err = iocopy.Do(context.Background(), t, nil, func(e iocopy.Event) {
+		maxInUse = max(maxInUse, iocopy.MemoryInUse())
+	}); err != nil {
+		log.Printf("iocopy.Do() error: %v", err)
+		return
+	}
+
+	buf, _ := os.ReadFile(dst)
+	fmt.Printf("same content: %v\n", bytes.Equal(buf, data))
+	fmt.Printf("max memory in use: %v KiB\n", maxInUse/1024)
+	fmt.Printf("memory in use after the download is done: %v\n", iocopy.MemoryInUse())
+
+	// Output:
+	// same content: true
+	// max memory in use: 128 KiB
+	// memory in use after the download is done: 0
+}
@@ -0,0 +1,215 @@
+package iocopy
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// CASFS implements [WriteFS] interface to write files into a content-addressable store.
+// Files are named by the hex encoded digests of their contents in the layout "objects/ab/cdef...".
+// It's a building block for backup or dedup tools.
+//
+// Bytes are written to a temporary file under "tmp" named by the name of the file,
+// so the tasks writing to it can be resumed.
+// [Do] commits the file when all bytes are written. The temporary file is moved to the object path
+// and the digest is added to the result of the task as "digest".
+// Identical contents are stored only once.
+type CASFS struct {
+	root    string
+	newHash func() hash.Hash
+}
+
+// NewCASFS returns a [*CASFS] which stores objects under root.
+// newHash: hash function to compute digests. [sha256.New] is used if it's nil.
+func NewCASFS(root string, newHash func() hash.Hash) *CASFS {
+	if newHash == nil {
+		newHash = sha256.New
+	}
+
+	return &CASFS{root: root, newHash: newHash}
+}
+
+// Path returns the path of the object with the hex encoded digest.
+func (fsys *CASFS) Path(digest string) string {
+	if len(digest) < 3 {
+		return filepath.Join(fsys.root, "objects", digest)
+	}
+	return filepath.Join(fsys.root, "objects", digest[:2], digest[2:])
+}
+
+// tmpPath returns the path of the temporary file of the named file.
+func (fsys *CASFS) tmpPath(name string) string {
+	sum := sha256.Sum256([]byte(filepath.ToSlash(filepath.Clean(name))))
+	return filepath.Join(fsys.root, "tmp", hex.EncodeToString(sum[:]))
+}
+
+// Create implements [WriteFS] interface.
+func (fsys *CASFS) Create(name string) (WriteFile, error) {
+	return fsys.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+}
+
+// OpenFile implements [WriteFS] interface.
+// It opens the temporary file of the named file.
+func (fsys *CASFS) OpenFile(name string, flag int, perm fs.FileMode) (WriteFile, error) {
+	tmp := fsys.tmpPath(name)
+	if err := os.MkdirAll(filepath.Dir(tmp), 0755); err != nil {
+		return nil, err
+	}
+
+	// The written bytes are read to compute the digest when resuming.
+	flag = flag&^(os.O_RDONLY|os.O_WRONLY|os.O_APPEND) | os.O_RDWR
+	f, err := os.OpenFile(tmp, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+
+	return &casFile{fsys: fsys, f: f, h: fsys.newHash()}, nil
+}
+
+// Mkdir implements [WriteFS] interface.
+// It does nothing because files are stored by digests instead of names.
+func (fsys *CASFS) Mkdir(name string, perm fs.FileMode) error {
+	return nil
+}
+
+// casFile implements [WriteFile] interface to write a file of [CASFS].
+// It also implements [Committer] and [Digester] interfaces.
+type casFile struct {
+	fsys *CASFS
+	f    *os.File
+	h    hash.Hash
+	// pos is the current position of f.
+	pos int64
+	// hashed is the number of bytes from the beginning written to h.
+	hashed int64
+	digest string
+}
+
+// rehash makes h contain the bytes before pos by reading them from the file.
+func (cf *casFile) rehash() error {
+	if cf.hashed == cf.pos {
+		return nil
+	}
+
+	cf.h.Reset()
+	cf.hashed = 0
+
+	n, err := io.Copy(cf.h, io.NewSectionReader(cf.f, 0, cf.pos))
+	cf.hashed = n
+	if err != nil {
+		return err
+	}
+
+	if n != cf.pos {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+
+// Write implements [io.Writer] interface.
+func (cf *casFile) Write(p []byte) (n int, err error) {
+	if err = cf.rehash(); err != nil {
+		return 0, err
+	}
+
+	n, err = cf.f.Write(p)
+	cf.h.Write(p[:n])
+	cf.pos += int64(n)
+	cf.hashed += int64(n)
+	return n, err
+}
+
+// Seek implements [io.Seeker] interface.
+func (cf *casFile) Seek(offset int64, whence int) (int64, error) {
+	pos, err := cf.f.Seek(offset, whence)
+	if err != nil {
+		return cf.pos, err
+	}
+
+	cf.pos = pos
+	return pos, nil
+}
+
+// Truncate implements [WriteFile] interface.
+func (cf *casFile) Truncate(size int64) error {
+	if err := cf.f.Truncate(size); err != nil {
+		return err
+	}
+
+	// Hashed bytes are dropped.
+	if cf.hashed > size {
+		cf.h.Reset()
+		cf.hashed = 0
+	}
+	return nil
+}
+
+// Commit implements [Committer] interface.
+// It moves the temporary file to the path of the object named by the digest.
+// The temporary file is removed if the object exists.
+func (cf *casFile) Commit() error {
+	if cf.digest != "" {
+		return nil
+	}
+
+	// Hash the bytes after the current position(if any).
+	size, err := cf.f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	cf.pos = size
+
+	if err = cf.rehash(); err != nil {
+		return err
+	}
+
+	if err = cf.f.Close(); err != nil {
+		return err
+	}
+
+	digest := hex.EncodeToString(cf.h.Sum(nil))
+	obj := cf.fsys.Path(digest)
+
+	if _, err = os.Stat(obj); err == nil {
+		// Same content exists.
+		if err = os.Remove(cf.f.Name()); err != nil {
+			return err
+		}
+	} else {
+		if !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+
+		if err = os.MkdirAll(filepath.Dir(obj), 0755); err != nil {
+			return err
+		}
+
+		if err = os.Rename(cf.f.Name(), obj); err != nil {
+			return err
+		}
+	}
+
+	cf.digest = digest
+	return nil
+}
+
+// Digest implements [Digester] interface.
+func (cf *casFile) Digest() string {
+	return cf.digest
+}
+
+// Close implements [io.Closer] interface.
+// It keeps the temporary file to resume if the file is not committed.
+func (cf *casFile) Close() error {
+	if cf.digest != "" {
+		// Closed by Commit.
+		return nil
+	}
+	return cf.f.Close()
+}
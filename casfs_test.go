@@ -0,0 +1,97 @@
+package iocopy_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/northbright/iocopy"
+)
+
+func ExampleCASFS() {
+	// This example copies a file into a content-addressable store.
+	// It stops the task after the first bytes written and resumes it.
+	// Then it copies another file with the same content which is stored only once.
+	dir, err := os.MkdirTemp("", "iocopy")
+	if err != nil {
+		log.Printf("os.MkdirTemp() error: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	data := bytes.Repeat([]byte("0123456789abcdef"), 256)
+	srcs := []string{filepath.Join(dir, "a"), filepath.Join(dir, "b")}
+	for _, src := range srcs {
+		if err = os.WriteFile(src, data, 0644); err != nil {
+			log.Printf("os.WriteFile() error: %v", err)
+			return
+		}
+	}
+
+	fsys := iocopy.NewCASFS(filepath.Join(dir, "store"), sha256.New)
+	buf := make([]byte, 1024)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var state []byte
+	t := iocopy.NewCopyFileTask("a", srcs[0], fsys)
+	iocopy.Do(ctx, t, buf, func(e iocopy.Event) {
+		switch e := e.(type) {
+		case *iocopy.EventWritten:
+			// Emulate user cancelation.
+			cancel()
+		case *iocopy.EventStop:
+			fmt.Printf("stopped: %v\n", e.Err)
+			state = e.State
+		}
+	})
+
+	var results [][]byte
+
+	// Resume the first task and copy the second file.
+	t, err = iocopy.LoadCopyFileTask(state, fsys)
+	if err != nil {
+		log.Printf("iocopy.LoadCopyFileTask() error: %v", err)
+		return
+	}
+
+	for _, task := range []iocopy.Task{t, iocopy.NewCopyFileTask("b", srcs[1], fsys)} {
+		if err = iocopy.Do(context.Background(), task, buf, func(e iocopy.Event) {
+			if e, ok := e.(*iocopy.EventOK); ok {
+				results = append(results, e.Result)
+			}
+		}); err != nil {
+			log.Printf("iocopy.Do() error: %v", err)
+			return
+		}
+	}
+
+	for _, result := range results {
+		var r struct {
+			Size   int64  `json:"size"`
+			Digest string `json:"digest"`
+		}
+		json.Unmarshal(result, &r)
+		fmt.Printf("size: %v, digest: %v\n", r.Size, r.Digest)
+	}
+
+	sum := sha256.Sum256(data)
+	stored, err := os.ReadFile(fsys.Path(fmt.Sprintf("%x", sum)))
+	if err != nil {
+		log.Printf("os.ReadFile() error: %v", err)
+		return
+	}
+	fmt.Printf("same content: %v\n", bytes.Equal(stored, data))
+
+	// Output:
+	// stopped: context canceled
+	// size: 4096, digest: 929b11f47a02202e710632002203f7ea8dd3c1bc51ef818e59b6a3cd1dc2d5dc
+	// size: 4096, digest: 929b11f47a02202e710632002203f7ea8dd3c1bc51ef818e59b6a3cd1dc2d5dc
+	// same content: true
+}
@@ -0,0 +1,300 @@
+package iocopy
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"slices"
+	"strconv"
+	"unicode/utf8"
+)
+
+// Major types of CBOR.
+const (
+	cborUint   = 0
+	cborNegInt = 1
+	cborBytes  = 2
+	cborText   = 3
+	cborArray  = 4
+	cborMap    = 5
+	cborTag    = 6
+	cborSimple = 7
+)
+
+// maxStateDepth is the max nesting depth of the decoded states to avoid stack exhaustion.
+const maxStateDepth = 1000
+
+// errCBOR is the error of malformed or unsupported CBOR data.
+var errCBOR = errors.New("invalid CBOR state")
+
+// cborCodec implements [StateCodec] by CBOR.
+type cborCodec struct{}
+
+// Name implements [StateCodec] interface.
+func (cborCodec) Name() string {
+	return "cbor"
+}
+
+// Marshal implements [StateCodec] interface.
+func (cborCodec) Marshal(state []byte) ([]byte, error) {
+	v, err := decodeJSONState(state)
+	if err != nil {
+		return nil, err
+	}
+	return appendCBOR(nil, v)
+}
+
+// Unmarshal implements [StateCodec] interface.
+func (cborCodec) Unmarshal(data []byte) ([]byte, error) {
+	d := &cborDecoder{b: data}
+	v, err := d.value(0)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(d.b) > 0 {
+		return nil, fmt.Errorf("%w: trailing data", errCBOR)
+	}
+	return json.Marshal(v)
+}
+
+// appendCBORHead appends the initial byte of the major type and the argument n.
+func appendCBORHead(b []byte, major byte, n uint64) []byte {
+	major <<= 5
+	switch {
+	case n < 24:
+		return append(b, major|byte(n))
+	case n <= math.MaxUint8:
+		return append(b, major|24, byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, major|25), uint16(n))
+	case n <= math.MaxUint32:
+		return binary.BigEndian.AppendUint32(append(b, major|26), uint32(n))
+	default:
+		return binary.BigEndian.AppendUint64(append(b, major|27), n)
+	}
+}
+
+// appendCBOR appends the CBOR encoding of the value decoded by [decodeJSONState].
+func appendCBOR(b []byte, v any) ([]byte, error) {
+	var err error
+	switch v := v.(type) {
+	case nil:
+		return append(b, cborSimple<<5|22), nil
+	case bool:
+		if v {
+			return append(b, cborSimple<<5|21), nil
+		}
+		return append(b, cborSimple<<5|20), nil
+	case string:
+		return append(appendCBORHead(b, cborText, uint64(len(v))), v...), nil
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			if i >= 0 {
+				return appendCBORHead(b, cborUint, uint64(i)), nil
+			}
+			return appendCBORHead(b, cborNegInt, uint64(-1-i)), nil
+		}
+
+		if u, err := strconv.ParseUint(v.String(), 10, 64); err == nil {
+			return appendCBORHead(b, cborUint, u), nil
+		}
+
+		f, err := v.Float64()
+		if err != nil {
+			return nil, err
+		}
+		return binary.BigEndian.AppendUint64(append(b, cborSimple<<5|27), math.Float64bits(f)), nil
+	case []any:
+		b = appendCBORHead(b, cborArray, uint64(len(v)))
+		for _, e := range v {
+			if b, err = appendCBOR(b, e); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		slices.Sort(keys)
+
+		b = appendCBORHead(b, cborMap, uint64(len(v)))
+		for _, k := range keys {
+			b = append(appendCBORHead(b, cborText, uint64(len(k))), k...)
+			if b, err = appendCBOR(b, v[k]); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	default:
+		return nil, fmt.Errorf("unsupported JSON value: %T", v)
+	}
+}
+
+// cborDecoder decodes CBOR data to the values which are marshaled to JSON.
+// Indefinite lengths are not supported.
+type cborDecoder struct {
+	// b is the data not decoded yet.
+	b []byte
+}
+
+// next consumes n bytes.
+func (d *cborDecoder) next(n uint64) ([]byte, error) {
+	if n > uint64(len(d.b)) {
+		return nil, fmt.Errorf("%w: unexpected end of data", errCBOR)
+	}
+	p := d.b[:n]
+	d.b = d.b[n:]
+	return p, nil
+}
+
+// head decodes the major type and the argument of the next data item.
+// info is the additional information of the initial byte.
+func (d *cborDecoder) head() (major, info byte, n uint64, err error) {
+	p, err := d.next(1)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	major, info = p[0]>>5, p[0]&0x1f
+
+	switch {
+	case info < 24:
+		return major, info, uint64(info), nil
+	case info <= 27:
+		if p, err = d.next(1 << (info - 24)); err != nil {
+			return 0, 0, 0, err
+		}
+
+		for _, c := range p {
+			n = n<<8 | uint64(c)
+		}
+		return major, info, n, nil
+	case info == 31:
+		return 0, 0, 0, fmt.Errorf("%w: indefinite length is not supported", errCBOR)
+	default:
+		return 0, 0, 0, fmt.Errorf("%w: reserved additional information %v", errCBOR, info)
+	}
+}
+
+// value decodes the next data item at the nesting depth.
+func (d *cborDecoder) value(depth int) (any, error) {
+	if depth > maxStateDepth {
+		return nil, fmt.Errorf("%w: too deep", errCBOR)
+	}
+
+	major, info, n, err := d.head()
+	if err != nil {
+		return nil, err
+	}
+
+	switch major {
+	case cborUint:
+		return json.Number(strconv.FormatUint(n, 10)), nil
+	case cborNegInt:
+		// -1-n may overflow int64.
+		i := new(big.Int).Sub(big.NewInt(-1), new(big.Int).SetUint64(n))
+		return json.Number(i.String()), nil
+	case cborBytes:
+		p, err := d.next(n)
+		if err != nil {
+			return nil, err
+		}
+		return base64.StdEncoding.EncodeToString(p), nil
+	case cborText:
+		p, err := d.next(n)
+		if err != nil {
+			return nil, err
+		}
+
+		if !utf8.Valid(p) {
+			return nil, fmt.Errorf("%w: invalid UTF-8 text", errCBOR)
+		}
+		return string(p), nil
+	case cborArray:
+		// Each item takes at least 1 byte.
+		if n > uint64(len(d.b)) {
+			return nil, fmt.Errorf("%w: unexpected end of data", errCBOR)
+		}
+
+		a := make([]any, n)
+		for i := range a {
+			if a[i], err = d.value(depth + 1); err != nil {
+				return nil, err
+			}
+		}
+		return a, nil
+	case cborMap:
+		if n > uint64(len(d.b)) {
+			return nil, fmt.Errorf("%w: unexpected end of data", errCBOR)
+		}
+
+		m := make(map[string]any, n)
+		for range n {
+			k, err := d.value(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+
+			key, ok := k.(string)
+			if !ok {
+				return nil, fmt.Errorf("%w: map key is not text", errCBOR)
+			}
+
+			if m[key], err = d.value(depth + 1); err != nil {
+				return nil, err
+			}
+		}
+		return m, nil
+	case cborTag:
+		// Tags(e.g. date/time) are ignored and the tagged items are decoded as is.
+		return d.value(depth + 1)
+	default:
+		switch info {
+		case 20:
+			return false, nil
+		case 21:
+			return true, nil
+		case 22, 23:
+			// null and undefined.
+			return nil, nil
+		case 25:
+			return float16(uint16(n)), nil
+		case 26:
+			return float64(math.Float32frombits(uint32(n))), nil
+		case 27:
+			return math.Float64frombits(n), nil
+		default:
+			return nil, fmt.Errorf("%w: unsupported simple value %v", errCBOR, n)
+		}
+	}
+}
+
+// float16 converts the IEEE 754 half-precision float to float64.
+func float16(h uint16) float64 {
+	exp, mant := int(h>>10&0x1f), float64(h&0x3ff)
+
+	var f float64
+	switch exp {
+	case 0:
+		f = math.Ldexp(mant, -24)
+	case 31:
+		if mant == 0 {
+			f = math.Inf(1)
+		} else {
+			f = math.NaN()
+		}
+	default:
+		f = math.Ldexp(mant+1024, exp-25)
+	}
+
+	if h&0x8000 != 0 {
+		return -f
+	}
+	return f
+}
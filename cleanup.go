@@ -0,0 +1,72 @@
+package iocopy
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// CleanupPolicy controls what happens to the partial destination file when a task fails or is stopped.
+type CleanupPolicy int
+
+const (
+	// CleanupKeep keeps the partial destination file to resume. It's the default policy.
+	CleanupKeep CleanupPolicy = iota
+	// CleanupDelete deletes the partial destination file.
+	CleanupDelete
+	// CleanupTrash moves the partial destination file to the trash directory
+	// with a metadata file("<name>.json") containing the error and the state.
+	CleanupTrash
+)
+
+// trashMeta is the metadata of the file moved to the trash directory.
+type trashMeta struct {
+	Dst   string          `json:"dst"`
+	Error string          `json:"error"`
+	Time  time.Time       `json:"time"`
+	State json.RawMessage `json:"state,omitempty"`
+}
+
+// cleanupDst cleans up the partial destination file by the policy.
+// trashDir: trash directory used by [CleanupTrash]. It should be on the same file system as the destination.
+// state: state of the task saved in the metadata.
+// cause: the error which makes the task fail or stop.
+// It does nothing if the destination file does not exist.
+func cleanupDst(name string, policy CleanupPolicy, trashDir string, state []byte, cause error) error {
+	switch policy {
+	case CleanupDelete:
+		if err := os.Remove(longPath(name)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		return nil
+	case CleanupTrash:
+		if _, err := os.Stat(longPath(name)); err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+
+		if err := os.MkdirAll(longPath(trashDir), 0755); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		trashed := filepath.Join(trashDir, fmt.Sprintf("%v.%v", filepath.Base(name), now.Format("20060102T150405.000000000")))
+		if err := os.Rename(longPath(name), longPath(trashed)); err != nil {
+			return err
+		}
+
+		buf, err := json.MarshalIndent(trashMeta{Dst: name, Error: cause.Error(), Time: now, State: state}, "", "    ")
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(longPath(trashed+".json"), buf, 0644)
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,76 @@
+package iocopy_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/northbright/iocopy"
+)
+
+func ExampleWithCleanup() {
+	// This example moves the partial destination file to the trash directory when the task is stopped.
+	dir, err := os.MkdirTemp("", "iocopy")
+	if err != nil {
+		log.Printf("os.MkdirTemp() error: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	src := filepath.Join(dir, "src")
+	dst := filepath.Join(dir, "dst")
+	trash := filepath.Join(dir, "trash")
+	data := bytes.Repeat([]byte("0123456789abcdef"), 256)
+	if err = os.WriteFile(src, data, 0644); err != nil {
+		log.Printf("os.WriteFile() error: %v", err)
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	t := iocopy.NewCopyFileTask(dst, src, nil, iocopy.WithCleanup(iocopy.CleanupTrash, trash))
+	iocopy.Do(ctx, t, make([]byte, 1024), func(e iocopy.Event) {
+		switch e := e.(type) {
+		case *iocopy.EventWritten:
+			// Emulate user cancelation.
+			cancel()
+		case *iocopy.EventStop:
+			fmt.Printf("stopped: %v, %v bytes copied\n", e.Err, t.Copied())
+		}
+	})
+
+	if _, err = os.Stat(dst); os.IsNotExist(err) {
+		fmt.Printf("partial file moved to trash\n")
+	}
+
+	metas, err := filepath.Glob(filepath.Join(trash, "dst.*.json"))
+	if err != nil || len(metas) != 1 {
+		log.Printf("metadata not found: %v", err)
+		return
+	}
+
+	buf, err := os.ReadFile(metas[0])
+	if err != nil {
+		log.Printf("os.ReadFile() error: %v", err)
+		return
+	}
+
+	var meta struct {
+		Error string `json:"error"`
+		State struct {
+			Copied int64 `json:"copied"`
+		} `json:"state"`
+	}
+	json.Unmarshal(buf, &meta)
+	fmt.Printf("metadata: error: %v, copied: %v\n", meta.Error, meta.State.Copied)
+
+	// Output:
+	// stopped: context canceled, 0 bytes copied
+	// partial file moved to trash
+	// metadata: error: context canceled, copied: 1024
+}
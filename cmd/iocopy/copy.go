@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+
+	"github.com/northbright/iocopy"
+)
+
+// runCopy parses the arguments and runs the copy command.
+func runCopy(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("copy", flag.ExitOnError)
+	stateFile := fs.String("state", "", "state file(default: <dst>"+stateFileExt+")")
+	fs.Parse(args)
+
+	if fs.NArg() != 2 {
+		return errors.New("src and dst are required")
+	}
+
+	st := &state{Cmd: "copy", Src: fs.Arg(0), Dst: fs.Arg(1), file: *stateFile}
+	if st.file == "" {
+		st.file = st.Dst + stateFileExt
+	}
+
+	return doCopy(ctx, st)
+}
+
+// doCopy copies the source file to the destination file.
+// It resumes the copy if the state contains the task state.
+func doCopy(ctx context.Context, st *state) error {
+	var (
+		t   *iocopy.CopyFileTask
+		err error
+	)
+
+	if len(st.Task) > 0 {
+		if t, err = iocopy.LoadCopyFileTask(st.Task, nil); err != nil {
+			return err
+		}
+	} else {
+		t = iocopy.NewCopyFileTask(st.Dst, st.Src, nil)
+	}
+
+	return runTask(ctx, st, t)
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+
+	"github.com/northbright/iocopy"
+)
+
+// runDownload parses the arguments and runs the download command.
+func runDownload(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("download", flag.ExitOnError)
+	stateFile := fs.String("state", "", "state file(default: <dst>"+stateFileExt+")")
+	fs.Parse(args)
+
+	if fs.NArg() != 2 {
+		return errors.New("url and dst are required")
+	}
+
+	st := &state{Cmd: "download", Src: fs.Arg(0), Dst: fs.Arg(1), file: *stateFile}
+	if st.file == "" {
+		st.file = st.Dst + stateFileExt
+	}
+
+	return doDownload(ctx, st)
+}
+
+// doDownload downloads the remote file to the destination file.
+// It resumes the download if the state contains the task state.
+func doDownload(ctx context.Context, st *state) error {
+	var (
+		t   *iocopy.DownloadTask
+		err error
+	)
+
+	if len(st.Task) > 0 {
+		if t, err = iocopy.LoadDownloadTask(st.Task, nil); err != nil {
+			return err
+		}
+	} else {
+		t = iocopy.NewDownloadTask(st.Dst, st.Src, nil)
+	}
+
+	return runTask(ctx, st, t)
+}
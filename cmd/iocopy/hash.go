@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/northbright/iocopy"
+)
+
+// algNames returns the sorted names of supported hash algorithms.
+func algNames() []string {
+	return slices.Sorted(maps.Keys(iocopy.HashFuncs))
+}
+
+// runHash parses the arguments and runs the hash command.
+func runHash(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("hash", flag.ExitOnError)
+	alg := fs.String("alg", "sha256", "hash algorithm")
+	stateFile := fs.String("state", "", "state file(default: <file>"+stateFileExt+")")
+	cache := fs.String("cache", "", "hash cache file to reuse checksums of unchanged files")
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		return errors.New("file is required")
+	}
+
+	st := &state{Cmd: "hash", Src: fs.Arg(0), Alg: *alg, Cache: *cache, file: *stateFile}
+	if st.file == "" {
+		st.file = st.Src + stateFileExt
+	}
+
+	return doHash(ctx, st)
+}
+
+// runVerify parses the arguments and runs the verify command.
+func runVerify(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("verify", flag.ExitOnError)
+	alg := fs.String("alg", "sha256", "hash algorithm")
+	stateFile := fs.String("state", "", "state file(default: <file>"+stateFileExt+")")
+	cache := fs.String("cache", "", "hash cache file to reuse checksums of unchanged files")
+	fs.Parse(args)
+
+	if fs.NArg() != 2 {
+		return errors.New("file and checksum are required")
+	}
+
+	st := &state{Cmd: "verify", Src: fs.Arg(0), Checksum: fs.Arg(1), Alg: *alg, Cache: *cache, file: *stateFile}
+	if st.file == "" {
+		st.file = st.Src + stateFileExt
+	}
+
+	return doHash(ctx, st)
+}
+
+// doHash computes the checksum of the file by [iocopy.HashTask].
+// It compares the checksum with the expected one for verify command.
+// It resumes the computing if the state contains the task state.
+// It reuses the checksum in the hash cache if the file is not changed.
+func doHash(ctx context.Context, st *state) error {
+	if _, ok := iocopy.HashFuncs[st.Alg]; !ok {
+		return fmt.Errorf("unsupported hash algorithm: %v", st.Alg)
+	}
+
+	var opts []iocopy.Option
+	// The checksum of the standard input is not cached.
+	if st.Cache != "" && !st.streaming() {
+		c, err := iocopy.LoadHashCache(st.Cache)
+		if err != nil {
+			return err
+		}
+		opts = append(opts, iocopy.WithHashCache(c))
+	}
+
+	var (
+		t   *iocopy.HashTask
+		err error
+	)
+
+	if len(st.Task) > 0 {
+		if t, err = iocopy.LoadHashTask(st.Task, opts...); err != nil {
+			return err
+		}
+	} else {
+		t = iocopy.NewHashTask(st.Src, []string{st.Alg}, opts...)
+	}
+
+	if err = runTask(ctx, st, t); err != nil {
+		return err
+	}
+
+	checksums, _ := t.Checksums()
+	checksum := checksums[st.Alg]
+
+	if st.Cmd == "verify" {
+		if !strings.EqualFold(checksum, st.Checksum) {
+			return fmt.Errorf("%w: %v, expected: %v", errMismatch, checksum, st.Checksum)
+		}
+		fmt.Printf("%v: OK\n", st.Src)
+		return nil
+	}
+
+	fmt.Printf("%v  %v\n", checksum, st.Src)
+	return nil
+}
@@ -0,0 +1,81 @@
+// Command iocopy copies, downloads, hashes and verifies files with progress bars.
+//
+// Usage:
+//
+//	iocopy copy [-state file] <src> <dst>
+//	iocopy download [-state file] <url> <dst>
+//	iocopy hash [-alg sha256] [-state file] [-cache file] <file>
+//	iocopy verify [-alg sha256] [-state file] [-cache file] <file> <checksum>
+//	iocopy resume <state file>
+//
+// Press Ctrl+C to stop a running command.
+// The progress is saved to the state file and can be resumed by "iocopy resume".
+//
+// <src> and <file> can be "-" for the standard input and <dst> can be "-" for the standard output,
+// e.g. "tar c dir | iocopy copy - backup.tar". They can't be resumed and the progress is drawn on the standard error.
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// errMismatch is returned by the verify command when the checksums do not match.
+var errMismatch = errors.New("checksum mismatch")
+
+// usage prints the usage of iocopy.
+func usage() {
+	fmt.Fprintf(os.Stderr, `Usage:
+  iocopy copy [-state file] <src> <dst>
+  iocopy download [-state file] <url> <dst>
+  iocopy hash [-alg sha256] [-state file] [-cache file] <file>
+  iocopy verify [-alg sha256] [-state file] [-cache file] <file> <checksum>
+  iocopy resume <state file>
+
+<src> and <file> can be "-" for stdin and <dst> can be "-" for stdout.
+Supported hash algorithms: %v
+`, algNames())
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	// Stop the IO copy on Ctrl+C or SIGTERM.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var err error
+	args := os.Args[2:]
+
+	switch os.Args[1] {
+	case "copy":
+		err = runCopy(ctx, args)
+	case "download":
+		err = runDownload(ctx, args)
+	case "hash":
+		err = runHash(ctx, args)
+	case "verify":
+		err = runVerify(ctx, args)
+	case "resume":
+		err = runResume(ctx, args)
+	case "help", "-h", "-help", "--help":
+		usage()
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command: %v\n", os.Args[1])
+		usage()
+		os.Exit(2)
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "iocopy %v: %v\n", os.Args[1], err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/northbright/iocopy"
+)
+
+// chdirTemp creates a temporary directory and changes the working directory to it,
+// so the file names printed by the commands are the same across runs.
+// It returns a func to restore the working directory and remove the temporary directory.
+func chdirTemp() (func(), error) {
+	wd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+
+	dir, err := os.MkdirTemp("", "iocopy")
+	if err != nil {
+		return nil, err
+	}
+
+	if err = os.Chdir(dir); err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+
+	return func() {
+		os.Chdir(wd)
+		os.RemoveAll(dir)
+	}, nil
+}
+
+func Example_copy() {
+	// This example copies a local file, stops the copy and resumes it from the state file set by -state.
+	restore, err := chdirTemp()
+	if err != nil {
+		log.Printf("chdirTemp() error: %v", err)
+		return
+	}
+	defer restore()
+
+	if err = os.WriteFile("src", []byte("Hello, World!"), 0644); err != nil {
+		log.Printf("os.WriteFile() error: %v", err)
+		return
+	}
+
+	// Emulate Ctrl+C before the copy starts.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err = runCopy(ctx, []string{"-state", "copy.json", "src", "dst"})
+	fmt.Printf("stopped: %v\n", errors.Is(err, errStopped))
+
+	_, err = os.Stat("copy.json")
+	fmt.Printf("state file saved: %v\n", err == nil)
+
+	if err = runResume(context.Background(), []string{"copy.json"}); err != nil {
+		log.Printf("runResume() error: %v", err)
+		return
+	}
+
+	buf, err := os.ReadFile("dst")
+	if err != nil {
+		log.Printf("os.ReadFile() error: %v", err)
+		return
+	}
+	fmt.Printf("dst: %s\n", buf)
+
+	_, err = os.Stat("copy.json")
+	fmt.Printf("state file removed: %v\n", errors.Is(err, os.ErrNotExist))
+
+	// Output:
+	// stopped: true
+	// state file saved: true
+	// dst: Hello, World!
+	// state file removed: true
+}
+
+func Example_hash() {
+	// This example hashes a local file by the algorithm set by -alg, stops the hashing and resumes it.
+	// Then it verifies the file with the hash cache set by -cache.
+	restore, err := chdirTemp()
+	if err != nil {
+		log.Printf("chdirTemp() error: %v", err)
+		return
+	}
+	defer restore()
+
+	if err = os.WriteFile("file", []byte("Hello, World!"), 0644); err != nil {
+		log.Printf("os.WriteFile() error: %v", err)
+		return
+	}
+
+	// Emulate Ctrl+C before the hashing starts.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err = runHash(ctx, []string{"-alg", "md5", "-state", "hash.json", "file"})
+	fmt.Printf("stopped: %v\n", errors.Is(err, errStopped))
+
+	// The state of the task in the state file is loaded by iocopy.LoadTask.
+	st, err := loadState("hash.json")
+	if err != nil {
+		log.Printf("loadState() error: %v", err)
+		return
+	}
+	if _, err = iocopy.LoadTask(st.Task); err != nil {
+		log.Printf("iocopy.LoadTask() error: %v", err)
+		return
+	}
+
+	if err = runResume(context.Background(), []string{"hash.json"}); err != nil {
+		log.Printf("runResume() error: %v", err)
+		return
+	}
+
+	if err = runVerify(context.Background(), []string{"-alg", "md5", "-cache", "cache.json", "file", "65a8e27d8879283831b664bd8b7f0ad4"}); err != nil {
+		log.Printf("runVerify() error: %v", err)
+		return
+	}
+
+	// The checksum of the file is reused from the cache.
+	err = runVerify(context.Background(), []string{"-alg", "md5", "-cache", "cache.json", "file", "0123456789abcdef0123456789abcdef"})
+	fmt.Printf("mismatch: %v\n", errors.Is(err, errMismatch))
+
+	// Output:
+	// stopped: true
+	// 65a8e27d8879283831b664bd8b7f0ad4  file
+	// file: OK
+	// mismatch: true
+}
+
+func Example_args() {
+	// This example shows the errors of the missing arguments and the unsupported hash algorithm.
+	ctx := context.Background()
+
+	fmt.Println(runCopy(ctx, []string{"src"}))
+	fmt.Println(runDownload(ctx, nil))
+	fmt.Println(runHash(ctx, nil))
+	fmt.Println(runVerify(ctx, []string{"file"}))
+	fmt.Println(runResume(ctx, nil))
+	fmt.Println(runHash(ctx, []string{"-alg", "crc32", "file"}))
+
+	// Output:
+	// src and dst are required
+	// url and dst are required
+	// file is required
+	// file and checksum are required
+	// state file is required
+	// unsupported hash algorithm: crc32
+}
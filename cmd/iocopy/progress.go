@@ -0,0 +1,26 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// barWidth is the width of the progress bar.
+const barWidth = 40
+
+// drawProgressBar draws a progress bar to w.
+// It draws the bytes copied and the speed if total size is unknown, e.g. streaming the standard input.
+func drawProgressBar(w io.Writer, name string, total, copied int64, percent float32, speed float64) {
+	if total < 0 {
+		fmt.Fprintf(w, "\r%v: %v bytes %.0f bytes/s", name, copied, speed)
+		return
+	}
+
+	n := int(percent / 100 * barWidth)
+	if n > barWidth {
+		n = barWidth
+	}
+	bar := strings.Repeat("=", n) + strings.Repeat(" ", barWidth-n)
+	fmt.Fprintf(w, "\r%v: [%v] %6.2f%% %v/%v bytes", name, bar, percent, copied, total)
+}
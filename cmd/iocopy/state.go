@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/northbright/iocopy"
+)
+
+const (
+	// bufSize is the size of the buffer used for IO copy.
+	bufSize = 1024 * 640
+
+	// stateFileExt is the extension of the default state file.
+	stateFileExt = ".iocopy.json"
+
+	// progressMinInterval and progressMaxInterval bound the interval to redraw the progress bar.
+	progressMinInterval = time.Millisecond * 50
+	progressMaxInterval = time.Millisecond * 500
+)
+
+// state is saved to the state file when a command is stopped.
+// It's used by the resume command to resume the IO copy.
+type state struct {
+	// Cmd is the name of the command: "copy", "download", "hash" or "verify".
+	Cmd string `json:"cmd"`
+	// Src is the source file or URL.
+	Src string `json:"src"`
+	// Dst is the destination file.
+	Dst string `json:"dst,omitempty"`
+	// Alg is the hash algorithm.
+	Alg string `json:"alg,omitempty"`
+	// Checksum is the expected checksum for verify command.
+	Checksum string `json:"checksum,omitempty"`
+	// Cache is the hash cache file for hash and verify commands.
+	Cache string `json:"cache,omitempty"`
+	// Total is the total number of bytes to copy.
+	// A negative value indicates total size is unknown.
+	Total int64 `json:"total"`
+	// Copied is the number of bytes copied.
+	Copied int64 `json:"copied"`
+	// Task is the state of the task.
+	Task json.RawMessage `json:"task,omitempty"`
+
+	// file is the path of the state file.
+	file string
+}
+
+// loadState loads the state from the file.
+func loadState(file string) (*state, error) {
+	buf, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	st := &state{}
+	if err = json.Unmarshal(buf, st); err != nil {
+		return nil, err
+	}
+	st.file = file
+
+	return st, nil
+}
+
+// save marshals the state and writes it to the state file.
+func (st *state) save() error {
+	buf, err := json.MarshalIndent(st, "", "    ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(st.file, buf, 0644)
+}
+
+// streaming reports whether the source is the standard input or the destination is the standard output("-").
+// The IO copy can't be resumed and the state is not saved.
+func (st *state) streaming() bool {
+	return st.Src == iocopy.Stdio || st.Dst == iocopy.Stdio
+}
+
+// remove removes the state file if it exists.
+func (st *state) remove() error {
+	if st.streaming() {
+		return nil
+	}
+
+	err := os.Remove(st.file)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// errStopped is returned by runTask when the IO copy is stopped by the user.
+var errStopped = errors.New("stopped")
+
+// runTask runs the task with a progress bar.
+// It saves the state to the state file and returns errStopped if the task is stopped,
+// or removes the state file if the task is done.
+func runTask(ctx context.Context, st *state, t iocopy.Task) error {
+	// Redraw the progress bar at an adaptive interval.
+	err := iocopy.Do(ctx, t, make([]byte, bufSize), iocopy.AdaptiveOnEvent(func(e iocopy.Event) {
+		switch e := e.(type) {
+		case *iocopy.EventWritten:
+			drawProgressBar(os.Stderr, st.Cmd, e.Total, e.Copied, e.Percent, e.AvgSpeed)
+		case *iocopy.EventOK:
+			// The last written event may be skipped if total size is unknown.
+			if t.Total() < 0 && e.Duration > 0 {
+				drawProgressBar(os.Stderr, st.Cmd, -1, t.Copied(), 0, float64(e.Stats.BytesWritten)/e.Duration.Seconds())
+			}
+		case *iocopy.EventStop:
+			st.Task = e.State
+		}
+	}, progressMinInterval, progressMaxInterval))
+	fmt.Fprintln(os.Stderr)
+
+	if err != nil {
+		if !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded) && !errors.Is(err, iocopy.ErrNoSpace) {
+			return err
+		}
+
+		// Stopped by the user or no space left.
+		st.Total = t.Total()
+		st.Copied = t.Copied()
+		if st.streaming() {
+			return fmt.Errorf("%w, %v bytes copied", errStopped, st.Copied)
+		}
+		if err = st.save(); err != nil {
+			return err
+		}
+		return fmt.Errorf("%w, %v bytes copied, run \"iocopy resume %v\" to resume", errStopped, st.Copied, st.file)
+	}
+
+	return st.remove()
+}
+
+// runResume loads the state file and resumes the command.
+func runResume(ctx context.Context, args []string) error {
+	if len(args) != 1 {
+		return errors.New("state file is required")
+	}
+
+	st, err := loadState(args[0])
+	if err != nil {
+		return err
+	}
+
+	switch st.Cmd {
+	case "copy":
+		return doCopy(ctx, st)
+	case "download":
+		return doDownload(ctx, st)
+	case "hash", "verify":
+		return doHash(ctx, st)
+	default:
+		return fmt.Errorf("unknown command in state file: %v", st.Cmd)
+	}
+}
@@ -0,0 +1,146 @@
+package iocopy
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+// CollisionPolicy controls what [CopyFSTask] does with the names which collide on case-insensitive
+// or Unicode-normalizing file systems(e.g. NTFS and APFS), e.g. "Foo" and "foo", or "é" in NFC and NFD.
+type CollisionPolicy int
+
+const (
+	// CollisionIgnore does not detect collisions. It's the default policy.
+	// The colliding files overwrite each other on such file systems.
+	CollisionIgnore CollisionPolicy = iota
+	// CollisionFail makes the task fail with a [*CollisionError] before copying anything.
+	CollisionFail
+	// CollisionSkip skips the colliding files and directories(with the files in them).
+	// The first one in lexical order is copied.
+	CollisionSkip
+	// CollisionRename renames the colliding files and directories by appending " (n)" to their names before the extensions,
+	// e.g. "foo (1).txt".
+	CollisionRename
+)
+
+// Collision is a name collision detected by [CopyFSTask] with [WithCollisionPolicy].
+type Collision struct {
+	// Name is the slash-separated name of the file or directory in the source file system.
+	Name string `json:"name"`
+	// With is the name of the file or directory it collides with.
+	With string `json:"with"`
+	// Dst is the slash-separated name it's renamed to by [CollisionRename]. It's empty if it's skipped.
+	Dst string `json:"dst,omitempty"`
+}
+
+// CollisionError is returned by [CopyFSTask] with [CollisionFail] policy when the names collide.
+type CollisionError struct {
+	// Name is the slash-separated name of the file or directory in the source file system.
+	Name string
+	// With is the name of the file or directory it collides with.
+	With string
+}
+
+// Error implements error interface.
+func (e *CollisionError) Error() string {
+	return fmt.Sprintf("name collision: %v with %v", e.Name, e.With)
+}
+
+// collisionKey returns the key to compare the names as case-insensitive and Unicode-normalizing file systems do.
+func collisionKey(name string) string {
+	return norm.NFC.String(cases.Fold().String(name))
+}
+
+// renamed returns the name with " (n)" appended before the extension.
+func renamed(name string, n int) string {
+	ext := path.Ext(name)
+	if ext == name {
+		// Dot files, e.g. ".bashrc".
+		ext = ""
+	}
+	return fmt.Sprintf("%v (%d)%v", strings.TrimSuffix(name, ext), n, ext)
+}
+
+// resolveCollisions detects the name collisions of the directories and files by the policy.
+// The directories are resolved before the files and the files in a skipped directory are skipped.
+// It returns the directories and files to copy, their total size,
+// the destination names of the renamed ones(and the ones in them) and the collisions.
+func resolveCollisions(dirs []string, files []CopyFSFile, policy CollisionPolicy) (outDirs []string, outFiles []CopyFSFile, total int64, renames map[string]string, collisions []Collision, err error) {
+	outDirs, outFiles = []string{}, []CopyFSFile{}
+	// seen maps the keys of the destination names to the source names.
+	seen := map[string]string{}
+	skipped := map[string]bool{}
+
+	resolve := func(name string) (keep bool, err error) {
+		if name == "." {
+			seen["."] = "."
+			return true, nil
+		}
+
+		parent, base := path.Dir(name), path.Base(name)
+		if skipped[parent] {
+			skipped[name] = true
+			return false, nil
+		}
+
+		parentDst := parent
+		if dst, ok := renames[parent]; ok {
+			parentDst = dst
+		}
+
+		dst := path.Join(parentDst, base)
+		if with, ok := seen[collisionKey(dst)]; ok {
+			switch policy {
+			case CollisionFail:
+				return false, &CollisionError{Name: name, With: with}
+			case CollisionSkip:
+				skipped[name] = true
+				collisions = append(collisions, Collision{Name: name, With: with})
+				return false, nil
+			}
+
+			for i := 1; ; i++ {
+				dst = path.Join(parentDst, renamed(base, i))
+				if _, ok := seen[collisionKey(dst)]; !ok {
+					break
+				}
+			}
+			collisions = append(collisions, Collision{Name: name, With: with, Dst: dst})
+		}
+
+		seen[collisionKey(dst)] = name
+		if dst != name {
+			if renames == nil {
+				renames = map[string]string{}
+			}
+			renames[name] = dst
+		}
+		return true, nil
+	}
+
+	for _, name := range dirs {
+		keep, err := resolve(name)
+		if err != nil {
+			return nil, nil, 0, nil, nil, err
+		}
+		if keep {
+			outDirs = append(outDirs, name)
+		}
+	}
+
+	for _, f := range files {
+		keep, err := resolve(f.Name)
+		if err != nil {
+			return nil, nil, 0, nil, nil, err
+		}
+		if keep {
+			outFiles = append(outFiles, f)
+			total += f.Size
+		}
+	}
+	return outDirs, outFiles, total, renames, collisions, nil
+}
@@ -0,0 +1,422 @@
+package iocopy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"net"
+	"os"
+)
+
+// CopyFileTask implements [Task] interface to copy a file.
+type CopyFileTask struct {
+	fsys   WriteFS
+	dst    string
+	src    string
+	total  int64
+	copied int64
+	srcF   io.ReadCloser
+	pf     *PrefetchReader
+	dstF   WriteFile
+	opts   options
+	// skipped is true if the copy is skipped by [WithSkipIfMatch].
+	skipped bool
+	// digest is the digest of the destination file if it implements [Digester].
+	digest string
+	// ownerErr is the error of preserving the owner by [WithOwner].
+	ownerErr string
+	// xattrErr is the error of copying the extended attributes by [WithXattrs].
+	xattrErr string
+}
+
+// CopyFileState is the typed state of [CopyFileTask].
+type CopyFileState struct {
+	// Version is the version of the state. See [StateVersion].
+	Version int `json:"version"`
+	// Type is the type of the state to load it by [LoadTask]: [StateTypeCopyFile].
+	Type string `json:"type"`
+	// Dst is the destination file.
+	Dst string `json:"dst"`
+	// Src is the source file.
+	Src string `json:"src"`
+	// Total is the total number of bytes to copy.
+	// A negative value indicates total size is unknown.
+	Total int64 `json:"total"`
+	// Copied is the number of bytes copied.
+	Copied int64 `json:"copied"`
+}
+
+// CopyFileResult is the typed result of [CopyFileTask].
+type CopyFileResult struct {
+	// Dst is the destination file.
+	Dst string `json:"dst"`
+	// Src is the source file.
+	Src string `json:"src"`
+	// Size is the size of the destination file.
+	Size int64 `json:"size"`
+	// Skipped is true if the copy is skipped by [WithSkipIfMatch].
+	Skipped bool `json:"skipped,omitempty"`
+	// Digest is the digest of the destination file if its file system computes it, e.g. [CASFS].
+	Digest string `json:"digest,omitempty"`
+	// OwnerErr is the error of preserving the owner by [WithOwner], e.g. not permitted. It's empty if it succeeds.
+	OwnerErr string `json:"owner_err,omitempty"`
+	// XattrErr is the error of copying the extended attributes by [WithXattrs]. It's empty if it succeeds.
+	XattrErr string `json:"xattr_err,omitempty"`
+}
+
+// NewCopyFileTask returns a [*CopyFileTask] which copies src to dst.
+// src can be the standard input and dst can be the standard output([Stdio]).
+// fsys is the file system of dst. [OSFS] is used if it's nil.
+// opts: optional parameters. e.g. [WithFollow], [WithADS], [WithMmap], [WithPrefetch],
+// [WithSkipIfMatch], [WithMaxBytes], [WithCleanup], [WithRateLimiter], [WithOverlapCheck].
+// Long paths are converted to extended-length paths(`\\?\`) on Windows.
+func NewCopyFileTask(dst, src string, fsys WriteFS, opts ...Option) *CopyFileTask {
+	if fsys == nil {
+		fsys = OSFS
+	}
+
+	return &CopyFileTask{fsys: fsys, dst: dst, src: src, total: -1, opts: newOptions(opts)}
+}
+
+// LoadCopyFileTask loads a [*CopyFileTask] from the state to resume the copy.
+// fsys is the file system of dst. [OSFS] is used if it's nil.
+// opts: optional parameters which are not saved in the state.
+func LoadCopyFileTask(state []byte, fsys WriteFS, opts ...Option) (*CopyFileTask, error) {
+	var s CopyFileState
+	if err := json.Unmarshal(state, &s); err != nil {
+		return nil, err
+	}
+
+	t := NewCopyFileTask(s.Dst, s.Src, fsys, opts...)
+	t.total = s.Total
+	t.copied = s.Copied
+	return t, nil
+}
+
+// ID implements [Identifier] interface.
+// It's computed from dst and src.
+func (t *CopyFileTask) ID() string {
+	return taskID("copy", t.dst, t.src)
+}
+
+// Endpoints implements [Endpointer] interface.
+func (t *CopyFileTask) Endpoints() (src, dst string) {
+	return t.src, t.dst
+}
+
+// Open implements [Task] interface.
+// It opens the source and destination files and seeks to the copied position.
+// The source can be a named pipe(FIFO), a unix socket or the standard input([Stdio]).
+// Total size is unknown for them and they can't be resumed.
+// The destination can be the standard output([Stdio]) which can't be resumed either.
+// In follow mode([WithFollow]), total is the stop marker size.
+func (t *CopyFileTask) Open(ctx context.Context) (dst io.Writer, src io.Reader, err error) {
+	stdout := t.fsys == OSFS && isStdout(t.dst)
+	if stdout && t.copied > 0 {
+		return nil, nil, errStdioResume(t.dst)
+	}
+
+	var fi fs.FileInfo
+	if isStdin(t.src) {
+		fi, err = os.Stdin.Stat()
+	} else {
+		fi, err = os.Stat(longPath(t.src))
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !fi.Mode().IsRegular() || isStdin(t.src) {
+		if t.copied > 0 {
+			return nil, nil, fmt.Errorf("%v is not a regular file and can't be resumed", t.src)
+		}
+
+		if t.srcF, err = openIrregular(ctx, t.src, fi.Mode()); err != nil {
+			return nil, nil, err
+		}
+		t.total = -1
+	} else {
+		if t.opts.follow {
+			// The source file is growing.
+			t.total = t.opts.followStopSize
+		} else {
+			if t.copied > 0 && fi.Size() != t.total {
+				return nil, nil, fmt.Errorf("size of %v changed: %v, previous: %v", t.src, fi.Size(), t.total)
+			}
+			t.total = fi.Size()
+		}
+
+		if t.opts.overlap > 0 && t.copied > 0 && t.fsys == OSFS {
+			if err = t.checkOverlap(); err != nil {
+				return nil, nil, err
+			}
+		}
+
+		if t.opts.skip && !t.opts.follow && t.copied == 0 {
+			same, err := t.sameDst(ctx)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			if same {
+				// Nothing to copy.
+				logDebug(ctx, "iocopy: destination matches, skip copy", "src", t.src, "dst", t.dst)
+				t.skipped = true
+				t.copied = t.total
+				return io.Discard, bytes.NewReader(nil), nil
+			}
+		}
+
+		var sf io.ReadSeekCloser
+		if t.opts.directIO && !t.opts.mmap && !t.opts.follow {
+			// Fall back to read the file through the page cache if direct IO is not supported.
+			if f, err := openDirect(longPath(t.src)); err == nil {
+				sf = newDirectReader(f, t.total)
+			} else {
+				logDebug(ctx, "iocopy: direct IO not supported, fall back to read", "src", t.src, "err", err)
+			}
+		}
+
+		if sf == nil {
+			f, err := os.Open(longPath(t.src))
+			if err != nil {
+				return nil, nil, err
+			}
+
+			sf = f
+			if t.opts.mmap && !t.opts.follow {
+				// Fall back to read the file if it can't be mapped.
+				if m, err := newMmapReader(f, t.total); err == nil {
+					sf = m
+				} else {
+					logDebug(ctx, "iocopy: mmap failed, fall back to read", "src", t.src, "err", err)
+				}
+			}
+		}
+		t.srcF = sf
+
+		if _, err = sf.Seek(t.copied, io.SeekStart); err != nil {
+			t.Close()
+			return nil, nil, err
+		}
+	}
+
+	src = t.srcF
+	if t.opts.follow {
+		stopSize := t.opts.followStopSize
+		if stopSize >= 0 {
+			stopSize -= t.copied
+		}
+		src = NewFollowReader(ctx, t.srcF, t.opts.followInterval, stopSize)
+	}
+
+	src = t.opts.limit(ctx, src)
+
+	// No need to prefetch the memory-mapped file.
+	// It also avoids reading the mapped memory in the goroutine after it's unmapped.
+	if _, mapped := t.srcF.(*mmapReader); t.opts.prefetch && !mapped {
+		t.pf = NewPrefetchReader(ctx, src, t.opts.prefetchDepth, t.opts.prefetchSize)
+		src = t.pf
+	}
+
+	if t.opts.maxBytes >= 0 && t.total > t.opts.maxBytes {
+		t.Close()
+		return nil, nil, &MaxBytesError{Limit: t.opts.maxBytes}
+	}
+
+	if stdout {
+		dst = os.Stdout
+	} else {
+		if t.dstF, err = openDst(t.fsys, t.dst, t.total, t.copied); err != nil {
+			t.Close()
+			return nil, nil, err
+		}
+		dst = t.dstF
+	}
+
+	if t.opts.maxBytes >= 0 {
+		return t.opts.throttle(ctx, newMaxBytesWriter(dst, t.opts.maxBytes, t.copied)), src, nil
+	}
+
+	return t.opts.throttle(ctx, dst), src, nil
+}
+
+// sameDst reports whether the destination file exists and matches the source file.
+// See [WithSkipIfMatch].
+func (t *CopyFileTask) sameDst(ctx context.Context) (bool, error) {
+	if t.fsys != OSFS {
+		return false, nil
+	}
+
+	fi, err := os.Stat(longPath(t.dst))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	if !fi.Mode().IsRegular() || fi.Size() != t.total {
+		return false, nil
+	}
+
+	if t.opts.skipHash == nil {
+		return true, nil
+	}
+
+	srcSum, err := fileSum(ctx, t.src, t.opts.skipHash())
+	if err != nil {
+		return false, err
+	}
+
+	dstSum, err := fileSum(ctx, t.dst, t.opts.skipHash())
+	if err != nil {
+		return false, err
+	}
+
+	return bytes.Equal(srcSum, dstSum), nil
+}
+
+// fileSum computes the checksum of the file by h.
+func fileSum(ctx context.Context, name string, h hash.Hash) ([]byte, error) {
+	f, err := os.Open(longPath(name))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	if _, err = Copy(ctx, h, f); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
+
+// openIrregular opens the source which is not a regular file.
+// It connects to the unix socket or opens the named pipe(FIFO) and other files by [os.Open].
+// The standard input is not closed when it's closed.
+func openIrregular(ctx context.Context, name string, mode fs.FileMode) (io.ReadCloser, error) {
+	if isStdin(name) {
+		return io.NopCloser(os.Stdin), nil
+	}
+
+	if mode&fs.ModeSocket != 0 {
+		var d net.Dialer
+		return d.DialContext(ctx, "unix", name)
+	}
+
+	if mode.IsDir() {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: errors.New("is a directory")}
+	}
+
+	return os.Open(longPath(name))
+}
+
+// Close implements [Task] interface.
+// It copies the NTFS alternate data streams after the copy is done if [WithADS] is set.
+func (t *CopyFileTask) Close() error {
+	var err error
+
+	if t.pf != nil {
+		t.pf.Close()
+		t.pf = nil
+	}
+
+	if t.srcF != nil {
+		t.srcF.Close()
+		t.srcF = nil
+	}
+
+	if t.dstF != nil {
+		err = t.dstF.Close()
+		if d, ok := t.dstF.(Digester); ok {
+			t.digest = d.Digest()
+		}
+		t.dstF = nil
+
+		if err == nil && t.opts.ads && t.fsys == OSFS && t.total >= 0 && t.copied == t.total {
+			err = copyStreams(t.dst, t.src)
+		}
+
+		if err == nil && t.opts.owner && t.fsys == OSFS && t.total >= 0 && t.copied == t.total {
+			t.ownerErr = ""
+			if fi, statErr := os.Stat(longPath(t.src)); statErr != nil {
+				t.ownerErr = statErr.Error()
+			} else if ownerErr := preserveOwner(t.dst, fi); ownerErr != nil {
+				// Degrade gracefully and report it in the result.
+				t.ownerErr = ownerErr.Error()
+			}
+		}
+
+		if err == nil && t.opts.xattrs != 0 && t.fsys == OSFS && t.total >= 0 && t.copied == t.total {
+			t.xattrErr = ""
+			if xattrErr := copyXattrs(t.dst, t.src, t.opts.xattrs); xattrErr != nil {
+				t.xattrErr = xattrErr.Error()
+			}
+		}
+	}
+
+	return err
+}
+
+// Cleanup implements [Cleaner] interface.
+// It cleans up the partial destination file by the policy set by [WithCleanup]
+// and resets the number of bytes copied to restart.
+func (t *CopyFileTask) Cleanup(cause error) error {
+	if t.opts.cleanup == CleanupKeep || t.fsys != OSFS || isStdout(t.dst) {
+		return nil
+	}
+
+	state, err := t.State()
+	if err != nil {
+		return err
+	}
+
+	if err = cleanupDst(t.dst, t.opts.cleanup, t.opts.trashDir, state, cause); err != nil {
+		return err
+	}
+
+	t.copied = 0
+	return nil
+}
+
+// Total implements [Task] interface.
+func (t *CopyFileTask) Total() int64 {
+	return t.total
+}
+
+// Copied implements [Task] interface.
+func (t *CopyFileTask) Copied() int64 {
+	return t.copied
+}
+
+// SetCopied implements [Task] interface.
+func (t *CopyFileTask) SetCopied(copied int64) {
+	t.copied = copied
+}
+
+// State implements [Task] interface.
+func (t *CopyFileTask) State() ([]byte, error) {
+	return json.Marshal(t.StateValue())
+}
+
+// StateValue implements [StateValuer] interface.
+// It returns the [CopyFileState].
+func (t *CopyFileTask) StateValue() any {
+	return CopyFileState{Version: StateVersion, Type: StateTypeCopyFile, Dst: t.dst, Src: t.src, Total: t.total, Copied: t.copied}
+}
+
+// Result implements [Task] interface.
+func (t *CopyFileTask) Result() ([]byte, error) {
+	return json.Marshal(t.ResultValue())
+}
+
+// ResultValue implements [ResultValuer] interface.
+// It returns the [CopyFileResult].
+func (t *CopyFileTask) ResultValue() any {
+	return CopyFileResult{Dst: t.dst, Src: t.src, Size: t.copied, Skipped: t.skipped, Digest: t.digest, OwnerErr: t.ownerErr, XattrErr: t.xattrErr}
+}
@@ -0,0 +1,278 @@
+package iocopy_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/northbright/iocopy"
+)
+
+func ExampleCopyFileTask() {
+	// This example uses iocopy.CopyFileTask to copy a file.
+	// It stops the task after the first bytes written to emulate user cancelation.
+	// Then it loads the task from the saved state to resume the copy.
+	dir, err := os.MkdirTemp("", "iocopy")
+	if err != nil {
+		log.Printf("os.MkdirTemp() error: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	src := filepath.Join(dir, "src")
+	dst := filepath.Join(dir, "dst", "file")
+	data := bytes.Repeat([]byte("0123456789abcdef"), 256)
+	if err = os.WriteFile(src, data, 0644); err != nil {
+		log.Printf("os.WriteFile() error: %v", err)
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var state []byte
+	buf := make([]byte, 1024)
+
+	t := iocopy.NewCopyFileTask(dst, src, nil)
+	iocopy.Do(ctx, t, buf, func(e iocopy.Event) {
+		switch e := e.(type) {
+		case *iocopy.EventWritten:
+			fmt.Printf("%v/%v bytes copied(%.2f%%)\n", e.Copied, e.Total, e.Percent)
+			// Emulate user cancelation.
+			cancel()
+		case *iocopy.EventStop:
+			fmt.Printf("stopped: %v\n", e.Err)
+			state = e.State
+		}
+	})
+
+	// Load the task from the state and resume.
+	t, err = iocopy.LoadCopyFileTask(state, nil)
+	if err != nil {
+		log.Printf("iocopy.LoadCopyFileTask() error: %v", err)
+		return
+	}
+
+	iocopy.Do(context.Background(), t, buf, func(e iocopy.Event) {
+		switch e := e.(type) {
+		case *iocopy.EventWritten:
+			fmt.Printf("%v/%v bytes copied(%.2f%%)\n", e.Copied, e.Total, e.Percent)
+		case *iocopy.EventOK:
+			fmt.Printf("done\n")
+		case *iocopy.EventError:
+			log.Printf("error: %v", e.Err)
+		}
+	})
+
+	copied, err := os.ReadFile(dst)
+	if err != nil {
+		log.Printf("os.ReadFile() error: %v", err)
+		return
+	}
+	fmt.Printf("same content: %v\n", bytes.Equal(copied, data))
+
+	// Output:
+	// 1024/4096 bytes copied(25.00%)
+	// stopped: context canceled
+	// 2048/4096 bytes copied(50.00%)
+	// 3072/4096 bytes copied(75.00%)
+	// 4096/4096 bytes copied(100.00%)
+	// done
+	// same content: true
+}
+
+func ExampleWithMmap() {
+	// This example copies a file by reading it through memory mapping.
+	dir, err := os.MkdirTemp("", "iocopy")
+	if err != nil {
+		log.Printf("os.MkdirTemp() error: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	src := filepath.Join(dir, "src")
+	dst := filepath.Join(dir, "dst")
+	data := bytes.Repeat([]byte("0123456789abcdef"), 256)
+	if err = os.WriteFile(src, data, 0644); err != nil {
+		log.Printf("os.WriteFile() error: %v", err)
+		return
+	}
+
+	t := iocopy.NewCopyFileTask(dst, src, nil, iocopy.WithMmap())
+	if err = iocopy.Do(context.Background(), t, make([]byte, 1024), func(e iocopy.Event) {
+		if e, ok := e.(*iocopy.EventWritten); ok {
+			fmt.Printf("%v/%v bytes copied(%.2f%%)\n", e.Copied, e.Total, e.Percent)
+		}
+	}); err != nil {
+		log.Printf("iocopy.Do() error: %v", err)
+		return
+	}
+
+	copied, err := os.ReadFile(dst)
+	if err != nil {
+		log.Printf("os.ReadFile() error: %v", err)
+		return
+	}
+	fmt.Printf("same content: %v\n", bytes.Equal(copied, data))
+
+	// Output:
+	// 1024/4096 bytes copied(25.00%)
+	// 2048/4096 bytes copied(50.00%)
+	// 3072/4096 bytes copied(75.00%)
+	// 4096/4096 bytes copied(100.00%)
+	// same content: true
+}
+
+func ExampleWithSkipIfMatch() {
+	// This example skips the copy because the destination file has the same size and SHA-256 checksum.
+	// The final written event is still reported to complete the progress.
+	dir, err := os.MkdirTemp("", "iocopy")
+	if err != nil {
+		log.Printf("os.MkdirTemp() error: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	src := filepath.Join(dir, "src")
+	dst := filepath.Join(dir, "dst")
+	data := bytes.Repeat([]byte("0123456789abcdef"), 256)
+	for _, name := range []string{src, dst} {
+		if err = os.WriteFile(name, data, 0644); err != nil {
+			log.Printf("os.WriteFile() error: %v", err)
+			return
+		}
+	}
+
+	t := iocopy.NewCopyFileTask(dst, src, nil, iocopy.WithSkipIfMatch(sha256.New))
+	if err = iocopy.Do(context.Background(), t, nil, func(e iocopy.Event) {
+		switch e := e.(type) {
+		case *iocopy.EventWritten:
+			fmt.Printf("%v/%v bytes copied\n", e.Copied, e.Total)
+		case *iocopy.EventOK:
+			var result struct {
+				Size    int64 `json:"size"`
+				Skipped bool  `json:"skipped"`
+			}
+			json.Unmarshal(e.Result, &result)
+			fmt.Printf("size: %v, skipped: %v\n", result.Size, result.Skipped)
+		}
+	}); err != nil {
+		log.Printf("iocopy.Do() error: %v", err)
+		return
+	}
+
+	// Output:
+	// 4096/4096 bytes copied
+	// size: 4096, skipped: true
+}
+
+func ExampleCopyFileResult() {
+	// This example reads the typed result of a copy task from iocopy.EventOK without decoding the JSON result.
+	dir, err := os.MkdirTemp("", "iocopy")
+	if err != nil {
+		log.Printf("os.MkdirTemp() error: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	src := filepath.Join(dir, "src")
+	if err = os.WriteFile(src, []byte("Hello, World!"), 0644); err != nil {
+		log.Printf("os.WriteFile() error: %v", err)
+		return
+	}
+
+	t := iocopy.NewCopyFileTask(filepath.Join(dir, "dst"), src, nil)
+	iocopy.Do(context.Background(), t, nil, func(e iocopy.Event) {
+		if e, ok := e.(*iocopy.EventOK); ok {
+			r := e.Value.(iocopy.CopyFileResult)
+			fmt.Printf("dst: %v, size: %v, duration >= 0: %v\n", filepath.Base(r.Dst), r.Size, e.Duration >= 0)
+		}
+	})
+
+	// Output:
+	// dst: dst, size: 13, duration >= 0: true
+}
+
+func ExampleWithOverlapCheck_copyFile() {
+	// This example resumes copying a file with the overlap check.
+	// The copy fails if the copied bytes of the source file are changed and it resumes if they're not.
+	dir, err := os.MkdirTemp("", "iocopy")
+	if err != nil {
+		log.Printf("os.MkdirTemp() error: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	src := filepath.Join(dir, "src")
+	data := bytes.Repeat([]byte("0123456789abcdef"), 64*1024)
+	if err = os.WriteFile(src, data, 0644); err != nil {
+		log.Printf("os.WriteFile() error: %v", err)
+		return
+	}
+
+	// stop copies the file to dst and stops the copy at 50%.
+	stop := func(dst string) []byte {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		var state []byte
+		t := iocopy.NewCopyFileTask(dst, src, nil)
+		iocopy.Do(ctx, t, make([]byte, 4096), func(e iocopy.Event) {
+			switch e := e.(type) {
+			case *iocopy.EventWritten:
+				if e.Percent >= 50 {
+					cancel()
+				}
+			case *iocopy.EventStop:
+				state = e.State
+			}
+		})
+		return state
+	}
+
+	// Resume the copy while the source file is not changed.
+	state := stop(filepath.Join(dir, "dst"))
+	t, err := iocopy.LoadCopyFileTask(state, nil, iocopy.WithOverlapCheck(4096))
+	if err != nil {
+		log.Printf("iocopy.LoadCopyFileTask() error: %v", err)
+		return
+	}
+
+	if err = iocopy.Do(context.Background(), t, nil, nil); err != nil {
+		log.Printf("iocopy.Do() error: %v", err)
+		return
+	}
+
+	buf, err := os.ReadFile(filepath.Join(dir, "dst"))
+	if err != nil {
+		log.Printf("os.ReadFile() error: %v", err)
+		return
+	}
+	fmt.Printf("resumed, same content: %v\n", bytes.Equal(buf, data))
+
+	// Change the tail of the copied bytes of the source file without changing its size.
+	state = stop(filepath.Join(dir, "dst2"))
+	if t, err = iocopy.LoadCopyFileTask(state, nil, iocopy.WithOverlapCheck(4096)); err != nil {
+		log.Printf("iocopy.LoadCopyFileTask() error: %v", err)
+		return
+	}
+
+	copy(data[t.Copied()-10:], "changed!!!")
+	if err = os.WriteFile(src, data, 0644); err != nil {
+		log.Printf("os.WriteFile() error: %v", err)
+		return
+	}
+
+	err = iocopy.Do(context.Background(), t, nil, nil)
+	fmt.Printf("overlap mismatch: %v\n", errors.Is(err, iocopy.ErrOverlapMismatch))
+
+	// Output:
+	// resumed, same content: true
+	// overlap mismatch: true
+}
@@ -0,0 +1,107 @@
+//go:build linux || darwin
+
+package iocopy_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"syscall"
+
+	"github.com/northbright/iocopy"
+)
+
+func ExampleCopyFileTask_namedPipe() {
+	// This example copies from a named pipe(FIFO) whose size is unknown.
+	dir, err := os.MkdirTemp("", "iocopy")
+	if err != nil {
+		log.Printf("os.MkdirTemp() error: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	src := filepath.Join(dir, "fifo")
+	if err = syscall.Mkfifo(src, 0644); err != nil {
+		log.Printf("syscall.Mkfifo() error: %v", err)
+		return
+	}
+
+	data := bytes.Repeat([]byte("a"), 64*1024)
+	go func() {
+		f, err := os.OpenFile(src, os.O_WRONLY, 0)
+		if err != nil {
+			log.Printf("os.OpenFile() error: %v", err)
+			return
+		}
+		defer f.Close()
+		f.Write(data)
+	}()
+
+	dst := filepath.Join(dir, "dst")
+	t := iocopy.NewCopyFileTask(dst, src, nil)
+
+	written := false
+	err = iocopy.Do(context.Background(), t, nil, func(e iocopy.Event) {
+		if e, ok := e.(*iocopy.EventWritten); ok && e.Total < 0 {
+			// Total size is unknown, show a spinner with bytes copied.
+			written = true
+		}
+	})
+	if err != nil {
+		log.Printf("iocopy.Do() error: %v", err)
+		return
+	}
+
+	fmt.Printf("written events reported: %v\n", written)
+	fmt.Printf("%v bytes copied, total: %v\n", t.Copied(), t.Total())
+
+	// Output:
+	// written events reported: true
+	// 65536 bytes copied, total: -1
+}
+
+func ExampleCopyFileTask_unixSocket() {
+	// This example copies from a unix socket.
+	dir, err := os.MkdirTemp("", "iocopy")
+	if err != nil {
+		log.Printf("os.MkdirTemp() error: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	src := filepath.Join(dir, "sock")
+	l, err := net.Listen("unix", src)
+	if err != nil {
+		log.Printf("net.Listen() error: %v", err)
+		return
+	}
+	defer l.Close()
+
+	data := bytes.Repeat([]byte("a"), 64*1024)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			log.Printf("l.Accept() error: %v", err)
+			return
+		}
+		defer conn.Close()
+		conn.Write(data)
+	}()
+
+	dst := filepath.Join(dir, "dst")
+	t := iocopy.NewCopyFileTask(dst, src, nil)
+
+	if err = iocopy.Do(context.Background(), t, nil, nil); err != nil {
+		log.Printf("iocopy.Do() error: %v", err)
+		return
+	}
+
+	fmt.Printf("%v bytes copied, total: %v\n", t.Copied(), t.Total())
+
+	// Output:
+	// 65536 bytes copied, total: -1
+}
@@ -0,0 +1,59 @@
+//go:build windows
+
+package iocopy_test
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/northbright/iocopy"
+)
+
+func ExampleWithADS() {
+	// This example copies a file in a deep directory tree whose path exceeds MAX_PATH,
+	// with an NTFS alternate data stream.
+	dir, err := os.MkdirTemp("", "iocopy")
+	if err != nil {
+		log.Printf("os.MkdirTemp() error: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	src := filepath.Join(dir, "src")
+	if err = os.WriteFile(src, []byte("hello"), 0644); err != nil {
+		log.Printf("os.WriteFile() error: %v", err)
+		return
+	}
+
+	// Write an alternate data stream.
+	if err = os.WriteFile(src+":tag", []byte("world"), 0644); err != nil {
+		log.Printf("os.WriteFile() error: %v", err)
+		return
+	}
+
+	deep := filepath.Join(dir, strings.Repeat("d", 100), strings.Repeat("e", 100), strings.Repeat("f", 100))
+	dst := filepath.Join(deep, "dst")
+	t := iocopy.NewCopyFileTask(dst, src, nil, iocopy.WithADS())
+
+	if err = iocopy.Do(context.Background(), t, nil, nil); err != nil {
+		log.Printf("iocopy.Do() error: %v", err)
+		return
+	}
+
+	fmt.Printf("%v bytes copied, total: %v\n", t.Copied(), t.Total())
+
+	data, err := os.ReadFile(dst + ":tag")
+	if err != nil {
+		log.Printf("os.ReadFile() error: %v", err)
+		return
+	}
+	fmt.Printf("stream: %s\n", data)
+
+	// Output:
+	// 5 bytes copied, total: 5
+	// stream: world
+}
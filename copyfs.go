@@ -0,0 +1,724 @@
+package iocopy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+)
+
+// CopyFSTask implements [Task] interface to copy a file system(e.g. [embed.FS] or [zip.Reader]) to a directory recursively,
+// like [os.CopyFS] but cancelable, progress-reporting, resumable and filterable.
+// The files are scanned when it's opened for the first time and the total is the sum of their sizes.
+// Only directories and regular files are copied. Executable bits of files are preserved on [OSFS].
+type CopyFSTask struct {
+	fsys   WriteFS
+	dir    string
+	src    fs.FS
+	files  []CopyFSFile
+	dirs   []string
+	total  int64
+	copied int64
+	r      *fsReader
+	w      *copyFSWriter
+	pf     *PrefetchReader
+	opts   options
+	// ownerErrs and ownerErr are the number and the first error of the failures of [WithOwner].
+	ownerErrs int
+	ownerErr  string
+	// xattrErrs and xattrErr are the number and the first error of the failures of [WithXattrs].
+	xattrErrs int
+	xattrErr  string
+	// renames maps the source names to the destination names renamed by [CollisionRename].
+	renames    map[string]string
+	collisions []Collision
+	// digests maps the keys of the files copied to their names and links maps the files linked to their targets
+	// by [WithHardlinkDedup].
+	digests map[string]string
+	links   map[string]string
+}
+
+// CopyFSFile is a file to copy by [CopyFSTask].
+type CopyFSFile struct {
+	// Name is the slash-separated name of the file in the source file system.
+	Name string `json:"name"`
+	// Size is the size of the file.
+	Size int64 `json:"size"`
+	// Mode is the permission bits of the file.
+	Mode fs.FileMode `json:"mode"`
+}
+
+// CopyFSState is the typed state of [CopyFSTask].
+type CopyFSState struct {
+	// Version is the version of the state. See [StateVersion].
+	Version int `json:"version"`
+	// Type is the type of the state to load it by [LoadTask]: [StateTypeCopyFS].
+	Type string `json:"type"`
+	// Dir is the destination directory.
+	Dir string `json:"dir"`
+	// Dirs are the slash-separated names of the directories to create.
+	// They're nil if the source is not scanned yet.
+	Dirs []string `json:"dirs"`
+	// Files are the files to copy in order.
+	// They're nil if the source is not scanned yet.
+	Files []CopyFSFile `json:"files"`
+	// Total is the total number of bytes to copy.
+	// A negative value indicates the source is not scanned yet.
+	Total int64 `json:"total"`
+	// Copied is the number of bytes copied.
+	Copied int64 `json:"copied"`
+	// Renames maps the names of the files and directories renamed by [CollisionRename] to their destination names.
+	Renames map[string]string `json:"renames,omitempty"`
+	// Collisions are the name collisions detected by [WithCollisionPolicy].
+	Collisions []Collision `json:"collisions,omitempty"`
+	// Digests maps the sizes, permission bits and checksums of the files copied to their names by [WithHardlinkDedup].
+	Digests map[string]string `json:"digests,omitempty"`
+	// Links maps the names of the files linked by [WithHardlinkDedup] to the names of the identical files.
+	Links map[string]string `json:"links,omitempty"`
+}
+
+// CopyFSResult is the typed result of [CopyFSTask].
+type CopyFSResult struct {
+	// Dir is the destination directory.
+	Dir string `json:"dir"`
+	// Dirs is the number of directories created.
+	Dirs int `json:"dirs"`
+	// Files is the number of files copied.
+	Files int `json:"files"`
+	// Size is the total size of the files copied.
+	Size int64 `json:"size"`
+	// OwnerErrs is the number of the files and directories whose owners fail to be preserved by [WithOwner].
+	OwnerErrs int `json:"owner_errs,omitempty"`
+	// OwnerErr is the first error of preserving the owners, e.g. not permitted. It's empty if they're preserved.
+	OwnerErr string `json:"owner_err,omitempty"`
+	// XattrErrs is the number of the files and directories whose extended attributes fail to be copied by [WithXattrs].
+	XattrErrs int `json:"xattr_errs,omitempty"`
+	// XattrErr is the first error of copying the extended attributes. It's empty if they're copied.
+	XattrErr string `json:"xattr_err,omitempty"`
+	// Collisions are the name collisions detected by [WithCollisionPolicy] and how they're resolved.
+	Collisions []Collision `json:"collisions,omitempty"`
+	// Links is the number of the files replaced with hardlinks by [WithHardlinkDedup].
+	Links int `json:"links,omitempty"`
+	// Saved is the total size of the files replaced with hardlinks, which is the space saved.
+	Saved int64 `json:"saved,omitempty"`
+}
+
+// NewCopyFSTask returns a [*CopyFSTask] which copies src to dir.
+// fsys is the file system of dir. [OSFS] is used if it's nil.
+// opts: optional parameters. e.g. [WithFilter], [WithExtractLimits], [WithPrefetch], [WithRateLimiter].
+func NewCopyFSTask(dir string, src fs.FS, fsys WriteFS, opts ...Option) *CopyFSTask {
+	if fsys == nil {
+		fsys = OSFS
+	}
+
+	return &CopyFSTask{fsys: fsys, dir: dir, src: src, total: -1, opts: newOptions(opts)}
+}
+
+// LoadCopyFSTask loads a [*CopyFSTask] from the state to resume the copy.
+// src is the source file system which can't be saved in the state.
+// The files scanned previously are copied and [WithFilter] is not needed.
+// fsys is the file system of the destination directory. [OSFS] is used if it's nil.
+// opts: optional parameters which are not saved in the state.
+func LoadCopyFSTask(state []byte, src fs.FS, fsys WriteFS, opts ...Option) (*CopyFSTask, error) {
+	var s CopyFSState
+	if err := json.Unmarshal(state, &s); err != nil {
+		return nil, err
+	}
+
+	if err := (stateHeader{s.Version, s.Type}).check(StateTypeCopyFS); err != nil {
+		return nil, err
+	}
+
+	t := NewCopyFSTask(s.Dir, src, fsys, opts...)
+	t.dirs = s.Dirs
+	t.files = s.Files
+	t.total = s.Total
+	t.copied = s.Copied
+	t.renames = s.Renames
+	t.collisions = s.Collisions
+	t.digests = s.Digests
+	t.links = s.Links
+	return t, nil
+}
+
+// scan walks the source file system to get the directories and files to copy.
+func (t *CopyFSTask) scan(ctx context.Context) (err error) {
+	t.dirs, t.files, t.total, err = scanFS(ctx, t.src, t.opts.filter)
+	if err != nil || t.opts.collision == CollisionIgnore {
+		return err
+	}
+
+	t.dirs, t.files, t.total, t.renames, t.collisions, err = resolveCollisions(t.dirs, t.files, t.opts.collision)
+	if err != nil {
+		t.dirs, t.files, t.total = nil, nil, -1
+	}
+	return err
+}
+
+// scanFS walks fsys to get the directories and regular files filtered by filter(if it's not nil)
+// and the total size of the files. Other types of files are not supported.
+func scanFS(ctx context.Context, fsys fs.FS, filter func(name string, d fs.DirEntry) bool) (dirs []string, files []CopyFSFile, total int64, err error) {
+	dirs, files = []string{}, []CopyFSFile{}
+
+	err = fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if err = ctx.Err(); err != nil {
+			return err
+		}
+
+		if name != "." && filter != nil && !filter(name, d) {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+
+		if _, err = filepath.Localize(name); err != nil {
+			return &fs.PathError{Op: "CopyFS", Path: name, Err: err}
+		}
+
+		switch {
+		case d.IsDir():
+			dirs = append(dirs, name)
+		case d.Type().IsRegular():
+			fi, err := d.Info()
+			if err != nil {
+				return err
+			}
+			files = append(files, CopyFSFile{Name: name, Size: fi.Size(), Mode: fi.Mode().Perm()})
+			total += fi.Size()
+		default:
+			return &fs.PathError{Op: "CopyFS", Path: name, Err: fs.ErrInvalid}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	return dirs, files, total, nil
+}
+
+// dstPath returns the path of the destination of the slash-separated name.
+// The name is the renamed one if it's renamed by [CollisionRename].
+func (t *CopyFSTask) dstPath(name string) (string, error) {
+	if dst, ok := t.renames[name]; ok {
+		name = dst
+	}
+
+	local, err := filepath.Localize(name)
+	if err != nil {
+		return "", &fs.PathError{Op: "CopyFS", Path: name, Err: err}
+	}
+	return filepath.Join(t.dir, local), nil
+}
+
+// Open implements [Task] interface.
+// It scans the source if it's not scanned yet, creates the directories and empty files,
+// and opens the file at the copied position.
+func (t *CopyFSTask) Open(ctx context.Context) (dst io.Writer, src io.Reader, err error) {
+	if t.files == nil {
+		if err = t.scan(ctx); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// The sizes scanned are enforced by reading.
+	if err = t.opts.extractLimits.check(t.src, t.dirs, t.files); err != nil {
+		return nil, nil, err
+	}
+
+	for _, name := range t.dirs {
+		dir, err := t.dstPath(name)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if err = mkdirAll(t.fsys, dir, 0755); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// Empty files are never written.
+	for _, f := range t.files {
+		if f.Size == 0 {
+			if err = t.writeEmpty(f); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+
+	i, off := t.position()
+	t.r = &fsReader{src: t.src, files: t.files, i: i, off: off, small: t.opts.smallSize, workers: t.opts.smallWorkers}
+	t.w = &copyFSWriter{t: t, i: i, off: off}
+	if t.opts.smallSize > 0 && t.fsys == OSFS {
+		t.w.pool = newSmallWriter(t.w, t.opts.smallWorkers)
+	}
+
+	src = t.r
+	src = t.opts.limit(ctx, src)
+
+	if t.opts.prefetch {
+		t.pf = NewPrefetchReader(ctx, src, t.opts.prefetchDepth, t.opts.prefetchSize)
+		src = t.pf
+	}
+
+	return t.opts.throttle(ctx, t.w), src, nil
+}
+
+// position returns the index of the file and the offset in it of the copied position.
+func (t *CopyFSTask) position() (int, int64) {
+	off := t.copied
+	for i, f := range t.files {
+		if off < f.Size {
+			return i, off
+		}
+		off -= f.Size
+	}
+	return len(t.files), 0
+}
+
+// offset returns the copied position of the beginning of the file i.
+func (t *CopyFSTask) offset(i int) int64 {
+	var off int64
+	for _, f := range t.files[:i] {
+		off += f.Size
+	}
+	return off
+}
+
+// filesProgress returns the progress of the file being copied at the copied position of the files.
+// A file which is just done is reported as 100% until the bytes of the next file are copied.
+func filesProgress(files []CopyFSFile, copied int64) (FileProgress, bool) {
+	if len(files) == 0 {
+		return FileProgress{}, false
+	}
+
+	i, off := len(files)-1, files[len(files)-1].Size
+	for j, f := range files {
+		if copied <= f.Size {
+			i, off = j, copied
+			break
+		}
+		copied -= f.Size
+	}
+
+	// The files before i are done and so is the file i if all of its bytes are copied.
+	done := i
+	if off >= files[i].Size {
+		done++
+	}
+
+	f := files[i]
+	return FileProgress{Index: i, Count: len(files), Done: done, Name: f.Name, Total: f.Size, Copied: off, Percent: computePercent(f.Size, 0, off)}, true
+}
+
+// FileProgress implements [FileProgresser] interface.
+func (t *CopyFSTask) FileProgress() (FileProgress, bool) {
+	return filesProgress(t.files, t.copied)
+}
+
+// writeEmpty creates the empty file.
+func (t *CopyFSTask) writeEmpty(f CopyFSFile) error {
+	name, err := t.dstPath(f.Name)
+	if err != nil {
+		return err
+	}
+
+	w, err := openDst(t.fsys, name, 0, 0)
+	if err != nil {
+		return err
+	}
+
+	if err = w.Close(); err != nil {
+		return err
+	}
+	return t.chmod(name, f.Mode)
+}
+
+// chmod preserves the executable bits of the file on [OSFS].
+func (t *CopyFSTask) chmod(name string, mode fs.FileMode) error {
+	return chmodExec(t.fsys, name, mode)
+}
+
+// chmodExec preserves the executable bits of mode of the file on [OSFS].
+func chmodExec(fsys WriteFS, name string, mode fs.FileMode) error {
+	if fsys != OSFS || mode&0111 == 0 {
+		return nil
+	}
+	return os.Chmod(longPath(name), 0644|mode&0111)
+}
+
+// Close implements [Task] interface.
+func (t *CopyFSTask) Close() error {
+	if t.pf != nil {
+		t.pf.Close()
+		t.pf = nil
+	}
+
+	if t.r != nil {
+		t.r.close()
+		t.r = nil
+	}
+
+	var err error
+	if t.w != nil {
+		err = t.w.close()
+		if flushErr := t.w.flush(); err == nil {
+			err = flushErr
+		}
+		t.w = nil
+
+		if err == nil && t.opts.owner && t.fsys == OSFS && t.copied == t.total {
+			t.ownerErrs, t.ownerErr = t.forEachCopied(func(name, dst string) error {
+				fi, err := fs.Stat(t.src, name)
+				if err != nil {
+					return err
+				}
+				return preserveOwner(dst, fi)
+			})
+		}
+
+		if err == nil && t.opts.xattrs != 0 && t.fsys == OSFS && t.copied == t.total {
+			t.xattrErrs, t.xattrErr = t.forEachCopied(func(name, dst string) error {
+				f, err := t.src.Open(name)
+				if err != nil {
+					return err
+				}
+				defer f.Close()
+
+				// Only OS directories(e.g. os.DirFS) have the paths to read the attributes.
+				if osf, ok := f.(*os.File); ok {
+					return copyXattrs(dst, osf.Name(), t.opts.xattrs)
+				}
+				return nil
+			})
+		}
+	}
+	return err
+}
+
+// forEachCopied calls fn with the names and the destination paths of the directories and files copied.
+// It calls fn for all of them even if it fails and returns the number of the failures and the first error.
+func (t *CopyFSTask) forEachCopied(fn func(name, dst string) error) (fails int, first string) {
+	names := slices.Clone(t.dirs)
+	for _, f := range t.files {
+		names = append(names, f.Name)
+	}
+
+	for _, name := range names {
+		dst, err := t.dstPath(name)
+		if err == nil {
+			err = fn(name, dst)
+		}
+
+		if err != nil {
+			if fails == 0 {
+				first = err.Error()
+			}
+			fails++
+		}
+	}
+	return fails, first
+}
+
+// Endpoints implements [Endpointer] interface. src is empty because the source file system has no name.
+func (t *CopyFSTask) Endpoints() (src, dst string) {
+	return "", t.dir
+}
+
+// Total implements [Task] interface.
+func (t *CopyFSTask) Total() int64 {
+	return t.total
+}
+
+// Copied implements [Task] interface.
+func (t *CopyFSTask) Copied() int64 {
+	return t.copied
+}
+
+// SetCopied implements [Task] interface.
+func (t *CopyFSTask) SetCopied(copied int64) {
+	t.copied = copied
+}
+
+// State implements [Task] interface.
+func (t *CopyFSTask) State() ([]byte, error) {
+	return json.Marshal(t.StateValue())
+}
+
+// StateValue implements [StateValuer] interface.
+// It returns the [CopyFSState].
+func (t *CopyFSTask) StateValue() any {
+	return CopyFSState{Version: StateVersion, Type: StateTypeCopyFS, Dir: t.dir, Dirs: t.dirs, Files: t.files, Total: t.total, Copied: t.copied, Renames: t.renames, Collisions: t.collisions, Digests: t.digests, Links: t.links}
+}
+
+// Result implements [Task] interface.
+func (t *CopyFSTask) Result() ([]byte, error) {
+	return json.Marshal(t.ResultValue())
+}
+
+// ResultValue implements [ResultValuer] interface.
+// It returns the [CopyFSResult].
+func (t *CopyFSTask) ResultValue() any {
+	links, saved := t.saved()
+	return CopyFSResult{Dir: t.dir, Dirs: len(t.dirs), Files: len(t.files), Size: t.copied, OwnerErrs: t.ownerErrs, OwnerErr: t.ownerErr, XattrErrs: t.xattrErrs, XattrErr: t.xattrErr, Collisions: t.collisions, Links: links, Saved: saved}
+}
+
+// fsReader reads the files of src one by one from the position.
+type fsReader struct {
+	src   fs.FS
+	files []CopyFSFile
+	// i is the index of the file to read.
+	i int
+	// off is the offset of the file to read.
+	off int64
+	f   fs.File
+	// small and workers are the max size of the small files and the number of the workers of [WithSmallFiles].
+	small   int64
+	workers int
+	batch   *smallBatch
+}
+
+// Read implements [io.Reader] interface.
+func (r *fsReader) Read(p []byte) (int, error) {
+	for {
+		if r.batch != nil {
+			if r.batch.off < len(r.batch.buf) {
+				n := copy(p, r.batch.buf[r.batch.off:])
+				r.batch.off += n
+				return n, nil
+			}
+			r.i, r.off, r.batch = r.batch.end, 0, nil
+		}
+
+		if r.i >= len(r.files) {
+			return 0, io.EOF
+		}
+
+		file := r.files[r.i]
+		if r.off >= file.Size {
+			r.close()
+			r.i++
+			r.off = 0
+			continue
+		}
+
+		if r.f == nil && r.off == 0 && file.Size <= r.small {
+			batch, err := readSmallFiles(r.src, r.files, r.i, r.small, r.workers)
+			if err != nil {
+				return 0, err
+			}
+			r.batch = batch
+			continue
+		}
+
+		if r.f == nil {
+			if err := r.open(file); err != nil {
+				return 0, err
+			}
+		}
+
+		n, err := r.f.Read(p[:min(int64(len(p)), file.Size-r.off)])
+		r.off += int64(n)
+		if err == io.EOF {
+			if r.off < file.Size {
+				return n, fmt.Errorf("size of %v changed: %w", file.Name, io.ErrUnexpectedEOF)
+			}
+			err = nil
+		}
+		return n, err
+	}
+}
+
+// open opens the file and skips to the offset.
+func (r *fsReader) open(file CopyFSFile) error {
+	f, err := r.src.Open(file.Name)
+	if err != nil {
+		return err
+	}
+	r.f = f
+
+	if r.off == 0 {
+		return nil
+	}
+
+	if s, ok := f.(io.Seeker); ok {
+		_, err = s.Seek(r.off, io.SeekStart)
+	} else {
+		// e.g. compressed files of zip.
+		_, err = io.CopyN(io.Discard, f, r.off)
+	}
+	return err
+}
+
+// close closes the file being read.
+func (r *fsReader) close() {
+	if r.f != nil {
+		r.f.Close()
+		r.f = nil
+	}
+}
+
+// copyFSWriter writes the bytes read by [fsReader] to the destination files one by one.
+type copyFSWriter struct {
+	t *CopyFSTask
+	// i is the index of the file to write.
+	i int
+	// off is the offset of the file to write.
+	off int64
+	f   WriteFile
+	// h hashes the file being written from the beginning by [WithHardlinkDedup].
+	h hash.Hash
+	// buf is the bytes of the small file being written by [WithSmallFiles]
+	// and pool writes the small files.
+	buf  []byte
+	pool *smallWriter
+	// mu guards the deduplication by the workers of pool.
+	mu sync.Mutex
+}
+
+// Write implements [io.Writer] interface.
+func (w *copyFSWriter) Write(p []byte) (n int, err error) {
+	if w.pool != nil {
+		if err = w.pool.error(); err != nil {
+			return 0, err
+		}
+	}
+
+	for len(p) > 0 {
+		if w.i >= len(w.t.files) {
+			return n, fmt.Errorf("bytes written beyond the files")
+		}
+
+		file := w.t.files[w.i]
+		if w.off >= file.Size {
+			if err = w.finish(); err != nil {
+				return n, err
+			}
+			continue
+		}
+
+		if w.buf == nil && w.f == nil && w.off == 0 && w.pool != nil && file.Size <= w.t.opts.smallSize {
+			w.buf = make([]byte, 0, file.Size)
+		}
+
+		if w.buf != nil {
+			m := min(int64(len(p)), file.Size-w.off)
+			w.buf = append(w.buf, p[:m]...)
+			w.off += m
+			n += int(m)
+			p = p[m:]
+			continue
+		}
+
+		if w.f == nil {
+			name, err := w.t.dstPath(file.Name)
+			if err != nil {
+				return n, err
+			}
+
+			if w.f, err = openDst(w.t.fsys, name, file.Size, w.off); err != nil {
+				return n, err
+			}
+
+			w.h = nil
+			if w.t.opts.dedupHash != nil && w.t.fsys == OSFS && w.off == 0 {
+				w.h = w.t.opts.dedupHash()
+			}
+		}
+
+		m, err := w.f.Write(p[:min(int64(len(p)), file.Size-w.off)])
+		if w.h != nil {
+			w.h.Write(p[:m])
+		}
+		w.off += int64(m)
+		n += m
+		p = p[m:]
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+// Commit implements [Committer] interface.
+// It closes the last file and waits for the small files of [WithSmallFiles] to be written.
+func (w *copyFSWriter) Commit() error {
+	if w.i < len(w.t.files) && w.off >= w.t.files[w.i].Size {
+		if err := w.finish(); err != nil {
+			return err
+		}
+	}
+	return w.flush()
+}
+
+// finish closes the current file which is written and moves to the next one.
+func (w *copyFSWriter) finish() error {
+	file := w.t.files[w.i]
+	if w.buf != nil {
+		// Write the small file by the workers.
+		w.pool.files <- smallFile{i: w.i, data: w.buf}
+		w.buf = nil
+		w.i++
+		w.off = 0
+		return w.pool.error()
+	}
+
+	if err := w.close(); err != nil {
+		return err
+	}
+
+	if file.Size > 0 {
+		name, err := w.t.dstPath(file.Name)
+		if err != nil {
+			return err
+		}
+
+		if err = w.t.chmod(name, file.Mode); err != nil {
+			return err
+		}
+
+		if w.h != nil {
+			w.mu.Lock()
+			err = w.t.dedupe(file, name, w.h.Sum(nil))
+			w.mu.Unlock()
+			if err != nil {
+				return err
+			}
+			w.h = nil
+		}
+	}
+
+	w.i++
+	w.off = 0
+	return nil
+}
+
+// close closes the file being written.
+// The beginning of the small file being written is written to resume if it's stopped in the middle of it.
+func (w *copyFSWriter) close() error {
+	if w.buf != nil {
+		err := w.writeSmall(w.i, w.buf)
+		w.buf = nil
+		if err != nil {
+			w.t.copied = min(w.t.copied, w.t.offset(w.i))
+		}
+		return err
+	}
+
+	if w.f != nil {
+		err := w.f.Close()
+		w.f = nil
+		return err
+	}
+	return nil
+}
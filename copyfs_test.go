@@ -0,0 +1,287 @@
+package iocopy_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing/fstest"
+
+	"github.com/northbright/iocopy"
+)
+
+func ExampleCopyFSTask() {
+	// This example copies a file system to a directory except the "docs" directory.
+	// It's stopped and resumed.
+	src := fstest.MapFS{
+		"app/bin/run":     {Data: []byte("#!/bin/sh\necho run\n"), Mode: 0755},
+		"app/data/large":  {Data: bytes.Repeat([]byte("0123456789abcdef"), 64*1024)},
+		"app/data/empty":  {},
+		"app/docs/README": {Data: []byte("docs")},
+	}
+
+	dir, err := os.MkdirTemp("", "iocopy")
+	if err != nil {
+		log.Printf("os.MkdirTemp() error: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	filter := iocopy.WithFilter(func(name string, d fs.DirEntry) bool {
+		return name != "app/docs"
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var state []byte
+	t := iocopy.NewCopyFSTask(dir, src, nil, filter)
+	iocopy.Do(ctx, t, make([]byte, 32*1024), func(e iocopy.Event) {
+		switch e := e.(type) {
+		case *iocopy.EventWritten:
+			// Emulate user cancelation.
+			cancel()
+		case *iocopy.EventStop:
+			state = e.State
+		}
+	})
+
+	// The source file system is passed again to resume.
+	if t, err = iocopy.LoadCopyFSTask(state, src, nil); err != nil {
+		log.Printf("iocopy.LoadCopyFSTask() error: %v", err)
+		return
+	}
+	fmt.Printf("resume: %v\n", t.Copied() > 0)
+
+	iocopy.Do(context.Background(), t, nil, func(e iocopy.Event) {
+		switch e := e.(type) {
+		case *iocopy.EventOK:
+			r := e.Value.(iocopy.CopyFSResult)
+			fmt.Printf("dirs: %v, files: %v, size: %v\n", r.Dirs, r.Files, r.Size)
+		case *iocopy.EventError:
+			log.Printf("iocopy.Do() error: %v", e.Err)
+		}
+	})
+
+	var names []string
+	filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			rel, _ := filepath.Rel(dir, path)
+			names = append(names, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	fmt.Printf("files: %v\n", strings.Join(names, ", "))
+
+	buf, _ := os.ReadFile(filepath.Join(dir, "app", "data", "large"))
+	fmt.Printf("same content: %v\n", bytes.Equal(buf, src["app/data/large"].Data))
+
+	// Output:
+	// resume: true
+	// dirs: 4, files: 3, size: 1048595
+	// files: app/bin/run, app/data/empty, app/data/large
+	// same content: true
+}
+
+func ExampleWithCollisionPolicy() {
+	// This example copies a file system whose names collide on case-insensitive or
+	// Unicode-normalizing file systems(e.g. Windows and macOS) and renames the colliding ones.
+	src := fstest.MapFS{
+		"Docs/a.txt":     {Data: []byte("a"), Mode: 0644},
+		"docs/b.txt":     {Data: []byte("b"), Mode: 0644},
+		"README":         {Data: []byte("1"), Mode: 0644},
+		"readme":         {Data: []byte("2"), Mode: 0644},
+		"caf\u00e9.txt":  {Data: []byte("nfc"), Mode: 0644},
+		"cafe\u0301.txt": {Data: []byte("nfd"), Mode: 0644},
+	}
+
+	dir, err := os.MkdirTemp("", "iocopy")
+	if err != nil {
+		log.Printf("os.MkdirTemp() error: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	t := iocopy.NewCopyFSTask(dir, src, nil, iocopy.WithCollisionPolicy(iocopy.CollisionRename))
+	if err = iocopy.Do(context.Background(), t, nil, nil); err != nil {
+		log.Printf("iocopy.Do() error: %v", err)
+		return
+	}
+
+	r := t.ResultValue().(iocopy.CopyFSResult)
+	for _, c := range r.Collisions {
+		fmt.Printf("%+q collides with %+q, renamed to %+q\n", c.Name, c.With, c.Dst)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "docs (1)", "b.txt"))
+	if err != nil {
+		log.Printf("os.ReadFile() error: %v", err)
+		return
+	}
+	fmt.Printf("docs (1)/b.txt: %s\n", data)
+
+	// It fails with CollisionFail policy.
+	t = iocopy.NewCopyFSTask(dir, src, nil, iocopy.WithCollisionPolicy(iocopy.CollisionFail))
+	err = iocopy.Do(context.Background(), t, nil, nil)
+	var ce *iocopy.CollisionError
+	fmt.Printf("collision error: %v\n", errors.As(err, &ce))
+
+	// Output:
+	// "docs" collides with "Docs", renamed to "docs (1)"
+	// "caf\u00e9.txt" collides with "cafe\u0301.txt", renamed to "caf\u00e9 (1).txt"
+	// "readme" collides with "README", renamed to "readme (1)"
+	// docs (1)/b.txt: b
+	// collision error: true
+}
+
+func ExampleFileProgress() {
+	// This example shows the progress of the current file when copying a file system.
+	src := fstest.MapFS{
+		"a.txt":        {Data: bytes.Repeat([]byte("a"), 4096), Mode: 0644},
+		"photos/b.jpg": {Data: bytes.Repeat([]byte("b"), 8192), Mode: 0644},
+	}
+
+	dir, err := os.MkdirTemp("", "iocopy")
+	if err != nil {
+		log.Printf("os.MkdirTemp() error: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	t := iocopy.NewCopyFSTask(dir, src, nil)
+	err = iocopy.Do(context.Background(), t, make([]byte, 2048), func(e iocopy.Event) {
+		if e, ok := e.(*iocopy.EventWritten); ok && e.File != nil {
+			f := e.File
+			fmt.Printf("copying %v/%v: %v (%.0f%%), total: %.0f%%\n", f.Index+1, f.Count, f.Name, f.Percent, e.Percent)
+		}
+	})
+	if err != nil {
+		log.Printf("iocopy.Do() error: %v", err)
+		return
+	}
+
+	// Output:
+	// copying 1/2: a.txt (50%), total: 17%
+	// copying 1/2: a.txt (100%), total: 33%
+	// copying 2/2: photos/b.jpg (25%), total: 50%
+	// copying 2/2: photos/b.jpg (50%), total: 67%
+	// copying 2/2: photos/b.jpg (75%), total: 83%
+	// copying 2/2: photos/b.jpg (100%), total: 100%
+}
+
+func ExampleWithHardlinkDedup() {
+	// This example copies a file system with duplicate files.
+	// The duplicates are replaced with hardlinks to the first copies.
+	photo := bytes.Repeat([]byte("photo"), 1024)
+	src := fstest.MapFS{
+		"2024/IMG_0001.jpg":        {Data: photo, Mode: 0644},
+		"2024/IMG_0002.jpg":        {Data: []byte("another photo"), Mode: 0644},
+		"backup/2024/IMG_0001.jpg": {Data: photo, Mode: 0644},
+		"export/IMG_0001.jpg":      {Data: photo, Mode: 0644},
+	}
+
+	dir, err := os.MkdirTemp("", "iocopy")
+	if err != nil {
+		log.Printf("os.MkdirTemp() error: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	t := iocopy.NewCopyFSTask(dir, src, nil, iocopy.WithHardlinkDedup(sha256.New))
+	if err = iocopy.Do(context.Background(), t, nil, nil); err != nil {
+		log.Printf("iocopy.Do() error: %v", err)
+		return
+	}
+
+	r := t.ResultValue().(iocopy.CopyFSResult)
+	fmt.Printf("files: %v, links: %v, saved: %v bytes\n", r.Files, r.Links, r.Saved)
+
+	fi1, err := os.Stat(filepath.Join(dir, "2024", "IMG_0001.jpg"))
+	if err != nil {
+		log.Printf("os.Stat() error: %v", err)
+		return
+	}
+
+	fi2, err := os.Stat(filepath.Join(dir, "export", "IMG_0001.jpg"))
+	if err != nil {
+		log.Printf("os.Stat() error: %v", err)
+		return
+	}
+	fmt.Printf("same file: %v\n", os.SameFile(fi1, fi2))
+
+	// Output:
+	// files: 4, links: 2, saved: 10240 bytes
+	// same file: true
+}
+
+func ExampleWithSmallFiles() {
+	// This example copies a tree with many tiny files by 4 workers in batches.
+	// It's stopped and resumed, and the progress is reported by file count.
+	src := fstest.MapFS{
+		"videos/large.mp4": {Data: bytes.Repeat([]byte("video"), 64*1024)},
+	}
+	for i := range 1000 {
+		src[fmt.Sprintf("notes/%03d/%03d.txt", i/100, i)] = &fstest.MapFile{Data: []byte(fmt.Sprintf("note %d\n", i))}
+	}
+
+	dir, err := os.MkdirTemp("", "iocopy")
+	if err != nil {
+		log.Printf("os.MkdirTemp() error: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	small := iocopy.WithSmallFiles(4096, 4)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var state []byte
+	t := iocopy.NewCopyFSTask(dir, src, nil, small)
+	iocopy.Do(ctx, t, make([]byte, 4096), func(e iocopy.Event) {
+		switch e := e.(type) {
+		case *iocopy.EventWritten:
+			// Emulate user cancelation.
+			cancel()
+		case *iocopy.EventStop:
+			state = e.State
+		}
+	})
+
+	if t, err = iocopy.LoadCopyFSTask(state, src, nil, small); err != nil {
+		log.Printf("iocopy.LoadCopyFSTask() error: %v", err)
+		return
+	}
+
+	var last iocopy.FileProgress
+	err = iocopy.Do(context.Background(), t, nil, func(e iocopy.Event) {
+		if e, ok := e.(*iocopy.EventWritten); ok && e.File != nil {
+			last = *e.File
+		}
+	})
+	if err != nil {
+		log.Printf("iocopy.Do() error: %v", err)
+		return
+	}
+	fmt.Printf("files: %v/%v\n", last.Done, last.Count)
+
+	// Compare the copies with the sources.
+	same := true
+	for name, f := range src {
+		buf, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+		if err != nil || !bytes.Equal(buf, f.Data) {
+			same = false
+		}
+	}
+	fmt.Printf("same: %v\n", same)
+
+	// Output:
+	// files: 1001/1001
+	// same: true
+}
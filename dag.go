@@ -0,0 +1,150 @@
+package iocopy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// dagNode is a task of [DAG] with its dependencies.
+type dagNode struct {
+	id   string
+	t    Task
+	deps []string
+	done bool
+}
+
+// DAG runs tasks with dependencies, e.g. download -> verify -> extract.
+// A task runs only after all its dependencies are done.
+// The state can be saved and loaded to resume the DAG mid-pipeline after a crash.
+type DAG struct {
+	nodes []*dagNode
+	index map[string]*dagNode
+}
+
+// dagState is the state of [DAG].
+type dagState struct {
+	Version int            `json:"version"`
+	Type    string         `json:"type"`
+	Nodes   []dagNodeState `json:"nodes"`
+}
+
+// dagNodeState is the state of a task of [DAG].
+type dagNodeState struct {
+	ID    string          `json:"id"`
+	Deps  []string        `json:"deps,omitempty"`
+	Done  bool            `json:"done"`
+	State json.RawMessage `json:"state"`
+}
+
+// NewDAG returns an empty [*DAG].
+func NewDAG() *DAG {
+	return &DAG{index: map[string]*dagNode{}}
+}
+
+// Add adds the task with the unique id and the ids of its dependencies.
+// Dependencies should be added before, so there's no cycle.
+func (g *DAG) Add(id string, t Task, deps ...string) error {
+	if _, ok := g.index[id]; ok {
+		return fmt.Errorf("task %v exists", id)
+	}
+
+	for _, dep := range deps {
+		if _, ok := g.index[dep]; !ok {
+			return fmt.Errorf("dependency %v of task %v not found", dep, id)
+		}
+	}
+
+	n := &dagNode{id: id, t: t, deps: deps}
+	g.nodes = append(g.nodes, n)
+	g.index[id] = n
+	return nil
+}
+
+// Task returns the task with the id or nil if it's not found.
+func (g *DAG) Task(id string) Task {
+	if n, ok := g.index[id]; ok {
+		return n.t
+	}
+	return nil
+}
+
+// Run runs the tasks which are not done by [Do] in the order they're added and reports the events by fn.
+// fn is also called with [*EventStarted] and [*EventFinished] for each task.
+// A task is marked as done before its [*EventOK] is reported,
+// so State called in fn can be saved to resume. It can also be saved on [*EventStop] or [*EventWritten].
+// Run returns the error of the first task which fails or is stopped.
+func (g *DAG) Run(ctx context.Context, buf []byte, fn OnTaskEventFunc) error {
+	emit := func(t Task, e Event) {
+		if fn != nil {
+			fn(t, e)
+		}
+	}
+
+	for _, n := range g.nodes {
+		if n.done {
+			continue
+		}
+
+		// Dependencies are always added and run before.
+		for _, dep := range n.deps {
+			if !g.index[dep].done {
+				return fmt.Errorf("dependency %v of task %v is not done", dep, n.id)
+			}
+		}
+
+		emit(n.t, &EventStarted{})
+		err := Do(ctx, n.t, buf, func(e Event) {
+			if _, ok := e.(*EventOK); ok {
+				n.done = true
+			}
+			emit(n.t, e)
+		})
+		emit(n.t, &EventFinished{Err: err})
+
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// State returns the marshaled state of the DAG which contains the states of the tasks.
+func (g *DAG) State() ([]byte, error) {
+	s := dagState{Version: StateVersion, Type: StateTypeDAG}
+	for _, n := range g.nodes {
+		state, err := n.t.State()
+		if err != nil {
+			return nil, err
+		}
+		s.Nodes = append(s.Nodes, dagNodeState{ID: n.id, Deps: n.deps, Done: n.done, State: state})
+	}
+	return json.Marshal(s)
+}
+
+// LoadDAG loads a [*DAG] from the state to resume.
+// load is called to load each task from its id and state, e.g. by [LoadDownloadTask].
+func LoadDAG(state []byte, load func(id string, state []byte) (Task, error)) (*DAG, error) {
+	var s dagState
+	if err := json.Unmarshal(state, &s); err != nil {
+		return nil, err
+	}
+
+	if err := (stateHeader{s.Version, s.Type}).check(StateTypeDAG); err != nil {
+		return nil, err
+	}
+
+	g := NewDAG()
+	for _, ns := range s.Nodes {
+		t, err := load(ns.ID, ns.State)
+		if err != nil {
+			return nil, err
+		}
+
+		if err = g.Add(ns.ID, t, ns.Deps...); err != nil {
+			return nil, err
+		}
+		g.index[ns.ID].done = ns.Done
+	}
+	return g, nil
+}
@@ -0,0 +1,95 @@
+package iocopy_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/northbright/iocopy"
+)
+
+func ExampleDAG() {
+	// This example downloads a file and then copies it to a backup directory.
+	// The copy task runs only after the download task is done.
+	// It stops the DAG on the first bytes copied and resumes it from the saved state.
+	data := bytes.Repeat([]byte("0123456789abcdef"), 64*1024)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "file", time.Time{}, bytes.NewReader(data))
+	}))
+	defer ts.Close()
+
+	dir, err := os.MkdirTemp("", "iocopy")
+	if err != nil {
+		log.Printf("os.MkdirTemp() error: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "file")
+	backup := filepath.Join(dir, "backup", "file")
+
+	g := iocopy.NewDAG()
+	g.Add("download", iocopy.NewDownloadTask(file, ts.URL, nil))
+	g.Add("backup", iocopy.NewCopyFileTask(backup, file, nil), "download")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var state []byte
+	g.Run(ctx, nil, func(t iocopy.Task, e iocopy.Event) {
+		switch e := e.(type) {
+		case *iocopy.EventOK:
+			fmt.Printf("download done: %v\n", t == g.Task("download"))
+		case *iocopy.EventWritten:
+			if t == g.Task("backup") {
+				// Emulate a crash.
+				cancel()
+			}
+		case *iocopy.EventStop:
+			fmt.Printf("backup stopped: %v\n", e.Err)
+			// Save the state of the DAG.
+			state, _ = g.State()
+		}
+	})
+
+	// Load the DAG and resume.
+	g, err = iocopy.LoadDAG(state, func(id string, state []byte) (iocopy.Task, error) {
+		switch id {
+		case "download":
+			return iocopy.LoadDownloadTask(state, nil)
+		default:
+			return iocopy.LoadCopyFileTask(state, nil)
+		}
+	})
+	if err != nil {
+		log.Printf("iocopy.LoadDAG() error: %v", err)
+		return
+	}
+
+	if err = g.Run(context.Background(), nil, func(t iocopy.Task, e iocopy.Event) {
+		if _, ok := e.(*iocopy.EventStarted); ok && t == g.Task("download") {
+			fmt.Printf("download runs again\n")
+		}
+	}); err != nil {
+		log.Printf("g.Run() error: %v", err)
+		return
+	}
+
+	copied, err := os.ReadFile(backup)
+	if err != nil {
+		log.Printf("os.ReadFile() error: %v", err)
+		return
+	}
+	fmt.Printf("same content: %v\n", bytes.Equal(copied, data))
+
+	// Output:
+	// download done: true
+	// backup stopped: context canceled
+	// same content: true
+}
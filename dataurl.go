@@ -0,0 +1,157 @@
+package iocopy
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// dataURLTask decodes the payload of a data: url(RFC 2397) to the part file of dst for [Download].
+// The part file is renamed to dst when it's done, or the state is saved to the sidecar state file to resume, like [DownloadTask].
+type dataURLTask struct {
+	dst    string
+	url    string
+	total  int64
+	copied int64
+	// enc is the encoding of the base64 payload. The payload is not encoded if it's nil.
+	enc     *base64.Encoding
+	payload string
+	f       WriteFile
+	opts    options
+	// done is true when the part file is committed by [Do].
+	done bool
+}
+
+// newDataURLTask returns a [*dataURLTask] which decodes the data url to dst.
+// ok is false if it's not a data url.
+// It resumes the decoding from the sidecar state file of the part file if it's valid.
+func newDataURLTask(dst, rawURL string, opts []Option) (t *dataURLTask, ok bool, err error) {
+	if len(rawURL) < 5 || !strings.EqualFold(rawURL[:5], "data:") {
+		return nil, false, nil
+	}
+
+	// data:[<mediatype>][;base64],<data>
+	meta, payload, found := strings.Cut(rawURL[5:], ",")
+	if !found {
+		return nil, true, fmt.Errorf("invalid data url: no comma")
+	}
+
+	if payload, err = url.PathUnescape(payload); err != nil {
+		return nil, true, fmt.Errorf("invalid data url: %w", err)
+	}
+
+	t = &dataURLTask{dst: dst, url: rawURL, payload: payload, opts: newOptions(opts)}
+	t.total = int64(len(payload))
+	if strings.HasSuffix(strings.ToLower(meta), ";base64") {
+		// Line breaks are ignored by the decoder.
+		t.payload = strings.NewReplacer("\r", "", "\n", "").Replace(payload)
+		t.enc = base64.StdEncoding
+		if len(t.payload)%4 != 0 {
+			t.enc = base64.RawStdEncoding
+		}
+		t.total = int64(t.enc.DecodedLen(len(t.payload)) - (len(t.payload) - len(strings.TrimRight(t.payload, "="))))
+	}
+
+	if isStdout(dst) {
+		return t, true, nil
+	}
+
+	if state, err := os.ReadFile(longPath(dst + PartStateExt)); err == nil {
+		// The part file should contain the decoded bytes.
+		var s DownloadState
+		if json.Unmarshal(state, &s) == nil && s.Dst == dst && s.URL == rawURL && s.Copied <= t.total {
+			if fi, err := os.Stat(longPath(dst + PartFileExt)); err == nil && fi.Size() >= s.Copied {
+				t.copied = s.Copied
+			}
+		}
+	}
+	return t, true, nil
+}
+
+// Open implements [Task] interface.
+// It decodes the payload from the beginning and skips the bytes decoded previously.
+func (t *dataURLTask) Open(ctx context.Context) (dst io.Writer, src io.Reader, err error) {
+	if t.opts.maxBytes >= 0 && t.total > t.opts.maxBytes {
+		return nil, nil, &MaxBytesError{Limit: t.opts.maxBytes}
+	}
+
+	src = strings.NewReader(t.payload)
+	if t.enc != nil {
+		src = base64.NewDecoder(t.enc, src)
+	}
+
+	if isStdout(t.dst) {
+		// Stream to the standard output without the part file.
+		if t.copied > 0 {
+			return nil, nil, errStdioResume(t.dst)
+		}
+		return t.opts.throttle(ctx, os.Stdout), t.opts.limit(ctx, src), nil
+	}
+
+	if _, err = io.CopyN(io.Discard, src, t.copied); err != nil {
+		return nil, nil, fmt.Errorf("invalid data url: %w", err)
+	}
+
+	if t.f, err = openDst(OSFS, t.dst+PartFileExt, t.total, t.copied); err != nil {
+		return nil, nil, err
+	}
+
+	t.done = false
+	dst = &commitWriter{Writer: t.f, fn: func() error {
+		t.done = true
+		return nil
+	}}
+	return t.opts.throttle(ctx, dst), t.opts.limit(ctx, src), nil
+}
+
+// Close implements [Task] interface.
+// It renames the part file to the destination if the decoding is done, or saves the state to the sidecar state file.
+func (t *dataURLTask) Close() error {
+	if t.f == nil {
+		return nil
+	}
+
+	err := t.f.Close()
+	t.f = nil
+	if err != nil {
+		return err
+	}
+	return closePart(t.dst, t.done, t.State)
+}
+
+// Total implements [Task] interface.
+func (t *dataURLTask) Total() int64 {
+	return t.total
+}
+
+// Copied implements [Task] interface.
+func (t *dataURLTask) Copied() int64 {
+	return t.copied
+}
+
+// SetCopied implements [Task] interface.
+func (t *dataURLTask) SetCopied(copied int64) {
+	t.copied = copied
+}
+
+// State implements [Task] interface.
+// It's the [DownloadState] which contains the data url.
+func (t *dataURLTask) State() ([]byte, error) {
+	return json.Marshal(DownloadState{Version: StateVersion, Type: StateTypeDownload, Dst: t.dst, URL: t.url, Total: t.total, Copied: t.copied})
+}
+
+// Result implements [Task] interface.
+func (t *dataURLTask) Result() ([]byte, error) {
+	return json.Marshal(t.ResultValue())
+}
+
+// ResultValue implements [ResultValuer] interface.
+// It returns the [DownloadResult].
+func (t *dataURLTask) ResultValue() any {
+	return DownloadResult{Dst: t.dst, URL: t.url, Size: t.copied}
+}
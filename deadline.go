@@ -0,0 +1,114 @@
+package iocopy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// ErrChunkTimeout is returned when a read or write does not finish in the deadline set by [ContextWithChunkDeadline].
+var ErrChunkTimeout = errors.New("chunk deadline exceeded")
+
+// chunkDeadlineKey is the context key of the chunk deadline.
+type chunkDeadlineKey struct{}
+
+// chunkDeadline is the deadline of each read and write and the max number of retries.
+type chunkDeadline struct {
+	d       time.Duration
+	retries int
+}
+
+// ContextWithChunkDeadline returns a copy of ctx which makes each read of the source and write of the destination
+// abandoned if it does not finish in d, independent of the deadline of ctx, e.g. a syscall hung on a flaky NFS mount.
+// The copy returns an error wrapping [ErrChunkTimeout] and [Do] closes and reopens the task to retry
+// from the bytes copied for at most retries times. The abandoned call may still finish in the background.
+// Each call runs in a goroutine on a private buffer, so it's slower and the fast paths(e.g. splice(2)) are not used.
+// It does nothing if d is not positive.
+func ContextWithChunkDeadline(ctx context.Context, d time.Duration, retries int) context.Context {
+	return context.WithValue(ctx, chunkDeadlineKey{}, chunkDeadline{d: d, retries: retries})
+}
+
+// chunkDeadlineOf returns the chunk deadline attached to ctx.
+func chunkDeadlineOf(ctx context.Context) (chunkDeadline, bool) {
+	cd, ok := ctx.Value(chunkDeadlineKey{}).(chunkDeadline)
+	return cd, ok && cd.d > 0
+}
+
+// ioResult is the result of a read or write.
+type ioResult struct {
+	n   int
+	err error
+}
+
+// runWithDeadline runs fn in a goroutine and waits for it at most d.
+// It returns false if fn is abandoned by the deadline or ctx.
+func runWithDeadline(ctx context.Context, d time.Duration, op string, fn func() (int, error)) (int, error, bool) {
+	ch := make(chan ioResult, 1)
+	go func() {
+		n, err := fn()
+		ch <- ioResult{n, err}
+	}()
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case r := <-ch:
+		return r.n, r.err, true
+	case <-timer.C:
+		return 0, fmt.Errorf("%v: %w after %v", op, ErrChunkTimeout, d), false
+	case <-ctx.Done():
+		return 0, ctx.Err(), false
+	}
+}
+
+// deadlineReader abandons the reads which do not finish in the deadline.
+type deadlineReader struct {
+	ctx context.Context
+	r   io.Reader
+	d   time.Duration
+	// buf is the private buffer of the reads. It's dropped if a read is abandoned since it may still be written.
+	buf []byte
+}
+
+// Read implements [io.Reader] interface.
+func (dr *deadlineReader) Read(p []byte) (int, error) {
+	if cap(dr.buf) < len(p) {
+		dr.buf = make([]byte, len(p))
+	}
+	b := dr.buf[:len(p)]
+
+	n, err, ok := runWithDeadline(dr.ctx, dr.d, "read", func() (int, error) {
+		return dr.r.Read(b)
+	})
+	if !ok {
+		dr.buf = nil
+		return 0, err
+	}
+	return copy(p, b[:n]), err
+}
+
+// deadlineWriter abandons the writes which do not finish in the deadline.
+type deadlineWriter struct {
+	ctx context.Context
+	w   io.Writer
+	d   time.Duration
+	// buf is the private copy of the bytes to write. It's dropped if a write is abandoned since it may still be read.
+	buf []byte
+}
+
+// Write implements [io.Writer] interface.
+func (dw *deadlineWriter) Write(p []byte) (int, error) {
+	dw.buf = append(dw.buf[:0], p...)
+	b := dw.buf
+
+	n, err, ok := runWithDeadline(dw.ctx, dw.d, "write", func() (int, error) {
+		return dw.w.Write(b)
+	})
+	if !ok {
+		dw.buf = nil
+	}
+	return n, err
+}
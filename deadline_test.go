@@ -0,0 +1,209 @@
+package iocopy_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"github.com/northbright/iocopy"
+)
+
+// hangingReaderAt hangs on the first read after off until release is closed,
+// like a syscall hung on a flaky NFS mount.
+type hangingReaderAt struct {
+	r       io.ReaderAt
+	off     int64
+	release chan struct{}
+	hung    atomic.Bool
+}
+
+func (h *hangingReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if off >= h.off && h.hung.CompareAndSwap(false, true) {
+		<-h.release
+	}
+	return h.r.ReadAt(p, off)
+}
+
+// reopenFailingTask fails to be reopened after it's opened once and counts the closes.
+type reopenFailingTask struct {
+	*iocopy.HashTask
+	opens, closes int
+}
+
+func (t *reopenFailingTask) Open(ctx context.Context) (io.Writer, io.Reader, error) {
+	if t.opens++; t.opens > 1 {
+		return nil, nil, errors.New("source is gone")
+	}
+	return t.HashTask.Open(ctx)
+}
+
+func (t *reopenFailingTask) Close() error {
+	t.closes++
+	return t.HashTask.Close()
+}
+
+// restartingTask restarts from the beginning when it's reopened, like a download of a changed remote file.
+type restartingTask struct {
+	*iocopy.HashTask
+	r      io.ReaderAt
+	size   int64
+	opens  int
+	reason string
+}
+
+func (t *restartingTask) Open(ctx context.Context) (io.Writer, io.Reader, error) {
+	if t.opens++; t.opens > 1 {
+		t.HashTask = iocopy.NewReaderAtHashTask("data", t.r, t.size, []string{"sha256"})
+		t.reason = "source changed"
+	}
+	return t.HashTask.Open(ctx)
+}
+
+func (t *restartingTask) Close() error {
+	t.reason = ""
+	return t.HashTask.Close()
+}
+
+func (t *restartingTask) Restarted() (string, int64) {
+	return t.reason, 0
+}
+
+func ExampleContextWithChunkDeadline() {
+	// This example hashes the bytes of a reader which hangs once in the middle.
+	// The hung read is abandoned after 100ms and the task is reopened to retry from the bytes hashed.
+	data := bytes.Repeat([]byte("0123456789abcdef"), 256*1024)
+	r := &hangingReaderAt{r: bytes.NewReader(data), off: int64(len(data) / 2), release: make(chan struct{})}
+	defer close(r.release)
+
+	ctx := iocopy.ContextWithChunkDeadline(context.Background(), 100*time.Millisecond, 3)
+	t := iocopy.NewReaderAtHashTask("data", r, int64(len(data)), []string{"sha256"})
+	err := iocopy.Do(ctx, t, nil, func(e iocopy.Event) {
+		if e, ok := e.(*iocopy.EventOK); ok {
+			fmt.Printf("retries: %v\n", e.Stats.Retries)
+		}
+	})
+	if err != nil {
+		log.Printf("iocopy.Do() error: %v", err)
+		return
+	}
+
+	checksums, n := t.Checksums()
+	sum := sha256.Sum256(data)
+	fmt.Printf("%v bytes hashed, SHA-256 matches: %v\n", n, checksums["sha256"] == hex.EncodeToString(sum[:]))
+
+	// Output:
+	// retries: 1
+	// 4194304 bytes hashed, SHA-256 matches: true
+}
+
+func ExampleIOStats_retries() {
+	// This example hashes the bytes of a reader which hangs twice.
+	// Each hung read is abandoned by the chunk deadline and the task is reopened once to retry,
+	// so Retries is the number of the chunk timeouts.
+	data := bytes.Repeat([]byte("0123456789abcdef"), 256*1024)
+	r1 := &hangingReaderAt{r: bytes.NewReader(data), off: int64(len(data) / 4), release: make(chan struct{})}
+	defer close(r1.release)
+	r2 := &hangingReaderAt{r: r1, off: int64(len(data) / 4 * 3), release: make(chan struct{})}
+	defer close(r2.release)
+
+	ctx := iocopy.ContextWithChunkDeadline(context.Background(), 100*time.Millisecond, 3)
+	t := iocopy.NewReaderAtHashTask("data", r2, int64(len(data)), []string{"sha256"})
+	restarts := 0
+	err := iocopy.Do(ctx, t, nil, func(e iocopy.Event) {
+		switch e := e.(type) {
+		case *iocopy.EventRestarted:
+			restarts++
+		case *iocopy.EventOK:
+			fmt.Printf("retries: %v, restarts: %v\n", e.Stats.Retries, restarts)
+		}
+	})
+	if err != nil {
+		log.Printf("iocopy.Do() error: %v", err)
+		return
+	}
+
+	// Output:
+	// retries: 2, restarts: 0
+}
+
+func ExampleContextWithChunkDeadline_reopenFailed() {
+	// This example hashes the bytes of a reader which hangs once and fails to be reopened to retry.
+	// The task is closed once after the hung read is abandoned, but not again after the reopen fails.
+	data := bytes.Repeat([]byte("0123456789abcdef"), 256*1024)
+	r := &hangingReaderAt{r: bytes.NewReader(data), off: int64(len(data) / 2), release: make(chan struct{})}
+	defer close(r.release)
+
+	ctx := iocopy.ContextWithChunkDeadline(context.Background(), 100*time.Millisecond, 3)
+	t := &reopenFailingTask{HashTask: iocopy.NewReaderAtHashTask("data", r, int64(len(data)), []string{"sha256"})}
+	err := iocopy.Do(ctx, t, nil, nil)
+	fmt.Printf("err: %v, opens: %v, closes: %v\n", err, t.opens, t.closes)
+
+	// Output:
+	// err: source is gone, opens: 2, closes: 1
+}
+
+func ExampleContextWithChunkDeadline_restarted() {
+	// This example resumes hashing the bytes of a reader which hangs once.
+	// The task restarts from the beginning when it's reopened to retry,
+	// so the bytes copied go back below the ones when it's resumed.
+	data := bytes.Repeat([]byte("0123456789abcdef"), 256*1024)
+	size := int64(len(data))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var state []byte
+	t := iocopy.NewReaderAtHashTask("data", bytes.NewReader(data), size, []string{"sha256"})
+	iocopy.Do(ctx, t, nil, func(e iocopy.Event) {
+		switch e := e.(type) {
+		case *iocopy.EventWritten:
+			// Emulate user cancelation.
+			cancel()
+		case *iocopy.EventStop:
+			state = e.State
+		}
+	})
+
+	r := &hangingReaderAt{r: bytes.NewReader(data), off: size / 2, release: make(chan struct{})}
+	defer close(r.release)
+
+	t, err := iocopy.LoadReaderAtHashTask(state, r)
+	if err != nil {
+		log.Printf("iocopy.LoadReaderAtHashTask() error: %v", err)
+		return
+	}
+
+	rt := &restartingTask{HashTask: t, r: r, size: size}
+	ctx = iocopy.ContextWithChunkDeadline(context.Background(), 100*time.Millisecond, 3)
+	if err = iocopy.Do(ctx, rt, nil, func(e iocopy.Event) {
+		switch e := e.(type) {
+		case *iocopy.EventRestarted:
+			fmt.Printf("restarted: %v\n", e.Reason)
+		case *iocopy.EventWritten:
+			if e.Speed < 0 {
+				fmt.Printf("negative speed: %v\n", e.Speed)
+			}
+		case *iocopy.EventOK:
+			fmt.Printf("retries: %v, bytes written: %v\n", e.Stats.Retries, e.Stats.BytesWritten)
+		}
+	}); err != nil {
+		log.Printf("iocopy.Do() error: %v", err)
+		return
+	}
+
+	checksums, n := rt.Checksums()
+	sum := sha256.Sum256(data)
+	fmt.Printf("%v bytes hashed, SHA-256 matches: %v\n", n, checksums["sha256"] == hex.EncodeToString(sum[:]))
+
+	// Output:
+	// restarted: source changed
+	// retries: 1, bytes written: 4194304
+	// 4194304 bytes hashed, SHA-256 matches: true
+}
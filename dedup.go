@@ -0,0 +1,60 @@
+package iocopy
+
+import (
+	"encoding/hex"
+	"fmt"
+	"os"
+)
+
+// dedupKey returns the key of the file to detect the identical ones by [WithHardlinkDedup].
+func dedupKey(f CopyFSFile, sum []byte) string {
+	return fmt.Sprintf("%d:%o:%s", f.Size, f.Mode, hex.EncodeToString(sum))
+}
+
+// dedupe replaces the copied file with a hardlink to the first identical one if any,
+// or records it as the first one of its checksum.
+// The copy is kept if it fails to be linked.
+func (t *CopyFSTask) dedupe(f CopyFSFile, dst string, sum []byte) error {
+	key := dedupKey(f, sum)
+	target, ok := t.digests[key]
+	if !ok {
+		if t.digests == nil {
+			t.digests = map[string]string{}
+		}
+		t.digests[key] = f.Name
+		return nil
+	}
+
+	targetDst, err := t.dstPath(target)
+	if err != nil {
+		return err
+	}
+
+	// Link to a temporary file and rename it to replace the copy atomically.
+	tmp := dst + ".link"
+	if err = os.Link(longPath(targetDst), longPath(tmp)); err != nil {
+		return nil
+	}
+
+	if err = os.Rename(longPath(tmp), longPath(dst)); err != nil {
+		os.Remove(longPath(tmp))
+		return nil
+	}
+
+	if t.links == nil {
+		t.links = map[string]string{}
+	}
+	t.links[f.Name] = target
+	return nil
+}
+
+// saved returns the number of the files linked by [WithHardlinkDedup] and the total size of them.
+func (t *CopyFSTask) saved() (links int, size int64) {
+	for _, f := range t.files {
+		if _, ok := t.links[f.Name]; ok {
+			links++
+			size += f.Size
+		}
+	}
+	return links, size
+}
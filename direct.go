@@ -0,0 +1,127 @@
+package iocopy
+
+import (
+	"errors"
+	"io"
+	"os"
+	"unsafe"
+)
+
+// directBufSize is the default size of the aligned buffer used for direct IO.
+const directBufSize = 32 * 1024
+
+// Alignment returns the alignment of the buffers and the file offsets required by direct IO(e.g. O_DIRECT).
+// It's the memory page size which is a multiple of the logical block sizes of the devices.
+func Alignment() int {
+	return os.Getpagesize()
+}
+
+// AlignedBuffer returns a buffer whose address is aligned to [Alignment]
+// and whose size is at least size and rounded up to a multiple of it.
+// Pass it to [Do] or [CopyBufferWithProgress] for direct IO([WithDirectIO]) or device IO to avoid extra copies.
+func AlignedBuffer(size int) []byte {
+	align := Alignment()
+	size = max((size+align-1)/align*align, align)
+
+	b := make([]byte, size+align)
+	off := 0
+	if rem := int(uintptr(unsafe.Pointer(&b[0])) & uintptr(align-1)); rem != 0 {
+		off = align - rem
+	}
+	return b[off : off+size : off+size]
+}
+
+// isAligned reports whether the address and the size of b are aligned to align.
+func isAligned(b []byte, align int) bool {
+	return len(b) > 0 && len(b)%align == 0 && uintptr(unsafe.Pointer(&b[0]))&uintptr(align-1) == 0
+}
+
+// aligner is implemented by readers which require aligned buffers to avoid extra copies.
+// The IO copy replaces unaligned buffers with the ones returned by [AlignedBuffer] for them.
+type aligner interface {
+	alignment() int
+}
+
+// directReader reads a file opened for direct IO.
+// Unaligned buffers or offsets are read through an internal aligned buffer,
+// so the reads never fail because of the alignment.
+type directReader struct {
+	f     *os.File
+	size  int64
+	align int
+	// off is the logical offset of the reader.
+	off int64
+	// skip is the number of bytes to skip from the aligned file offset after seeking.
+	skip int
+	buf  []byte
+	// pending are the bytes read into buf but not returned yet.
+	pending []byte
+}
+
+// newDirectReader returns a [*directReader] which reads the file opened for direct IO with the size.
+func newDirectReader(f *os.File, size int64) *directReader {
+	return &directReader{f: f, size: size, align: Alignment()}
+}
+
+// alignment implements aligner interface.
+func (r *directReader) alignment() int {
+	return r.align
+}
+
+// Read implements [io.Reader] interface.
+func (r *directReader) Read(p []byte) (n int, err error) {
+	if r.off >= r.size {
+		return 0, io.EOF
+	}
+
+	if len(r.pending) == 0 {
+		if r.skip == 0 && len(p) >= r.align && isAligned(p[:len(p)/r.align*r.align], r.align) {
+			// Read into p directly.
+			n, err = r.f.Read(p[:len(p)/r.align*r.align])
+			r.off += int64(n)
+			return n, err
+		}
+
+		if r.buf == nil {
+			r.buf = AlignedBuffer(directBufSize)
+		}
+
+		m, err := r.f.Read(r.buf)
+		if m <= r.skip {
+			r.skip -= m
+			return 0, err
+		}
+		r.pending = r.buf[r.skip:m]
+		r.skip = 0
+	}
+
+	n = copy(p, r.pending)
+	r.pending = r.pending[n:]
+	r.off += int64(n)
+	return n, nil
+}
+
+// Seek implements [io.Seeker] interface.
+// The file is seeked to the aligned offset and the bytes before offset are skipped when they're read.
+func (r *directReader) Seek(offset int64, whence int) (int64, error) {
+	if whence != io.SeekStart {
+		return 0, errors.New("only io.SeekStart is supported")
+	}
+
+	if offset < 0 {
+		return 0, errors.New("negative position")
+	}
+
+	aligned := offset / int64(r.align) * int64(r.align)
+	if _, err := r.f.Seek(aligned, io.SeekStart); err != nil {
+		return 0, err
+	}
+
+	r.off, r.skip, r.pending = offset, int(offset-aligned), nil
+	return offset, nil
+}
+
+// Close implements [io.Closer] interface.
+func (r *directReader) Close() error {
+	return r.f.Close()
+}
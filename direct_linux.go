@@ -0,0 +1,11 @@
+package iocopy
+
+import (
+	"os"
+	"syscall"
+)
+
+// openDirect opens the file for reading with O_DIRECT to bypass the page cache.
+func openDirect(name string) (*os.File, error) {
+	return os.OpenFile(name, os.O_RDONLY|syscall.O_DIRECT, 0)
+}
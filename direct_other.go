@@ -0,0 +1,13 @@
+//go:build !linux
+
+package iocopy
+
+import (
+	"errors"
+	"os"
+)
+
+// openDirect is only implemented on Linux.
+func openDirect(name string) (*os.File, error) {
+	return nil, errors.ErrUnsupported
+}
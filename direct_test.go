@@ -0,0 +1,52 @@
+package iocopy_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/northbright/iocopy"
+)
+
+func ExampleWithDirectIO() {
+	// This example copies a file with direct IO to bypass the page cache.
+	// The unaligned buffer is replaced with an aligned one by the IO copy.
+	dir, err := os.MkdirTemp("", "iocopy")
+	if err != nil {
+		log.Printf("os.MkdirTemp() error: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	// The size is not a multiple of the alignment.
+	data := bytes.Repeat([]byte("0123456789abcdef"), 64*1024+7)
+	src := filepath.Join(dir, "src")
+	if err = os.WriteFile(src, data, 0644); err != nil {
+		log.Printf("os.WriteFile() error: %v", err)
+		return
+	}
+
+	dst := filepath.Join(dir, "dst")
+	t := iocopy.NewCopyFileTask(dst, src, nil, iocopy.WithDirectIO())
+	if err = iocopy.Do(context.Background(), t, make([]byte, 1000), nil); err != nil {
+		log.Printf("iocopy.Do() error: %v", err)
+		return
+	}
+
+	copied, err := os.ReadFile(dst)
+	if err != nil {
+		log.Printf("os.ReadFile() error: %v", err)
+		return
+	}
+	fmt.Printf("copied: %v\n", bytes.Equal(copied, data))
+
+	buf := iocopy.AlignedBuffer(1000)
+	fmt.Printf("size of the aligned buffer is a multiple of the alignment: %v\n", len(buf)%iocopy.Alignment() == 0)
+
+	// Output:
+	// copied: true
+	// size of the aligned buffer is a multiple of the alignment: true
+}
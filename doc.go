@@ -0,0 +1,7 @@
+// Package iocopy provides [context.Context] aware IO copy functions and tasks which can be stopped and resumed.
+//
+// Sizes, offsets and numbers of bytes are int64 across the API, e.g. the totals and the counters of the copy functions,
+// [Task], the events of [Do] and the states of the tasks, like [io.Copy], [net/http.Response.ContentLength] and [io/fs.FileInfo.Size].
+// A negative total means the size is unknown.
+// Sizes of the buffers and the chunks in memory are int, e.g. [WithPrefetch], since they're the lengths of slices.
+package iocopy
@@ -0,0 +1,580 @@
+package iocopy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// DownloadTask implements [Task] interface to download a remote file.
+// It resumes the download by setting "range" header.
+// It restarts the download if the server does not support range.
+type DownloadTask struct {
+	fsys   WriteFS
+	dst    string
+	url    string
+	total  int64
+	copied int64
+	resp   *http.Response
+	pf     *PrefetchReader
+	pr     *parallelReader
+	dstF   WriteFile
+	opts   options
+	// digest is the digest of the destination file if it implements [Digester].
+	digest string
+	// done is true when the destination is committed by [Do].
+	done bool
+	// verify is true if the downloaded bytes should be verified with the mirror.
+	verify bool
+	// hs computes the checksums of the downloaded bytes if [WithHash] is set.
+	hs *hashSet
+	// hashStates are the marshaled states of the hashes loaded from the state.
+	hashStates map[string][]byte
+	// ranges are the downloaded ranges if they have holes, e.g. written out of order.
+	// It's nil if the bytes are downloaded sequentially and copied is the end of them.
+	ranges RangeSet
+	// segments are the digests of the segments downloaded by the previous runs
+	// and sh computes the ones downloaded by the connections of the current run.
+	segments []SegmentDigest
+	sh       *segmentHashes
+	// loaded is true if the task is loaded from the state and the remote file is not probed yet.
+	loaded bool
+	// etag is the ETag of the remote file.
+	etag string
+	// restartReason and discarded are reported by [Restarter] if the download restarts when it's opened.
+	restartReason string
+	discarded     int64
+	// expected is the expected digest of [WithExpectedDigest] and verification is the outcome of verifying it.
+	expected     *ExpectedDigest
+	verification *DigestVerification
+}
+
+// DownloadState is the typed state of [DownloadTask].
+type DownloadState struct {
+	// Version is the version of the state. See [StateVersion].
+	Version int `json:"version"`
+	// Type is the type of the state to load it by [LoadTask]: [StateTypeDownload].
+	Type string `json:"type"`
+	// Dst is the destination file.
+	Dst string `json:"dst"`
+	// URL is the url of the remote file.
+	URL string `json:"url"`
+	// Total is the total number of bytes to download.
+	// A negative value indicates total size is unknown.
+	Total int64 `json:"total"`
+	// Copied is the number of bytes downloaded.
+	Copied int64 `json:"copied"`
+	// Hashes are the marshaled states of the hashes by their algorithms if [WithHash] is set.
+	Hashes map[string][]byte `json:"hashes,omitempty"`
+	// Done are the downloaded ranges if they have holes, e.g. written out of order.
+	// The task resumes by fetching exactly the missing ranges and Copied is the total size of them.
+	// It's omitted if the bytes are downloaded sequentially.
+	Done RangeSet `json:"done,omitempty"`
+	// Segments are the digests of the segments downloaded by the connections of [WithConnections].
+	// The bytes downloaded sequentially are not covered. See [DownloadTask.VerifySegments].
+	Segments []SegmentDigest `json:"segments,omitempty"`
+	// ETag is the ETag of the remote file. The download restarts if it changes when it's resumed.
+	ETag string `json:"etag,omitempty"`
+	// Expected is the expected digest set by [WithExpectedDigest].
+	Expected *ExpectedDigest `json:"expected,omitempty"`
+}
+
+// DownloadResult is the typed result of [DownloadTask].
+type DownloadResult struct {
+	// Dst is the destination file.
+	Dst string `json:"dst"`
+	// URL is the url of the remote file.
+	URL string `json:"url"`
+	// Size is the size of the destination file.
+	Size int64 `json:"size"`
+	// Digest is the digest of the destination file if its file system computes it, e.g. [CASFS].
+	Digest string `json:"digest,omitempty"`
+	// Checksums are the hex encoded checksums by the algorithms if [WithHash] is set.
+	Checksums map[string]string `json:"checksums,omitempty"`
+	// Verification is the outcome of verifying the checksum against the digest of [WithExpectedDigest].
+	Verification *DigestVerification `json:"verification,omitempty"`
+	// Segments are the digests of the segments downloaded by the connections of [WithConnections].
+	Segments []SegmentDigest `json:"segments,omitempty"`
+}
+
+// NewDownloadTask returns a [*DownloadTask] which downloads url to dst.
+// dst can be the standard output([Stdio]) if fsys is [OSFS]. It can't be resumed and the part file is not used.
+// fsys is the file system of dst. [OSFS] is used if it's nil.
+// opts: optional parameters. e.g. [WithPrefetch], [WithMaxBytes], [WithCleanup],
+// [WithPartFile], [WithSignature], [WithRateLimiter], [WithHash], [WithMethod],
+// [WithRequestHook], [WithHTTPClient], [WithConnections], [WithReconnect], [WithOverlapCheck].
+func NewDownloadTask(dst, url string, fsys WriteFS, opts ...Option) *DownloadTask {
+	if fsys == nil {
+		fsys = OSFS
+	}
+
+	t := &DownloadTask{fsys: fsys, dst: dst, url: url, total: -1, opts: newOptions(opts)}
+	t.expected = t.opts.expected
+	t.expectDigest()
+	return t
+}
+
+// LoadDownloadTask loads a [*DownloadTask] from the state to resume the download.
+// When it's opened, the remote file is probed by a HEAD request before resuming. If the server does not support range
+// any more or the size(or ETag) does not match the state, it restarts the download instead of appending to the file.
+// fsys is the file system of dst. [OSFS] is used if it's nil.
+// opts: optional parameters which are not saved in the state.
+// Use [WithMirror] to resume from a different url.
+func LoadDownloadTask(state []byte, fsys WriteFS, opts ...Option) (*DownloadTask, error) {
+	var s DownloadState
+	if err := json.Unmarshal(state, &s); err != nil {
+		return nil, err
+	}
+
+	t := NewDownloadTask(s.Dst, s.URL, fsys, opts...)
+	t.total = s.Total
+	t.copied = s.Copied
+	t.hashStates = s.Hashes
+	t.etag = s.ETag
+	t.loaded = true
+	if s.Done != nil {
+		t.ranges = s.Done
+		t.copied = s.Done.Size()
+		t.segments = s.Segments
+	}
+
+	if s.Expected != nil {
+		t.expected = s.Expected
+	}
+	t.expectDigest()
+
+	if t.opts.mirror != "" && t.opts.mirror != s.URL {
+		// Switch to the mirror.
+		t.url = t.opts.mirror
+		t.verify = true
+	}
+	return t, nil
+}
+
+// ID implements [Identifier] interface.
+// It's computed from dst and url.
+func (t *DownloadTask) ID() string {
+	return taskID("download", t.dst, t.url)
+}
+
+// reconnectPolicy implements reconnecter interface.
+func (t *DownloadTask) reconnectPolicy() reconnectPolicy {
+	return t.opts.reconnect
+}
+
+// Endpoints implements [Endpointer] interface.
+func (t *DownloadTask) Endpoints() (src, dst string) {
+	return t.url, t.dst
+}
+
+// newRequest returns the request to download the file from url.
+// It's a GET request unless the method and body are set by [WithMethod].
+func (t *DownloadTask) newRequest(ctx context.Context) (*http.Request, error) {
+	method := http.MethodGet
+	if t.opts.method != "" {
+		method = t.opts.method
+	}
+
+	var body io.Reader
+	if t.opts.body != nil {
+		body = bytes.NewReader(t.opts.body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, t.url, body)
+	if err != nil {
+		return nil, err
+	}
+
+	if t.opts.contentType != "" {
+		req.Header.Set("content-type", t.opts.contentType)
+	}
+	return req, nil
+}
+
+// do applies the request hook set by [WithRequestHook] and sends the request
+// by the client set by [WithHTTPClient] or [http.DefaultClient].
+func (t *DownloadTask) do(req *http.Request) (*http.Response, error) {
+	if t.opts.requestHook != nil {
+		if err := t.opts.requestHook(req); err != nil {
+			return nil, fmt.Errorf("request hook: %w", err)
+		}
+	}
+
+	c := t.opts.client
+	if c == nil {
+		c = http.DefaultClient
+	}
+	return c.Do(req)
+}
+
+// Open implements [Task] interface.
+// It makes the HTTP request and opens the destination file.
+// If the downloaded ranges have holes, it fetches the missing ranges one by one.
+func (t *DownloadTask) Open(ctx context.Context) (dst io.Writer, src io.Reader, err error) {
+	t.compactRanges()
+
+	if t.loaded {
+		t.reprobe(ctx)
+		t.loaded = false
+	}
+
+	if t.verify && t.copied > 0 {
+		if err = t.verifyMirror(ctx); err != nil {
+			return nil, nil, err
+		}
+	} else if t.opts.overlap > 0 && t.copied > 0 && t.fsys == OSFS {
+		if err = t.checkOverlap(ctx); err != nil {
+			return nil, nil, err
+		}
+	}
+	t.verify = false
+
+	// Compute the hashes of the downloaded bytes again if their states are not loaded, e.g. from a token.
+	if len(t.opts.hashAlgs) > 0 && t.hs == nil && t.hashStates == nil && t.ranges == nil && t.copied > 0 {
+		if t.fsys != OSFS {
+			t.copied = 0
+		} else if err = t.hashDstPrefix(t.copied); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	req, err := t.newRequest(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	stdout := t.fsys == OSFS && isStdout(t.dst)
+	if stdout && (t.copied > 0 || t.ranges != nil) {
+		return nil, nil, errStdioResume(t.dst)
+	}
+
+	// missing are the ranges to fetch if the downloaded ranges have holes.
+	var missing RangeSet
+	if t.ranges != nil {
+		if t.total < 0 || t.fsys != OSFS {
+			return nil, nil, errors.New("holes of the download can't be filled without total size or OSFS")
+		}
+		missing = t.ranges.Missing(t.total)
+		logDebug(ctx, "iocopy: fetch missing ranges", "url", t.url, "missing", len(missing), "size", missing.Size())
+		req.Header.Set("range", rangeHeader(missing[0]))
+	} else if t.copied > 0 || t.opts.connections > 1 {
+		// Range is also requested to detect if it's supported for the connections.
+		req.Header.Set("range", fmt.Sprintf("bytes=%d-", t.copied))
+	}
+
+	if t.copied > 0 && t.etag != "" && !strings.HasPrefix(t.etag, "W/") {
+		// The server sends the whole file if it changed.
+		req.Header.Set("if-range", t.etag)
+	}
+
+	if t.resp, err = t.do(req); err != nil {
+		return nil, nil, err
+	}
+
+	defer func() {
+		if err != nil {
+			t.Close()
+		}
+	}()
+
+	switch t.resp.StatusCode {
+	case http.StatusOK:
+		// New download, the server does not support range or the remote file changed.
+		t.restart(ctx, "range not supported or the remote file changed")
+		t.total = t.resp.ContentLength
+		t.etag = t.resp.Header.Get("etag")
+	case http.StatusPartialContent:
+		if t.copied > 0 || t.ranges != nil {
+			if reason := t.changed(t.resp); reason != "" {
+				// Download the new file from the beginning instead of appending mismatched bytes.
+				t.resp.Body.Close()
+				t.resp = nil
+				t.restart(ctx, reason)
+				return t.Open(ctx)
+			}
+		}
+
+		if etag := t.resp.Header.Get("etag"); etag != "" {
+			t.etag = etag
+		}
+
+		if t.ranges != nil {
+			break
+		}
+
+		t.total = -1
+		if t.resp.ContentLength >= 0 {
+			t.total = t.copied + t.resp.ContentLength
+		}
+	default:
+		return nil, nil, fmt.Errorf("unexpected status: %v", t.resp.Status)
+	}
+
+	if t.opts.maxBytes >= 0 && t.total > t.opts.maxBytes {
+		return nil, nil, &MaxBytesError{Limit: t.opts.maxBytes}
+	}
+
+	parallel := t.opts.connections > 1 && t.fsys == OSFS && !stdout && t.total >= 0 && t.resp.StatusCode == http.StatusPartialContent
+	if t.opts.connections > 1 {
+		logDebug(ctx, "iocopy: download by multiple connections", "url", t.url, "parallel", parallel, "connections", t.opts.connections)
+	}
+
+	if parallel && t.ranges == nil {
+		// Track the downloaded ranges since the segments are written out of order.
+		t.ranges = RangeSet{}
+		t.ranges.Add(0, t.copied)
+		missing = t.ranges.Missing(t.total)
+	}
+
+	t.done = false
+	if t.ranges != nil {
+		if t.dstF, err = t.fsys.OpenFile(t.dstName(), os.O_WRONLY|os.O_CREATE, 0644); err != nil {
+			return nil, nil, err
+		}
+
+		// The checksums are computed from the destination file when it's committed.
+		if parallel {
+			if dst, src, err = t.openParallel(ctx, missing); err != nil {
+				return nil, nil, err
+			}
+		} else {
+			dst = newRangeWriter(t.dstF, missing, &t.ranges)
+			src = newRangeReader(ctx, t, missing)
+		}
+	} else {
+		if stdout {
+			dst = os.Stdout
+		} else {
+			if t.dstF, err = openDst(t.fsys, t.dstName(), t.total, t.copied); err != nil {
+				return nil, nil, err
+			}
+			dst = t.dstF
+		}
+
+		if t.opts.maxBytes >= 0 {
+			dst = newMaxBytesWriter(dst, t.opts.maxBytes, t.copied)
+		}
+
+		if len(t.opts.hashAlgs) > 0 {
+			// The hashes are restarted if the download is restarted.
+			if t.hs == nil || t.copied == 0 {
+				if t.hs, err = newHashSet(t.opts.hashAlgs, t.hashStates, t.copied); err != nil {
+					return nil, nil, err
+				}
+			}
+			if t.opts.hashWorkers > 0 {
+				t.hs.offload(t.opts.hashWorkers)
+			}
+			dst = &hashWriter{w: dst, hs: t.hs}
+		}
+
+		src = t.resp.Body
+	}
+
+	if t.opts.verifier != nil || t.expected != nil || t.usePartFile() || len(t.opts.hashAlgs) > 0 && t.ranges != nil {
+		dst = &commitWriter{Writer: dst, fn: func() error {
+			return t.commit(ctx)
+		}}
+	}
+
+	src = t.opts.limit(ctx, src)
+
+	// The segments are read ahead by the connections.
+	if t.opts.prefetch && t.pr == nil {
+		t.pf = NewPrefetchReader(ctx, src, t.opts.prefetchDepth, t.opts.prefetchSize)
+		src = t.pf
+	}
+
+	return t.opts.throttle(ctx, dst), src, nil
+}
+
+// openParallel preallocates the destination file
+// and starts the connections set by [WithConnections] to download the missing ranges.
+func (t *DownloadTask) openParallel(ctx context.Context, missing RangeSet) (dst io.Writer, src io.Reader, err error) {
+	f, ok := t.dstF.(io.WriterAt)
+	if !ok {
+		return nil, nil, fmt.Errorf("destination file does not support WriteAt")
+	}
+
+	if err = t.dstF.Truncate(t.total); err != nil {
+		return nil, nil, err
+	}
+
+	segs := splitRanges(missing, t.opts.connections, minSegmentSize)
+	t.pr = newParallelReader(ctx, t, segs, t.opts.connections)
+	t.sh = newSegmentHashes(segs)
+	return &parallelWriter{pr: t.pr, f: f, ranges: &t.ranges, sh: t.sh}, t.pr, nil
+}
+
+// commit is called by [Do] when all bytes are written.
+// It computes the checksums of the destination file if the holes are filled and [WithHash] is set,
+// verifies the checksum if [WithExpectedDigest] is set
+// and verifies the signature if [WithSignature] is set.
+func (t *DownloadTask) commit(ctx context.Context) error {
+	if t.ranges != nil && len(t.opts.hashAlgs) > 0 {
+		if err := t.hashDst(); err != nil {
+			return err
+		}
+	}
+
+	if t.expected != nil {
+		if err := t.verifyDigest(); err != nil {
+			return err
+		}
+	}
+
+	if t.opts.verifier != nil {
+		if err := t.verifySignature(ctx); err != nil {
+			return err
+		}
+	}
+
+	t.done = true
+	return nil
+}
+
+// WorkerProgress implements [WorkerProgresser] interface.
+// It returns the bytes downloaded by each connection of [WithConnections] since the task is opened.
+func (t *DownloadTask) WorkerProgress() []int64 {
+	if t.pr == nil {
+		return nil
+	}
+	return t.pr.pool.Progress()
+}
+
+// Close implements [Task] interface.
+func (t *DownloadTask) Close() error {
+	var err error
+
+	if t.pf != nil {
+		t.pf.Close()
+		t.pf = nil
+	}
+
+	if t.pr != nil {
+		t.pr.Close()
+		t.pr = nil
+		t.segments = t.segmentDigests()
+		t.sh = nil
+	}
+
+	if t.resp != nil {
+		t.resp.Body.Close()
+		t.resp = nil
+	}
+
+	if t.hs != nil {
+		t.hs.stop()
+	}
+
+	if t.dstF != nil {
+		err = t.dstF.Close()
+		if d, ok := t.dstF.(Digester); ok {
+			t.digest = d.Digest()
+		}
+		t.dstF = nil
+
+		if err == nil && t.usePartFile() {
+			err = t.closePartFile()
+		}
+	}
+
+	t.restartReason, t.discarded = "", 0
+	return err
+}
+
+// Cleanup implements [Cleaner] interface.
+// It cleans up the partial destination file by the policy set by [WithCleanup]
+// and resets the number of bytes copied to restart.
+func (t *DownloadTask) Cleanup(cause error) error {
+	if t.opts.cleanup == CleanupKeep || t.fsys != OSFS || isStdout(t.dst) {
+		return nil
+	}
+
+	state, err := t.State()
+	if err != nil {
+		return err
+	}
+
+	if err = cleanupDst(t.dstName(), t.opts.cleanup, t.opts.trashDir, state, cause); err != nil {
+		return err
+	}
+
+	if t.usePartFile() {
+		if err = os.Remove(longPath(t.dst + PartStateExt)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+
+	t.copied = 0
+	t.ranges = nil
+	t.segments = nil
+	return nil
+}
+
+// Total implements [Task] interface.
+func (t *DownloadTask) Total() int64 {
+	return t.total
+}
+
+// Copied implements [Task] interface.
+func (t *DownloadTask) Copied() int64 {
+	return t.copied
+}
+
+// SetCopied implements [Task] interface.
+func (t *DownloadTask) SetCopied(copied int64) {
+	t.copied = copied
+}
+
+// State implements [Task] interface.
+func (t *DownloadTask) State() ([]byte, error) {
+	s, err := t.state()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(s)
+}
+
+// StateValue implements [StateValuer] interface.
+// It returns the [DownloadState].
+// Hashes are omitted if they fail to be marshaled.
+func (t *DownloadTask) StateValue() any {
+	s, _ := t.state()
+	return s
+}
+
+// state returns the [DownloadState] with the marshaled states of the hashes.
+func (t *DownloadTask) state() (DownloadState, error) {
+	s := DownloadState{Version: StateVersion, Type: StateTypeDownload, Dst: t.dst, URL: t.url, Total: t.total, Copied: t.copied, Hashes: t.hashStates, Done: t.ranges, Segments: t.segmentDigests(), ETag: t.etag, Expected: t.expected}
+	if t.hs != nil {
+		states, _, err := t.hs.states()
+		if err != nil {
+			return s, err
+		}
+		s.Hashes = states
+	}
+	return s, nil
+}
+
+// Result implements [Task] interface.
+func (t *DownloadTask) Result() ([]byte, error) {
+	return json.Marshal(t.ResultValue())
+}
+
+// ResultValue implements [ResultValuer] interface.
+// It returns the [DownloadResult].
+func (t *DownloadTask) ResultValue() any {
+	r := DownloadResult{Dst: t.dst, URL: t.url, Size: t.copied, Digest: t.digest, Verification: t.verification, Segments: t.segmentDigests()}
+	if t.hs != nil {
+		r.Checksums, _ = t.hs.checksums()
+	}
+	return r
+}
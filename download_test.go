@@ -0,0 +1,1363 @@
+package iocopy_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/northbright/iocopy"
+)
+
+func ExampleDownloadTask() {
+	// This example uses iocopy.DownloadTask to download a remote file.
+	// It stops the task after the first bytes written to emulate user cancelation.
+	// Then it loads the task from the saved state to resume the download.
+	data := bytes.Repeat([]byte("0123456789abcdef"), 64*1024)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "file", time.Time{}, bytes.NewReader(data))
+	}))
+	defer ts.Close()
+
+	dir, err := os.MkdirTemp("", "iocopy")
+	if err != nil {
+		log.Printf("os.MkdirTemp() error: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	dst := filepath.Join(dir, "file")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var state []byte
+
+	t := iocopy.NewDownloadTask(dst, ts.URL, nil)
+	iocopy.Do(ctx, t, nil, func(e iocopy.Event) {
+		switch e := e.(type) {
+		case *iocopy.EventWritten:
+			// Emulate user cancelation.
+			cancel()
+		case *iocopy.EventStop:
+			fmt.Printf("stopped: %v\n", e.Err)
+			state = e.State
+		}
+	})
+
+	// Load the task from the state and resume.
+	t, err = iocopy.LoadDownloadTask(state, nil)
+	if err != nil {
+		log.Printf("iocopy.LoadDownloadTask() error: %v", err)
+		return
+	}
+	fmt.Printf("resume: %v\n", t.Copied() > 0)
+
+	iocopy.Do(context.Background(), t, nil, func(e iocopy.Event) {
+		switch e := e.(type) {
+		case *iocopy.EventOK:
+			fmt.Printf("done: %v/%v bytes downloaded\n", t.Copied(), t.Total())
+		case *iocopy.EventError:
+			log.Printf("error: %v", e.Err)
+		}
+	})
+
+	downloaded, err := os.ReadFile(dst)
+	if err != nil {
+		log.Printf("os.ReadFile() error: %v", err)
+		return
+	}
+	fmt.Printf("same content: %v\n", bytes.Equal(downloaded, data))
+
+	// Output:
+	// stopped: context canceled
+	// resume: true
+	// done: 1048576/1048576 bytes downloaded
+	// same content: true
+}
+
+func ExampleWithMaxBytes() {
+	// This example limits the bytes written by a DownloadTask.
+	// The server sends a chunked response without Content-Length which is larger than the limit.
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for i := 0; i < 64; i++ {
+			w.Write(bytes.Repeat([]byte("0123456789abcdef"), 1024))
+			w.(http.Flusher).Flush()
+		}
+	}))
+	defer ts.Close()
+
+	dir, err := os.MkdirTemp("", "iocopy")
+	if err != nil {
+		log.Printf("os.MkdirTemp() error: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	dst := filepath.Join(dir, "file")
+	t := iocopy.NewDownloadTask(dst, ts.URL, nil, iocopy.WithMaxBytes(256*1024))
+
+	err = iocopy.Do(context.Background(), t, nil, nil)
+
+	var maxBytesErr *iocopy.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		fmt.Printf("aborted: %v, written bytes <= limit: %v\n", err, t.Copied() <= maxBytesErr.Limit)
+	}
+
+	// Output:
+	// aborted: exceeded max bytes: 262144, written bytes <= limit: true
+}
+
+func ExampleDownload() {
+	// This example downloads a remote file to a part file.
+	// It stops the download after the first bytes written to emulate user cancelation.
+	// Then it calls iocopy.Download again which resumes the part file automatically.
+	data := bytes.Repeat([]byte("0123456789abcdef"), 64*1024)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "file", time.Time{}, bytes.NewReader(data))
+	}))
+	defer ts.Close()
+
+	dir, err := os.MkdirTemp("", "iocopy")
+	if err != nil {
+		log.Printf("os.MkdirTemp() error: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	dst := filepath.Join(dir, "file")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var stopped int64
+	iocopy.Download(ctx, dst, ts.URL, nil, func(e iocopy.Event) {
+		switch e := e.(type) {
+		case *iocopy.EventWritten:
+			stopped = e.Copied
+			// Emulate user cancelation.
+			cancel()
+		case *iocopy.EventStop:
+			fmt.Printf("stopped: %v\n", e.Err)
+		}
+	})
+
+	for _, name := range []string{dst, dst + iocopy.PartFileExt, dst + iocopy.PartStateExt} {
+		_, err := os.Stat(name)
+		fmt.Printf("%v exists: %v\n", filepath.Base(name), err == nil)
+	}
+
+	resumed := false
+	if err = iocopy.Download(context.Background(), dst, ts.URL, nil, func(e iocopy.Event) {
+		if e, ok := e.(*iocopy.EventWritten); ok && !resumed {
+			resumed = true
+			fmt.Printf("resumed: %v\n", e.Copied > stopped)
+		}
+	}); err != nil {
+		log.Printf("iocopy.Download() error: %v", err)
+		return
+	}
+
+	for _, name := range []string{dst, dst + iocopy.PartFileExt, dst + iocopy.PartStateExt} {
+		_, err := os.Stat(name)
+		fmt.Printf("%v exists: %v\n", filepath.Base(name), err == nil)
+	}
+
+	downloaded, err := os.ReadFile(dst)
+	if err != nil {
+		log.Printf("os.ReadFile() error: %v", err)
+		return
+	}
+	fmt.Printf("same content: %v\n", bytes.Equal(downloaded, data))
+
+	// Output:
+	// stopped: context canceled
+	// file exists: false
+	// file.iocopy-part exists: true
+	// file.iocopy-part.json exists: true
+	// resumed: true
+	// file exists: true
+	// file.iocopy-part exists: false
+	// file.iocopy-part.json exists: false
+	// same content: true
+}
+
+func ExampleDownload_fileURL() {
+	// This example downloads a local file by a file:// url, e.g. a local cache in a list of mirrors.
+	// It's copied by iocopy.CopyFileTask to the part file which is renamed to dst when it's done.
+	dir, err := os.MkdirTemp("", "iocopy")
+	if err != nil {
+		log.Printf("os.MkdirTemp() error: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	data := bytes.Repeat([]byte("0123456789abcdef"), 64*1024)
+	src := filepath.Join(dir, "cache")
+	if err = os.WriteFile(src, data, 0644); err != nil {
+		log.Printf("os.WriteFile() error: %v", err)
+		return
+	}
+
+	dst := filepath.Join(dir, "file")
+	u := &url.URL{Scheme: "file", Path: filepath.ToSlash(src)}
+	if !strings.HasPrefix(u.Path, "/") {
+		// Windows path: "/C:/...".
+		u.Path = "/" + u.Path
+	}
+
+	err = iocopy.Download(context.Background(), dst, u.String(), nil, func(e iocopy.Event) {
+		if e, ok := e.(*iocopy.EventOK); ok {
+			r := e.Value.(iocopy.CopyFileResult)
+			fmt.Printf("dst: %v, size: %v\n", filepath.Base(r.Dst), r.Size)
+		}
+	})
+	if err != nil {
+		log.Printf("iocopy.Download() error: %v", err)
+		return
+	}
+
+	for _, name := range []string{dst + iocopy.PartFileExt, dst + iocopy.PartStateExt} {
+		_, err := os.Stat(name)
+		fmt.Printf("%v exists: %v\n", filepath.Base(name), err == nil)
+	}
+
+	downloaded, err := os.ReadFile(dst)
+	if err != nil {
+		log.Printf("os.ReadFile() error: %v", err)
+		return
+	}
+	fmt.Printf("same content: %v\n", bytes.Equal(downloaded, data))
+
+	// Output:
+	// dst: file, size: 1048576
+	// file.iocopy-part exists: false
+	// file.iocopy-part.json exists: false
+	// same content: true
+}
+
+func ExampleDownload_dataURL() {
+	// This example downloads a data: url with a base64 payload, e.g. a small embedded asset or a test fixture.
+	dir, err := os.MkdirTemp("", "iocopy")
+	if err != nil {
+		log.Printf("os.MkdirTemp() error: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	data := bytes.Repeat([]byte("0123456789abcdef"), 64*1024)
+	u := "data:application/octet-stream;base64," + base64.StdEncoding.EncodeToString(data)
+	dst := filepath.Join(dir, "file")
+
+	err = iocopy.Download(context.Background(), dst, u, nil, func(e iocopy.Event) {
+		switch e := e.(type) {
+		case *iocopy.EventWritten:
+			if e.Percent == 100 {
+				fmt.Printf("total: %v, copied: %v\n", e.Total, e.Copied)
+			}
+		case *iocopy.EventOK:
+			fmt.Printf("size: %v\n", e.Value.(iocopy.DownloadResult).Size)
+		}
+	})
+	if err != nil {
+		log.Printf("iocopy.Download() error: %v", err)
+		return
+	}
+
+	downloaded, err := os.ReadFile(dst)
+	if err != nil {
+		log.Printf("os.ReadFile() error: %v", err)
+		return
+	}
+	fmt.Printf("same content: %v\n", bytes.Equal(downloaded, data))
+
+	// Output:
+	// total: 1048576, copied: 1048576
+	// size: 1048576
+	// same content: true
+}
+
+func ExampleWithMirror() {
+	// This example stops a download and resumes it from a mirror.
+	// The downloaded bytes are verified with the mirror before continuing.
+	data := bytes.Repeat([]byte("0123456789abcdef"), 64*1024)
+	newServer := func(data []byte) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.ServeContent(w, r, "file", time.Time{}, bytes.NewReader(data))
+		}))
+	}
+
+	origin := newServer(data)
+	defer origin.Close()
+
+	mirror := newServer(data)
+	defer mirror.Close()
+
+	// The content of the bad mirror is different.
+	badMirror := newServer(bytes.ToUpper(data))
+	defer badMirror.Close()
+
+	dir, err := os.MkdirTemp("", "iocopy")
+	if err != nil {
+		log.Printf("os.MkdirTemp() error: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var state []byte
+	dst := filepath.Join(dir, "file")
+	t := iocopy.NewDownloadTask(dst, origin.URL, nil)
+	iocopy.Do(ctx, t, nil, func(e iocopy.Event) {
+		switch e := e.(type) {
+		case *iocopy.EventWritten:
+			// Emulate user cancelation.
+			cancel()
+		case *iocopy.EventStop:
+			state = e.State
+		}
+	})
+
+	for _, url := range []string{badMirror.URL, mirror.URL} {
+		t, err = iocopy.LoadDownloadTask(state, nil, iocopy.WithMirror(url, 0))
+		if err != nil {
+			log.Printf("iocopy.LoadDownloadTask() error: %v", err)
+			return
+		}
+
+		err = iocopy.Do(context.Background(), t, nil, nil)
+		fmt.Printf("mismatch: %v\n", errors.Is(err, iocopy.ErrMirrorMismatch))
+	}
+
+	downloaded, err := os.ReadFile(dst)
+	if err != nil {
+		log.Printf("os.ReadFile() error: %v", err)
+		return
+	}
+	fmt.Printf("same content: %v\n", bytes.Equal(downloaded, data))
+
+	// Output:
+	// mismatch: true
+	// mismatch: false
+	// same content: true
+}
+
+func ExampleWithHash() {
+	// This example computes the SHA-256 checksum of the downloaded bytes as they stream to the destination.
+	// The download is stopped and resumed, and the states of the hashes are saved in the state of the task.
+	data := bytes.Repeat([]byte("0123456789abcdef"), 64*1024)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "file", time.Time{}, bytes.NewReader(data))
+	}))
+	defer ts.Close()
+
+	dir, err := os.MkdirTemp("", "iocopy")
+	if err != nil {
+		log.Printf("os.MkdirTemp() error: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	dst := filepath.Join(dir, "file")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var state []byte
+	t := iocopy.NewDownloadTask(dst, ts.URL, nil, iocopy.WithHash("sha256"))
+	iocopy.Do(ctx, t, nil, func(e iocopy.Event) {
+		switch e := e.(type) {
+		case *iocopy.EventWritten:
+			// Emulate user cancelation.
+			cancel()
+		case *iocopy.EventStop:
+			state = e.State
+		}
+	})
+
+	if t, err = iocopy.LoadDownloadTask(state, nil, iocopy.WithHash("sha256")); err != nil {
+		log.Printf("iocopy.LoadDownloadTask() error: %v", err)
+		return
+	}
+	fmt.Printf("resume: %v\n", t.Copied() > 0)
+
+	iocopy.Do(context.Background(), t, nil, func(e iocopy.Event) {
+		if e, ok := e.(*iocopy.EventOK); ok {
+			sum := sha256.Sum256(data)
+			r := e.Value.(iocopy.DownloadResult)
+			fmt.Printf("SHA-256 matches: %v\n", r.Checksums["sha256"] == hex.EncodeToString(sum[:]))
+		}
+	})
+
+	// Output:
+	// resume: true
+	// SHA-256 matches: true
+}
+
+func ExampleWithHashWorkers() {
+	// This example computes the SHA-256 and SHA-512 checksums of the downloaded bytes by 2 background workers,
+	// so the writes are not gated by the hashes. The download is stopped and resumed.
+	data := bytes.Repeat([]byte("0123456789abcdef"), 64*1024)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "file", time.Time{}, bytes.NewReader(data))
+	}))
+	defer ts.Close()
+
+	dir, err := os.MkdirTemp("", "iocopy")
+	if err != nil {
+		log.Printf("os.MkdirTemp() error: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	dst := filepath.Join(dir, "file")
+	opts := []iocopy.Option{iocopy.WithHash("sha256", "sha512"), iocopy.WithHashWorkers(2)}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var state []byte
+	t := iocopy.NewDownloadTask(dst, ts.URL, nil, opts...)
+	iocopy.Do(ctx, t, nil, func(e iocopy.Event) {
+		switch e := e.(type) {
+		case *iocopy.EventWritten:
+			// Emulate user cancelation.
+			cancel()
+		case *iocopy.EventStop:
+			state = e.State
+		}
+	})
+
+	if t, err = iocopy.LoadDownloadTask(state, nil, opts...); err != nil {
+		log.Printf("iocopy.LoadDownloadTask() error: %v", err)
+		return
+	}
+
+	iocopy.Do(context.Background(), t, nil, func(e iocopy.Event) {
+		if e, ok := e.(*iocopy.EventOK); ok {
+			sum256, sum512 := sha256.Sum256(data), sha512.Sum512(data)
+			r := e.Value.(iocopy.DownloadResult)
+			fmt.Printf("SHA-256 matches: %v\n", r.Checksums["sha256"] == hex.EncodeToString(sum256[:]))
+			fmt.Printf("SHA-512 matches: %v\n", r.Checksums["sha512"] == hex.EncodeToString(sum512[:]))
+		}
+	})
+
+	// Output:
+	// SHA-256 matches: true
+	// SHA-512 matches: true
+}
+
+func ExampleWithLocalAddr() {
+	// This example dials the connections of a download from the local address 127.0.0.1.
+	// An interface name like "eth1" can also be used on multi-homed hosts.
+	data := bytes.Repeat([]byte("0123456789abcdef"), 1024)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, _ := net.SplitHostPort(r.RemoteAddr)
+		fmt.Printf("remote address of the request: %v\n", host)
+		http.ServeContent(w, r, "file", time.Time{}, bytes.NewReader(data))
+	}))
+	defer ts.Close()
+
+	dir, err := os.MkdirTemp("", "iocopy")
+	if err != nil {
+		log.Printf("os.MkdirTemp() error: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	t := iocopy.NewDownloadTask(filepath.Join(dir, "file"), ts.URL, nil, iocopy.WithLocalAddr("127.0.0.1"))
+	if err = iocopy.Do(context.Background(), t, nil, nil); err != nil {
+		log.Printf("iocopy.Do() error: %v", err)
+		return
+	}
+
+	// Output:
+	// remote address of the request: 127.0.0.1
+}
+
+func ExampleWithLocalAddr_interface() {
+	// This example dials the connections of a download from the address of the loopback interface by its name,
+	// e.g. "lo" on Linux or "lo0" on macOS. The IPv4 address of the interface is preferred.
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, _ := net.SplitHostPort(r.RemoteAddr)
+		fmt.Printf("remote address of the request: %v\n", host)
+		w.Write([]byte("Hello, World!"))
+	}))
+	defer ts.Close()
+
+	dir, err := os.MkdirTemp("", "iocopy")
+	if err != nil {
+		log.Printf("os.MkdirTemp() error: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	ifis, err := net.Interfaces()
+	if err != nil {
+		log.Printf("net.Interfaces() error: %v", err)
+		return
+	}
+
+	var loopback string
+	for _, ifi := range ifis {
+		if ifi.Flags&net.FlagLoopback != 0 {
+			loopback = ifi.Name
+			break
+		}
+	}
+
+	t := iocopy.NewDownloadTask(filepath.Join(dir, "file"), ts.URL, nil, iocopy.WithLocalAddr(loopback))
+	if err = iocopy.Do(context.Background(), t, nil, nil); err != nil {
+		log.Printf("iocopy.Do() error: %v", err)
+		return
+	}
+
+	// The download fails if the interface does not exist.
+	t = iocopy.NewDownloadTask(filepath.Join(dir, "file2"), ts.URL, nil, iocopy.WithLocalAddr("no-such-nic0"))
+	err = iocopy.Do(context.Background(), t, nil, nil)
+	fmt.Printf("unknown interface: %v\n", err != nil && strings.Contains(err.Error(), "local address no-such-nic0"))
+
+	// Output:
+	// remote address of the request: 127.0.0.1
+	// unknown interface: true
+}
+
+func ExampleWithMethod() {
+	// This example downloads the file returned by a POST request to an export endpoint.
+	// The download is stopped and resumed by sending the same request with the Range header.
+	data := bytes.Repeat([]byte("0123456789abcdef"), 64*1024)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query, _ := io.ReadAll(r.Body)
+		if r.Method != http.MethodPost || r.Header.Get("content-type") != "application/json" || string(query) != `{"format":"csv"}` {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		http.ServeContent(w, r, "export.csv", time.Time{}, bytes.NewReader(data))
+	}))
+	defer ts.Close()
+
+	dir, err := os.MkdirTemp("", "iocopy")
+	if err != nil {
+		log.Printf("os.MkdirTemp() error: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	dst := filepath.Join(dir, "export.csv")
+	opt := iocopy.WithMethod(http.MethodPost, []byte(`{"format":"csv"}`), "application/json")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var state []byte
+	t := iocopy.NewDownloadTask(dst, ts.URL, nil, opt)
+	iocopy.Do(ctx, t, nil, func(e iocopy.Event) {
+		switch e := e.(type) {
+		case *iocopy.EventWritten:
+			// Emulate user cancelation.
+			cancel()
+		case *iocopy.EventStop:
+			state = e.State
+		}
+	})
+
+	// The method and body are not saved in the state. Pass them again.
+	if t, err = iocopy.LoadDownloadTask(state, nil, opt); err != nil {
+		log.Printf("iocopy.LoadDownloadTask() error: %v", err)
+		return
+	}
+	fmt.Printf("resume: %v\n", t.Copied() > 0)
+
+	if err = iocopy.Do(context.Background(), t, nil, nil); err != nil {
+		log.Printf("iocopy.Do() error: %v", err)
+		return
+	}
+
+	buf, _ := os.ReadFile(dst)
+	fmt.Printf("same content: %v\n", bytes.Equal(buf, data))
+
+	// Output:
+	// resume: true
+	// same content: true
+}
+
+func ExampleWithRequestHook() {
+	// This example sets a token header on every request by a request hook,
+	// e.g. to sign requests or refresh short-lived tokens.
+	data := bytes.Repeat([]byte("0123456789abcdef"), 64*1024)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("authorization") == "" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		http.ServeContent(w, r, "file", time.Time{}, bytes.NewReader(data))
+	}))
+	defer ts.Close()
+
+	dir, err := os.MkdirTemp("", "iocopy")
+	if err != nil {
+		log.Printf("os.MkdirTemp() error: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	dst := filepath.Join(dir, "file")
+
+	requests := 0
+	opt := iocopy.WithRequestHook(func(req *http.Request) error {
+		requests++
+		req.Header.Set("authorization", fmt.Sprintf("Bearer token-%d", requests))
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var state []byte
+	t := iocopy.NewDownloadTask(dst, ts.URL, nil, opt)
+	iocopy.Do(ctx, t, nil, func(e iocopy.Event) {
+		switch e := e.(type) {
+		case *iocopy.EventWritten:
+			// Emulate user cancelation.
+			cancel()
+		case *iocopy.EventStop:
+			state = e.State
+		}
+	})
+
+	// The hook is not saved in the state. Pass it again.
+	if t, err = iocopy.LoadDownloadTask(state, nil, opt); err != nil {
+		log.Printf("iocopy.LoadDownloadTask() error: %v", err)
+		return
+	}
+
+	if err = iocopy.Do(context.Background(), t, nil, nil); err != nil {
+		log.Printf("iocopy.Do() error: %v", err)
+		return
+	}
+
+	buf, _ := os.ReadFile(dst)
+	// The requests are the first download, the probe and the resumed download.
+	fmt.Printf("requests: %v\n", requests)
+	fmt.Printf("same content: %v\n", bytes.Equal(buf, data))
+
+	// Output:
+	// requests: 3
+	// same content: true
+}
+
+// protoTransport is an [http.RoundTripper] which records the protocol of the responses.
+type protoTransport struct {
+	protos []string
+}
+
+func (t *protoTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := http.DefaultTransport.RoundTrip(req)
+	if err == nil {
+		t.protos = append(t.protos, resp.Proto)
+	}
+	return resp, err
+}
+
+func ExampleWithHTTPClient() {
+	// This example downloads the file by a custom client.
+	// An HTTP/3 client, e.g. &http.Client{Transport: &http3.Transport{}}, can be used in the same way.
+	data := bytes.Repeat([]byte("0123456789abcdef"), 64*1024)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "file", time.Time{}, bytes.NewReader(data))
+	}))
+	defer ts.Close()
+
+	dir, err := os.MkdirTemp("", "iocopy")
+	if err != nil {
+		log.Printf("os.MkdirTemp() error: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	dst := filepath.Join(dir, "file")
+
+	tr := &protoTransport{}
+	opt := iocopy.WithHTTPClient(&http.Client{Transport: tr})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var state []byte
+	t := iocopy.NewDownloadTask(dst, ts.URL, nil, opt)
+	iocopy.Do(ctx, t, nil, func(e iocopy.Event) {
+		switch e := e.(type) {
+		case *iocopy.EventWritten:
+			// Emulate user cancelation.
+			cancel()
+		case *iocopy.EventStop:
+			state = e.State
+		}
+	})
+
+	// The client is not saved in the state. Pass it again.
+	if t, err = iocopy.LoadDownloadTask(state, nil, opt); err != nil {
+		log.Printf("iocopy.LoadDownloadTask() error: %v", err)
+		return
+	}
+
+	if err = iocopy.Do(context.Background(), t, nil, nil); err != nil {
+		log.Printf("iocopy.Do() error: %v", err)
+		return
+	}
+
+	buf, _ := os.ReadFile(dst)
+	fmt.Printf("protocols: %v\n", tr.protos)
+	fmt.Printf("same content: %v\n", bytes.Equal(buf, data))
+
+	// Output:
+	// protocols: [HTTP/1.1 HTTP/1.1 HTTP/1.1]
+	// same content: true
+}
+
+func ExampleLoadDownloadTask_holes() {
+	// This example resumes a download whose bytes were written out of order,
+	// e.g. by multiple connections. Only the missing ranges are fetched.
+	data := bytes.Repeat([]byte("0123456789abcdef"), 64*1024)
+
+	var ranges []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Skip the probe of the remote file.
+		if r.Method == http.MethodGet {
+			ranges = append(ranges, r.Header.Get("range"))
+		}
+		http.ServeContent(w, r, "file", time.Time{}, bytes.NewReader(data))
+	}))
+	defer ts.Close()
+
+	dir, err := os.MkdirTemp("", "iocopy")
+	if err != nil {
+		log.Printf("os.MkdirTemp() error: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	dst := filepath.Join(dir, "file")
+
+	// Write the downloaded ranges [0, 1000) and [5000, 8000).
+	var done iocopy.RangeSet
+	buf := make([]byte, len(data))
+	for _, r := range []iocopy.Range{{Start: 0, End: 1000}, {Start: 5000, End: 8000}} {
+		copy(buf[r.Start:r.End], data[r.Start:r.End])
+		done.Add(r.Start, r.End)
+	}
+	if err = os.WriteFile(dst, buf[:8000], 0644); err != nil {
+		log.Printf("os.WriteFile() error: %v", err)
+		return
+	}
+
+	state, _ := json.Marshal(iocopy.DownloadState{
+		Dst:   dst,
+		URL:   ts.URL,
+		Total: int64(len(data)),
+		Done:  done,
+	})
+
+	t, err := iocopy.LoadDownloadTask(state, nil, iocopy.WithHash("sha256"))
+	if err != nil {
+		log.Printf("iocopy.LoadDownloadTask() error: %v", err)
+		return
+	}
+	fmt.Printf("copied: %v\n", t.Copied())
+
+	iocopy.Do(context.Background(), t, nil, func(e iocopy.Event) {
+		switch e := e.(type) {
+		case *iocopy.EventOK:
+			sum := sha256.Sum256(data)
+			r := e.Value.(iocopy.DownloadResult)
+			fmt.Printf("size: %v, SHA-256 matches: %v\n", r.Size, r.Checksums["sha256"] == hex.EncodeToString(sum[:]))
+		case *iocopy.EventError:
+			log.Printf("iocopy.Do() error: %v", e.Err)
+		}
+	})
+
+	fmt.Printf("ranges: %q\n", ranges)
+
+	got, _ := os.ReadFile(dst)
+	fmt.Printf("same content: %v\n", bytes.Equal(got, data))
+
+	// Output:
+	// copied: 4000
+	// size: 1048576, SHA-256 matches: true
+	// ranges: ["bytes=1000-4999" "bytes=8000-1048575"]
+	// same content: true
+}
+
+func ExampleWithConnections() {
+	// This example downloads the file by 4 connections in parallel.
+	// The download is stopped and resumed by fetching the missing ranges.
+	data := bytes.Repeat([]byte("0123456789abcdef"), 256*1024)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "file", time.Time{}, bytes.NewReader(data))
+	}))
+	defer ts.Close()
+
+	dir, err := os.MkdirTemp("", "iocopy")
+	if err != nil {
+		log.Printf("os.MkdirTemp() error: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	dst := filepath.Join(dir, "file")
+	opts := []iocopy.Option{iocopy.WithConnections(4), iocopy.WithHash("sha256")}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var state []byte
+	t := iocopy.NewDownloadTask(dst, ts.URL, nil, opts...)
+	iocopy.Do(ctx, t, nil, func(e iocopy.Event) {
+		switch e := e.(type) {
+		case *iocopy.EventWritten:
+			// Emulate user cancelation when half of the file is downloaded.
+			if e.Copied >= int64(len(data))/2 {
+				cancel()
+			}
+		case *iocopy.EventStop:
+			state = e.State
+		}
+	})
+
+	s, _ := iocopy.StateAs[iocopy.DownloadState](t)
+	fmt.Printf("downloaded ranges saved: %v\n", s.Done.Size() == s.Copied)
+
+	// The destination is preallocated.
+	fi, _ := os.Stat(dst)
+	fmt.Printf("preallocated: %v\n", fi.Size() == int64(len(data)))
+
+	if t, err = iocopy.LoadDownloadTask(state, nil, opts...); err != nil {
+		log.Printf("iocopy.LoadDownloadTask() error: %v", err)
+		return
+	}
+
+	iocopy.Do(context.Background(), t, nil, func(e iocopy.Event) {
+		switch e := e.(type) {
+		case *iocopy.EventOK:
+			sum := sha256.Sum256(data)
+			r := e.Value.(iocopy.DownloadResult)
+			fmt.Printf("size: %v, SHA-256 matches: %v\n", r.Size, r.Checksums["sha256"] == hex.EncodeToString(sum[:]))
+		case *iocopy.EventError:
+			log.Printf("iocopy.Do() error: %v", e.Err)
+		}
+	})
+
+	buf, _ := os.ReadFile(dst)
+	fmt.Printf("same content: %v\n", bytes.Equal(buf, data))
+
+	// Output:
+	// downloaded ranges saved: true
+	// preallocated: true
+	// size: 4194304, SHA-256 matches: true
+	// same content: true
+}
+
+func ExampleLoadDownloadTask_changed() {
+	// This example resumes a download after the remote file is replaced by a larger one.
+	// The remote file is probed before resuming and the download restarts since the size changed.
+	data := bytes.Repeat([]byte("0123456789abcdef"), 64*1024)
+	newData := bytes.Repeat([]byte("fedcba9876543210"), 128*1024)
+
+	var replaced atomic.Bool
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if replaced.Load() {
+			http.ServeContent(w, r, "file", time.Time{}, bytes.NewReader(newData))
+			return
+		}
+		http.ServeContent(w, r, "file", time.Time{}, bytes.NewReader(data))
+	}))
+	defer ts.Close()
+
+	dir, err := os.MkdirTemp("", "iocopy")
+	if err != nil {
+		log.Printf("os.MkdirTemp() error: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	dst := filepath.Join(dir, "file")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var state []byte
+	t := iocopy.NewDownloadTask(dst, ts.URL, nil)
+	iocopy.Do(ctx, t, nil, func(e iocopy.Event) {
+		switch e := e.(type) {
+		case *iocopy.EventWritten:
+			// Emulate user cancelation.
+			cancel()
+		case *iocopy.EventStop:
+			state = e.State
+		}
+	})
+
+	// Replace the remote file.
+	replaced.Store(true)
+
+	if t, err = iocopy.LoadDownloadTask(state, nil); err != nil {
+		log.Printf("iocopy.LoadDownloadTask() error: %v", err)
+		return
+	}
+
+	if err = iocopy.Do(context.Background(), t, nil, nil); err != nil {
+		log.Printf("iocopy.Do() error: %v", err)
+		return
+	}
+
+	buf, _ := os.ReadFile(dst)
+	fmt.Printf("total: %v\n", t.Total())
+	fmt.Printf("same content: %v\n", bytes.Equal(buf, newData))
+
+	// Output:
+	// total: 2097152
+	// same content: true
+}
+
+func ExampleEventRestarted() {
+	// This example resumes a download after the remote file is replaced by another one of the same size.
+	// The change is detected by the ETag and the download restarts instead of appending mismatched bytes.
+	data := bytes.Repeat([]byte("0123456789abcdef"), 64*1024)
+	newData := bytes.Repeat([]byte("fedcba9876543210"), 64*1024)
+
+	var replaced atomic.Bool
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if replaced.Load() {
+			w.Header().Set("etag", `"v2"`)
+			http.ServeContent(w, r, "file", time.Time{}, bytes.NewReader(newData))
+			return
+		}
+		w.Header().Set("etag", `"v1"`)
+		http.ServeContent(w, r, "file", time.Time{}, bytes.NewReader(data))
+	}))
+	defer ts.Close()
+
+	dir, err := os.MkdirTemp("", "iocopy")
+	if err != nil {
+		log.Printf("os.MkdirTemp() error: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	dst := filepath.Join(dir, "file")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var state []byte
+	t := iocopy.NewDownloadTask(dst, ts.URL, nil)
+	iocopy.Do(ctx, t, nil, func(e iocopy.Event) {
+		switch e := e.(type) {
+		case *iocopy.EventWritten:
+			// Emulate user cancelation.
+			cancel()
+		case *iocopy.EventStop:
+			state = e.State
+		}
+	})
+
+	// Replace the remote file.
+	replaced.Store(true)
+
+	if t, err = iocopy.LoadDownloadTask(state, nil); err != nil {
+		log.Printf("iocopy.LoadDownloadTask() error: %v", err)
+		return
+	}
+
+	err = iocopy.Do(context.Background(), t, nil, func(e iocopy.Event) {
+		if e, ok := e.(*iocopy.EventRestarted); ok {
+			fmt.Printf("restarted: %v, discarded: %v\n", e.Reason, e.Discarded > 0)
+		}
+	})
+	if err != nil {
+		log.Printf("iocopy.Do() error: %v", err)
+		return
+	}
+
+	buf, _ := os.ReadFile(dst)
+	fmt.Printf("same content: %v\n", bytes.Equal(buf, newData))
+
+	// Output:
+	// restarted: etag of the remote file changed: "v2", previous: "v1", discarded: true
+	// same content: true
+}
+
+func ExampleLoadDownloadTask_headRejected() {
+	// This example resumes a download from a server which rejects HEAD requests.
+	// The remote file is probed by a GET request of the first byte instead,
+	// and the download restarts since the size changed.
+	data := bytes.Repeat([]byte("0123456789abcdef"), 64*1024)
+	newData := bytes.Repeat([]byte("fedcba9876543210"), 128*1024)
+
+	var (
+		replaced atomic.Bool
+		mu       sync.Mutex
+		requests []string
+	)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests = append(requests, strings.TrimSpace(r.Method+" "+r.Header.Get("range")))
+		mu.Unlock()
+
+		if r.Method == http.MethodHead {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		if replaced.Load() {
+			http.ServeContent(w, r, "file", time.Time{}, bytes.NewReader(newData))
+			return
+		}
+		http.ServeContent(w, r, "file", time.Time{}, bytes.NewReader(data))
+	}))
+	defer ts.Close()
+
+	dir, err := os.MkdirTemp("", "iocopy")
+	if err != nil {
+		log.Printf("os.MkdirTemp() error: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	dst := filepath.Join(dir, "file")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var state []byte
+	t := iocopy.NewDownloadTask(dst, ts.URL, nil)
+	iocopy.Do(ctx, t, nil, func(e iocopy.Event) {
+		switch e := e.(type) {
+		case *iocopy.EventWritten:
+			// Emulate user cancelation.
+			cancel()
+		case *iocopy.EventStop:
+			state = e.State
+		}
+	})
+
+	// Replace the remote file.
+	replaced.Store(true)
+
+	if t, err = iocopy.LoadDownloadTask(state, nil); err != nil {
+		log.Printf("iocopy.LoadDownloadTask() error: %v", err)
+		return
+	}
+
+	err = iocopy.Do(context.Background(), t, nil, func(e iocopy.Event) {
+		if e, ok := e.(*iocopy.EventRestarted); ok {
+			fmt.Printf("restarted: %v\n", e.Reason)
+		}
+	})
+	if err != nil {
+		log.Printf("iocopy.Do() error: %v", err)
+		return
+	}
+
+	buf, _ := os.ReadFile(dst)
+	mu.Lock()
+	fmt.Printf("requests: %q\n", requests)
+	mu.Unlock()
+	fmt.Printf("same content: %v\n", bytes.Equal(buf, newData))
+
+	// Output:
+	// restarted: size of the remote file changed: 2097152, previous: 1048576
+	// requests: ["GET" "HEAD" "GET bytes=0-0" "GET"]
+	// same content: true
+}
+
+func ExampleWithReconnect() {
+	// This example downloads a file from a server which drops the first connection halfway, e.g. Wi-Fi switches to LTE.
+	// The download reconnects and resumes from the bytes downloaded inside the same Do call.
+	data := bytes.Repeat([]byte("0123456789abcdef"), 64*1024)
+
+	var dropped atomic.Bool
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !dropped.Swap(true) {
+			// Send half of the file and drop the connection.
+			w.Header().Set("content-length", fmt.Sprint(len(data)))
+			w.Write(data[:len(data)/2])
+			w.(http.Flusher).Flush()
+			panic(http.ErrAbortHandler)
+		}
+		http.ServeContent(w, r, "file", time.Time{}, bytes.NewReader(data))
+	}))
+	// Suppress the log of the aborted handler.
+	ts.Config.ErrorLog = log.New(io.Discard, "", 0)
+	defer ts.Close()
+
+	dir, err := os.MkdirTemp("", "iocopy")
+	if err != nil {
+		log.Printf("os.MkdirTemp() error: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	dst := filepath.Join(dir, "file")
+
+	t := iocopy.NewDownloadTask(dst, ts.URL, nil, iocopy.WithReconnect(3, time.Millisecond*10, time.Second))
+	err = iocopy.Do(context.Background(), t, nil, func(e iocopy.Event) {
+		if e, ok := e.(*iocopy.EventReconnect); ok {
+			fmt.Printf("reconnect: attempt: %v, delay: %v, err: %v\n", e.Attempt, e.Delay, e.Err)
+		}
+	})
+	if err != nil {
+		log.Printf("iocopy.Do() error: %v", err)
+		return
+	}
+
+	buf, _ := os.ReadFile(dst)
+	fmt.Printf("same content: %v\n", bytes.Equal(buf, data))
+
+	// Output:
+	// reconnect: attempt: 1, delay: 10ms, err: unexpected EOF
+	// same content: true
+}
+
+func ExampleWithOverlapCheck() {
+	// This example resumes a download after the remote file is replaced by another one of the same size without ETag.
+	// The change can't be detected by the size, but the overlapping bytes re-fetched from the server don't match
+	// and the download restarts instead of appending mismatched bytes.
+	data := bytes.Repeat([]byte("0123456789abcdef"), 64*1024)
+	newData := bytes.Repeat([]byte("fedcba9876543210"), 64*1024)
+
+	var replaced atomic.Bool
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if replaced.Load() {
+			http.ServeContent(w, r, "file", time.Time{}, bytes.NewReader(newData))
+			return
+		}
+		http.ServeContent(w, r, "file", time.Time{}, bytes.NewReader(data))
+	}))
+	defer ts.Close()
+
+	dir, err := os.MkdirTemp("", "iocopy")
+	if err != nil {
+		log.Printf("os.MkdirTemp() error: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	dst := filepath.Join(dir, "file")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var state []byte
+	t := iocopy.NewDownloadTask(dst, ts.URL, nil)
+	iocopy.Do(ctx, t, nil, func(e iocopy.Event) {
+		switch e := e.(type) {
+		case *iocopy.EventWritten:
+			// Emulate user cancelation.
+			cancel()
+		case *iocopy.EventStop:
+			state = e.State
+		}
+	})
+
+	// Replace the remote file.
+	replaced.Store(true)
+
+	// Compare the last 4 KiB downloaded bytes before resuming.
+	if t, err = iocopy.LoadDownloadTask(state, nil, iocopy.WithOverlapCheck(4096)); err != nil {
+		log.Printf("iocopy.LoadDownloadTask() error: %v", err)
+		return
+	}
+
+	err = iocopy.Do(context.Background(), t, nil, func(e iocopy.Event) {
+		if e, ok := e.(*iocopy.EventRestarted); ok {
+			fmt.Printf("restarted: %v, discarded: %v\n", e.Reason, e.Discarded > 0)
+		}
+	})
+	if err != nil {
+		log.Printf("iocopy.Do() error: %v", err)
+		return
+	}
+
+	buf, _ := os.ReadFile(dst)
+	fmt.Printf("same content: %v\n", bytes.Equal(buf, newData))
+
+	// Output:
+	// restarted: overlap mismatch: last downloaded bytes changed, discarded: true
+	// same content: true
+}
+
+func ExampleWithExpectedDigest() {
+	// This example downloads a file with the published SHA-256 checksum.
+	// The download is stopped and loaded from the state without the option, and it's still verified when it's done.
+	data := bytes.Repeat([]byte("0123456789abcdef"), 64*1024)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "file", time.Time{}, bytes.NewReader(data))
+	}))
+	defer ts.Close()
+
+	dir, err := os.MkdirTemp("", "iocopy")
+	if err != nil {
+		log.Printf("os.MkdirTemp() error: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	sum := sha256.Sum256(data)
+	expected := iocopy.WithExpectedDigest("sha256", hex.EncodeToString(sum[:]))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var state []byte
+	t := iocopy.NewDownloadTask(filepath.Join(dir, "file"), ts.URL, nil, expected)
+	iocopy.Do(ctx, t, nil, func(e iocopy.Event) {
+		switch e := e.(type) {
+		case *iocopy.EventWritten:
+			// Emulate user cancelation.
+			cancel()
+		case *iocopy.EventStop:
+			state = e.State
+		}
+	})
+
+	resumed, err := iocopy.LoadTask(state)
+	if err != nil {
+		log.Printf("iocopy.LoadTask() error: %v", err)
+		return
+	}
+	fmt.Printf("resume: %v\n", resumed.Copied() > 0)
+
+	iocopy.Do(context.Background(), resumed, nil, func(e iocopy.Event) {
+		switch e := e.(type) {
+		case *iocopy.EventOK:
+			var r struct {
+				Verification struct {
+					Alg string `json:"alg"`
+					OK  bool   `json:"ok"`
+				} `json:"verification"`
+			}
+			json.Unmarshal(e.Result, &r)
+			fmt.Printf("verified by %v: %v\n", r.Verification.Alg, r.Verification.OK)
+		case *iocopy.EventError:
+			log.Printf("iocopy.Do() error: %v", e.Err)
+		}
+	})
+
+	// A corrupted mirror fails the verification.
+	t = iocopy.NewDownloadTask(filepath.Join(dir, "bad"), ts.URL, nil, iocopy.WithExpectedDigest("sha256", strings.Repeat("0", 64)))
+	err = iocopy.Do(context.Background(), t, nil, nil)
+	fmt.Printf("mismatch: %v\n", errors.Is(err, iocopy.ErrChecksumMismatch))
+
+	// Output:
+	// resume: true
+	// verified by sha256: true
+	// mismatch: true
+}
+
+func ExampleDownloadTask_VerifySegments() {
+	// This example stops a download by 4 connections, corrupts a downloaded segment and resumes it.
+	// The segments are verified against their digests in the state before resuming
+	// and the corrupted one is downloaded again.
+	data := bytes.Repeat([]byte("0123456789abcdef"), 256*1024)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "file", time.Time{}, bytes.NewReader(data))
+	}))
+	defer ts.Close()
+
+	dir, err := os.MkdirTemp("", "iocopy")
+	if err != nil {
+		log.Printf("os.MkdirTemp() error: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	dst := filepath.Join(dir, "file")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var state []byte
+	t := iocopy.NewDownloadTask(dst, ts.URL, nil, iocopy.WithConnections(4))
+	iocopy.Do(ctx, t, nil, func(e iocopy.Event) {
+		switch e := e.(type) {
+		case *iocopy.EventWritten:
+			// Emulate user cancelation when half of the file is downloaded.
+			if e.Copied >= int64(len(data))/2 {
+				cancel()
+			}
+		case *iocopy.EventStop:
+			state = e.State
+		}
+	})
+
+	// covered returns the number of bytes of the segments.
+	covered := func(segments []iocopy.SegmentDigest) int64 {
+		n := int64(0)
+		for _, s := range segments {
+			n += s.End - s.Start
+		}
+		return n
+	}
+
+	s, _ := iocopy.StateAs[iocopy.DownloadState](t)
+	fmt.Printf("segments cover the downloaded bytes: %v\n", covered(s.Segments) == s.Copied)
+
+	// Corrupt the first byte of the file.
+	f, err := os.OpenFile(dst, os.O_WRONLY, 0644)
+	if err != nil {
+		log.Printf("os.OpenFile() error: %v", err)
+		return
+	}
+	f.WriteAt([]byte("x"), 0)
+	f.Close()
+
+	if t, err = iocopy.LoadDownloadTask(state, nil, iocopy.WithConnections(4)); err != nil {
+		log.Printf("iocopy.LoadDownloadTask() error: %v", err)
+		return
+	}
+
+	bad, err := t.VerifySegments()
+	if err != nil {
+		log.Printf("VerifySegments() error: %v", err)
+		return
+	}
+	fmt.Printf("bad segments: %v, starts at 0: %v\n", len(bad), len(bad) == 1 && bad[0].Start == 0)
+	fmt.Printf("bytes to download again: %v\n", s.Copied-t.Copied() == bad[0].End)
+
+	if err = iocopy.Do(context.Background(), t, nil, nil); err != nil {
+		log.Printf("iocopy.Do() error: %v", err)
+		return
+	}
+
+	r := t.ResultValue().(iocopy.DownloadResult)
+	fmt.Printf("segments cover the file: %v\n", covered(r.Segments) == int64(len(data)))
+
+	buf, _ := os.ReadFile(dst)
+	fmt.Printf("same content: %v\n", bytes.Equal(buf, data))
+
+	// Output:
+	// segments cover the downloaded bytes: true
+	// bad segments: 1, starts at 0: true
+	// bytes to download again: true
+	// segments cover the file: true
+	// same content: true
+}
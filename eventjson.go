@@ -0,0 +1,373 @@
+package iocopy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// EventSchemaVersion is the version of the JSON schema of events.
+// It's increased when fields are changed incompatibly. New fields may be added without increasing it.
+//
+// Events are marshaled as JSON objects with "version" and "type" fields, followed by the fields of the event:
+//
+//	written:       {"version":1,"type":"written","total":1024,"copied":512,"percent":50,"indeterminate":false,"elapsed":1000000,"speed":1000,"avg_speed":900}
+//	stop:          {"version":1,"type":"stop","err":"context canceled","state":{...}}
+//	ok:            {"version":1,"type":"ok","result":{...},"duration":1000000}
+//	error:         {"version":1,"type":"error","err":"..."}
+//	queued:        {"version":1,"type":"queued"}
+//	started:       {"version":1,"type":"started"}
+//	finished:      {"version":1,"type":"finished","err":"..."}
+//	file_verified: {"version":1,"type":"file_verified","file":"a","ok":true,"expected":"...","actual":"...","err":"..."}
+//	restarted:     {"version":1,"type":"restarted","reason":"...","discarded":1024}
+//	heartbeat:     {"version":1,"type":"heartbeat","opened":true,"copied":512,"elapsed":1000000,"idle":1000000}
+//	reconnect:     {"version":1,"type":"reconnect","attempt":1,"delay":1000000,"copied":512,"err":"unexpected EOF"}
+//
+// "written" and "ok" also have "read_time" and "write_time" if they're recorded.
+// "ok" reported by [Do] also has "stats" with the [IOStats]:
+// {"reads":2,"writes":1,"bytes_read":1024,"bytes_written":1024,"read_time":1000,"write_time":1000,"retries":0}.
+// "written" of multi-file tasks also has "file" with the progress of the current file:
+// {"index":0,"count":2,"done":0,"name":"a","total":1024,"copied":512,"percent":50}.
+// "written" of the tasks with parallel workers also has "workers" with the bytes read by each worker: [1024,512].
+// "state" and "result" are the marshaled state and result of the task.
+// "stop" has "encoded_state"(base64 encoded) instead of "state" if the state is encoded by a binary [StateCodec].
+// "elapsed", "duration", "idle", "delay", "read_time" and "write_time" are in nanoseconds.
+// "err" is the error message and it's omitted if there's no error.
+// Use [UnmarshalEvent] to unmarshal the events.
+const EventSchemaVersion = 1
+
+// eventHeader is the common fields of the marshaled events.
+type eventHeader struct {
+	Version int    `json:"version"`
+	Type    string `json:"type"`
+}
+
+type writtenJSON struct {
+	eventHeader
+	Total         int64         `json:"total"`
+	Copied        int64         `json:"copied"`
+	Percent       float32       `json:"percent"`
+	Indeterminate bool          `json:"indeterminate"`
+	Elapsed       time.Duration `json:"elapsed"`
+	Speed         float64       `json:"speed"`
+	AvgSpeed      float64       `json:"avg_speed"`
+	ReadTime      time.Duration `json:"read_time,omitempty"`
+	WriteTime     time.Duration `json:"write_time,omitempty"`
+	File          *FileProgress `json:"file,omitempty"`
+	Workers       []int64       `json:"workers,omitempty"`
+}
+
+type stopJSON struct {
+	eventHeader
+	Err          string          `json:"err,omitempty"`
+	State        json.RawMessage `json:"state,omitempty"`
+	EncodedState []byte          `json:"encoded_state,omitempty"`
+}
+
+type okJSON struct {
+	eventHeader
+	Result    json.RawMessage `json:"result,omitempty"`
+	Duration  time.Duration   `json:"duration"`
+	ReadTime  time.Duration   `json:"read_time,omitempty"`
+	WriteTime time.Duration   `json:"write_time,omitempty"`
+	Stats     *IOStats        `json:"stats,omitempty"`
+}
+
+type errorJSON struct {
+	eventHeader
+	Err string `json:"err,omitempty"`
+}
+
+type fileVerifiedJSON struct {
+	eventHeader
+	File     string `json:"file"`
+	OK       bool   `json:"ok"`
+	Expected string `json:"expected"`
+	Actual   string `json:"actual,omitempty"`
+	Err      string `json:"err,omitempty"`
+}
+
+type restartedJSON struct {
+	eventHeader
+	Reason    string `json:"reason"`
+	Discarded int64  `json:"discarded"`
+}
+
+type heartbeatJSON struct {
+	eventHeader
+	Opened  bool          `json:"opened"`
+	Copied  int64         `json:"copied"`
+	Elapsed time.Duration `json:"elapsed"`
+	Idle    time.Duration `json:"idle"`
+}
+
+type reconnectJSON struct {
+	eventHeader
+	Attempt int           `json:"attempt"`
+	Delay   time.Duration `json:"delay"`
+	Copied  int64         `json:"copied"`
+	Err     string        `json:"err,omitempty"`
+}
+
+// header returns the header of the event type.
+func header(typ string) eventHeader {
+	return eventHeader{Version: EventSchemaVersion, Type: typ}
+}
+
+// errString returns the message of err or an empty string if it's nil.
+func errString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+// parseErr returns the error of the message or nil if it's empty.
+// The causes of [*EventStop] are restored, so they can be checked by [errors.Is].
+func parseErr(msg string) error {
+	switch {
+	case msg == "":
+		return nil
+	case msg == context.Canceled.Error():
+		return context.Canceled
+	case msg == context.DeadlineExceeded.Error():
+		return context.DeadlineExceeded
+	case strings.HasPrefix(msg, context.Canceled.Error()+": "):
+		return fmt.Errorf("%w%v", context.Canceled, strings.TrimPrefix(msg, context.Canceled.Error()))
+	case strings.HasPrefix(msg, ErrNoSpace.Error()):
+		return fmt.Errorf("%w%v", ErrNoSpace, strings.TrimPrefix(msg, ErrNoSpace.Error()))
+	default:
+		return errors.New(msg)
+	}
+}
+
+// rawJSON returns b if it's valid JSON, or nil otherwise.
+func rawJSON(b []byte) json.RawMessage {
+	if json.Valid(b) {
+		return b
+	}
+	return nil
+}
+
+// MarshalJSON implements [json.Marshaler] interface. See [EventSchemaVersion].
+func (e *EventWritten) MarshalJSON() ([]byte, error) {
+	return json.Marshal(writtenJSON{header("written"), e.Total, e.Copied, e.Percent, e.Indeterminate, e.Elapsed, e.Speed, e.AvgSpeed, e.ReadTime, e.WriteTime, e.File, e.Workers})
+}
+
+// UnmarshalJSON implements [json.Unmarshaler] interface.
+func (e *EventWritten) UnmarshalJSON(b []byte) error {
+	var v writtenJSON
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	*e = EventWritten{
+		Total:         v.Total,
+		Copied:        v.Copied,
+		Percent:       v.Percent,
+		Indeterminate: v.Indeterminate,
+		Elapsed:       v.Elapsed,
+		Speed:         v.Speed,
+		AvgSpeed:      v.AvgSpeed,
+		ReadTime:      v.ReadTime,
+		WriteTime:     v.WriteTime,
+		File:          v.File,
+		Workers:       v.Workers,
+	}
+	return nil
+}
+
+// MarshalJSON implements [json.Marshaler] interface. See [EventSchemaVersion].
+func (e *EventStop) MarshalJSON() ([]byte, error) {
+	v := stopJSON{eventHeader: header("stop"), Err: errString(e.Err), State: rawJSON(e.State)}
+	if v.State == nil && len(e.State) > 0 {
+		// The state is encoded by a binary StateCodec.
+		v.EncodedState = e.State
+	}
+	return json.Marshal(v)
+}
+
+// UnmarshalJSON implements [json.Unmarshaler] interface.
+func (e *EventStop) UnmarshalJSON(b []byte) error {
+	var v stopJSON
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	*e = EventStop{Err: parseErr(v.Err), State: v.State}
+	if v.EncodedState != nil {
+		e.State = v.EncodedState
+	}
+	return nil
+}
+
+// MarshalJSON implements [json.Marshaler] interface. See [EventSchemaVersion].
+// Value is not marshaled. It's the same as Result.
+func (e *EventOK) MarshalJSON() ([]byte, error) {
+	v := okJSON{header("ok"), rawJSON(e.Result), e.Duration, e.ReadTime, e.WriteTime, nil}
+	if e.Stats != (IOStats{}) {
+		v.Stats = &e.Stats
+	}
+	return json.Marshal(v)
+}
+
+// UnmarshalJSON implements [json.Unmarshaler] interface.
+// Value is nil. Use [json.Unmarshal] to decode Result into the typed result.
+func (e *EventOK) UnmarshalJSON(b []byte) error {
+	var v okJSON
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	*e = EventOK{Result: v.Result, Duration: v.Duration, ReadTime: v.ReadTime, WriteTime: v.WriteTime}
+	if v.Stats != nil {
+		e.Stats = *v.Stats
+	}
+	return nil
+}
+
+// MarshalJSON implements [json.Marshaler] interface. See [EventSchemaVersion].
+func (e *EventError) MarshalJSON() ([]byte, error) {
+	return json.Marshal(errorJSON{header("error"), errString(e.Err)})
+}
+
+// UnmarshalJSON implements [json.Unmarshaler] interface.
+func (e *EventError) UnmarshalJSON(b []byte) error {
+	var v errorJSON
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	*e = EventError{Err: parseErr(v.Err)}
+	return nil
+}
+
+// MarshalJSON implements [json.Marshaler] interface. See [EventSchemaVersion].
+func (e *EventQueued) MarshalJSON() ([]byte, error) {
+	return json.Marshal(header("queued"))
+}
+
+// MarshalJSON implements [json.Marshaler] interface. See [EventSchemaVersion].
+func (e *EventStarted) MarshalJSON() ([]byte, error) {
+	return json.Marshal(header("started"))
+}
+
+// MarshalJSON implements [json.Marshaler] interface. See [EventSchemaVersion].
+func (e *EventFinished) MarshalJSON() ([]byte, error) {
+	return json.Marshal(errorJSON{header("finished"), errString(e.Err)})
+}
+
+// UnmarshalJSON implements [json.Unmarshaler] interface.
+func (e *EventFinished) UnmarshalJSON(b []byte) error {
+	var v errorJSON
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	*e = EventFinished{Err: parseErr(v.Err)}
+	return nil
+}
+
+// MarshalJSON implements [json.Marshaler] interface. See [EventSchemaVersion].
+func (e *EventFileVerified) MarshalJSON() ([]byte, error) {
+	return json.Marshal(fileVerifiedJSON{header("file_verified"), e.File, e.OK, e.Expected, e.Actual, errString(e.Err)})
+}
+
+// UnmarshalJSON implements [json.Unmarshaler] interface.
+func (e *EventFileVerified) UnmarshalJSON(b []byte) error {
+	var v fileVerifiedJSON
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	*e = EventFileVerified{File: v.File, OK: v.OK, Expected: v.Expected, Actual: v.Actual, Err: parseErr(v.Err)}
+	return nil
+}
+
+// MarshalJSON implements [json.Marshaler] interface. See [EventSchemaVersion].
+func (e *EventRestarted) MarshalJSON() ([]byte, error) {
+	return json.Marshal(restartedJSON{header("restarted"), e.Reason, e.Discarded})
+}
+
+// UnmarshalJSON implements [json.Unmarshaler] interface.
+func (e *EventRestarted) UnmarshalJSON(b []byte) error {
+	var v restartedJSON
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	*e = EventRestarted{Reason: v.Reason, Discarded: v.Discarded}
+	return nil
+}
+
+// MarshalJSON implements [json.Marshaler] interface. See [EventSchemaVersion].
+func (e *EventHeartbeat) MarshalJSON() ([]byte, error) {
+	return json.Marshal(heartbeatJSON{header("heartbeat"), e.Opened, e.Copied, e.Elapsed, e.Idle})
+}
+
+// UnmarshalJSON implements [json.Unmarshaler] interface.
+func (e *EventHeartbeat) UnmarshalJSON(b []byte) error {
+	var v heartbeatJSON
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	*e = EventHeartbeat{Opened: v.Opened, Copied: v.Copied, Elapsed: v.Elapsed, Idle: v.Idle}
+	return nil
+}
+
+// MarshalJSON implements [json.Marshaler] interface. See [EventSchemaVersion].
+func (e *EventReconnect) MarshalJSON() ([]byte, error) {
+	return json.Marshal(reconnectJSON{header("reconnect"), e.Attempt, e.Delay, e.Copied, errString(e.Err)})
+}
+
+// UnmarshalJSON implements [json.Unmarshaler] interface.
+func (e *EventReconnect) UnmarshalJSON(b []byte) error {
+	var v reconnectJSON
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	*e = EventReconnect{Attempt: v.Attempt, Delay: v.Delay, Copied: v.Copied, Err: parseErr(v.Err)}
+	return nil
+}
+
+// UnmarshalEvent unmarshals an event marshaled by [json.Marshal], e.g. received over IPC or websocket.
+// It returns an error if the version is missing or newer than [EventSchemaVersion], or the type is unknown.
+func UnmarshalEvent(b []byte) (Event, error) {
+	var h eventHeader
+	if err := json.Unmarshal(b, &h); err != nil {
+		return nil, err
+	}
+
+	if h.Version < 1 || h.Version > EventSchemaVersion {
+		return nil, fmt.Errorf("unsupported event schema version: %v", h.Version)
+	}
+
+	var e Event
+	switch h.Type {
+	case "written":
+		e = &EventWritten{}
+	case "stop":
+		e = &EventStop{}
+	case "ok":
+		e = &EventOK{}
+	case "error":
+		e = &EventError{}
+	case "queued":
+		return &EventQueued{}, nil
+	case "started":
+		return &EventStarted{}, nil
+	case "finished":
+		e = &EventFinished{}
+	case "file_verified":
+		e = &EventFileVerified{}
+	case "restarted":
+		e = &EventRestarted{}
+	case "heartbeat":
+		e = &EventHeartbeat{}
+	case "reconnect":
+		e = &EventReconnect{}
+	default:
+		return nil, fmt.Errorf("unknown event type: %q", h.Type)
+	}
+
+	if err := json.Unmarshal(b, e); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
@@ -0,0 +1,132 @@
+package iocopy_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"reflect"
+	"time"
+
+	"github.com/northbright/iocopy"
+)
+
+func ExampleUnmarshalEvent() {
+	// This example marshals events to forward them over IPC or websocket and unmarshals them on the other side.
+	events := []iocopy.Event{
+		&iocopy.EventWritten{Total: 1024, Copied: 512, Percent: 50},
+		&iocopy.EventStop{Err: context.Canceled, State: []byte(`{"copied":512}`)},
+		&iocopy.EventOK{Result: []byte(`{"size":1024}`), Duration: time.Second},
+		&iocopy.EventFinished{},
+	}
+
+	for _, e := range events {
+		b, err := json.Marshal(e)
+		if err != nil {
+			log.Printf("json.Marshal() error: %v", err)
+			return
+		}
+		fmt.Printf("%s\n", b)
+
+		e, err = iocopy.UnmarshalEvent(b)
+		if err != nil {
+			log.Printf("iocopy.UnmarshalEvent() error: %v", err)
+			return
+		}
+
+		if e, ok := e.(*iocopy.EventStop); ok {
+			fmt.Printf("canceled: %v\n", errors.Is(e.Err, context.Canceled))
+		}
+	}
+
+	// Output:
+	// {"version":1,"type":"written","total":1024,"copied":512,"percent":50,"indeterminate":false,"elapsed":0,"speed":0,"avg_speed":0}
+	// {"version":1,"type":"stop","err":"context canceled","state":{"copied":512}}
+	// canceled: true
+	// {"version":1,"type":"ok","result":{"size":1024},"duration":1000000000}
+	// {"version":1,"type":"finished"}
+}
+
+func ExampleUnmarshalEvent_roundTrip() {
+	// This example marshals and unmarshals the events of each type
+	// and checks the unmarshaled events are marshaled to the same JSON.
+	events := []iocopy.Event{
+		&iocopy.EventWritten{
+			Total: 1024, Copied: 512, Percent: 50, Elapsed: time.Second, Speed: 1000, AvgSpeed: 900,
+			ReadTime: time.Millisecond, WriteTime: 2 * time.Millisecond,
+			File: &iocopy.FileProgress{Index: 1, Count: 2, Done: 1, Name: "a", Total: 100, Copied: 50, Percent: 50},
+		},
+		&iocopy.EventWritten{Copied: 512, Indeterminate: true},
+		&iocopy.EventStop{Err: context.Canceled, State: []byte(`{"copied":512}`)},
+		// The state encoded by a binary StateCodec.
+		&iocopy.EventStop{Err: context.DeadlineExceeded, State: []byte{0xa1, 0x66, 0x63, 0x6f, 0x70, 0x69, 0x65, 0x64, 0x19, 0x02, 0x00}},
+		&iocopy.EventOK{
+			Result: []byte(`{"size":1024}`), Duration: time.Second, ReadTime: time.Millisecond, WriteTime: time.Millisecond,
+			Stats: iocopy.IOStats{Reads: 2, Writes: 1, BytesRead: 1024, BytesWritten: 1024, ReadTime: time.Millisecond, WriteTime: time.Millisecond, Retries: 1},
+		},
+		&iocopy.EventError{Err: errors.New("no such file")},
+		&iocopy.EventQueued{},
+		&iocopy.EventStarted{},
+		&iocopy.EventFinished{Err: iocopy.ErrNoSpace},
+		&iocopy.EventFileVerified{File: "a", OK: false, Expected: "00", Actual: "01", Err: iocopy.ErrChecksumMismatch},
+		&iocopy.EventRestarted{Reason: "etag changed", Discarded: 1024},
+		&iocopy.EventHeartbeat{Opened: true, Copied: 512, Elapsed: time.Second, Idle: time.Millisecond},
+		&iocopy.EventReconnect{Attempt: 1, Delay: time.Second, Copied: 512, Err: errors.New("unexpected EOF")},
+	}
+
+	for _, e := range events {
+		b, err := json.Marshal(e)
+		if err != nil {
+			log.Printf("json.Marshal() error: %v", err)
+			return
+		}
+
+		e2, err := iocopy.UnmarshalEvent(b)
+		if err != nil {
+			log.Printf("iocopy.UnmarshalEvent() error: %v", err)
+			return
+		}
+
+		b2, err := json.Marshal(e2)
+		if err != nil {
+			log.Printf("json.Marshal() error: %v", err)
+			return
+		}
+		fmt.Printf("%T: same type: %v, same JSON: %v\n", e2, reflect.TypeOf(e) == reflect.TypeOf(e2), bytes.Equal(b, b2))
+	}
+
+	// The causes of the errors are restored.
+	e, _ := iocopy.UnmarshalEvent([]byte(`{"version":1,"type":"finished","err":"no space left on destination: write a: no space left on device"}`))
+	fmt.Printf("no space: %v\n", errors.Is(e.(*iocopy.EventFinished).Err, iocopy.ErrNoSpace))
+
+	// Events of unknown versions and types are rejected.
+	for _, b := range []string{
+		`{"version":2,"type":"written","total":1024}`,
+		`{"type":"written","total":1024}`,
+		`{"version":1,"type":"unknown"}`,
+	} {
+		_, err := iocopy.UnmarshalEvent([]byte(b))
+		fmt.Printf("%v\n", err)
+	}
+
+	// Output:
+	// *iocopy.EventWritten: same type: true, same JSON: true
+	// *iocopy.EventWritten: same type: true, same JSON: true
+	// *iocopy.EventStop: same type: true, same JSON: true
+	// *iocopy.EventStop: same type: true, same JSON: true
+	// *iocopy.EventOK: same type: true, same JSON: true
+	// *iocopy.EventError: same type: true, same JSON: true
+	// *iocopy.EventQueued: same type: true, same JSON: true
+	// *iocopy.EventStarted: same type: true, same JSON: true
+	// *iocopy.EventFinished: same type: true, same JSON: true
+	// *iocopy.EventFileVerified: same type: true, same JSON: true
+	// *iocopy.EventRestarted: same type: true, same JSON: true
+	// *iocopy.EventHeartbeat: same type: true, same JSON: true
+	// *iocopy.EventReconnect: same type: true, same JSON: true
+	// no space: true
+	// unsupported event schema version: 2
+	// unsupported event schema version: 0
+	// unknown event type: "unknown"
+}
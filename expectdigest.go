@@ -0,0 +1,88 @@
+package iocopy
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ExpectedDigest is the expected checksum of the file set by [WithExpectedDigest].
+type ExpectedDigest struct {
+	// Alg is the hash algorithm in [HashFuncs], e.g. "sha256".
+	Alg string `json:"alg"`
+	// Digest is the hex encoded expected checksum.
+	Digest string `json:"digest"`
+}
+
+// DigestVerification is the outcome of verifying the checksum against the [ExpectedDigest].
+type DigestVerification struct {
+	// Alg is the hash algorithm.
+	Alg string `json:"alg"`
+	// Expected is the hex encoded expected checksum.
+	Expected string `json:"expected"`
+	// Actual is the hex encoded checksum computed.
+	Actual string `json:"actual"`
+	// OK is true if they match.
+	OK bool `json:"ok"`
+}
+
+// verifyDigest verifies the checksums of the file against the expected digest.
+// It returns the outcome and an error wrapping [ErrChecksumMismatch] if they don't match.
+func verifyDigest(d *ExpectedDigest, checksums map[string]string, file string) (*DigestVerification, error) {
+	v := &DigestVerification{Alg: d.Alg, Expected: d.Digest, Actual: checksums[d.Alg]}
+	v.OK = v.Actual != "" && strings.EqualFold(v.Expected, v.Actual)
+	if !v.OK {
+		return v, fmt.Errorf("%w: %v of %v", ErrChecksumMismatch, d.Alg, file)
+	}
+	return v, nil
+}
+
+// expectDigest makes the task hash the downloaded bytes by the algorithm of the expected digest
+// if it's not set by [WithHash].
+// The hashes are computed again from the downloaded bytes(if any) if its state is not loaded.
+func (t *DownloadTask) expectDigest() {
+	if t.expected == nil {
+		return
+	}
+
+	if !slices.Contains(t.opts.hashAlgs, t.expected.Alg) {
+		t.opts.hashAlgs = append(slices.Clone(t.opts.hashAlgs), t.expected.Alg)
+	}
+
+	if _, ok := t.hashStates[t.expected.Alg]; !ok {
+		t.hashStates = nil
+	}
+}
+
+// verifyDigest verifies the checksum of the downloaded bytes against the expected digest.
+func (t *DownloadTask) verifyDigest() (err error) {
+	var checksums map[string]string
+	if t.hs != nil {
+		checksums, _ = t.hs.checksums()
+	}
+	t.verification, err = verifyDigest(t.expected, checksums, t.dst)
+	return err
+}
+
+// expectDigest makes the task hash the file by the algorithm of the expected digest if it's not in the algorithms.
+// The file is hashed again from the beginning if its state is not loaded.
+func (t *HashTask) expectDigest() {
+	if t.expected == nil {
+		return
+	}
+
+	if !slices.Contains(t.algs, t.expected.Alg) {
+		t.algs = append(slices.Clone(t.algs), t.expected.Alg)
+	}
+
+	if _, ok := t.states[t.expected.Alg]; !ok {
+		t.states = nil
+	}
+}
+
+// verifyDigest verifies the checksum of the file against the expected digest.
+func (t *HashTask) verifyDigest() (err error) {
+	checksums, _ := t.Checksums()
+	t.verification, err = verifyDigest(t.expected, checksums, t.file)
+	return err
+}
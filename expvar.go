@@ -0,0 +1,54 @@
+package iocopy
+
+import (
+	"expvar"
+	"sync"
+	"sync/atomic"
+)
+
+// copyStats are the counters of the copy activity published by [PublishExpvar].
+var copyStats struct {
+	enabled atomic.Bool
+	once    sync.Once
+	// bytes is the total number of bytes copied.
+	bytes atomic.Int64
+	// active is the number of copies running.
+	active atomic.Int64
+	// errors is the number of copies failed. Stopped ones are not counted.
+	errors atomic.Int64
+}
+
+// PublishExpvar publishes the counters of the copy activity as the expvar variable "iocopy":
+// "bytes_copied"(total bytes copied), "active"(copies running) and "errors"(copies failed, stopped ones are not counted).
+// The counters are updated by the copies of all functions and tasks after it's called.
+// It's safe to call it more than once.
+// Import [net/http/pprof] or serve [expvar.Handler] to expose the variables by HTTP.
+func PublishExpvar() {
+	copyStats.once.Do(func() {
+		expvar.Publish("iocopy", expvar.Func(func() any {
+			return map[string]int64{
+				"bytes_copied": copyStats.bytes.Load(),
+				"active":       copyStats.active.Load(),
+				"errors":       copyStats.errors.Load(),
+			}
+		}))
+		copyStats.enabled.Store(true)
+	})
+}
+
+// statsStart updates the counters when a copy starts.
+// It returns the function to update them when the copy ends.
+func statsStart() func(written int64, err error) {
+	if !copyStats.enabled.Load() {
+		return func(int64, error) {}
+	}
+
+	copyStats.active.Add(1)
+	return func(written int64, err error) {
+		copyStats.active.Add(-1)
+		copyStats.bytes.Add(written)
+		if err != nil && !isStopped(err) {
+			copyStats.errors.Add(1)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package iocopy_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"io"
+
+	"github.com/northbright/iocopy"
+)
+
+func ExamplePublishExpvar() {
+	// This example publishes the counters of the copy activity as the expvar variable "iocopy".
+	iocopy.PublishExpvar()
+
+	stats := func() map[string]int64 {
+		var m map[string]int64
+		json.Unmarshal([]byte(expvar.Get("iocopy").String()), &m)
+		return m
+	}
+
+	before := stats()
+	iocopy.Copy(context.Background(), io.Discard, bytes.NewReader(make([]byte, 4096)))
+	after := stats()
+
+	fmt.Printf("bytes copied: %v\n", after["bytes_copied"]-before["bytes_copied"])
+	fmt.Printf("active: %v, errors: %v\n", after["active"], after["errors"]-before["errors"])
+
+	// Output:
+	// bytes copied: 4096
+	// active: 0, errors: 0
+}
@@ -0,0 +1,513 @@
+package iocopy
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// gzipMagic is the magic number of gzip streams.
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// ExtractTarTask implements [Task] interface to extract a tar or gzip compressed tar(tar.gz) file to a directory.
+// The compression is detected by the magic number. The entries are extracted while the archive is read,
+// so the total is unknown(-1) until all entries are extracted.
+// Only directories and regular files are extracted. Other entries, e.g. symbolic links, are skipped.
+// Executable bits of files are preserved on [OSFS].
+//
+// It resumes at entry boundaries: the number of the entries done and the offset of the next entry in the tar stream are saved.
+// A tar file is read from the offset directly. A tar.gz file is decompressed from the beginning since gzip streams can't be seeked,
+// but the entries done are skipped instead of being extracted again. The file being extracted when it's stopped is extracted again.
+// It restarts from the beginning if the size of the archive is changed.
+type ExtractTarTask struct {
+	fsys WriteFS
+	dir  string
+	src  string
+	// size is the size of the archive when the extraction starts. It's -1 if it's not started.
+	size   int64
+	total  int64
+	copied int64
+	// done is the number of the entries done and offset is the offset of the next entry in the tar stream.
+	done   int
+	offset int64
+	// extracted is the total size of the files done.
+	extracted int64
+	files     int
+	dirs      int
+	skipped   int
+	// skipDirs are the directories skipped by [WithFilter] whose entries are skipped too.
+	skipDirs []string
+	// restartReason is the reason why it restarts when it's opened. It's empty if it does not restart.
+	restartReason string
+	discarded     int64
+	r             *tarReader
+	opts          options
+}
+
+// ExtractTarState is the typed state of [ExtractTarTask].
+type ExtractTarState struct {
+	// Version is the version of the state. See [StateVersion].
+	Version int `json:"version"`
+	// Type is the type of the state to load it by [LoadTask]: [StateTypeExtractTar].
+	Type string `json:"type"`
+	// Dir is the destination directory.
+	Dir string `json:"dir"`
+	// Src is the archive.
+	Src string `json:"src"`
+	// Size is the size of the archive. It's -1 if the extraction is not started.
+	Size int64 `json:"size"`
+	// Total is the total size of the files. It's -1 until all entries are extracted.
+	Total int64 `json:"total"`
+	// Copied is the number of bytes extracted.
+	Copied int64 `json:"copied"`
+	// Done is the number of the entries done, including the skipped ones.
+	Done int `json:"done"`
+	// Offset is the offset of the next entry in the tar stream(after decompression).
+	Offset int64 `json:"offset"`
+	// Extracted is the total size of the files done.
+	Extracted int64 `json:"extracted"`
+	// Files is the number of the files extracted.
+	Files int `json:"files"`
+	// Dirs is the number of the directories created.
+	Dirs int `json:"dirs"`
+	// Skipped is the number of the entries skipped.
+	Skipped int `json:"skipped"`
+	// SkipDirs are the slash-separated names of the directories skipped by [WithFilter].
+	SkipDirs []string `json:"skip_dirs,omitempty"`
+}
+
+// ExtractTarResult is the typed result of [ExtractTarTask].
+type ExtractTarResult struct {
+	// Dir is the destination directory.
+	Dir string `json:"dir"`
+	// Src is the archive.
+	Src string `json:"src"`
+	// Files is the number of the files extracted.
+	Files int `json:"files"`
+	// Dirs is the number of the directories created.
+	Dirs int `json:"dirs"`
+	// Skipped is the number of the entries skipped, e.g. symbolic links or the ones filtered by [WithFilter].
+	Skipped int `json:"skipped"`
+	// Size is the total size of the files extracted.
+	Size int64 `json:"size"`
+}
+
+// NewExtractTarTask returns a [*ExtractTarTask] which extracts the tar or tar.gz file src to dir.
+// fsys is the file system of dir. [OSFS] is used if it's nil.
+// opts: optional parameters. e.g. [WithFilter], [WithExtractLimits], [WithRateLimiter].
+func NewExtractTarTask(dir, src string, fsys WriteFS, opts ...Option) *ExtractTarTask {
+	if fsys == nil {
+		fsys = OSFS
+	}
+
+	return &ExtractTarTask{fsys: fsys, dir: dir, src: src, size: -1, total: -1, opts: newOptions(opts)}
+}
+
+// LoadExtractTarTask loads a [*ExtractTarTask] from the state to resume the extraction.
+// fsys is the file system of the destination directory. [OSFS] is used if it's nil.
+// opts: optional parameters which are not saved in the state.
+func LoadExtractTarTask(state []byte, fsys WriteFS, opts ...Option) (*ExtractTarTask, error) {
+	var s ExtractTarState
+	if err := json.Unmarshal(state, &s); err != nil {
+		return nil, err
+	}
+
+	if err := (stateHeader{s.Version, s.Type}).check(StateTypeExtractTar); err != nil {
+		return nil, err
+	}
+
+	t := NewExtractTarTask(s.Dir, s.Src, fsys, opts...)
+	t.size = s.Size
+	t.total = s.Total
+	t.copied = s.Copied
+	t.done = s.Done
+	t.offset = s.Offset
+	t.extracted = s.Extracted
+	t.files = s.Files
+	t.dirs = s.Dirs
+	t.skipped = s.Skipped
+	t.skipDirs = s.SkipDirs
+	return t, nil
+}
+
+// restart resets the progress to extract from the beginning.
+func (t *ExtractTarTask) restart(reason string) {
+	t.restartReason, t.discarded = reason, t.copied
+	t.total, t.copied, t.done, t.offset, t.extracted = -1, 0, 0, 0, 0
+	t.files, t.dirs, t.skipped, t.skipDirs = 0, 0, 0, nil
+}
+
+// Open implements [Task] interface.
+// It opens the archive and skips the entries done if it's resumed.
+func (t *ExtractTarTask) Open(ctx context.Context) (dst io.Writer, src io.Reader, err error) {
+	f, err := os.Open(longPath(t.src))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	defer func() {
+		if err != nil {
+			t.Close()
+		}
+	}()
+
+	t.r = &tarReader{t: t, f: f}
+
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if t.size >= 0 && t.size != fi.Size() {
+		t.restart("archive changed")
+	}
+	t.size = fi.Size()
+
+	magic := make([]byte, len(gzipMagic))
+	n, err := io.ReadFull(f, magic)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, nil, err
+	}
+
+	if _, err = f.Seek(0, io.SeekStart); err != nil {
+		return nil, nil, err
+	}
+
+	var r io.Reader = f
+	if bytes.Equal(magic[:n], gzipMagic) {
+		if t.r.gz, err = gzip.NewReader(f); err != nil {
+			return nil, nil, err
+		}
+		r = t.r.gz
+	}
+
+	t.r.cr = &countReader{r: r}
+	if t.offset > 0 {
+		if t.r.gz == nil {
+			if _, err = f.Seek(t.offset, io.SeekStart); err != nil {
+				return nil, nil, err
+			}
+			t.r.cr.n = t.offset
+		} else if err = t.r.skip(ctx, t.offset); err != nil {
+			return nil, nil, err
+		}
+	}
+	t.r.tr = tar.NewReader(t.r.cr)
+
+	// The file being extracted is extracted again.
+	t.copied = t.extracted
+
+	// The entries are extracted while they're read, so the destination only counts the bytes.
+	return io.Discard, t.opts.limit(ctx, t.r), nil
+}
+
+// Restarted implements [Restarter] interface.
+func (t *ExtractTarTask) Restarted() (reason string, discarded int64) {
+	return t.restartReason, t.discarded
+}
+
+// Close implements [Task] interface.
+// The file being extracted is closed and it's extracted again when the task is resumed.
+func (t *ExtractTarTask) Close() error {
+	t.restartReason, t.discarded = "", 0
+
+	var err error
+	if t.r != nil {
+		err = t.r.close()
+		t.r = nil
+	}
+	return err
+}
+
+// Endpoints implements [Endpointer] interface.
+func (t *ExtractTarTask) Endpoints() (src, dst string) {
+	return t.src, t.dir
+}
+
+// Total implements [Task] interface.
+func (t *ExtractTarTask) Total() int64 {
+	return t.total
+}
+
+// Copied implements [Task] interface.
+func (t *ExtractTarTask) Copied() int64 {
+	return t.copied
+}
+
+// SetCopied implements [Task] interface.
+func (t *ExtractTarTask) SetCopied(copied int64) {
+	t.copied = copied
+}
+
+// State implements [Task] interface.
+func (t *ExtractTarTask) State() ([]byte, error) {
+	return json.Marshal(t.StateValue())
+}
+
+// StateValue implements [StateValuer] interface.
+// It returns the [ExtractTarState].
+func (t *ExtractTarTask) StateValue() any {
+	return ExtractTarState{
+		Version:   StateVersion,
+		Type:      StateTypeExtractTar,
+		Dir:       t.dir,
+		Src:       t.src,
+		Size:      t.size,
+		Total:     t.total,
+		Copied:    t.copied,
+		Done:      t.done,
+		Offset:    t.offset,
+		Extracted: t.extracted,
+		Files:     t.files,
+		Dirs:      t.dirs,
+		Skipped:   t.skipped,
+		SkipDirs:  t.skipDirs,
+	}
+}
+
+// Result implements [Task] interface.
+func (t *ExtractTarTask) Result() ([]byte, error) {
+	return json.Marshal(t.ResultValue())
+}
+
+// ResultValue implements [ResultValuer] interface.
+// It returns the [ExtractTarResult].
+func (t *ExtractTarTask) ResultValue() any {
+	return ExtractTarResult{Dir: t.dir, Src: t.src, Files: t.files, Dirs: t.dirs, Skipped: t.skipped, Size: t.extracted}
+}
+
+// countReader counts the bytes read.
+type countReader struct {
+	r io.Reader
+	n int64
+}
+
+// Read implements [io.Reader] interface.
+func (r *countReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.n += int64(n)
+	return n, err
+}
+
+// tarReader extracts the entries of the tar stream while the contents of the files are read.
+type tarReader struct {
+	t  *ExtractTarTask
+	f  *os.File
+	gz *gzip.Reader
+	// cr counts the bytes of the tar stream to get the offsets of the entries.
+	cr *countReader
+	tr *tar.Reader
+	// w is the file being extracted and hdr is its header.
+	w    WriteFile
+	hdr  *tar.Header
+	name string
+}
+
+// skip discards the first n bytes of the tar stream.
+func (r *tarReader) skip(ctx context.Context, n int64) error {
+	src := readFunc(func(p []byte) (int, error) {
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+		return r.cr.Read(p)
+	})
+
+	if _, err := io.CopyN(io.Discard, src, n); err != nil {
+		if err == io.EOF {
+			return fmt.Errorf("%v is shorter than the offset to resume: %v", r.t.src, n)
+		}
+		return err
+	}
+	return nil
+}
+
+// Read implements [io.Reader] interface.
+// It writes the bytes of the current file to the destination and returns them.
+func (r *tarReader) Read(p []byte) (int, error) {
+	for {
+		if r.w == nil {
+			if err := r.next(); err != nil {
+				return 0, err
+			}
+			continue
+		}
+
+		n, err := r.tr.Read(p)
+		if n > 0 {
+			if _, werr := r.w.Write(p[:n]); werr != nil {
+				return 0, werr
+			}
+		}
+
+		if err == io.EOF {
+			if err = r.finish(); err != nil {
+				return n, err
+			}
+
+			if n == 0 {
+				continue
+			}
+			return n, nil
+		}
+		return n, err
+	}
+}
+
+// next reads the header of the next entry, creates the directory or opens the file.
+// Entries without contents are done when it returns.
+func (r *tarReader) next() error {
+	t := r.t
+	hdr, err := r.tr.Next()
+	if err != nil {
+		if err == io.EOF {
+			t.total = t.extracted
+		}
+		return err
+	}
+
+	name := strings.TrimPrefix(strings.TrimSuffix(hdr.Name, "/"), "./")
+
+	if !r.included(name, hdr) {
+		return r.skipEntry()
+	}
+
+	local, err := filepath.Localize(name)
+	if err != nil {
+		return &fs.PathError{Op: "ExtractTar", Path: hdr.Name, Err: err}
+	}
+	dst := filepath.Join(t.dir, local)
+
+	switch hdr.Typeflag {
+	case tar.TypeDir:
+		if err = r.checkLimits(hdr); err != nil {
+			return err
+		}
+
+		if err = mkdirAll(t.fsys, dst, 0755); err != nil {
+			return err
+		}
+		t.dirs++
+		r.entryDone()
+	case tar.TypeReg:
+		if err = r.checkLimits(hdr); err != nil {
+			return err
+		}
+
+		if r.w, err = openDst(t.fsys, dst, hdr.Size, 0); err != nil {
+			return err
+		}
+		r.hdr, r.name = hdr, dst
+	default:
+		return r.skipEntry()
+	}
+	return nil
+}
+
+// included reports whether the entry is included by [WithFilter].
+// The entries of the directories skipped are skipped too.
+func (r *tarReader) included(name string, hdr *tar.Header) bool {
+	t := r.t
+	for _, dir := range t.skipDirs {
+		if strings.HasPrefix(name, dir+"/") {
+			return false
+		}
+	}
+
+	if name == "." || t.opts.filter == nil {
+		return true
+	}
+
+	if t.opts.filter(name, fs.FileInfoToDirEntry(hdr.FileInfo())) {
+		return true
+	}
+
+	if hdr.Typeflag == tar.TypeDir {
+		t.skipDirs = append(t.skipDirs, name)
+	}
+	return false
+}
+
+// checkLimits checks the entry against the limits set by [WithExtractLimits].
+func (r *tarReader) checkLimits(hdr *tar.Header) error {
+	t := r.t
+	l := t.opts.extractLimits
+	if l.MaxEntries > 0 && t.files+t.dirs+1 > l.MaxEntries {
+		return &ExtractLimitError{Limit: "entries", Value: float64(t.files + t.dirs + 1), Max: float64(l.MaxEntries)}
+	}
+
+	if hdr.Typeflag != tar.TypeReg {
+		return nil
+	}
+
+	if l.MaxEntrySize > 0 && hdr.Size > l.MaxEntrySize {
+		return &ExtractLimitError{Limit: "entry size", Entry: hdr.Name, Value: float64(hdr.Size), Max: float64(l.MaxEntrySize)}
+	}
+
+	if total := t.extracted + hdr.Size; l.MaxTotal > 0 && total > l.MaxTotal {
+		return &ExtractLimitError{Limit: "total", Value: float64(total), Max: float64(l.MaxTotal)}
+	}
+	return nil
+}
+
+// skipEntry discards the contents of the entry which is not extracted.
+func (r *tarReader) skipEntry() error {
+	if _, err := io.Copy(io.Discard, r.tr); err != nil {
+		return err
+	}
+	r.t.skipped++
+	r.entryDone()
+	return nil
+}
+
+// finish closes the file whose contents are all written.
+func (r *tarReader) finish() error {
+	t := r.t
+	w := r.w
+	r.w = nil
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	if err := chmodExec(t.fsys, r.name, r.hdr.FileInfo().Mode()); err != nil {
+		return err
+	}
+
+	t.files++
+	t.extracted += r.hdr.Size
+	r.entryDone()
+	return nil
+}
+
+// entryDone records the entry done and the offset of the next entry.
+// The entries are aligned to 512-byte blocks in the tar stream.
+func (r *tarReader) entryDone() {
+	const blockSize = 512
+	r.t.done++
+	r.t.offset = (r.cr.n + blockSize - 1) / blockSize * blockSize
+}
+
+// close closes the file being extracted and the archive.
+func (r *tarReader) close() error {
+	var err error
+	if r.w != nil {
+		err = r.w.Close()
+		r.w = nil
+	}
+
+	if r.gz != nil {
+		r.gz.Close()
+	}
+
+	if closeErr := r.f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
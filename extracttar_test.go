@@ -0,0 +1,199 @@
+package iocopy_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/northbright/iocopy"
+)
+
+// writeTar writes the entries to w as a tar stream. The names ending with "/" are directories
+// and the names ending with "@" are symbolic links.
+func writeTar(w io.Writer, names []string, data map[string][]byte) error {
+	tw := tar.NewWriter(w)
+	for _, name := range names {
+		var h *tar.Header
+		switch {
+		case strings.HasSuffix(name, "/"):
+			h = &tar.Header{Typeflag: tar.TypeDir, Name: name, Mode: 0755}
+		case strings.HasSuffix(name, "@"):
+			h = &tar.Header{Typeflag: tar.TypeSymlink, Name: strings.TrimSuffix(name, "@"), Linkname: "a.txt", Mode: 0777}
+		default:
+			h = &tar.Header{Typeflag: tar.TypeReg, Name: name, Size: int64(len(data[name])), Mode: 0644}
+		}
+
+		if err := tw.WriteHeader(h); err != nil {
+			return err
+		}
+
+		if _, err := tw.Write(data[name]); err != nil {
+			return err
+		}
+	}
+	return tw.Close()
+}
+
+func ExampleExtractTarTask() {
+	// This example extracts a tar.gz file. It's stopped when the large file is being extracted and resumed.
+	// The entries done are skipped instead of being extracted again.
+	dir, err := os.MkdirTemp("", "iocopy")
+	if err != nil {
+		log.Printf("os.MkdirTemp() error: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	names := []string{"docs/", "docs/a.txt", "docs/b.md", "data/", "data/large", "data/c.txt"}
+	data := map[string][]byte{
+		"docs/a.txt": []byte("hello"),
+		"docs/b.md":  []byte("# b"),
+		"data/large": bytes.Repeat([]byte("0123456789abcdef"), 64*1024),
+		"data/c.txt": []byte("c"),
+	}
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if err = writeTar(zw, names, data); err != nil {
+		log.Printf("writeTar() error: %v", err)
+		return
+	}
+	zw.Close()
+
+	src := filepath.Join(dir, "src.tar.gz")
+	if err = os.WriteFile(src, buf.Bytes(), 0644); err != nil {
+		log.Printf("os.WriteFile() error: %v", err)
+		return
+	}
+
+	dst := filepath.Join(dir, "out")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var state []byte
+	t := iocopy.NewExtractTarTask(dst, src, nil)
+	iocopy.Do(ctx, t, make([]byte, 32*1024), func(e iocopy.Event) {
+		switch e := e.(type) {
+		case *iocopy.EventWritten:
+			// Emulate user cancelation when the large file is being extracted.
+			if e.Copied > 64*1024 {
+				cancel()
+			}
+		case *iocopy.EventStop:
+			state = e.State
+		}
+	})
+
+	s, err := iocopy.StateAs[iocopy.ExtractTarState](t)
+	if err != nil {
+		log.Printf("iocopy.StateAs() error: %v", err)
+		return
+	}
+	fmt.Printf("stopped: entries done: %v, offset of next entry: %v, extracted: %v\n", s.Done, s.Offset, s.Extracted)
+
+	// Resume by LoadTask.
+	resumed, err := iocopy.LoadTask(state)
+	if err != nil {
+		log.Printf("iocopy.LoadTask() error: %v", err)
+		return
+	}
+
+	if err = iocopy.Do(context.Background(), resumed, nil, nil); err != nil {
+		log.Printf("iocopy.Do() error: %v", err)
+		return
+	}
+
+	r := resumed.(*iocopy.ExtractTarTask).ResultValue().(iocopy.ExtractTarResult)
+	fmt.Printf("files: %v, dirs: %v, size: %v, total: %v\n", r.Files, r.Dirs, r.Size, resumed.Total())
+
+	for _, name := range names {
+		if b, ok := data[name]; ok {
+			got, err := os.ReadFile(filepath.Join(dst, filepath.FromSlash(name)))
+			fmt.Printf("%v: %v\n", name, err == nil && bytes.Equal(got, b))
+		}
+	}
+
+	// Output:
+	// stopped: entries done: 4, offset of next entry: 3072, extracted: 8
+	// files: 4, dirs: 2, size: 1048585, total: 1048585
+	// docs/a.txt: true
+	// docs/b.md: true
+	// data/large: true
+	// data/c.txt: true
+}
+
+func ExampleExtractTarTask_tar() {
+	// This example extracts a tar file with a filter. Symbolic links are skipped.
+	// Entries whose names escape the destination directory fail the extraction.
+	dir, err := os.MkdirTemp("", "iocopy")
+	if err != nil {
+		log.Printf("os.MkdirTemp() error: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	names := []string{"./", "./a.txt", "./link@", "./tmp/", "./tmp/b.txt", "./c.txt"}
+	data := map[string][]byte{"./a.txt": []byte("a"), "./tmp/b.txt": []byte("b"), "./c.txt": []byte("c")}
+
+	src := filepath.Join(dir, "src.tar")
+	f, err := os.Create(src)
+	if err != nil {
+		log.Printf("os.Create() error: %v", err)
+		return
+	}
+
+	if err = writeTar(f, names, data); err != nil {
+		log.Printf("writeTar() error: %v", err)
+		return
+	}
+	f.Close()
+
+	// Skip the "tmp" directory and its entries.
+	filter := func(name string, d fs.DirEntry) bool {
+		return !(d.IsDir() && name == "tmp")
+	}
+
+	t := iocopy.NewExtractTarTask(filepath.Join(dir, "out"), src, nil, iocopy.WithFilter(filter))
+	if err = iocopy.Do(context.Background(), t, nil, nil); err != nil {
+		log.Printf("iocopy.Do() error: %v", err)
+		return
+	}
+
+	r := t.ResultValue().(iocopy.ExtractTarResult)
+	fmt.Printf("files: %v, dirs: %v, skipped: %v\n", r.Files, r.Dirs, r.Skipped)
+
+	_, err = os.Stat(filepath.Join(dir, "out", "tmp"))
+	fmt.Printf("tmp skipped: %v\n", errors.Is(err, fs.ErrNotExist))
+
+	// An entry escapes the destination directory.
+	evil := filepath.Join(dir, "evil.tar")
+	if f, err = os.Create(evil); err != nil {
+		log.Printf("os.Create() error: %v", err)
+		return
+	}
+
+	if err = writeTar(f, []string{"../evil.txt"}, nil); err != nil {
+		log.Printf("writeTar() error: %v", err)
+		return
+	}
+	f.Close()
+
+	err = iocopy.Do(context.Background(), iocopy.NewExtractTarTask(filepath.Join(dir, "out"), evil, nil), nil, nil)
+	var pathErr *fs.PathError
+	fmt.Printf("invalid path: %v\n", errors.As(err, &pathErr))
+
+	// Output:
+	// files: 2, dirs: 1, skipped: 3
+	// tmp skipped: true
+	// invalid path: true
+}
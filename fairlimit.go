@@ -0,0 +1,204 @@
+package iocopy
+
+import (
+	"container/heap"
+	"context"
+	"io"
+	"sync"
+
+	"golang.org/x/time/rate"
+)
+
+// FairLimiter shares a bandwidth among the tasks(flows) fairly by their weights,
+// so one giant download doesn't starve small ones like a shared [*rate.Limiter].
+// The requests of the flows are served one by one in the order of their virtual finish times(weighted fair queuing)
+// and the sizes of the reads are in proportion to the weights, so the busy flows get the bandwidth in proportion to their weights.
+// It's work-conserving: a flow gets all the bandwidth when others are idle.
+// Use [WithFairShare] to make the tasks share it and [TaskManager.SetFairLimiter] to adjust the weights by the task manager.
+type FairLimiter struct {
+	l *rate.Limiter
+
+	mu    sync.Mutex
+	flows map[string]*fairFlow
+	queue fairQueue
+	// vtime is the virtual time: the start tag of the request being served.
+	vtime float64
+	// serving is true if a request is waiting for l.
+	serving bool
+}
+
+// fairFlow is a flow of [FairLimiter].
+type fairFlow struct {
+	weight float64
+	// finish is the virtual finish time of the last request.
+	finish float64
+}
+
+// fairRequest is a request of n bytes waiting to be served.
+type fairRequest struct {
+	start, finish float64
+	n             int
+	ready         chan struct{}
+	// index is the index in the queue or -1 if it's served.
+	index int
+}
+
+// NewFairLimiter returns a [*FairLimiter] which limits the total bandwidth to limit bytes per second with burst size.
+func NewFairLimiter(limit rate.Limit, burst int) *FairLimiter {
+	return &FairLimiter{l: rate.NewLimiter(limit, burst), flows: map[string]*fairFlow{}}
+}
+
+// SetWeight sets the weight of the flow. The default weight is 1. It's ignored if w is not positive.
+// It takes effect on the next requests of the flow and can be called at runtime.
+func (fl *FairLimiter) SetWeight(id string, w float64) {
+	if w <= 0 {
+		return
+	}
+
+	fl.mu.Lock()
+	defer fl.mu.Unlock()
+	fl.flow(id).weight = w
+}
+
+// Weight returns the weight of the flow.
+func (fl *FairLimiter) Weight(id string) float64 {
+	fl.mu.Lock()
+	defer fl.mu.Unlock()
+
+	if f, ok := fl.flows[id]; ok {
+		return f.weight
+	}
+	return 1
+}
+
+// Remove removes the flow, e.g. when the task is done.
+func (fl *FairLimiter) Remove(id string) {
+	fl.mu.Lock()
+	defer fl.mu.Unlock()
+	delete(fl.flows, id)
+}
+
+// quantum returns the max number of bytes of a read of the flow.
+// It's the burst size scaled by the ratio of the weight of the flow to the max weight of the flows.
+func (fl *FairLimiter) quantum(id string) int {
+	fl.mu.Lock()
+	defer fl.mu.Unlock()
+
+	w, maxWeight := fl.flow(id).weight, 0.0
+	for _, f := range fl.flows {
+		maxWeight = max(maxWeight, f.weight)
+	}
+	return max(1, int(float64(fl.l.Burst())*w/maxWeight))
+}
+
+// flow returns the flow of the id and creates it if it does not exist.
+func (fl *FairLimiter) flow(id string) *fairFlow {
+	f, ok := fl.flows[id]
+	if !ok {
+		f = &fairFlow{weight: 1}
+		fl.flows[id] = f
+	}
+	return f
+}
+
+// WaitN blocks until n bytes of the flow are allowed in its fair share.
+// n should not be larger than the burst size.
+// It returns ctx.Err() if ctx is done before that.
+func (fl *FairLimiter) WaitN(ctx context.Context, id string, n int) error {
+	fl.mu.Lock()
+	f := fl.flow(id)
+	start := max(fl.vtime, f.finish)
+	f.finish = start + float64(n)/f.weight
+	req := &fairRequest{start: start, finish: f.finish, n: n, ready: make(chan struct{})}
+	heap.Push(&fl.queue, req)
+	fl.dispatch()
+	fl.mu.Unlock()
+
+	select {
+	case <-req.ready:
+	case <-ctx.Done():
+		fl.mu.Lock()
+		if req.index >= 0 {
+			heap.Remove(&fl.queue, req.index)
+		} else {
+			// It's being served. Serve the next one.
+			fl.serving = false
+			fl.dispatch()
+		}
+		fl.mu.Unlock()
+		return ctx.Err()
+	}
+
+	err := fl.l.WaitN(ctx, n)
+
+	fl.mu.Lock()
+	fl.serving = false
+	fl.dispatch()
+	fl.mu.Unlock()
+	return err
+}
+
+// dispatch serves the request with the earliest virtual finish time if no request is being served.
+func (fl *FairLimiter) dispatch() {
+	if fl.serving || fl.queue.Len() == 0 {
+		return
+	}
+
+	req := heap.Pop(&fl.queue).(*fairRequest)
+	fl.serving = true
+	fl.vtime = req.start
+	close(req.ready)
+}
+
+// fairQueue is a min-heap of the requests by the virtual finish times.
+type fairQueue []*fairRequest
+
+func (q fairQueue) Len() int           { return len(q) }
+func (q fairQueue) Less(i, j int) bool { return q[i].finish < q[j].finish }
+
+func (q fairQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *fairQueue) Push(x any) {
+	req := x.(*fairRequest)
+	req.index = len(*q)
+	*q = append(*q, req)
+}
+
+func (q *fairQueue) Pop() any {
+	old := *q
+	req := old[len(old)-1]
+	old[len(old)-1] = nil
+	req.index = -1
+	*q = old[:len(old)-1]
+	return req
+}
+
+// fairReader throttles reading by the fair share of the flow of a [FairLimiter].
+type fairReader struct {
+	ctx context.Context
+	r   io.Reader
+	fl  *FairLimiter
+	id  string
+}
+
+// Read implements [io.Reader] interface.
+// Reads are split into chunks not larger than the quantum of the flow.
+func (fr *fairReader) Read(p []byte) (n int, err error) {
+	if fr.fl.l.Limit() != rate.Inf {
+		if q := fr.fl.quantum(fr.id); len(p) > q {
+			p = p[:q]
+		}
+	}
+
+	n, err = fr.r.Read(p)
+	if n > 0 {
+		if waitErr := fr.fl.WaitN(fr.ctx, fr.id, n); waitErr != nil {
+			return n, waitErr
+		}
+	}
+	return n, err
+}
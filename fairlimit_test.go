@@ -0,0 +1,50 @@
+package iocopy_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/northbright/iocopy"
+)
+
+func ExampleFairLimiter() {
+	// This example hashes 2 sources of the same size with a shared fair limiter: 1 MiB/s with 16 KiB burst.
+	// "small" gets 3/4 of the bandwidth by its weight and finishes first.
+	fl := iocopy.NewFairLimiter(1024*1024, 16*1024)
+	fl.SetWeight("small", 3)
+
+	buf := bytes.Repeat([]byte("0123456789abcdef"), 16*1024)
+
+	var (
+		mu    sync.Mutex
+		order []string
+		wg    sync.WaitGroup
+	)
+
+	for _, id := range []string{"big", "small"} {
+		t := iocopy.NewReaderAtHashTask(id, bytes.NewReader(buf), int64(len(buf)), []string{"sha256"}, iocopy.WithFairShare(fl, id))
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			if err := iocopy.Do(context.Background(), t, nil, nil); err != nil {
+				log.Printf("iocopy.Do() error: %v", err)
+				return
+			}
+
+			mu.Lock()
+			order = append(order, id)
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	fmt.Printf("order: %v\n", order)
+
+	// Output:
+	// order: [small big]
+}
@@ -0,0 +1,200 @@
+package iocopy
+
+import (
+	"errors"
+	"io"
+	"sync"
+)
+
+const (
+	// DefaultFanOutDepth is the default number of buffers shared by the destinations of [FanOutWriter].
+	DefaultFanOutDepth = 8
+	// DefaultFanOutChunkSize is the default size of the buffers of [FanOutWriter].
+	DefaultFanOutChunkSize = 32 * 1024
+)
+
+// ErrFanOutClosed is returned by [FanOutWriter] when it's written after it's closed.
+var ErrFanOutClosed = errors.New("fan-out writer closed")
+
+// fanOutBuf is a filled buffer shared by the destinations of [FanOutWriter].
+type fanOutBuf struct {
+	b []byte
+	n int
+	// refs is the number of destinations which have not written the buffer yet.
+	refs int
+}
+
+// FanOutWriter writes the same bytes to multiple destinations concurrently,
+// e.g. a file and a hash or multiple files on different disks.
+// Unlike [io.MultiWriter] which writes to the destinations one by one,
+// each destination is written by a dedicated goroutine from a shared ring of filled buffers,
+// so the slowest destination bounds the throughput instead of the sum of all.
+// Memory is bounded to depth * chunk size.
+// Write returns after the bytes are copied into the ring. Errors of the destinations are returned by the next calls.
+// It implements [Committer], so [Do] waits for the pending writes and commits the destinations when the copy is done.
+type FanOutWriter struct {
+	ws     []io.Writer
+	queues []chan *fanOutBuf
+	free   chan *fanOutBuf
+	wg     sync.WaitGroup
+	// pending is the number of buffers not written by all destinations.
+	pending sync.WaitGroup
+
+	mu     sync.Mutex
+	err    error
+	closed bool
+}
+
+// NewFanOutWriter returns a [*FanOutWriter] which writes to ws in new goroutines.
+// depth: number of the buffers in the ring. [DefaultFanOutDepth] is used if it's not positive.
+// chunkSize: size of each buffer. [DefaultFanOutChunkSize] is used if it's not positive.
+// Call Close to wait for the pending writes and stop the goroutines when it's not used.
+func NewFanOutWriter(depth, chunkSize int, ws ...io.Writer) *FanOutWriter {
+	if depth <= 0 {
+		depth = DefaultFanOutDepth
+	}
+
+	if chunkSize <= 0 {
+		chunkSize = DefaultFanOutChunkSize
+	}
+
+	fw := &FanOutWriter{
+		ws:   ws,
+		free: make(chan *fanOutBuf, depth),
+	}
+
+	for i := 0; i < depth; i++ {
+		fw.free <- &fanOutBuf{b: make([]byte, chunkSize)}
+	}
+
+	fw.wg.Add(len(ws))
+	for _, w := range ws {
+		q := make(chan *fanOutBuf, depth)
+		fw.queues = append(fw.queues, q)
+		go fw.write(w, q)
+	}
+	return fw
+}
+
+// write writes the buffers in q to w in order.
+// The buffers are still consumed after an error to recycle them.
+func (fw *FanOutWriter) write(w io.Writer, q <-chan *fanOutBuf) {
+	defer fw.wg.Done()
+
+	var err error
+	for buf := range q {
+		if err == nil {
+			if _, err = w.Write(buf.b[:buf.n]); err != nil {
+				fw.setErr(err)
+			}
+		}
+		fw.release(buf)
+	}
+}
+
+// release recycles the buffer after it's written by all destinations.
+func (fw *FanOutWriter) release(buf *fanOutBuf) {
+	fw.mu.Lock()
+	buf.refs--
+	done := buf.refs == 0
+	fw.mu.Unlock()
+
+	if done {
+		fw.free <- buf
+		fw.pending.Done()
+	}
+}
+
+// setErr records the first error of the destinations.
+func (fw *FanOutWriter) setErr(err error) {
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+
+	if fw.err == nil {
+		fw.err = err
+	}
+}
+
+// status returns whether it's closed and the first error of the destinations.
+func (fw *FanOutWriter) status() (closed bool, err error) {
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+
+	return fw.closed, fw.err
+}
+
+// Write implements [io.Writer] interface.
+// It copies p into the buffers of the ring and queues them to all destinations.
+// It returns the first error of the destinations if any.
+func (fw *FanOutWriter) Write(p []byte) (n int, err error) {
+	for len(p) > 0 {
+		if closed, err := fw.status(); err != nil || closed {
+			if err == nil {
+				err = ErrFanOutClosed
+			}
+			return n, err
+		}
+
+		buf := <-fw.free
+		buf.n = copy(buf.b, p)
+		buf.refs = len(fw.ws)
+
+		if buf.refs == 0 {
+			fw.free <- buf
+		} else {
+			fw.pending.Add(1)
+			for _, q := range fw.queues {
+				q <- buf
+			}
+		}
+
+		n += buf.n
+		p = p[buf.n:]
+	}
+	return n, nil
+}
+
+// Flush waits for the pending writes and returns the first error of the destinations.
+func (fw *FanOutWriter) Flush() error {
+	fw.pending.Wait()
+	_, err := fw.status()
+	return err
+}
+
+// Commit implements [Committer] interface.
+// It waits for the pending writes and commits the destinations which implement [Committer].
+func (fw *FanOutWriter) Commit() error {
+	if err := fw.Flush(); err != nil {
+		return err
+	}
+
+	for _, w := range fw.ws {
+		if c, ok := w.(Committer); ok {
+			if err := c.Commit(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Close implements [io.Closer] interface.
+// It waits for the pending writes, stops the goroutines and returns the first error of the destinations.
+// It does not close the destinations. Write and Close should not be called concurrently.
+func (fw *FanOutWriter) Close() error {
+	fw.mu.Lock()
+	if fw.closed {
+		fw.mu.Unlock()
+		return fw.err
+	}
+	fw.closed = true
+	fw.mu.Unlock()
+
+	for _, q := range fw.queues {
+		close(q)
+	}
+	fw.wg.Wait()
+
+	_, err := fw.status()
+	return err
+}
@@ -0,0 +1,42 @@
+package iocopy_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"log"
+
+	"github.com/northbright/iocopy"
+)
+
+func ExampleFanOutWriter() {
+	// This example writes the same bytes to two buffers and a hash concurrently.
+	data := bytes.Repeat([]byte("0123456789abcdef"), 64*1024)
+
+	var dst1, dst2 bytes.Buffer
+	h := sha256.New()
+	fw := iocopy.NewFanOutWriter(0, 0, &dst1, &dst2, h)
+
+	n, err := iocopy.Copy(context.Background(), fw, bytes.NewReader(data))
+	if err != nil {
+		log.Printf("iocopy.Copy() error: %v", err)
+		return
+	}
+
+	// Wait for the pending writes.
+	if err = fw.Close(); err != nil {
+		log.Printf("fw.Close() error: %v", err)
+		return
+	}
+
+	sum := sha256.Sum256(data)
+	fmt.Printf("%v bytes copied\n", n)
+	fmt.Printf("same content: %v, %v\n", bytes.Equal(dst1.Bytes(), data), bytes.Equal(dst2.Bytes(), data))
+	fmt.Printf("same checksum: %v\n", bytes.Equal(h.Sum(nil), sum[:]))
+
+	// Output:
+	// 1048576 bytes copied
+	// same content: true, true
+	// same checksum: true
+}
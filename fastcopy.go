@@ -0,0 +1,82 @@
+package iocopy
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// DefaultFastCopyChunkSize is the default size of the chunks copied by [ContextWithFastCopy].
+const DefaultFastCopyChunkSize = 8 * 1024 * 1024
+
+// fastCopyKey is the context key of the chunk size of the fast copy.
+type fastCopyKey struct{}
+
+// ContextWithFastCopy returns a copy of ctx which makes [Do] and the copy functions keep the fast paths
+// of [io.ReaderFrom] and [io.WriterTo](e.g. copy_file_range(2) and sendfile(2) of [*os.File])
+// when they run with the returned context.
+// The fast paths are defeated by the wrappers which make the copy cancelable and report the progress.
+// Instead, if dst implements [io.ReaderFrom], the bytes are copied by ReadFrom in chunks of chunkSize
+// and the copy is canceled and the progress is reported between the chunks.
+// If src implements [io.WriterTo], the bytes are copied by WriteTo and the copy is canceled on each write.
+// [DefaultFastCopyChunkSize] is used if chunkSize is not positive.
+// Counts of the reads and writes are not recorded in the fast paths.
+func ContextWithFastCopy(ctx context.Context, chunkSize int64) context.Context {
+	if chunkSize <= 0 {
+		chunkSize = DefaultFastCopyChunkSize
+	}
+	return context.WithValue(ctx, fastCopyKey{}, chunkSize)
+}
+
+// fastCopy copies src to dst by the fast paths if ctx is returned by [ContextWithFastCopy]
+// and dst implements [io.ReaderFrom] or src implements [io.WriterTo].
+// handled is false if the fast paths are not used.
+func fastCopy(ctx context.Context, dst io.Writer, src io.Reader, pr *progress) (written int64, handled bool, err error) {
+	chunkSize, ok := ctx.Value(fastCopyKey{}).(int64)
+	if !ok {
+		return 0, false, nil
+	}
+
+	if _, ok := dst.(io.ReaderFrom); ok {
+		for {
+			if err = ctx.Err(); err != nil {
+				return written, true, err
+			}
+
+			start := time.Now()
+			// io.CopyN calls ReadFrom with an [io.LimitedReader] which [*os.File] still accelerates.
+			n, err := io.CopyN(dst, src, chunkSize)
+			pr.writeTime += time.Since(start)
+			if n > 0 {
+				written += n
+				pr.written(n)
+			}
+
+			if err == io.EOF {
+				return written, true, nil
+			}
+			if err != nil {
+				return written, true, err
+			}
+		}
+	}
+
+	if wt, ok := src.(io.WriterTo); ok {
+		written, err = wt.WriteTo(writeFunc(func(p []byte) (int, error) {
+			if err := ctx.Err(); err != nil {
+				return 0, err
+			}
+
+			start := time.Now()
+			n, err := dst.Write(p)
+			pr.writeTime += time.Since(start)
+			pr.writes++
+			if n > 0 {
+				pr.written(int64(n))
+			}
+			return n, err
+		}))
+		return written, true, err
+	}
+	return 0, false, nil
+}
@@ -0,0 +1,75 @@
+package iocopy_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/northbright/iocopy"
+)
+
+// readFromFile is an [*os.File] which counts the calls of ReadFrom.
+type readFromFile struct {
+	*os.File
+	calls int
+}
+
+func (f *readFromFile) ReadFrom(r io.Reader) (int64, error) {
+	f.calls++
+	return f.File.ReadFrom(r)
+}
+
+func ExampleContextWithFastCopy() {
+	// This example copies a file to another one by ReadFrom of *os.File(copy_file_range(2) on Linux)
+	// in chunks of 1 MiB, so the copy is still cancelable and reports the progress.
+	dir, err := os.MkdirTemp("", "iocopy")
+	if err != nil {
+		log.Printf("os.MkdirTemp() error: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	data := bytes.Repeat([]byte("a"), 4*1024*1024)
+	if err = os.WriteFile(filepath.Join(dir, "src"), data, 0644); err != nil {
+		log.Printf("os.WriteFile() error: %v", err)
+		return
+	}
+
+	src, err := os.Open(filepath.Join(dir, "src"))
+	if err != nil {
+		log.Printf("os.Open() error: %v", err)
+		return
+	}
+	defer src.Close()
+
+	f, err := os.Create(filepath.Join(dir, "dst"))
+	if err != nil {
+		log.Printf("os.Create() error: %v", err)
+		return
+	}
+	defer f.Close()
+	dst := &readFromFile{File: f}
+
+	ctx := iocopy.ContextWithFastCopy(context.Background(), 1024*1024)
+	n, err := iocopy.CopyBufferWithProgress(ctx, dst, src, nil, int64(len(data)), 0, func(p iocopy.ProgressInfo) {
+		fmt.Printf("%v%% copied\n", p.Percent)
+	})
+	if err != nil {
+		log.Printf("iocopy.CopyBufferWithProgress() error: %v", err)
+		return
+	}
+
+	// The last call returns io.EOF.
+	fmt.Printf("%v bytes copied by %v calls of ReadFrom\n", n, dst.calls)
+
+	// Output:
+	// 25% copied
+	// 50% copied
+	// 75% copied
+	// 100% copied
+	// 4194304 bytes copied by 5 calls of ReadFrom
+}
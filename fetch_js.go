@@ -0,0 +1,393 @@
+//go:build js && wasm
+
+package iocopy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"syscall/js"
+)
+
+// StateTypeFetch is the type of the state of [FetchTask] to load it by [LoadTask].
+const StateTypeFetch = "fetch"
+
+func init() {
+	RegisterLoader(StateTypeFetch, func(state []byte) (Task, error) { return LoadFetchTask(state, nil) })
+}
+
+// fetchReader reads the response body of the Fetch API by a ReadableStreamDefaultReader.
+type fetchReader struct {
+	ctx    context.Context
+	reader js.Value
+	buf    []byte
+	done   bool
+	stop   func() bool
+}
+
+// await waits for the JavaScript promise to be settled.
+func await(p js.Value) (js.Value, error) {
+	ch := make(chan js.Value, 1)
+	errCh := make(chan error, 1)
+
+	onFulfilled := js.FuncOf(func(this js.Value, args []js.Value) any {
+		ch <- args[0]
+		return nil
+	})
+	defer onFulfilled.Release()
+
+	onRejected := js.FuncOf(func(this js.Value, args []js.Value) any {
+		errCh <- js.Error{Value: args[0]}
+		return nil
+	})
+	defer onRejected.Release()
+
+	p.Call("then", onFulfilled, onRejected)
+
+	select {
+	case v := <-ch:
+		return v, nil
+	case err := <-errCh:
+		return js.Undefined(), err
+	}
+}
+
+// Read implements [io.Reader] interface.
+func (r *fetchReader) Read(p []byte) (n int, err error) {
+	if len(r.buf) == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+
+		v, err := await(r.reader.Call("read"))
+		if err != nil {
+			if r.ctx.Err() != nil {
+				return 0, r.ctx.Err()
+			}
+			return 0, err
+		}
+
+		if v.Get("done").Bool() {
+			r.done = true
+			return 0, io.EOF
+		}
+
+		chunk := v.Get("value")
+		r.buf = make([]byte, chunk.Get("length").Int())
+		js.CopyBytesToGo(r.buf, chunk)
+	}
+
+	n = copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+// Close implements [io.Closer] interface.
+func (r *fetchReader) Close() error {
+	r.stop()
+	if !r.done {
+		r.done = true
+		r.reader.Call("cancel")
+	}
+	return nil
+}
+
+// fetch makes a GET request with the headers by the Fetch API of the browser(or other JavaScript runtimes).
+// It returns the response and the reader of its body. The request is aborted when ctx is done.
+// The caller should close body when done.
+func fetch(ctx context.Context, url string, headers map[string]string) (resp js.Value, body *fetchReader, err error) {
+	controller := js.Global().Get("AbortController").New()
+	stop := context.AfterFunc(ctx, func() {
+		controller.Call("abort")
+	})
+
+	opts := js.Global().Get("Object").New()
+	opts.Set("signal", controller.Get("signal"))
+	if len(headers) > 0 {
+		h := js.Global().Get("Object").New()
+		for k, v := range headers {
+			h.Set(k, v)
+		}
+		opts.Set("headers", h)
+	}
+
+	resp, err = await(js.Global().Call("fetch", url, opts))
+	if err != nil {
+		stop()
+		if ctx.Err() != nil {
+			return js.Undefined(), nil, ctx.Err()
+		}
+		return js.Undefined(), nil, err
+	}
+
+	body = &fetchReader{ctx: ctx, stop: stop}
+	if b := resp.Get("body"); b.IsNull() {
+		body.done = true
+	} else {
+		body.reader = b.Call("getReader")
+	}
+	return resp, body, nil
+}
+
+// fetchHeader returns the value of the header of the response or an empty string if it's not present.
+func fetchHeader(resp js.Value, name string) string {
+	if v := resp.Get("headers").Call("get", name); v.Type() == js.TypeString {
+		return v.String()
+	}
+	return ""
+}
+
+// fetchContentLength returns the Content-Length of the response or -1 if it's unknown.
+func fetchContentLength(resp js.Value) (int64, error) {
+	v := fetchHeader(resp, "content-length")
+	if v == "" {
+		return -1, nil
+	}
+	return strconv.ParseInt(v, 10, 64)
+}
+
+// Fetch makes a GET request by the Fetch API of the browser(or other JavaScript runtimes) and returns the response body.
+// It's only available for GOOS=js and GOARCH=wasm.
+// The request is aborted when ctx is done.
+// size is the Content-Length of the response.
+// A negative value indicates the size is unknown.
+// body can be passed to [CopyWithProgress] to report the progress of the download in WASM apps.
+// The caller should close body when done.
+// Use [FetchTask] to run the download by [Do] and resume it.
+func Fetch(ctx context.Context, url string) (body io.ReadCloser, size int64, err error) {
+	resp, r, err := fetch(ctx, url, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if !resp.Get("ok").Bool() {
+		r.Close()
+		return nil, 0, fmt.Errorf("fetch %v: %v %v", url, resp.Get("status").Int(), resp.Get("statusText").String())
+	}
+
+	if size, err = fetchContentLength(resp); err != nil {
+		r.Close()
+		return nil, 0, err
+	}
+	return r, size, nil
+}
+
+// FetchTask implements [Task] interface to download a remote file by the Fetch API in WASM apps.
+// It's only available for GOOS=js and GOARCH=wasm.
+// It resumes the download by setting "range" header.
+// It restarts the download if the server does not support range or the size of the remote file changed.
+type FetchTask struct {
+	fsys   WriteFS
+	dst    string
+	url    string
+	total  int64
+	copied int64
+	body   *fetchReader
+	dstF   WriteFile
+	opts   options
+	// restartReason and discarded are reported by [Restarter] if the download restarts when it's opened.
+	restartReason string
+	discarded     int64
+}
+
+// FetchState is the typed state of [FetchTask].
+type FetchState struct {
+	// Version is the version of the state. See [StateVersion].
+	Version int `json:"version"`
+	// Type is the type of the state to load it by [LoadTask]: [StateTypeFetch].
+	Type string `json:"type"`
+	// Dst is the destination file.
+	Dst string `json:"dst"`
+	// URL is the url of the remote file.
+	URL string `json:"url"`
+	// Total is the total number of bytes to download.
+	// A negative value indicates total size is unknown.
+	Total int64 `json:"total"`
+	// Copied is the number of bytes downloaded.
+	Copied int64 `json:"copied"`
+}
+
+// FetchResult is the typed result of [FetchTask].
+type FetchResult struct {
+	// Dst is the destination file.
+	Dst string `json:"dst"`
+	// URL is the url of the remote file.
+	URL string `json:"url"`
+	// Size is the size of the destination file.
+	Size int64 `json:"size"`
+}
+
+// NewFetchTask returns a [*FetchTask] which downloads url to dst by the Fetch API.
+// fsys is the file system of dst, e.g. a [WriteFS] backed by the storage of the browser. [OSFS] is used if it's nil.
+// opts: optional parameters. e.g. [WithMaxBytes], [WithRateLimiter], [WithWriteRateLimiter].
+func NewFetchTask(dst, url string, fsys WriteFS, opts ...Option) *FetchTask {
+	if fsys == nil {
+		fsys = OSFS
+	}
+
+	return &FetchTask{fsys: fsys, dst: dst, url: url, total: -1, opts: newOptions(opts)}
+}
+
+// LoadFetchTask loads a [*FetchTask] from the state to resume the download.
+// fsys is the file system of dst. [OSFS] is used if it's nil.
+// opts: optional parameters which are not saved in the state.
+func LoadFetchTask(state []byte, fsys WriteFS, opts ...Option) (*FetchTask, error) {
+	var s FetchState
+	if err := json.Unmarshal(state, &s); err != nil {
+		return nil, err
+	}
+
+	t := NewFetchTask(s.Dst, s.URL, fsys, opts...)
+	t.total = s.Total
+	t.copied = s.Copied
+	return t, nil
+}
+
+// ID implements [Identifier] interface.
+// It's computed from dst and url.
+func (t *FetchTask) ID() string {
+	return taskID("fetch", t.dst, t.url)
+}
+
+// Endpoints implements [Endpointer] interface.
+func (t *FetchTask) Endpoints() (src, dst string) {
+	return t.url, t.dst
+}
+
+// Open implements [Task] interface.
+// It requests the bytes from the copied position by "range" header and opens the destination file to append them.
+func (t *FetchTask) Open(ctx context.Context) (dst io.Writer, src io.Reader, err error) {
+	var headers map[string]string
+	if t.copied > 0 {
+		headers = map[string]string{"range": fmt.Sprintf("bytes=%d-", t.copied)}
+	}
+
+	resp, body, err := fetch(ctx, t.url, headers)
+	if err != nil {
+		return nil, nil, err
+	}
+	t.body = body
+
+	defer func() {
+		if err != nil {
+			t.Close()
+		}
+	}()
+
+	n, err := fetchContentLength(resp)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch status := resp.Get("status").Int(); status {
+	case http.StatusOK:
+		// New download or the server does not support range.
+		t.restart(ctx, "range not supported")
+		t.total = n
+	case http.StatusPartialContent:
+		if total := contentRangeTotal(fetchHeader(resp, "content-range")); total >= 0 && t.total >= 0 && total != t.total {
+			// Download the new file from the beginning instead of appending mismatched bytes.
+			t.body.Close()
+			t.body = nil
+			t.restart(ctx, fmt.Sprintf("size of the remote file changed: %v, previous: %v", total, t.total))
+			return t.Open(ctx)
+		}
+
+		t.total = -1
+		if n >= 0 {
+			t.total = t.copied + n
+		}
+	default:
+		return nil, nil, fmt.Errorf("fetch %v: %v %v", t.url, status, resp.Get("statusText").String())
+	}
+
+	if t.opts.maxBytes >= 0 && t.total > t.opts.maxBytes {
+		return nil, nil, &MaxBytesError{Limit: t.opts.maxBytes}
+	}
+
+	if t.dstF, err = openDst(t.fsys, t.dst, t.total, t.copied); err != nil {
+		return nil, nil, err
+	}
+	dst = t.dstF
+
+	if t.opts.maxBytes >= 0 {
+		dst = newMaxBytesWriter(dst, t.opts.maxBytes, t.copied)
+	}
+
+	return t.opts.throttle(ctx, dst), t.opts.limit(ctx, t.body), nil
+}
+
+// restart resets the task to download from the beginning and records the reason reported by [*EventRestarted].
+func (t *FetchTask) restart(ctx context.Context, reason string) {
+	if t.copied > 0 {
+		logDebug(ctx, "iocopy: restart fetch", "url", t.url, "reason", reason, "copied", t.copied)
+		if t.restartReason == "" {
+			t.restartReason = reason
+		}
+		t.discarded += t.copied
+	}
+	t.copied = 0
+}
+
+// Restarted implements [Restarter] interface.
+func (t *FetchTask) Restarted() (reason string, discarded int64) {
+	return t.restartReason, t.discarded
+}
+
+// Close implements [Task] interface.
+func (t *FetchTask) Close() error {
+	var err error
+
+	if t.body != nil {
+		t.body.Close()
+		t.body = nil
+	}
+
+	if t.dstF != nil {
+		err = t.dstF.Close()
+		t.dstF = nil
+	}
+
+	t.restartReason, t.discarded = "", 0
+	return err
+}
+
+// Total implements [Task] interface.
+func (t *FetchTask) Total() int64 {
+	return t.total
+}
+
+// Copied implements [Task] interface.
+func (t *FetchTask) Copied() int64 {
+	return t.copied
+}
+
+// SetCopied implements [Task] interface.
+func (t *FetchTask) SetCopied(copied int64) {
+	t.copied = copied
+}
+
+// State implements [Task] interface.
+func (t *FetchTask) State() ([]byte, error) {
+	return json.Marshal(t.StateValue())
+}
+
+// StateValue implements [StateValuer] interface.
+// It returns the [FetchState].
+func (t *FetchTask) StateValue() any {
+	return FetchState{Version: StateVersion, Type: StateTypeFetch, Dst: t.dst, URL: t.url, Total: t.total, Copied: t.copied}
+}
+
+// Result implements [Task] interface.
+func (t *FetchTask) Result() ([]byte, error) {
+	return json.Marshal(t.ResultValue())
+}
+
+// ResultValue implements [ResultValuer] interface.
+// It returns the [FetchResult].
+func (t *FetchTask) ResultValue() any {
+	return FetchResult{Dst: t.dst, URL: t.url, Size: t.copied}
+}
@@ -0,0 +1,105 @@
+//go:build js && wasm
+
+package iocopy_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/northbright/iocopy"
+)
+
+func ExampleFetch() {
+	// This example uses iocopy.Fetch to read the response body by the Fetch API,
+	// and copies it to a buffer with progress.
+	url := "data:text/plain;base64,SGVsbG8sIFdvcmxkIQ=="
+
+	body, size, err := iocopy.Fetch(context.Background(), url)
+	if err != nil {
+		log.Printf("iocopy.Fetch() error: %v", err)
+		return
+	}
+	defer body.Close()
+
+	buf := &bytes.Buffer{}
+	n, err := iocopy.CopyWithProgress(
+		context.Background(),
+		buf,
+		body,
+		size,
+		0,
+		func(p iocopy.ProgressInfo) {
+			log.Printf("%v/%v(%.2f%%) bytes copied", p.Copied(), p.Total, p.Percent)
+		})
+	if err != nil {
+		log.Printf("iocopy.CopyWithProgress() error: %v", err)
+		return
+	}
+
+	fmt.Printf("%v bytes copied: %s\n", n, buf.Bytes())
+
+	// Output:
+	// 13 bytes copied: Hello, World!
+}
+
+func ExampleFetchTask() {
+	// This example downloads a file by the Fetch API with FetchTask.
+	// Then it resumes the download from a state with some bytes downloaded.
+	// The server of data URLs does not support range, so the download restarts from the beginning.
+	url := "data:text/plain;base64,SGVsbG8sIFdvcmxkIQ=="
+
+	dir, err := os.MkdirTemp("", "iocopy")
+	if err != nil {
+		log.Printf("os.MkdirTemp() error: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	dst := filepath.Join(dir, "hello.txt")
+	onEvent := func(e iocopy.Event) {
+		switch e := e.(type) {
+		case *iocopy.EventRestarted:
+			fmt.Printf("restarted: %v, discarded: %v\n", e.Reason, e.Discarded)
+		case *iocopy.EventOK:
+			r := e.Value.(iocopy.FetchResult)
+			fmt.Printf("%v bytes downloaded\n", r.Size)
+		}
+	}
+
+	t := iocopy.NewFetchTask(dst, url, nil)
+	if err = iocopy.Do(context.Background(), t, nil, onEvent); err != nil {
+		log.Printf("iocopy.Do() error: %v", err)
+		return
+	}
+
+	// Resume from the state with 5 bytes downloaded.
+	state, _ := json.Marshal(iocopy.FetchState{Version: iocopy.StateVersion, Type: iocopy.StateTypeFetch, Dst: dst, URL: url, Total: 13, Copied: 5})
+	task, err := iocopy.LoadTask(state)
+	if err != nil {
+		log.Printf("iocopy.LoadTask() error: %v", err)
+		return
+	}
+
+	if err = iocopy.Do(context.Background(), task, nil, onEvent); err != nil {
+		log.Printf("iocopy.Do() error: %v", err)
+		return
+	}
+
+	buf, err := os.ReadFile(dst)
+	if err != nil {
+		log.Printf("os.ReadFile() error: %v", err)
+		return
+	}
+	fmt.Printf("%s\n", buf)
+
+	// Output:
+	// 13 bytes downloaded
+	// restarted: range not supported, discarded: 5
+	// 13 bytes downloaded
+	// Hello, World!
+}
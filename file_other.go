@@ -0,0 +1,13 @@
+//go:build !windows
+
+package iocopy
+
+// longPath returns the path as is. Long paths only need to be converted on Windows.
+func longPath(path string) string {
+	return path
+}
+
+// copyStreams does nothing. NTFS alternate data streams only exist on Windows.
+func copyStreams(dst, src string) error {
+	return nil
+}
@@ -0,0 +1,122 @@
+//go:build windows
+
+package iocopy
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+// longPathThreshold is the length of paths which need the `\\?\` prefix.
+// Directory paths are limited to MAX_PATH(260) - 12 characters.
+const longPathThreshold = 248
+
+// longPath converts the path to an extended-length path with the `\\?\` prefix
+// if it's too long for Windows APIs.
+// Paths with the prefix are not normalized by Windows, so it's made absolute and cleaned first.
+func longPath(path string) string {
+	if len(path) < longPathThreshold || strings.HasPrefix(path, `\\?\`) || strings.HasPrefix(path, `\\.\`) {
+		return path
+	}
+
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return path
+	}
+
+	// UNC path: \\server\share\...
+	if strings.HasPrefix(abs, `\\`) {
+		return `\\?\UNC\` + abs[2:]
+	}
+	return `\\?\` + abs
+}
+
+var (
+	modkernel32          = syscall.NewLazyDLL("kernel32.dll")
+	procFindFirstStreamW = modkernel32.NewProc("FindFirstStreamW")
+	procFindNextStreamW  = modkernel32.NewProc("FindNextStreamW")
+)
+
+// win32FindStreamData is the WIN32_FIND_STREAM_DATA structure.
+type win32FindStreamData struct {
+	StreamSize int64
+	StreamName [syscall.MAX_PATH + 36]uint16
+}
+
+// streamNames returns the names of the alternate data streams of the file.
+// The default data stream("::$DATA") is not included.
+func streamNames(name string) ([]string, error) {
+	p, err := syscall.UTF16PtrFromString(longPath(name))
+	if err != nil {
+		return nil, err
+	}
+
+	var data win32FindStreamData
+
+	// 0: FindStreamInfoStandard.
+	r, _, err := procFindFirstStreamW.Call(uintptr(unsafe.Pointer(p)), 0, uintptr(unsafe.Pointer(&data)), 0)
+	h := syscall.Handle(r)
+	if h == syscall.InvalidHandle {
+		if errors.Is(err, syscall.ERROR_HANDLE_EOF) {
+			return nil, nil
+		}
+		return nil, &os.PathError{Op: "FindFirstStreamW", Path: name, Err: err}
+	}
+	defer syscall.FindClose(h)
+
+	var names []string
+	for {
+		// Stream names are in the form of ":name:$DATA".
+		s := syscall.UTF16ToString(data.StreamName[:])
+		if s = strings.TrimSuffix(s, ":$DATA"); s != ":" && s != "" {
+			names = append(names, s)
+		}
+
+		if r, _, err = procFindNextStreamW.Call(uintptr(h), uintptr(unsafe.Pointer(&data))); r == 0 {
+			if errors.Is(err, syscall.ERROR_HANDLE_EOF) {
+				return names, nil
+			}
+			return nil, &os.PathError{Op: "FindNextStreamW", Path: name, Err: err}
+		}
+	}
+}
+
+// copyStreams copies the NTFS alternate data streams of src to dst.
+func copyStreams(dst, src string) error {
+	names, err := streamNames(src)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		if err = copyStream(dst+name, src+name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// copyStream copies the stream of the file.
+func copyStream(dst, src string) error {
+	srcF, err := os.Open(longPath(src))
+	if err != nil {
+		return err
+	}
+	defer srcF.Close()
+
+	dstF, err := os.Create(longPath(dst))
+	if err != nil {
+		return err
+	}
+
+	if _, err = io.Copy(dstF, srcF); err != nil {
+		dstF.Close()
+		return err
+	}
+	return dstF.Close()
+}
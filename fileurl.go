@@ -0,0 +1,123 @@
+package iocopy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// fileURLPath returns the local path of a file:// url.
+// ok is false if it's not a file url.
+// On Windows, "file:///C:/a.txt" is "C:\a.txt" and "file://server/share/a.txt" is "\\server\share\a.txt".
+func fileURLPath(rawURL string) (p string, ok bool, err error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || !strings.EqualFold(u.Scheme, "file") {
+		return "", false, nil
+	}
+
+	p = u.Path
+	if u.Opaque != "" {
+		// "file:a.txt".
+		p = u.Opaque
+	}
+
+	switch {
+	case u.Host != "" && u.Host != "localhost":
+		if runtime.GOOS != "windows" {
+			return "", true, fmt.Errorf("file url of remote host is not supported: %v", rawURL)
+		}
+		p = `\\` + u.Host + filepath.FromSlash(p)
+	case runtime.GOOS == "windows" && len(p) >= 3 && p[0] == '/' && p[2] == ':':
+		// "/C:/a.txt".
+		p = filepath.FromSlash(p[1:])
+	default:
+		p = filepath.FromSlash(p)
+	}
+
+	if p == "" {
+		return "", true, fmt.Errorf("no path in file url: %v", rawURL)
+	}
+	return p, true, nil
+}
+
+// fileURLTask copies the local file of a file:// url to the part file of dst by [CopyFileTask] for [Download].
+// The part file is renamed to dst when it's done, or the state is saved to the sidecar state file to resume, like [DownloadTask].
+type fileURLTask struct {
+	*CopyFileTask
+	url string
+	// final is the destination file which the part file is renamed to.
+	final string
+	// done is true when the part file is committed by [Do].
+	done bool
+}
+
+// newFileURLTask returns a [*fileURLTask] which copies src of the url to dst.
+// It resumes the copy from the sidecar state file of the part file if it's valid.
+func newFileURLTask(dst, rawURL, src string, opts []Option) *fileURLTask {
+	part := dst + PartFileExt
+	t := &fileURLTask{url: rawURL, final: dst}
+	if isStdout(dst) {
+		// Stream to the standard output without the part file.
+		t.CopyFileTask = NewCopyFileTask(dst, src, nil, opts...)
+		return t
+	}
+
+	if state, err := os.ReadFile(longPath(dst + PartStateExt)); err == nil {
+		// The part file should contain the copied bytes.
+		if ct, err := LoadCopyFileTask(state, nil, opts...); err == nil && ct.dst == part && ct.src == src {
+			if fi, err := os.Stat(longPath(part)); err == nil && fi.Size() >= ct.copied {
+				t.CopyFileTask = ct
+				return t
+			}
+		}
+	}
+
+	t.CopyFileTask = NewCopyFileTask(part, src, nil, opts...)
+	return t
+}
+
+// Endpoints implements [Endpointer] interface.
+func (t *fileURLTask) Endpoints() (src, dst string) {
+	return t.url, t.final
+}
+
+// Open implements [Task] interface.
+func (t *fileURLTask) Open(ctx context.Context) (dst io.Writer, src io.Reader, err error) {
+	if dst, src, err = t.CopyFileTask.Open(ctx); err != nil {
+		return nil, nil, err
+	}
+
+	t.done = false
+	return &commitWriter{Writer: dst, fn: func() error {
+		t.done = true
+		return nil
+	}}, src, nil
+}
+
+// Close implements [Task] interface.
+// It renames the part file to the destination if the copy is done, or saves the state to the sidecar state file.
+func (t *fileURLTask) Close() error {
+	if err := t.CopyFileTask.Close(); err != nil || isStdout(t.final) {
+		return err
+	}
+	return closePart(t.final, t.done, t.CopyFileTask.State)
+}
+
+// Result implements [Task] interface.
+func (t *fileURLTask) Result() ([]byte, error) {
+	return json.Marshal(t.ResultValue())
+}
+
+// ResultValue implements [ResultValuer] interface.
+// It returns the [CopyFileResult] with the destination file instead of the part file.
+func (t *fileURLTask) ResultValue() any {
+	r := t.CopyFileTask.ResultValue().(CopyFileResult)
+	r.Dst = t.final
+	return r
+}
@@ -0,0 +1,32 @@
+package iocopy
+
+import "context"
+
+// OnFinishFunc is the callback function when the IO copy ends, no matter it succeeds, fails or is stopped.
+// p has the final counters and the elapsed time, e.g. to render the final line of a progress bar.
+// err is the error of the copy or nil if it succeeds.
+type OnFinishFunc func(p ProgressInfo, err error)
+
+// onFinishKey is the context key of [OnFinishFunc].
+type onFinishKey struct{}
+
+// ContextWithOnFinish returns a copy of ctx which makes [CopyBufferWithProgress] and the other copy functions
+// call fn exactly once when the copy ends, after the last call of [OnWrittenFunc].
+// Callers don't need their own bookkeeping of the counters and the time to report the totals.
+// Use the events reported by [Do] for the tasks instead, e.g. [*EventOK] and [*EventStop].
+func ContextWithOnFinish(ctx context.Context, fn OnFinishFunc) context.Context {
+	return context.WithValue(ctx, onFinishKey{}, fn)
+}
+
+// finishProgress calls the [OnFinishFunc] attached to ctx(if any) with the final counters of pr.
+func finishProgress(ctx context.Context, pr *progress, err error) {
+	fn, _ := ctx.Value(onFinishKey{}).(OnFinishFunc)
+	if fn == nil {
+		return
+	}
+
+	if pr.totalFn != nil {
+		pr.total = pr.totalFn()
+	}
+	fn(pr.info(), err)
+}
@@ -0,0 +1,36 @@
+package iocopy_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+
+	"github.com/northbright/iocopy"
+)
+
+func ExampleContextWithOnFinish() {
+	// This example prints the final totals once when the copy ends without tracking the progress.
+	onFinish := func(p iocopy.ProgressInfo, err error) {
+		fmt.Printf("finished: %v/%v bytes, %.2f%%, elapsed: %v, err: %v\n", p.Copied(), p.Total, p.Percent, p.Elapsed > 0, err)
+	}
+
+	s := "Hello, World!"
+	ctx := iocopy.ContextWithOnFinish(context.Background(), onFinish)
+	if _, err := iocopy.CopyWithProgress(ctx, io.Discard, strings.NewReader(s), int64(len(s)), 0, nil); err != nil {
+		log.Printf("iocopy.CopyWithProgress() error: %v", err)
+		return
+	}
+
+	// It's also called when the copy is stopped.
+	ctx, cancel := context.WithCancel(ctx)
+	cancel()
+	iocopy.CopyWithProgress(ctx, io.Discard, strings.NewReader(s), int64(len(s)), 7, func(p iocopy.ProgressInfo) {
+		fmt.Printf("written: %v/%v\n", p.Copied(), p.Total)
+	})
+
+	// Output:
+	// finished: 13/13 bytes, 100.00%, elapsed: true, err: <nil>
+	// finished: 7/13 bytes, 53.85%, elapsed: true, err: context canceled
+}
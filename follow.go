@@ -0,0 +1,131 @@
+package iocopy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// DefaultFollowInterval is the default poll interval of [FollowReader].
+const DefaultFollowInterval = time.Second
+
+var (
+	// ErrFollowTruncated is returned by [FollowReader] when the followed file is truncated below the read offset,
+	// e.g. the log is rotated by copying and truncating it.
+	ErrFollowTruncated = errors.New("followed file truncated")
+
+	// ErrFollowRotated is returned by [FollowReader] when the path of the followed file refers to another file,
+	// e.g. the log is rotated by renaming it and creating a new one.
+	ErrFollowRotated = errors.New("followed file rotated")
+)
+
+// FollowReader reads a growing source like "tail -f".
+// Upon hitting EOF, it waits for more data by polling instead of returning [io.EOF],
+// until the context is done or the stop marker size is reached.
+// It's useful for log shipping.
+//
+// If the source is a regular [*os.File], it checks the file on each poll:
+// it returns [ErrFollowTruncated] if the size is less than the read offset,
+// and [ErrFollowRotated] after all bytes of the file are read if its name refers to another file.
+// The caller can reopen the new file and follow it from the start.
+type FollowReader struct {
+	ctx      context.Context
+	r        io.Reader
+	interval time.Duration
+	stopSize int64
+	n        int64
+}
+
+// NewFollowReader returns a [*FollowReader] which reads from r.
+// ctx: it returns ctx.Err() when ctx is done while waiting for more data.
+// interval: poll interval. [DefaultFollowInterval] is used if it's not positive.
+// stopSize: it returns [io.EOF] after stopSize bytes are read.
+// A negative value means no stop marker size and it only stops when ctx is done.
+func NewFollowReader(ctx context.Context, r io.Reader, interval time.Duration, stopSize int64) *FollowReader {
+	if interval <= 0 {
+		interval = DefaultFollowInterval
+	}
+
+	return &FollowReader{ctx: ctx, r: r, interval: interval, stopSize: stopSize}
+}
+
+// Read implements [io.Reader] interface.
+func (fr *FollowReader) Read(p []byte) (n int, err error) {
+	if fr.stopSize >= 0 {
+		if fr.n >= fr.stopSize {
+			return 0, io.EOF
+		}
+
+		if remain := fr.stopSize - fr.n; int64(len(p)) > remain {
+			p = p[:remain]
+		}
+	}
+
+	for {
+		n, err = fr.r.Read(p)
+		fr.n += int64(n)
+
+		if err != io.EOF {
+			return n, err
+		}
+
+		if n > 0 {
+			return n, nil
+		}
+
+		if err = fr.check(); err != nil {
+			return 0, err
+		}
+
+		// EOF, wait for more data.
+		select {
+		case <-fr.ctx.Done():
+			return 0, fr.ctx.Err()
+		case <-time.After(fr.interval):
+		}
+	}
+}
+
+// check returns an error if the followed file is truncated or rotated.
+func (fr *FollowReader) check() error {
+	f, ok := fr.r.(*os.File)
+	if !ok {
+		return nil
+	}
+
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	// Pipes and sockets can't be truncated or rotated.
+	if !fi.Mode().IsRegular() {
+		return nil
+	}
+
+	offset, err := f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+
+	if fi.Size() < offset {
+		return fmt.Errorf("%w: %v, size: %v, offset: %v", ErrFollowTruncated, f.Name(), fi.Size(), offset)
+	}
+
+	// The file may be removed and not created again yet. Keep waiting for the writer which may still hold it.
+	nfi, err := os.Stat(f.Name())
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+
+	if !os.SameFile(fi, nfi) {
+		return fmt.Errorf("%w: %v", ErrFollowRotated, f.Name())
+	}
+	return nil
+}
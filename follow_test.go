@@ -0,0 +1,138 @@
+package iocopy_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/northbright/iocopy"
+)
+
+func ExampleWithFollow() {
+	// This example copies a growing log file like "tail -f".
+	// It stops after 3 lines are copied.
+	dir, err := os.MkdirTemp("", "iocopy")
+	if err != nil {
+		log.Printf("os.MkdirTemp() error: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	line := "2024/01/01 00:00:00 hello\n"
+	src := filepath.Join(dir, "app.log")
+	if err = os.WriteFile(src, []byte(line), 0644); err != nil {
+		log.Printf("os.WriteFile() error: %v", err)
+		return
+	}
+
+	// Append lines to the log file.
+	go func() {
+		f, err := os.OpenFile(src, os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			log.Printf("os.OpenFile() error: %v", err)
+			return
+		}
+		defer f.Close()
+
+		for i := 0; i < 2; i++ {
+			time.Sleep(time.Millisecond * 50)
+			f.WriteString(line)
+		}
+	}()
+
+	dst := filepath.Join(dir, "copy.log")
+	stopSize := int64(len(line) * 3)
+	t := iocopy.NewCopyFileTask(dst, src, nil, iocopy.WithFollow(time.Millisecond*10, stopSize))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	if err = iocopy.Do(ctx, t, nil, nil); err != nil {
+		log.Printf("iocopy.Do() error: %v", err)
+		return
+	}
+
+	buf, err := os.ReadFile(dst)
+	if err != nil {
+		log.Printf("os.ReadFile() error: %v", err)
+		return
+	}
+	fmt.Printf("%v lines copied\n", strings.Count(string(buf), "\n"))
+
+	// Output:
+	// 3 lines copied
+}
+
+func ExampleNewFollowReader() {
+	// This example follows a log file which is rotated by renaming it and creating a new one.
+	// FollowReader reads the rest of the old file and returns ErrFollowRotated.
+	// Then it follows the new file which is truncated and returns ErrFollowTruncated.
+	dir, err := os.MkdirTemp("", "iocopy")
+	if err != nil {
+		log.Printf("os.MkdirTemp() error: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	src := filepath.Join(dir, "app.log")
+	if err = os.WriteFile(src, []byte("line 1\n"), 0644); err != nil {
+		log.Printf("os.WriteFile() error: %v", err)
+		return
+	}
+
+	f, err := os.Open(src)
+	if err != nil {
+		log.Printf("os.Open() error: %v", err)
+		return
+	}
+	defer f.Close()
+
+	// Rotate the log file.
+	go func() {
+		time.Sleep(time.Millisecond * 50)
+		if err := os.Rename(src, src+".1"); err != nil {
+			log.Printf("os.Rename() error: %v", err)
+			return
+		}
+		if err := os.WriteFile(src, []byte("line 2\n"), 0644); err != nil {
+			log.Printf("os.WriteFile() error: %v", err)
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	r := iocopy.NewFollowReader(ctx, f, time.Millisecond*10, -1)
+	buf, err := io.ReadAll(r)
+	fmt.Printf("read: %q, rotated: %v\n", buf, errors.Is(err, iocopy.ErrFollowRotated))
+
+	// Reopen the new file and follow it from the start.
+	f2, err := os.Open(src)
+	if err != nil {
+		log.Printf("os.Open() error: %v", err)
+		return
+	}
+	defer f2.Close()
+
+	// Truncate the log file.
+	go func() {
+		time.Sleep(time.Millisecond * 50)
+		if err := os.Truncate(src, 0); err != nil {
+			log.Printf("os.Truncate() error: %v", err)
+		}
+	}()
+
+	r = iocopy.NewFollowReader(ctx, f2, time.Millisecond*10, -1)
+	buf, err = io.ReadAll(r)
+	fmt.Printf("read: %q, truncated: %v\n", buf, errors.Is(err, iocopy.ErrFollowTruncated))
+
+	// Output:
+	// read: "line 1\n", rotated: true
+	// read: "line 2\n", truncated: true
+}
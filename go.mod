@@ -1,3 +1,12 @@
 module github.com/northbright/iocopy
 
-go 1.23
+go 1.23.0
+
+require (
+	github.com/fsnotify/fsnotify v1.10.1
+	golang.org/x/crypto v0.40.0
+	golang.org/x/text v0.27.0
+	golang.org/x/time v0.12.0
+)
+
+require golang.org/x/sys v0.34.0 // indirect
@@ -0,0 +1,46 @@
+package iocopy
+
+import (
+	"context"
+	"time"
+)
+
+// gracePeriodKey is the context key of the grace period.
+type gracePeriodKey struct{}
+
+// ContextWithGracePeriod returns a copy of ctx which gives [Do] and the copy functions a grace period of d
+// to finish writing the bytes already read when ctx is canceled, instead of abandoning them mid-buffer.
+// No more bytes are read after ctx is canceled. The writes which don't finish in d are abandoned as usual.
+// Then [Do] closes the task and reports [*EventStop] with the state including the bytes flushed.
+// It does nothing if d is not positive.
+// The destinations which watch ctx by themselves(e.g. [RateLimitWriter]) may still fail after ctx is canceled.
+func ContextWithGracePeriod(ctx context.Context, d time.Duration) context.Context {
+	return context.WithValue(ctx, gracePeriodKey{}, d)
+}
+
+// graceContext returns the context to write the bytes read.
+// It's done after the grace period set by [ContextWithGracePeriod] when ctx is done, or ctx itself if it's not set.
+// The errors should still be read from ctx. stop releases the resources.
+func graceContext(ctx context.Context) (wctx context.Context, stop func()) {
+	d, _ := ctx.Value(gracePeriodKey{}).(time.Duration)
+	if d <= 0 {
+		return ctx, func() {}
+	}
+
+	wctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	var timer *time.Timer
+	done := make(chan struct{})
+	stopAfter := context.AfterFunc(ctx, func() {
+		timer = time.AfterFunc(d, cancel)
+		close(done)
+	})
+
+	return wctx, func() {
+		if !stopAfter() {
+			// The grace period has started.
+			<-done
+			timer.Stop()
+		}
+		cancel()
+	}
+}
@@ -0,0 +1,48 @@
+package iocopy_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/northbright/iocopy"
+)
+
+// cancelingReader cancels the context while the n-th read is in flight.
+type cancelingReader struct {
+	r      io.Reader
+	n      int
+	cancel context.CancelFunc
+}
+
+func (c *cancelingReader) Read(p []byte) (int, error) {
+	if c.n--; c.n == 0 {
+		c.cancel()
+	}
+	return c.r.Read(p)
+}
+
+func ExampleContextWithGracePeriod() {
+	// This example cancels the copy while the second buffer is being read.
+	// Without the grace period, the bytes of the second buffer are read but abandoned.
+	// With the grace period, they're written before the copy returns.
+	data := bytes.Repeat([]byte("a"), 4096)
+	buf := make([]byte, 1024)
+
+	for _, grace := range []time.Duration{0, time.Second} {
+		ctx, cancel := context.WithCancel(context.Background())
+		ctx = iocopy.ContextWithGracePeriod(ctx, grace)
+		src := &cancelingReader{r: bytes.NewReader(data), n: 2, cancel: cancel}
+
+		var dst bytes.Buffer
+		n, err := iocopy.CopyBufferWithProgress(ctx, &dst, src, buf, int64(len(data)), 0, func(p iocopy.ProgressInfo) {})
+		fmt.Printf("grace period: %v, %v bytes written, err: %v\n", grace, n, err)
+		cancel()
+	}
+
+	// Output:
+	// grace period: 0s, 1024 bytes written, err: context canceled
+	// grace period: 1s, 2048 bytes written, err: context canceled
+}
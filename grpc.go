@@ -0,0 +1,365 @@
+package iocopy
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// GRPCServicePath is the path prefix of the methods of the gRPC service defined in iocopy.proto.
+const GRPCServicePath = "/iocopy.v1.TaskService/"
+
+// grpcMaxMessageSize is the max size of the request messages. It's the default of gRPC.
+const grpcMaxMessageSize = 4 << 20
+
+// Status codes of gRPC.
+const (
+	grpcOK                = 0
+	grpcInvalidArgument   = 3
+	grpcNotFound          = 5
+	grpcAlreadyExists     = 6
+	grpcResourceExhausted = 8
+	grpcUnimplemented     = 12
+	grpcInternal          = 13
+	grpcUnavailable       = 14
+)
+
+// grpcError is an error with the gRPC status code.
+type grpcError struct {
+	code int
+	msg  string
+}
+
+func (e *grpcError) Error() string {
+	return e.msg
+}
+
+// GRPCServer implements the gRPC service TaskService defined in iocopy.proto over [TaskManager],
+// so transfer daemons can be controlled out of process:
+//
+//   - CreateTask submits a task loaded from the state by the function registered for its kind by [TaskManager.Register],
+//     or by [LoadTask] if no function is registered.
+//   - ListTasks returns the [TaskStatus] of the running and pending tasks by [TaskManager.List].
+//   - WatchTask streams the events of a task until it finishes. The events are also marshaled as JSON(see [EventSchemaVersion]).
+//
+// It's an [http.Handler] which speaks the gRPC protocol over HTTP/2 without compression,
+// so it doesn't depend on the gRPC library. Serve it by an [http.Server] with TLS,
+// or with unencrypted HTTP/2 enabled by the Protocols field of [http.Server](Go 1.24 or later).
+type GRPCServer struct {
+	m *TaskManager
+}
+
+// NewGRPCServer returns a [*GRPCServer] which controls the tasks of m.
+func NewGRPCServer(m *TaskManager) *GRPCServer {
+	return &GRPCServer{m: m}
+}
+
+// ServeHTTP implements [http.Handler] interface.
+func (s *GRPCServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || !strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
+		http.Error(w, "gRPC request required", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/grpc")
+	w.Header().Set("Trailer", "Grpc-Status, Grpc-Message")
+	w.WriteHeader(http.StatusOK)
+
+	err := s.serve(w, r)
+	code := grpcOK
+	if err != nil {
+		code = grpcInternal
+		var e *grpcError
+		if errors.As(err, &e) {
+			code = e.code
+		}
+		w.Header().Set("Grpc-Message", grpcEscape(err.Error()))
+	}
+	w.Header().Set("Grpc-Status", strconv.Itoa(code))
+}
+
+// serve reads the request message and calls the method.
+func (s *GRPCServer) serve(w http.ResponseWriter, r *http.Request) error {
+	method, ok := strings.CutPrefix(r.URL.Path, GRPCServicePath)
+	if !ok {
+		return &grpcError{grpcUnimplemented, fmt.Sprintf("unknown service: %v", r.URL.Path)}
+	}
+
+	req, err := readGRPCMessage(r.Body)
+	if err != nil {
+		return err
+	}
+
+	fields, err := decodeProtoFields(req)
+	if err != nil {
+		return &grpcError{grpcInvalidArgument, err.Error()}
+	}
+
+	switch method {
+	case "CreateTask":
+		return s.createTask(w, fields)
+	case "ListTasks":
+		return s.listTasks(w)
+	case "WatchTask":
+		return s.watchTask(w, r, fields)
+	default:
+		return &grpcError{grpcUnimplemented, fmt.Sprintf("unknown method: %v", method)}
+	}
+}
+
+// createTask implements CreateTask: message CreateTaskRequest { string id = 1; string kind = 2; bytes state = 3; }.
+// It responds message CreateTaskResponse { string id = 1; }.
+func (s *GRPCServer) createTask(w io.Writer, fields []protoField) error {
+	var id, kind string
+	var state []byte
+	for _, f := range fields {
+		switch {
+		case f.num == 1 && f.wireType == protoBytes:
+			id = string(f.p)
+		case f.num == 2 && f.wireType == protoBytes:
+			kind = string(f.p)
+		case f.num == 3 && f.wireType == protoBytes:
+			state = f.p
+		}
+	}
+
+	s.m.mu.Lock()
+	load, ok := s.m.loaders[kind]
+	s.m.mu.Unlock()
+	if !ok {
+		load = LoadTask
+	}
+
+	t, err := load(state)
+	if err != nil {
+		return &grpcError{grpcInvalidArgument, err.Error()}
+	}
+
+	if i, ok := t.(Identifier); ok && id == "" {
+		id = i.ID()
+	}
+
+	if id == "" {
+		return &grpcError{grpcInvalidArgument, fmt.Sprintf("id is required: the %q task has no ID", kind)}
+	}
+
+	if err = s.m.Submit(id, kind, t); err != nil {
+		switch {
+		case errors.Is(err, ErrInvalidTaskID):
+			return &grpcError{grpcInvalidArgument, err.Error()}
+		case errors.Is(err, ErrTaskExists):
+			return &grpcError{grpcAlreadyExists, err.Error()}
+		case errors.Is(err, ErrShutdown):
+			return &grpcError{grpcUnavailable, err.Error()}
+		}
+		return err
+	}
+
+	return writeGRPCMessage(w, appendProtoBytes(nil, 1, []byte(id)))
+}
+
+// listTasks implements ListTasks: message ListTasksRequest {}.
+// It responds message ListTasksResponse { repeated TaskStatus tasks = 1; } with
+// message TaskStatus { string id = 1; string kind = 2; bool running = 3; int64 total = 4; int64 copied = 5; }.
+func (s *GRPCServer) listTasks(w io.Writer) error {
+	var resp []byte
+	for _, st := range s.m.List() {
+		var b []byte
+		b = appendProtoBytes(b, 1, []byte(st.ID))
+		b = appendProtoBytes(b, 2, []byte(st.Kind))
+		if st.Running {
+			b = appendProtoVarint(b, 3, 1)
+		}
+		b = appendProtoVarint(b, 4, uint64(st.Total))
+		b = appendProtoVarint(b, 5, uint64(st.Copied))
+		resp = appendProtoBytes(resp, 1, b)
+	}
+	return writeGRPCMessage(w, resp)
+}
+
+// grpcEvents queues the encoded events of a stream of WatchTask.
+// The callbacks of the task manager must not block, so the events are queued without a limit,
+// but consecutive written events are coalesced to the latest one if the client is slower than the task.
+// The other events(e.g. ok, stop and finished) are never dropped.
+type grpcEvents struct {
+	mu   sync.Mutex
+	msgs [][]byte
+	// written is true if the last message queued is a written event.
+	written bool
+	// done is true if the finished event is queued.
+	done bool
+	// ready is signaled when messages are queued.
+	ready chan struct{}
+}
+
+// push encodes and queues the event.
+// The events are encoded in the callback, so they're not shared with the stream.
+func (q *grpcEvents) push(e Event) {
+	msg, err := encodeGRPCEvent(e)
+	if err != nil {
+		return
+	}
+
+	_, written := e.(*EventWritten)
+	_, finished := e.(*EventFinished)
+
+	q.mu.Lock()
+	if written && q.written {
+		q.msgs[len(q.msgs)-1] = msg
+	} else {
+		q.msgs = append(q.msgs, msg)
+	}
+	q.written = written
+	q.done = q.done || finished
+	q.mu.Unlock()
+
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+}
+
+// pop returns the queued messages and whether the finished event is queued.
+func (q *grpcEvents) pop() (msgs [][]byte, done bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	msgs, q.msgs, q.written = q.msgs, nil, false
+	return msgs, q.done
+}
+
+// watchTask implements WatchTask: message WatchTaskRequest { string id = 1; }.
+// It streams message TaskEvent { string type = 1; int64 total = 2; int64 copied = 3; string err = 4; bytes json = 5; }
+// until the task finishes or the client cancels. See [grpcEvents] for the written events coalesced.
+func (s *GRPCServer) watchTask(w http.ResponseWriter, r *http.Request, fields []protoField) error {
+	var id string
+	for _, f := range fields {
+		if f.num == 1 && f.wireType == protoBytes {
+			id = string(f.p)
+		}
+	}
+
+	q := &grpcEvents{ready: make(chan struct{}, 1)}
+	unsubscribe, ok := s.m.subscribe(id, q.push, true)
+	defer unsubscribe()
+
+	if !ok {
+		return &grpcError{grpcNotFound, fmt.Sprintf("task not found: %q", id)}
+	}
+
+	// Send the headers, so the client knows the events are subscribed.
+	flusher, _ := w.(http.Flusher)
+	if flusher != nil {
+		flusher.Flush()
+	}
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return r.Context().Err()
+		case <-q.ready:
+		}
+
+		msgs, done := q.pop()
+		for _, msg := range msgs {
+			if err := writeGRPCMessage(w, msg); err != nil {
+				return err
+			}
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+
+		// The finished event is the last one of the task.
+		if done {
+			return nil
+		}
+	}
+}
+
+// encodeGRPCEvent encodes the event as the message TaskEvent.
+func encodeGRPCEvent(e Event) ([]byte, error) {
+	js, err := json.Marshal(e)
+	if err != nil {
+		return nil, err
+	}
+
+	var h eventHeader
+	if err = json.Unmarshal(js, &h); err != nil {
+		return nil, err
+	}
+
+	b := appendProtoBytes(nil, 1, []byte(h.Type))
+	var eventErr error
+	switch e := e.(type) {
+	case *EventWritten:
+		b = appendProtoVarint(b, 2, uint64(e.Total))
+		b = appendProtoVarint(b, 3, uint64(e.Copied))
+	case *EventStop:
+		eventErr = e.Err
+	case *EventError:
+		eventErr = e.Err
+	case *EventFinished:
+		eventErr = e.Err
+	}
+	if eventErr != nil {
+		b = appendProtoBytes(b, 4, []byte(eventErr.Error()))
+	}
+	return appendProtoBytes(b, 5, js), nil
+}
+
+// appendProtoVarint appends the varint field, e.g. int64 and bool.
+func appendProtoVarint(b []byte, field int, v uint64) []byte {
+	b = appendProtoTag(b, field, protoVarint)
+	return binary.AppendUvarint(b, v)
+}
+
+// readGRPCMessage reads a length-prefixed message of a gRPC request.
+func readGRPCMessage(r io.Reader) ([]byte, error) {
+	var prefix [5]byte
+	if _, err := io.ReadFull(r, prefix[:]); err != nil {
+		return nil, &grpcError{grpcInvalidArgument, fmt.Sprintf("read message: %v", err)}
+	}
+
+	if prefix[0] != 0 {
+		return nil, &grpcError{grpcUnimplemented, "compressed messages are not supported"}
+	}
+
+	n := binary.BigEndian.Uint32(prefix[1:])
+	if n > grpcMaxMessageSize {
+		return nil, &grpcError{grpcResourceExhausted, fmt.Sprintf("message too large: %v > %v", n, grpcMaxMessageSize)}
+	}
+
+	msg := make([]byte, n)
+	if _, err := io.ReadFull(r, msg); err != nil {
+		return nil, &grpcError{grpcInvalidArgument, fmt.Sprintf("read message: %v", err)}
+	}
+	return msg, nil
+}
+
+// writeGRPCMessage writes a length-prefixed message of a gRPC response.
+func writeGRPCMessage(w io.Writer, msg []byte) error {
+	b := make([]byte, 5, 5+len(msg))
+	binary.BigEndian.PutUint32(b[1:], uint32(len(msg)))
+	_, err := w.Write(append(b, msg...))
+	return err
+}
+
+// grpcEscape percent-encodes the status message of gRPC.
+func grpcEscape(msg string) string {
+	var sb strings.Builder
+	for i := 0; i < len(msg); i++ {
+		if c := msg[i]; c < 0x20 || c > 0x7e || c == '%' {
+			fmt.Fprintf(&sb, "%%%02X", c)
+			continue
+		}
+		sb.WriteByte(msg[i])
+	}
+	return sb.String()
+}
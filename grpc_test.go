@@ -0,0 +1,226 @@
+package iocopy_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/northbright/iocopy"
+)
+
+// grpcField is a field of a protobuf message: the value of a varint field or the payload of a length-delimited one.
+type grpcField struct {
+	num int
+	n   uint64
+	p   []byte
+}
+
+// grpcFields decodes the varint and length-delimited fields of a protobuf message.
+func grpcFields(b []byte) []grpcField {
+	var fields []grpcField
+	for len(b) > 0 {
+		tag, k := binary.Uvarint(b)
+		b = b[k:]
+
+		f := grpcField{num: int(tag >> 3)}
+		n, k := binary.Uvarint(b)
+		b = b[k:]
+		if tag&7 == 2 {
+			f.p, b = b[:n], b[n:]
+		} else {
+			f.n = n
+		}
+		fields = append(fields, f)
+	}
+	return fields
+}
+
+// grpcString encodes a string field of a protobuf message.
+func grpcString(num int, s string) []byte {
+	b := binary.AppendUvarint(nil, uint64(num)<<3|2)
+	b = binary.AppendUvarint(b, uint64(len(s)))
+	return append(b, s...)
+}
+
+// grpcCall calls the method of the gRPC service of iocopy with the request message.
+// Read the response messages by grpcMessages.
+func grpcCall(c *http.Client, url, method string, req []byte) (*http.Response, error) {
+	body := binary.BigEndian.AppendUint32([]byte{0}, uint32(len(req)))
+	r, err := http.NewRequest(http.MethodPost, url+iocopy.GRPCServicePath+method, bytes.NewReader(append(body, req...)))
+	if err != nil {
+		return nil, err
+	}
+	r.Header.Set("Content-Type", "application/grpc")
+	r.Header.Set("TE", "trailers")
+	return c.Do(r)
+}
+
+// grpcMessages reads the response messages and returns them with the status in the trailers.
+func grpcMessages(resp *http.Response) ([][]byte, string, error) {
+	defer resp.Body.Close()
+
+	var msgs [][]byte
+	for {
+		var prefix [5]byte
+		if _, err := io.ReadFull(resp.Body, prefix[:]); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, "", err
+		}
+
+		msg := make([]byte, binary.BigEndian.Uint32(prefix[1:]))
+		if _, err := io.ReadFull(resp.Body, msg); err != nil {
+			return nil, "", err
+		}
+		msgs = append(msgs, msg)
+	}
+	return msgs, resp.Trailer.Get("Grpc-Status"), nil
+}
+
+func ExampleGRPCServer() {
+	// This example controls a task manager by the gRPC service over HTTP/2.
+	// It creates a download task, lists the tasks and watches the progress of the download.
+	release := make(chan struct{})
+	data := []byte(strings.Repeat("a", 1024))
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Wait for the client to watch the task.
+		<-release
+		w.Write(data)
+	}))
+	defer ts.Close()
+
+	dir, err := os.MkdirTemp("", "iocopy")
+	if err != nil {
+		log.Printf("os.MkdirTemp() error: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	m := iocopy.NewTaskManager(filepath.Join(dir, "tasks"), 2, nil)
+
+	srv := httptest.NewUnstartedServer(iocopy.NewGRPCServer(m))
+	srv.EnableHTTP2 = true
+	srv.StartTLS()
+	defer srv.Close()
+	c := srv.Client()
+
+	// Create a download task by its state.
+	state, err := iocopy.NewDownloadTask(filepath.Join(dir, "file"), ts.URL, nil).State()
+	if err != nil {
+		log.Printf("State() error: %v", err)
+		return
+	}
+
+	req := append(grpcString(1, "file"), grpcString(2, "download")...)
+	req = append(req, grpcString(3, string(state))...)
+	resp, err := grpcCall(c, srv.URL, "CreateTask", req)
+	if err != nil {
+		log.Printf("grpcCall() error: %v", err)
+		return
+	}
+
+	msgs, status, err := grpcMessages(resp)
+	if err != nil {
+		log.Printf("grpcMessages() error: %v", err)
+		return
+	}
+	fmt.Printf("CreateTask: proto: %v, status: %v, id: %s\n", resp.ProtoMajor, status, grpcFields(msgs[0])[0].p)
+
+	// A task without its own ID needs an id.
+	zipState, err := iocopy.NewZipDirTask(filepath.Join(dir, "docs.zip"), dir).State()
+	if err != nil {
+		log.Printf("State() error: %v", err)
+		return
+	}
+
+	req = append(grpcString(2, "zipdir"), grpcString(3, string(zipState))...)
+	if resp, err = grpcCall(c, srv.URL, "CreateTask", req); err != nil {
+		log.Printf("grpcCall() error: %v", err)
+		return
+	}
+
+	if _, status, err = grpcMessages(resp); err != nil {
+		log.Printf("grpcMessages() error: %v", err)
+		return
+	}
+	fmt.Printf("CreateTask: status: %v, message: %v\n", status, resp.Trailer.Get("Grpc-Message"))
+
+	// List the tasks.
+	if resp, err = grpcCall(c, srv.URL, "ListTasks", nil); err != nil {
+		log.Printf("grpcCall() error: %v", err)
+		return
+	}
+
+	if msgs, status, err = grpcMessages(resp); err != nil {
+		log.Printf("grpcMessages() error: %v", err)
+		return
+	}
+	for _, f := range grpcFields(msgs[0]) {
+		task := grpcFields(f.p)
+		fmt.Printf("ListTasks: status: %v, id: %s, kind: %s\n", status, task[0].p, task[1].p)
+	}
+
+	// Watch the progress. The events are subscribed when the response is returned.
+	if resp, err = grpcCall(c, srv.URL, "WatchTask", grpcString(1, "file")); err != nil {
+		log.Printf("grpcCall() error: %v", err)
+		return
+	}
+	close(release)
+
+	if msgs, status, err = grpcMessages(resp); err != nil {
+		log.Printf("grpcMessages() error: %v", err)
+		return
+	}
+
+	var types []string
+	for _, msg := range msgs {
+		var typ string
+		var copied uint64
+		for _, f := range grpcFields(msg) {
+			switch f.num {
+			case 1:
+				typ = string(f.p)
+			case 3:
+				copied = f.n
+			}
+		}
+
+		switch typ {
+		case "written":
+			if copied != uint64(len(data)) {
+				continue
+			}
+			typ = fmt.Sprintf("written(%v)", copied)
+		}
+		if len(types) == 0 || types[len(types)-1] != typ {
+			types = append(types, typ)
+		}
+	}
+	fmt.Printf("WatchTask: status: %v, events: %v\n", status, types)
+
+	// The task is not found after it finishes.
+	if resp, err = grpcCall(c, srv.URL, "WatchTask", grpcString(1, "file")); err != nil {
+		log.Printf("grpcCall() error: %v", err)
+		return
+	}
+
+	if _, status, err = grpcMessages(resp); err != nil {
+		log.Printf("grpcMessages() error: %v", err)
+		return
+	}
+	fmt.Printf("WatchTask: status: %v, message: %v\n", status, resp.Trailer.Get("Grpc-Message"))
+
+	// Output:
+	// CreateTask: proto: 2, status: 0, id: file
+	// CreateTask: status: 3, message: id is required: the "zipdir" task has no ID
+	// ListTasks: status: 0, id: file, kind: download
+	// WatchTask: status: 0, events: [written(1024) ok finished]
+	// WatchTask: status: 5, message: task not found: "file"
+}
@@ -0,0 +1,580 @@
+package iocopy
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"maps"
+	"os"
+	"strings"
+	"sync"
+)
+
+// HashFuncs contains the hash algorithms supported by [HashTask] by their names.
+// More algorithms can be added before tasks are created.
+// Hashes should implement [encoding.BinaryMarshaler] and [encoding.BinaryUnmarshaler] to resume.
+var HashFuncs = map[string]func() hash.Hash{
+	"md5":    md5.New,
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+	"sha512": sha512.New,
+}
+
+// HashTask implements [Task] interface to compute the checksums of a file with one or more hash algorithms.
+// It resumes by saving the marshaled states of the hashes.
+type HashTask struct {
+	file   string
+	algs   []string
+	total  int64
+	copied int64
+	srcF   io.ReadCloser
+	pf     *PrefetchReader
+	opts   options
+	// ra is the source to hash instead of the file if it's not nil. See [NewReaderAtHashTask].
+	ra io.ReaderAt
+	// rr reads the source by multiple readers if [WithParallelReads] is set.
+	rr *readAtReader
+
+	// mu protects hs and copied which are updated by Open and Do and read by Checksums.
+	mu sync.Mutex
+	hs *hashSet
+	// states are the marshaled states of the hashes loaded from the state of the task.
+	states map[string][]byte
+	// expected is the expected digest of [WithExpectedDigest] and verification is the outcome of verifying it.
+	expected     *ExpectedDigest
+	verification *DigestVerification
+	// fi is the file info of the file when it's opened to cache the checksums by [WithHashCache].
+	// cached are the checksums found in the cache if the file is not changed.
+	fi     os.FileInfo
+	cached map[string]string
+}
+
+// HashState is the typed state of [HashTask].
+type HashState struct {
+	// Version is the version of the state. See [StateVersion].
+	Version int `json:"version"`
+	// Type is the type of the state to load it by [LoadTask]: [StateTypeHash].
+	Type string `json:"type"`
+	// File is the file to hash.
+	File string `json:"file"`
+	// Algs are the names of the hash algorithms.
+	Algs []string `json:"algs"`
+	// Total is the total number of bytes to hash.
+	Total int64 `json:"total"`
+	// Copied is the number of bytes hashed.
+	Copied int64 `json:"copied"`
+	// Hashes are the marshaled states of the hashes by their algorithms.
+	Hashes map[string][]byte `json:"hashes,omitempty"`
+	// Expected is the expected digest set by [WithExpectedDigest].
+	Expected *ExpectedDigest `json:"expected,omitempty"`
+}
+
+// HashResult is the typed result of [HashTask].
+type HashResult struct {
+	// File is the file to hash.
+	File string `json:"file"`
+	// Size is the size of the file.
+	Size int64 `json:"size"`
+	// Checksums are the hex encoded checksums by the algorithms.
+	Checksums map[string]string `json:"checksums"`
+	// Multihashes are the checksums encoded as multibase multihash strings by the algorithms
+	// if [WithMultihash] is set. Algorithms without multihash codes are omitted.
+	Multihashes map[string]string `json:"multihashes,omitempty"`
+	// Verification is the outcome of verifying the checksum against the digest of [WithExpectedDigest].
+	Verification *DigestVerification `json:"verification,omitempty"`
+}
+
+// NewHashTask returns a [*HashTask] which computes the checksums of file.
+// file can be the standard input([Stdio]).
+// algs: names of the hash algorithms in [HashFuncs], e.g. "sha256".
+// opts: optional parameters. e.g. [WithPrefetch], [WithRateLimiter], [WithMultihash], [WithParallelReads], [WithHashCache], [WithMmap].
+func NewHashTask(file string, algs []string, opts ...Option) *HashTask {
+	t := &HashTask{file: file, algs: algs, total: -1, opts: newOptions(opts)}
+	t.expected = t.opts.expected
+	t.expectDigest()
+	return t
+}
+
+// NewReaderAtHashTask returns a [*HashTask] which computes the checksums of the first size bytes of r,
+// e.g. a blob in an object store or a file opened by other means.
+// name is the name of the source reported in the state and the result.
+// Use [WithParallelReads] to read r by multiple readers when it supports concurrent random access.
+func NewReaderAtHashTask(name string, r io.ReaderAt, size int64, algs []string, opts ...Option) *HashTask {
+	t := NewHashTask(name, algs, opts...)
+	t.ra = r
+	t.total = size
+	return t
+}
+
+// LoadReaderAtHashTask loads a [*HashTask] created by [NewReaderAtHashTask] from the state to resume the hashing.
+// r is the source which can't be saved in the state. Its size should be the same as the saved one.
+// opts: optional parameters which are not saved in the state.
+func LoadReaderAtHashTask(state []byte, r io.ReaderAt, opts ...Option) (*HashTask, error) {
+	t, err := LoadHashTask(state, opts...)
+	if err != nil {
+		return nil, err
+	}
+	t.ra = r
+	return t, nil
+}
+
+// LoadHashTask loads a [*HashTask] from the state to resume the hashing.
+// opts: optional parameters which are not saved in the state.
+func LoadHashTask(state []byte, opts ...Option) (*HashTask, error) {
+	var s HashState
+	if err := json.Unmarshal(state, &s); err != nil {
+		return nil, err
+	}
+
+	t := NewHashTask(s.File, s.Algs, opts...)
+	t.total = s.Total
+	t.copied = s.Copied
+	t.states = s.Hashes
+	if s.Expected != nil {
+		t.expected = s.Expected
+	}
+	t.expectDigest()
+	return t, nil
+}
+
+// ID implements [Identifier] interface.
+// It's computed from the file and the algorithms.
+func (t *HashTask) ID() string {
+	return taskID("hash", t.file, strings.Join(t.algs, ","))
+}
+
+// Endpoints implements [Endpointer] interface. dst is empty.
+func (t *HashTask) Endpoints() (src, dst string) {
+	return t.file, ""
+}
+
+// Open implements [Task] interface.
+// It opens the file, seeks to the hashed position and restores the hashes from the state.
+// Nothing is read if the checksums of the unchanged file are in the cache of [WithHashCache].
+func (t *HashTask) Open(ctx context.Context) (dst io.Writer, src io.Reader, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if len(t.algs) == 0 {
+		return nil, nil, fmt.Errorf("no hash algorithms")
+	}
+
+	if t.openCached() {
+		// The checksums are cached, so nothing is read.
+		dst = io.Discard
+		if t.expected != nil {
+			dst = &commitWriter{Writer: dst, fn: t.verifyDigest}
+		}
+		return dst, strings.NewReader(""), nil
+	}
+
+	if t.hs == nil {
+		if t.states == nil {
+			// Hash the file again if the states are not loaded, e.g. from a token.
+			t.copied = 0
+		}
+
+		if t.hs, err = newHashSet(t.algs, t.states, t.copied); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if src, err = t.openSrc(ctx); err != nil {
+		t.Close()
+		return nil, nil, err
+	}
+
+	src = t.opts.limit(ctx, src)
+
+	// No need to prefetch the memory-mapped file.
+	// It also avoids reading the mapped memory in the goroutine after it's unmapped.
+	if _, mapped := t.srcF.(*mmapReader); t.opts.prefetch && !mapped {
+		t.pf = NewPrefetchReader(ctx, src, t.opts.prefetchDepth, t.opts.prefetchSize)
+		src = t.pf
+	}
+
+	if t.expected != nil || t.opts.hashCache != nil {
+		return &commitWriter{Writer: t.hs, fn: t.commit}, src, nil
+	}
+	return t.hs, src, nil
+}
+
+// openCached looks up the checksums of the file in the cache of [WithHashCache] if it's not opened yet.
+// It returns true and marks the file hashed if all of them are cached.
+func (t *HashTask) openCached() bool {
+	if t.cached != nil {
+		return true
+	}
+
+	c := t.opts.hashCache
+	if c == nil || t.ra != nil || isStdin(t.file) || t.hs != nil {
+		return false
+	}
+
+	// Errors are reported when the file is opened.
+	fi, err := os.Stat(longPath(t.file))
+	if err != nil || !fi.Mode().IsRegular() {
+		return false
+	}
+
+	sums, ok := c.lookup(t.file, fi, t.algs)
+	if !ok {
+		return false
+	}
+
+	t.cached = sums
+	t.total = fi.Size()
+	t.copied = t.total
+	return true
+}
+
+// commit verifies the checksum against the expected digest and caches the checksums when all bytes are hashed.
+func (t *HashTask) commit() error {
+	if t.expected != nil {
+		if err := t.verifyDigest(); err != nil {
+			return err
+		}
+	}
+
+	if t.fi == nil {
+		return nil
+	}
+
+	checksums, _ := t.hs.checksums()
+	return t.opts.hashCache.store(t.file, t.fi, checksums)
+}
+
+// openSrc opens the source at the hashed position.
+// It's read by multiple readers if [WithParallelReads] is set.
+func (t *HashTask) openSrc(ctx context.Context) (io.Reader, error) {
+	ra := t.ra
+	if ra == nil && isStdin(t.file) {
+		// Stream the standard input with unknown total size.
+		if t.copied > 0 {
+			return nil, errStdioResume(t.file)
+		}
+		t.total = -1
+		return os.Stdin, nil
+	}
+
+	if ra == nil {
+		f, err := os.Open(longPath(t.file))
+		if err != nil {
+			return nil, err
+		}
+		t.srcF = f
+
+		fi, err := f.Stat()
+		if err != nil {
+			return nil, err
+		}
+
+		if t.copied > 0 && fi.Size() != t.total {
+			return nil, fmt.Errorf("size of %v changed: %v, previous: %v", t.file, fi.Size(), t.total)
+		}
+		t.total = fi.Size()
+
+		if t.opts.hashCache != nil && fi.Mode().IsRegular() {
+			t.fi = fi
+		}
+
+		var sf interface {
+			io.ReadSeekCloser
+			io.ReaderAt
+		} = f
+		if t.opts.mmap && fi.Mode().IsRegular() {
+			// Fall back to read the file if it can't be mapped.
+			if m, err := newMmapReader(f, t.total); err == nil {
+				sf = m
+				t.srcF = m
+			} else {
+				logDebug(ctx, "iocopy: mmap failed, fall back to read", "file", t.file, "err", err)
+			}
+		}
+
+		if t.opts.parallelReads <= 1 {
+			if _, err = sf.Seek(t.copied, io.SeekStart); err != nil {
+				return nil, err
+			}
+			return sf, nil
+		}
+		ra = sf
+	}
+
+	if t.opts.parallelReads > 1 {
+		t.rr = newReadAtReader(ctx, ra, t.copied, t.total, t.opts.parallelReads, t.opts.readAtSize)
+		return t.rr, nil
+	}
+	return io.NewSectionReader(ra, t.copied, t.total-t.copied), nil
+}
+
+// WorkerProgress implements [WorkerProgresser] interface.
+// It returns the bytes read by each reader of [WithParallelReads] since the task is opened.
+func (t *HashTask) WorkerProgress() []int64 {
+	if t.rr == nil {
+		return nil
+	}
+	return t.rr.pool.Progress()
+}
+
+// Close implements [Task] interface.
+func (t *HashTask) Close() error {
+	if t.pf != nil {
+		t.pf.Close()
+		t.pf = nil
+	}
+
+	if t.rr != nil {
+		t.rr.Close()
+		t.rr = nil
+	}
+
+	if t.srcF != nil {
+		err := t.srcF.Close()
+		t.srcF = nil
+		return err
+	}
+	return nil
+}
+
+// Checksums returns the hex encoded checksums of the bytes hashed so far by the algorithms
+// and the number of bytes hashed.
+// It's safe to be called while the task is running,
+// e.g. to show intermediate digests or save rolling verification points.
+// It returns nil checksums if the hashing does not start.
+func (t *HashTask) Checksums() (checksums map[string]string, n int64) {
+	t.mu.Lock()
+	hs, copied, cached := t.hs, t.copied, t.cached
+	t.mu.Unlock()
+
+	if cached != nil {
+		return maps.Clone(cached), copied
+	}
+
+	if hs == nil {
+		return nil, copied
+	}
+	return hs.checksums()
+}
+
+// Total implements [Task] interface.
+func (t *HashTask) Total() int64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.total
+}
+
+// Copied implements [Task] interface.
+func (t *HashTask) Copied() int64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.copied
+}
+
+// SetCopied implements [Task] interface.
+func (t *HashTask) SetCopied(copied int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.copied = copied
+}
+
+// State implements [Task] interface.
+// It marshals the states of the hashes.
+// The number of bytes hashed is taken from the hashes with their states,
+// so they match even if the state is saved while the task is running.
+func (t *HashTask) State() ([]byte, error) {
+	t.mu.Lock()
+	hs := t.hs
+	s := HashState{Version: StateVersion, Type: StateTypeHash, File: t.file, Algs: t.algs, Total: t.total, Copied: t.copied, Hashes: t.states, Expected: t.expected}
+	t.mu.Unlock()
+
+	if hs != nil {
+		var err error
+		if s.Hashes, s.Copied, err = hs.states(); err != nil {
+			return nil, err
+		}
+	}
+
+	return json.Marshal(s)
+}
+
+// Result implements [Task] interface.
+func (t *HashTask) Result() ([]byte, error) {
+	return json.Marshal(t.ResultValue())
+}
+
+// ResultValue implements [ResultValuer] interface.
+// It returns the [HashResult].
+func (t *HashTask) ResultValue() any {
+	checksums, n := t.Checksums()
+	r := HashResult{File: t.file, Size: n, Checksums: checksums, Verification: t.verification}
+
+	if t.opts.multihash {
+		r.Multihashes = map[string]string{}
+		for alg, sum := range checksums {
+			digest, _ := hex.DecodeString(sum)
+			if mh, err := Multihash(alg, digest); err == nil {
+				r.Multihashes[alg] = mh
+			}
+		}
+	}
+	return r
+}
+
+// hashSet computes the checksums of the written bytes with one or more hash algorithms.
+// It's safe to read the checksums while writing.
+type hashSet struct {
+	algs []string
+
+	mu     sync.Mutex
+	hashes []hash.Hash
+	// n is the number of bytes written to the hashes.
+	n int64
+	// fw hashes the bytes in the background workers if it's not nil. See [WithHashWorkers].
+	fw *FanOutWriter
+}
+
+// newHashSet creates the hashes of the algorithms in [HashFuncs].
+// If n > 0, the hashes are restored from the marshaled states
+// and n is the number of bytes written previously.
+func newHashSet(algs []string, states map[string][]byte, n int64) (*hashSet, error) {
+	hs := &hashSet{algs: algs, n: n}
+	for _, alg := range algs {
+		newHash, ok := HashFuncs[alg]
+		if !ok {
+			return nil, fmt.Errorf("unsupported hash algorithm: %v", alg)
+		}
+
+		h := newHash()
+		if n > 0 {
+			u, ok := h.(encoding.BinaryUnmarshaler)
+			if !ok {
+				return nil, fmt.Errorf("hash of %v does not support unmarshaling", alg)
+			}
+
+			if err := u.UnmarshalBinary(states[alg]); err != nil {
+				return nil, fmt.Errorf("restore hash of %v: %w", alg, err)
+			}
+		}
+		hs.hashes = append(hs.hashes, h)
+	}
+	return hs, nil
+}
+
+// offload makes the hashes computed by at most n background workers fed by the copies of the bytes written.
+// The hashes are distributed to the workers and each one is computed by a single worker in order.
+func (hs *hashSet) offload(n int) {
+	hs.stop()
+
+	n = min(n, len(hs.hashes))
+	ws := make([]io.Writer, n)
+	for i := range ws {
+		var group []io.Writer
+		for j := i; j < len(hs.hashes); j += n {
+			group = append(group, hs.hashes[j])
+		}
+		ws[i] = io.MultiWriter(group...)
+	}
+	hs.fw = NewFanOutWriter(0, 0, ws...)
+}
+
+// stop waits for the background workers to hash the pending bytes and stops them.
+func (hs *hashSet) stop() {
+	if hs.fw != nil {
+		hs.fw.Close()
+		hs.fw = nil
+	}
+}
+
+// Write implements [io.Writer] interface.
+func (hs *hashSet) Write(p []byte) (int, error) {
+	// The lock is held while the bytes are queued to the background workers,
+	// so the hashes flushed by checksums and states always match n.
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+
+	if hs.fw != nil {
+		n, err := hs.fw.Write(p)
+		hs.n += int64(n)
+		return n, err
+	}
+
+	for _, h := range hs.hashes {
+		h.Write(p)
+	}
+	hs.n += int64(len(p))
+	return len(p), nil
+}
+
+// flush waits for the background workers to hash the pending bytes.
+func (hs *hashSet) flush() {
+	if hs.fw != nil {
+		hs.fw.Flush()
+	}
+}
+
+// checksums returns the hex encoded checksums by the algorithms and the number of bytes written.
+func (hs *hashSet) checksums() (map[string]string, int64) {
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+	hs.flush()
+
+	checksums := map[string]string{}
+	for i, h := range hs.hashes {
+		// Sum does not change the state of the hash.
+		checksums[hs.algs[i]] = hex.EncodeToString(h.Sum(nil))
+	}
+	return checksums, hs.n
+}
+
+// states returns the marshaled states of the hashes by the algorithms and the number of bytes written to them.
+func (hs *hashSet) states() (map[string][]byte, int64, error) {
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+	hs.flush()
+
+	states := map[string][]byte{}
+	for i, h := range hs.hashes {
+		m, ok := h.(encoding.BinaryMarshaler)
+		if !ok {
+			return nil, 0, fmt.Errorf("hash of %v does not support marshaling", hs.algs[i])
+		}
+
+		state, err := m.MarshalBinary()
+		if err != nil {
+			return nil, 0, err
+		}
+		states[hs.algs[i]] = state
+	}
+	return states, hs.n, nil
+}
+
+// hashWriter writes to w and the bytes written are written to hs.
+// It forwards Commit to w if w implements [Committer].
+type hashWriter struct {
+	w  io.Writer
+	hs *hashSet
+}
+
+// Write implements [io.Writer] interface.
+func (hw *hashWriter) Write(p []byte) (n int, err error) {
+	n, err = hw.w.Write(p)
+	hw.hs.Write(p[:n])
+	return n, err
+}
+
+// Commit implements [Committer] interface.
+func (hw *hashWriter) Commit() error {
+	if c, ok := hw.w.(Committer); ok {
+		return c.Commit()
+	}
+	return nil
+}
@@ -0,0 +1,301 @@
+package iocopy_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/northbright/iocopy"
+)
+
+func ExampleHashTask_Checksums() {
+	// This example computes the SHA-256 and MD5 checksums of a file.
+	// It reads the intermediate checksums while the task is running and stops the task at 50%.
+	// Then it loads the task from the saved state to resume the hashing.
+	dir, err := os.MkdirTemp("", "iocopy")
+	if err != nil {
+		log.Printf("os.MkdirTemp() error: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "file")
+	data := bytes.Repeat([]byte("0123456789abcdef"), 1024)
+	if err = os.WriteFile(file, data, 0644); err != nil {
+		log.Printf("os.WriteFile() error: %v", err)
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var state []byte
+	buf := make([]byte, 1024)
+
+	t := iocopy.NewHashTask(file, []string{"sha256", "md5"})
+	iocopy.Do(ctx, t, buf, func(e iocopy.Event) {
+		switch e := e.(type) {
+		case *iocopy.EventWritten:
+			if e.Percent == 50 {
+				checksums, n := t.Checksums()
+				sum := sha256.Sum256(data[:n])
+				fmt.Printf("%v bytes hashed, intermediate SHA-256 matches: %v\n", n, checksums["sha256"] == hex.EncodeToString(sum[:]))
+				cancel()
+			}
+		case *iocopy.EventStop:
+			state = e.State
+		}
+	})
+
+	if t, err = iocopy.LoadHashTask(state); err != nil {
+		log.Printf("iocopy.LoadHashTask() error: %v", err)
+		return
+	}
+
+	if err = iocopy.Do(context.Background(), t, buf, nil); err != nil {
+		log.Printf("iocopy.Do() error: %v", err)
+		return
+	}
+
+	r, err := iocopy.ResultAs[iocopy.HashResult](t)
+	if err != nil {
+		log.Printf("iocopy.ResultAs() error: %v", err)
+		return
+	}
+
+	sha256Sum, md5Sum := sha256.Sum256(data), md5.Sum(data)
+	fmt.Printf("size: %v, SHA-256 matches: %v, MD5 matches: %v\n",
+		r.Size,
+		r.Checksums["sha256"] == hex.EncodeToString(sha256Sum[:]),
+		r.Checksums["md5"] == hex.EncodeToString(md5Sum[:]),
+	)
+
+	// Output:
+	// 8192 bytes hashed, intermediate SHA-256 matches: true
+	// size: 16384, SHA-256 matches: true, MD5 matches: true
+}
+
+func ExampleWithMultihash() {
+	// This example adds the multihash of the SHA-256 checksum to the result of a hash task.
+	dir, err := os.MkdirTemp("", "iocopy")
+	if err != nil {
+		log.Printf("os.MkdirTemp() error: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "file")
+	if err = os.WriteFile(file, []byte("Hello, World!"), 0644); err != nil {
+		log.Printf("os.WriteFile() error: %v", err)
+		return
+	}
+
+	t := iocopy.NewHashTask(file, []string{"sha256"}, iocopy.WithMultihash())
+	if err = iocopy.Do(context.Background(), t, nil, nil); err != nil {
+		log.Printf("iocopy.Do() error: %v", err)
+		return
+	}
+
+	r, _ := iocopy.ResultAs[iocopy.HashResult](t)
+	fmt.Printf("%v\n", r.Multihashes["sha256"])
+
+	// Output:
+	// bciqn77laeg5sxvnqv5twfeeat3b2kmmr3wa4p5ykjmugrcrwegbjq3y
+}
+
+func ExampleNewReaderAtHashTask() {
+	// This example hashes an io.ReaderAt by 4 readers in parallel.
+	// The chunks are read concurrently and fed to the hashes in order.
+	// It's stopped after the first bytes hashed and resumed from the state.
+	data := bytes.Repeat([]byte("0123456789abcdef"), 256*1024)
+	r := bytes.NewReader(data)
+	opt := iocopy.WithParallelReads(4, 64*1024)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var state []byte
+	t := iocopy.NewReaderAtHashTask("data", r, int64(len(data)), []string{"sha256"}, opt)
+	iocopy.Do(ctx, t, nil, func(e iocopy.Event) {
+		switch e := e.(type) {
+		case *iocopy.EventWritten:
+			// Emulate user cancelation.
+			cancel()
+		case *iocopy.EventStop:
+			state = e.State
+		}
+	})
+
+	t, err := iocopy.LoadReaderAtHashTask(state, r, opt)
+	if err != nil {
+		log.Printf("iocopy.LoadReaderAtHashTask() error: %v", err)
+		return
+	}
+
+	if err = iocopy.Do(context.Background(), t, nil, nil); err != nil {
+		log.Printf("iocopy.Do() error: %v", err)
+		return
+	}
+
+	checksums, n := t.Checksums()
+	sum := sha256.Sum256(data)
+	fmt.Printf("%v bytes hashed, SHA-256 matches: %v\n", n, checksums["sha256"] == hex.EncodeToString(sum[:]))
+
+	// Output:
+	// 4194304 bytes hashed, SHA-256 matches: true
+}
+
+func ExampleHashTask_expectedDigest() {
+	// This example verifies the SHA-256 checksum of a file against the published one.
+	dir, err := os.MkdirTemp("", "iocopy")
+	if err != nil {
+		log.Printf("os.MkdirTemp() error: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "file")
+	data := bytes.Repeat([]byte("0123456789abcdef"), 1024)
+	if err = os.WriteFile(file, data, 0644); err != nil {
+		log.Printf("os.WriteFile() error: %v", err)
+		return
+	}
+
+	sum := sha256.Sum256(data)
+	digest := hex.EncodeToString(sum[:])
+
+	t := iocopy.NewHashTask(file, []string{"sha256"}, iocopy.WithExpectedDigest("sha256", digest))
+	if err = iocopy.Do(context.Background(), t, nil, nil); err != nil {
+		log.Printf("iocopy.Do() error: %v", err)
+		return
+	}
+
+	r, err := iocopy.ResultAs[iocopy.HashResult](t)
+	if err != nil {
+		log.Printf("iocopy.ResultAs() error: %v", err)
+		return
+	}
+	fmt.Printf("verified: %v\n", r.Verification.OK)
+
+	// The file doesn't match a wrong digest.
+	t = iocopy.NewHashTask(file, []string{"sha256"}, iocopy.WithExpectedDigest("sha256", strings.Repeat("0", 64)))
+	err = iocopy.Do(context.Background(), t, nil, nil)
+	fmt.Printf("mismatch: %v\n", errors.Is(err, iocopy.ErrChecksumMismatch))
+
+	// Stop hashing the file by MD5 at 50% and resume it with the expected SHA-256 digest.
+	// The saved state has no SHA-256 hash, so the file is hashed again from the beginning.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var state []byte
+	buf := make([]byte, 1024)
+
+	t = iocopy.NewHashTask(file, []string{"md5"})
+	iocopy.Do(ctx, t, buf, func(e iocopy.Event) {
+		switch e := e.(type) {
+		case *iocopy.EventWritten:
+			if e.Percent == 50 {
+				cancel()
+			}
+		case *iocopy.EventStop:
+			state = e.State
+		}
+	})
+
+	if t, err = iocopy.LoadHashTask(state, iocopy.WithExpectedDigest("sha256", digest)); err != nil {
+		log.Printf("iocopy.LoadHashTask() error: %v", err)
+		return
+	}
+
+	first := int64(-1)
+	if err = iocopy.Do(context.Background(), t, buf, func(e iocopy.Event) {
+		if e, ok := e.(*iocopy.EventWritten); ok && first < 0 {
+			first = e.Copied
+		}
+	}); err != nil {
+		log.Printf("iocopy.Do() error: %v", err)
+		return
+	}
+
+	if r, err = iocopy.ResultAs[iocopy.HashResult](t); err != nil {
+		log.Printf("iocopy.ResultAs() error: %v", err)
+		return
+	}
+
+	md5Sum := md5.Sum(data)
+	fmt.Printf("hashed from the beginning: %v\n", first == int64(len(buf)))
+	fmt.Printf("verified: %v, MD5 matches: %v\n", r.Verification.OK, r.Checksums["md5"] == hex.EncodeToString(md5Sum[:]))
+
+	// Output:
+	// verified: true
+	// mismatch: true
+	// hashed from the beginning: true
+	// verified: true, MD5 matches: true
+}
+
+func ExampleHashTask_mmap() {
+	// This example hashes a file by reading it through memory mapping.
+	// It's stopped at 50% and resumed from the state by 2 readers which read the mapped memory in parallel.
+	dir, err := os.MkdirTemp("", "iocopy")
+	if err != nil {
+		log.Printf("os.MkdirTemp() error: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "file")
+	data := bytes.Repeat([]byte("0123456789abcdef"), 256)
+	if err = os.WriteFile(file, data, 0644); err != nil {
+		log.Printf("os.WriteFile() error: %v", err)
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var state []byte
+	onEvent := func(e iocopy.Event) {
+		switch e := e.(type) {
+		case *iocopy.EventWritten:
+			fmt.Printf("%v/%v bytes hashed(%.2f%%)\n", e.Copied, e.Total, e.Percent)
+			if e.Percent == 50 {
+				// Emulate user cancelation.
+				cancel()
+			}
+		case *iocopy.EventStop:
+			state = e.State
+		}
+	}
+
+	t := iocopy.NewHashTask(file, []string{"sha256"}, iocopy.WithMmap())
+	iocopy.Do(ctx, t, make([]byte, 1024), onEvent)
+
+	if t, err = iocopy.LoadHashTask(state, iocopy.WithMmap(), iocopy.WithParallelReads(2, 1024)); err != nil {
+		log.Printf("iocopy.LoadHashTask() error: %v", err)
+		return
+	}
+
+	if err = iocopy.Do(context.Background(), t, make([]byte, 1024), onEvent); err != nil {
+		log.Printf("iocopy.Do() error: %v", err)
+		return
+	}
+
+	checksums, _ := t.Checksums()
+	sum := sha256.Sum256(data)
+	fmt.Printf("SHA-256 matches: %v\n", checksums["sha256"] == hex.EncodeToString(sum[:]))
+
+	// Output:
+	// 1024/4096 bytes hashed(25.00%)
+	// 2048/4096 bytes hashed(50.00%)
+	// 3072/4096 bytes hashed(75.00%)
+	// 4096/4096 bytes hashed(100.00%)
+	// SHA-256 matches: true
+}
@@ -0,0 +1,149 @@
+package iocopy
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// HashCache is a persistent cache of checksums stored in a JSON file.
+// Entries are keyed by the absolute paths of files
+// and invalidated when the size or modification time of a file changes.
+// It makes repeatedly hashing or verifying unchanged files not re-read them. See [WithHashCache].
+// It's safe to be shared by the tasks running concurrently.
+type HashCache struct {
+	file string
+
+	mu      sync.Mutex
+	entries map[string]*hashCacheEntry
+}
+
+// hashCacheFile is the content of the cache file.
+type hashCacheFile struct {
+	// Entries contains the cached checksums of files.
+	Entries map[string]*hashCacheEntry `json:"entries"`
+}
+
+// hashCacheEntry contains the cached checksums of a file.
+type hashCacheEntry struct {
+	// Size is the size of the file when the checksums are computed.
+	Size int64 `json:"size"`
+	// ModTime is the modification time of the file when the checksums are computed.
+	ModTime time.Time `json:"mod_time"`
+	// Sums contains the hex encoded checksums keyed by the hash algorithms.
+	Sums map[string]string `json:"sums"`
+}
+
+// LoadHashCache loads the hash cache from the file.
+// It returns an empty cache if the file does not exist.
+func LoadHashCache(file string) (*HashCache, error) {
+	c := &HashCache{file: file, entries: map[string]*hashCacheEntry{}}
+
+	buf, err := os.ReadFile(file)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return c, nil
+		}
+		return nil, err
+	}
+
+	var f hashCacheFile
+	if err = json.Unmarshal(buf, &f); err != nil {
+		return nil, err
+	}
+
+	if f.Entries != nil {
+		c.entries = f.Entries
+	}
+	return c, nil
+}
+
+// Get returns the cached checksum of the file computed by alg.
+// fi is the current file info of the file.
+// It returns false if there's no cached checksum or the file is changed.
+func (c *HashCache) Get(name string, fi fs.FileInfo, alg string) (string, bool) {
+	abs, err := filepath.Abs(name)
+	if err != nil {
+		return "", false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[abs]
+	if !ok || e.Size != fi.Size() || !e.ModTime.Equal(fi.ModTime()) {
+		return "", false
+	}
+
+	sum, ok := e.Sums[alg]
+	return sum, ok
+}
+
+// Set caches the checksum of the file computed by alg.
+// fi is the file info of the file when the checksum is computed.
+// Checksums of other algorithms are dropped if the file is changed.
+func (c *HashCache) Set(name string, fi fs.FileInfo, alg, sum string) error {
+	abs, err := filepath.Abs(name)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[abs]
+	if !ok || e.Size != fi.Size() || !e.ModTime.Equal(fi.ModTime()) {
+		e = &hashCacheEntry{Size: fi.Size(), ModTime: fi.ModTime(), Sums: map[string]string{}}
+		c.entries[abs] = e
+	}
+
+	e.Sums[alg] = sum
+	return nil
+}
+
+// Save writes the cache to the cache file.
+// It writes a temporary file and renames it to avoid corrupting the cache file.
+func (c *HashCache) Save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	buf, err := json.MarshalIndent(hashCacheFile{Entries: c.entries}, "", "    ")
+	if err != nil {
+		return err
+	}
+
+	tmp := c.file + ".tmp"
+	if err = os.WriteFile(tmp, buf, 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, c.file)
+}
+
+// lookup returns the cached checksums of the file computed by all the algorithms.
+// It returns false if any of them is not cached or the file is changed.
+func (c *HashCache) lookup(name string, fi fs.FileInfo, algs []string) (map[string]string, bool) {
+	sums := map[string]string{}
+	for _, alg := range algs {
+		sum, ok := c.Get(name, fi, alg)
+		if !ok {
+			return nil, false
+		}
+		sums[alg] = sum
+	}
+	return sums, true
+}
+
+// store caches the checksums of the file and saves the cache.
+func (c *HashCache) store(name string, fi fs.FileInfo, checksums map[string]string) error {
+	for alg, sum := range checksums {
+		if err := c.Set(name, fi, alg, sum); err != nil {
+			return err
+		}
+	}
+	return c.Save()
+}
@@ -0,0 +1,69 @@
+package iocopy_test
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/northbright/iocopy"
+)
+
+func ExampleWithHashCache() {
+	// This example reuses the checksum of an unchanged file in the hash cache instead of reading it.
+	dir, err := os.MkdirTemp("", "iocopy")
+	if err != nil {
+		log.Printf("os.MkdirTemp() error: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "file")
+	if err = os.WriteFile(file, []byte("Hello, World!"), 0644); err != nil {
+		log.Printf("os.WriteFile() error: %v", err)
+		return
+	}
+
+	cacheFile := filepath.Join(dir, "cache.json")
+	hashFile := func() {
+		c, err := iocopy.LoadHashCache(cacheFile)
+		if err != nil {
+			log.Printf("iocopy.LoadHashCache() error: %v", err)
+			return
+		}
+
+		t := iocopy.NewHashTask(file, []string{"md5"}, iocopy.WithHashCache(c))
+		if err := iocopy.Do(context.Background(), t, nil, nil); err != nil {
+			log.Printf("iocopy.Do() error: %v", err)
+			return
+		}
+
+		r := t.ResultValue().(iocopy.HashResult)
+		fmt.Printf("size: %v, md5: %v\n", r.Size, r.Checksums["md5"])
+	}
+
+	// The checksum is computed and saved in the cache file.
+	hashFile()
+
+	// Change the file without changing its size and modification time.
+	// The cached checksum is reused since the file is not read.
+	fi, err := os.Stat(file)
+	if err != nil {
+		log.Printf("os.Stat() error: %v", err)
+		return
+	}
+	os.WriteFile(file, []byte("Hello, Gophe!"), 0644)
+	os.Chtimes(file, time.Time{}, fi.ModTime())
+	hashFile()
+
+	// The checksum is computed again after the modification time is changed.
+	os.Chtimes(file, time.Time{}, fi.ModTime().Add(time.Second))
+	hashFile()
+
+	// Output:
+	// size: 13, md5: 65a8e27d8879283831b664bd8b7f0ad4
+	// size: 13, md5: 65a8e27d8879283831b664bd8b7f0ad4
+	// size: 13, md5: de4847186df1e1a9cc59ca7d96392d79
+}
@@ -0,0 +1,139 @@
+package iocopy
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// EventHeartbeat is reported periodically by [Do] if no other events are reported in the interval set by [ContextWithHeartbeat],
+// e.g. when the connection is being established or the server stalls.
+// [*EventWritten] is only reported when the percent changes, so supervisors can't tell a stalled task from a dead one without it.
+type EventHeartbeat struct {
+	// Opened is false if the task is still being opened, e.g. connecting to the server.
+	Opened bool
+	// Copied is the number of bytes copied including the ones copied previously. It's 0 before the task is opened.
+	Copied int64
+	// Elapsed is the time since Do started.
+	Elapsed time.Duration
+	// Idle is the time since the last bytes were written(or Do started if no bytes are written).
+	// A growing Idle means the task makes no progress.
+	Idle time.Duration
+}
+
+func (e *EventHeartbeat) event() {}
+
+// heartbeatKey is the context key of the heartbeat interval.
+type heartbeatKey struct{}
+
+// ContextWithHeartbeat returns a copy of ctx which makes [Do] report [*EventHeartbeat]
+// every interval in which no other events are reported, even if no bytes are copied.
+// It does nothing if interval is not positive.
+func ContextWithHeartbeat(ctx context.Context, interval time.Duration) context.Context {
+	return context.WithValue(ctx, heartbeatKey{}, interval)
+}
+
+// heartbeat serializes the events reported by [Do] and reports [*EventHeartbeat] when there're no events in the interval.
+type heartbeat struct {
+	fn    OnEventFunc
+	start time.Time
+	// mu protects the calls of fn and the fields below.
+	mu      sync.Mutex
+	last    time.Time
+	opened  bool
+	stopped bool
+	// copied and lastWrite(in nanoseconds since start) are updated on every write.
+	copied    atomic.Int64
+	lastWrite atomic.Int64
+	done      chan struct{}
+	wg        sync.WaitGroup
+}
+
+// startHeartbeat returns a heartbeat which calls fn with the events.
+// It starts reporting [*EventHeartbeat] if ctx is returned by [ContextWithHeartbeat].
+func startHeartbeat(ctx context.Context, fn OnEventFunc) *heartbeat {
+	now := time.Now()
+	h := &heartbeat{fn: fn, start: now, last: now, done: make(chan struct{})}
+
+	interval, _ := ctx.Value(heartbeatKey{}).(time.Duration)
+	if fn == nil || interval <= 0 {
+		return h
+	}
+
+	h.wg.Add(1)
+	go func() {
+		defer h.wg.Done()
+		h.run(interval)
+	}()
+	return h
+}
+
+// run reports [*EventHeartbeat] every interval in which no other events are reported until it's stopped.
+func (h *heartbeat) run(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-h.done:
+			return
+		case <-ticker.C:
+			h.mu.Lock()
+			now := time.Now()
+			if !h.stopped && now.Sub(h.last) >= interval {
+				h.last = now
+				elapsed := now.Sub(h.start)
+				h.fn(&EventHeartbeat{
+					Opened:  h.opened,
+					Copied:  h.copied.Load(),
+					Elapsed: elapsed,
+					Idle:    elapsed - time.Duration(h.lastWrite.Load()),
+				})
+			}
+			h.mu.Unlock()
+		}
+	}
+}
+
+// emit calls fn with the event. It stops reporting [*EventHeartbeat] after the final events.
+func (h *heartbeat) emit(e Event) {
+	if h.fn == nil {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	switch e.(type) {
+	case *EventOK, *EventStop, *EventError:
+		h.stopped = true
+	}
+
+	h.last = time.Now()
+	h.fn(e)
+}
+
+// setOpened records the task is opened and the number of bytes copied previously.
+func (h *heartbeat) setOpened(copied int64) {
+	h.copied.Store(copied)
+	h.mu.Lock()
+	h.opened = true
+	h.mu.Unlock()
+}
+
+// written records the number of bytes copied on every write.
+func (h *heartbeat) written(copied int64) {
+	h.copied.Store(copied)
+	h.lastWrite.Store(int64(time.Since(h.start)))
+}
+
+// stop stops reporting [*EventHeartbeat] and waits for it to exit.
+func (h *heartbeat) stop() {
+	h.mu.Lock()
+	h.stopped = true
+	h.mu.Unlock()
+
+	close(h.done)
+	h.wg.Wait()
+}
@@ -0,0 +1,57 @@
+package iocopy_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"github.com/northbright/iocopy"
+)
+
+// stalledReaderAt stalls on the first read like a server which does not send any bytes for a while.
+type stalledReaderAt struct {
+	r       io.ReaderAt
+	d       time.Duration
+	stalled atomic.Bool
+}
+
+func (s *stalledReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if s.stalled.CompareAndSwap(false, true) {
+		time.Sleep(s.d)
+	}
+	return s.r.ReadAt(p, off)
+}
+
+func ExampleContextWithHeartbeat() {
+	// This example hashes the bytes of a reader which stalls for 500ms before the first bytes.
+	// Heartbeats are reported every 100ms while no bytes are copied, so the stalled task is not taken as dead.
+	data := bytes.Repeat([]byte("a"), 1024*1024)
+	r := &stalledReaderAt{r: bytes.NewReader(data), d: 500 * time.Millisecond}
+
+	heartbeats := 0
+	ctx := iocopy.ContextWithHeartbeat(context.Background(), 100*time.Millisecond)
+	t := iocopy.NewReaderAtHashTask("data", r, int64(len(data)), []string{"sha256"})
+	err := iocopy.Do(ctx, t, nil, func(e iocopy.Event) {
+		switch e := e.(type) {
+		case *iocopy.EventHeartbeat:
+			heartbeats++
+			if heartbeats == 1 {
+				fmt.Printf("heartbeat: opened: %v, copied: %v, idle >= 100ms: %v\n", e.Opened, e.Copied, e.Idle >= 100*time.Millisecond)
+			}
+		case *iocopy.EventOK:
+			fmt.Printf("done, heartbeats >= 3: %v\n", heartbeats >= 3)
+		}
+	})
+	if err != nil {
+		log.Printf("iocopy.Do() error: %v", err)
+		return
+	}
+
+	// Output:
+	// heartbeat: opened: true, copied: 0, idle >= 100ms: true
+	// done, heartbeats >= 3: true
+}
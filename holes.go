@@ -0,0 +1,191 @@
+package iocopy
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+)
+
+// compactRanges switches to the sequential download
+// if the downloaded ranges have no holes except the one at the end.
+func (t *DownloadTask) compactRanges() {
+	switch {
+	case t.ranges == nil:
+	case len(t.ranges) == 0:
+		t.ranges = nil
+		t.copied = 0
+	case len(t.ranges) == 1 && t.ranges[0].Start == 0:
+		t.copied = t.ranges[0].End
+		t.ranges = nil
+	}
+}
+
+// rangeHeader returns the value of the "range" header of r.
+func rangeHeader(r Range) string {
+	return fmt.Sprintf("bytes=%d-%d", r.Start, r.End-1)
+}
+
+// hashDst computes the checksums of the destination file by the algorithms set by [WithHash].
+// It's used when the holes are filled since the bytes are not written in order.
+func (t *DownloadTask) hashDst() error {
+	return t.hashDstPrefix(-1)
+}
+
+// hashDstPrefix computes the checksums of the first n bytes of the destination file(all bytes if n < 0)
+// by the algorithms set by [WithHash].
+// It's also used when the task is loaded without the states of the hashes, e.g. from a token.
+func (t *DownloadTask) hashDstPrefix(n int64) error {
+	hs, err := newHashSet(t.opts.hashAlgs, nil, 0)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(longPath(t.dstName()))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if n >= 0 {
+		r = io.LimitReader(f, n)
+	}
+
+	if m, err := io.Copy(hs, r); err != nil {
+		return err
+	} else if n >= 0 && m != n {
+		return fmt.Errorf("size of %v is less than %v bytes downloaded", t.dstName(), n)
+	}
+
+	t.hs = hs
+	return nil
+}
+
+// rangeReader reads the missing ranges of the download in order.
+// The response of the first range is requested by [DownloadTask.Open]
+// and the other ones are requested when the previous one is read.
+type rangeReader struct {
+	ctx     context.Context
+	t       *DownloadTask
+	missing RangeSet
+	// r reads the current range from the response.
+	r *io.LimitedReader
+}
+
+// newRangeReader returns a [*rangeReader] which reads the missing ranges.
+// The response of the first one should be t.resp.
+func newRangeReader(ctx context.Context, t *DownloadTask, missing RangeSet) *rangeReader {
+	return &rangeReader{
+		ctx:     ctx,
+		t:       t,
+		missing: missing,
+		r:       &io.LimitedReader{R: t.resp.Body, N: missing[0].Size()},
+	}
+}
+
+// Read implements [io.Reader] interface.
+func (rr *rangeReader) Read(p []byte) (int, error) {
+	for {
+		if rr.r == nil {
+			if len(rr.missing) == 0 {
+				return 0, io.EOF
+			}
+
+			if err := rr.next(); err != nil {
+				return 0, err
+			}
+		}
+
+		n, err := rr.r.Read(p)
+		if err != io.EOF {
+			return n, err
+		}
+
+		if rr.r.N > 0 {
+			return n, io.ErrUnexpectedEOF
+		}
+
+		// The current range is read.
+		rr.r = nil
+		rr.missing = rr.missing[1:]
+		if n > 0 {
+			return n, nil
+		}
+	}
+}
+
+// next requests the next missing range.
+func (rr *rangeReader) next() error {
+	t := rr.t
+	t.resp.Body.Close()
+
+	req, err := t.newRequest(rr.ctx)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("range", rangeHeader(rr.missing[0]))
+
+	resp, err := t.do(req)
+	if err != nil {
+		return err
+	}
+	t.resp = resp
+
+	if resp.StatusCode != http.StatusPartialContent {
+		return fmt.Errorf("unexpected status of range %v: %v", rangeHeader(rr.missing[0]), resp.Status)
+	}
+
+	rr.r = &io.LimitedReader{R: resp.Body, N: rr.missing[0].Size()}
+	return nil
+}
+
+// rangeWriter writes the bytes of the missing ranges in order to f
+// and adds the written bytes to the downloaded ranges.
+type rangeWriter struct {
+	f       WriteFile
+	missing RangeSet
+	ranges  *RangeSet
+	// seek is true if f should seek to the start of the current range.
+	seek bool
+}
+
+// newRangeWriter returns a [*rangeWriter] which writes the missing ranges to f
+// and adds the written bytes to ranges.
+func newRangeWriter(f WriteFile, missing RangeSet, ranges *RangeSet) *rangeWriter {
+	return &rangeWriter{f: f, missing: slices.Clone(missing), ranges: ranges, seek: true}
+}
+
+// Write implements [io.Writer] interface.
+func (rw *rangeWriter) Write(p []byte) (n int, err error) {
+	for len(p) > 0 {
+		if len(rw.missing) == 0 {
+			return n, fmt.Errorf("bytes written beyond the missing ranges")
+		}
+
+		r := &rw.missing[0]
+		if rw.seek {
+			if _, err = rw.f.Seek(r.Start, io.SeekStart); err != nil {
+				return n, err
+			}
+			rw.seek = false
+		}
+
+		m, err := rw.f.Write(p[:min(int64(len(p)), r.Size())])
+		rw.ranges.Add(r.Start, r.Start+int64(m))
+		r.Start += int64(m)
+		n += m
+		p = p[m:]
+		if err != nil {
+			return n, err
+		}
+
+		if r.Size() == 0 {
+			rw.missing = rw.missing[1:]
+			rw.seek = true
+		}
+	}
+	return n, nil
+}
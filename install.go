@@ -0,0 +1,345 @@
+package iocopy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ErrChecksumMismatch is returned by [Installer] when the checksum or the size of an installed asset
+// does not match the manifest.
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
+// Asset is an asset in the [Manifest] to install.
+type Asset struct {
+	// Path is the slash-separated path of the asset relative to the source and the target directory.
+	Path string `json:"path"`
+	// Size is the size of the asset.
+	Size int64 `json:"size"`
+	// Hashes are the expected hex encoded checksums by the hash algorithms in [HashFuncs], e.g. "sha256".
+	Hashes map[string]string `json:"hashes,omitempty"`
+}
+
+// Manifest describes the assets to install by [Installer].
+type Manifest struct {
+	Assets []Asset `json:"assets"`
+}
+
+// InstallResult is the typed result of [Installer].
+type InstallResult struct {
+	// Dir is the target directory.
+	Dir string `json:"dir"`
+	// Assets is the number of the assets installed.
+	Assets int `json:"assets"`
+	// Size is the total size of the assets installed.
+	Size int64 `json:"size"`
+}
+
+// installerState is the state of [Installer].
+type installerState struct {
+	Version   int             `json:"version"`
+	Type      string          `json:"type"`
+	Dir       string          `json:"dir"`
+	BaseURL   string          `json:"base_url,omitempty"`
+	Manifest  Manifest        `json:"manifest"`
+	Installed int             `json:"installed"`
+	Current   json.RawMessage `json:"current,omitempty"`
+}
+
+// Installer installs the assets of a [Manifest] from a file system(e.g. [embed.FS]) or a base url to a directory
+// with overall progress, verification and resume.
+// The assets are installed one by one by [CopyFSTask] or [DownloadTask] and verified against their sizes and checksums.
+type Installer struct {
+	dir     string
+	m       Manifest
+	src     fs.FS
+	baseURL string
+	opts    []Option
+	// installed is the number of the assets installed and verified.
+	installed int
+	cur       Task
+}
+
+// NewInstaller returns an [*Installer] which installs the assets of m from src to dir.
+// opts: optional parameters of the tasks, e.g. [WithRateLimiter].
+func NewInstaller(dir string, m Manifest, src fs.FS, opts ...Option) *Installer {
+	return &Installer{dir: dir, m: m, src: src, opts: opts}
+}
+
+// NewURLInstaller returns an [*Installer] which downloads the assets of m from baseURL to dir.
+// The url of an asset is its path joined to baseURL.
+// opts: optional parameters of the tasks, e.g. [WithConnections], [WithRequestHook].
+func NewURLInstaller(dir string, m Manifest, baseURL string, opts ...Option) *Installer {
+	return &Installer{dir: dir, m: m, baseURL: baseURL, opts: opts}
+}
+
+// LoadInstaller loads an [*Installer] from the state reported by [*EventStop] to resume.
+// src is the source file system which can't be saved in the state. It's ignored for the ones created by [NewURLInstaller].
+// opts: optional parameters of the tasks which are not saved in the state.
+func LoadInstaller(state []byte, src fs.FS, opts ...Option) (*Installer, error) {
+	var s installerState
+	if err := json.Unmarshal(state, &s); err != nil {
+		return nil, err
+	}
+
+	if err := (stateHeader{s.Version, s.Type}).check(StateTypeInstaller); err != nil {
+		return nil, err
+	}
+
+	in := &Installer{dir: s.Dir, m: s.Manifest, src: src, baseURL: s.BaseURL, opts: opts, installed: s.Installed}
+	if len(s.Current) > 0 {
+		var err error
+		if in.baseURL != "" {
+			in.cur, err = LoadDownloadTask(s.Current, nil, in.taskOpts(in.m.Assets[in.installed])...)
+		} else {
+			in.cur, err = LoadCopyFSTask(s.Current, src, nil, opts...)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return in, nil
+}
+
+// algs returns the names of the hash algorithms of the asset in order.
+func (a Asset) algs() []string {
+	var algs []string
+	for alg := range a.Hashes {
+		algs = append(algs, alg)
+	}
+	slices.Sort(algs)
+	return algs
+}
+
+// taskOpts returns the options of the task to install the asset.
+func (in *Installer) taskOpts(a Asset) []Option {
+	opts := slices.Clone(in.opts)
+	if in.baseURL != "" && len(a.Hashes) > 0 {
+		// Hash the downloaded bytes to avoid reading the file again.
+		opts = append(opts, WithHash(a.algs()...))
+	}
+	return opts
+}
+
+// dstPath returns the path of the installed asset.
+func (in *Installer) dstPath(a Asset) (string, error) {
+	local, err := filepath.Localize(a.Path)
+	if err != nil {
+		return "", fmt.Errorf("invalid asset path %q: %w", a.Path, err)
+	}
+	return filepath.Join(in.dir, local), nil
+}
+
+// newTask returns the task to install the asset.
+func (in *Installer) newTask(a Asset) (Task, error) {
+	dst, err := in.dstPath(a)
+	if err != nil {
+		return nil, err
+	}
+
+	if in.baseURL != "" {
+		u, err := url.JoinPath(in.baseURL, a.Path)
+		if err != nil {
+			return nil, err
+		}
+		return NewDownloadTask(dst, u, nil, in.taskOpts(a)...), nil
+	}
+
+	fi, err := fs.Stat(in.src, a.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	// Copy the asset only.
+	t := NewCopyFSTask(in.dir, in.src, nil, in.opts...)
+	t.dirs = []string{path.Dir(a.Path)}
+	t.files = []CopyFSFile{{Name: a.Path, Size: a.Size, Mode: fi.Mode().Perm()}}
+	t.total = a.Size
+	return t, nil
+}
+
+// verify verifies the size and checksums of the installed asset.
+// It returns the first expected and actual checksums which don't match(or the first ones if all match).
+func (in *Installer) verify(a Asset) (expected, actual string, err error) {
+	dst, err := in.dstPath(a)
+	if err != nil {
+		return "", "", err
+	}
+
+	fi, err := os.Stat(longPath(dst))
+	if err != nil {
+		return "", "", err
+	}
+
+	if fi.Size() != a.Size {
+		return "", "", fmt.Errorf("%w: size of %v: %v, expected: %v", ErrChecksumMismatch, a.Path, fi.Size(), a.Size)
+	}
+
+	if len(a.Hashes) == 0 {
+		return "", "", nil
+	}
+
+	algs := a.algs()
+	var checksums map[string]string
+	if t, ok := in.cur.(*DownloadTask); ok && t.hs != nil {
+		checksums, _ = t.hs.checksums()
+	} else {
+		hs, err := newHashSet(algs, nil, 0)
+		if err != nil {
+			return "", "", err
+		}
+
+		f, err := os.Open(longPath(dst))
+		if err != nil {
+			return "", "", err
+		}
+		defer f.Close()
+
+		if _, err = io.Copy(hs, f); err != nil {
+			return "", "", err
+		}
+		checksums, _ = hs.checksums()
+	}
+
+	expected, actual = a.Hashes[algs[0]], checksums[algs[0]]
+	for _, alg := range algs {
+		if !strings.EqualFold(a.Hashes[alg], checksums[alg]) {
+			return a.Hashes[alg], checksums[alg], fmt.Errorf("%w: %v of %v", ErrChecksumMismatch, alg, a.Path)
+		}
+	}
+	return expected, actual, nil
+}
+
+// Run installs the assets which are not installed yet in order and reports the events by fn.
+// It reports [*EventWritten] with the overall progress of all assets and the progress of the current asset by File,
+// [*EventFileVerified] for each asset after it's installed and verified,
+// and [*EventOK] with the [InstallResult] when all assets are installed.
+// If it's stopped by ctx, it reports [*EventStop] with the state and returns ctx.Err().
+// If an asset does not match the manifest, it returns an error wrapping [ErrChecksumMismatch]
+// and the asset is installed again when it's run again.
+func (in *Installer) Run(ctx context.Context, buf []byte, fn OnEventFunc) (err error) {
+	emit := func(e Event) {
+		if fn != nil {
+			fn(e)
+		}
+	}
+
+	defer func() {
+		if err != nil && !isStopped(err) {
+			emit(&EventError{Err: err})
+		}
+	}()
+
+	var total, done int64
+	for i, a := range in.m.Assets {
+		total += a.Size
+		if i < in.installed {
+			done += a.Size
+		}
+	}
+
+	prev := done
+	if in.cur != nil {
+		prev += in.cur.Copied()
+	}
+	start := time.Now()
+	last, lastCopied := start, prev
+
+	for in.installed < len(in.m.Assets) {
+		a := in.m.Assets[in.installed]
+		if in.cur == nil {
+			if in.cur, err = in.newTask(a); err != nil {
+				return err
+			}
+		}
+
+		installErr := Do(ctx, in.cur, buf, func(e Event) {
+			if _, ok := e.(*EventWritten); !ok {
+				return
+			}
+
+			now := time.Now()
+			copied := done + in.cur.Copied()
+			w := &EventWritten{Total: total, Copied: copied, Percent: computePercent(total, 0, copied), Elapsed: now.Sub(start)}
+			w.File = &FileProgress{Index: in.installed, Count: len(in.m.Assets), Done: in.installed, Name: a.Path, Total: a.Size, Copied: in.cur.Copied(), Percent: computePercent(a.Size, 0, in.cur.Copied())}
+			if d := now.Sub(last).Seconds(); d > 0 {
+				w.Speed = float64(copied-lastCopied) / d
+			}
+			if d := now.Sub(start).Seconds(); d > 0 {
+				w.AvgSpeed = float64(copied-prev) / d
+			}
+			last, lastCopied = now, copied
+
+			emit(w)
+		})
+
+		if isStopped(installErr) {
+			state, err := in.State()
+			if err != nil {
+				return err
+			}
+
+			if state, err = stopState(ctx, state); err != nil {
+				return err
+			}
+			emit(&EventStop{Err: installErr, State: state})
+			return installErr
+		}
+
+		if installErr != nil {
+			return fmt.Errorf("install %v: %w", a.Path, installErr)
+		}
+
+		expected, actual, verifyErr := in.verify(a)
+		emit(&EventFileVerified{File: a.Path, OK: verifyErr == nil, Expected: expected, Actual: actual, Err: verifyErr})
+		// Install the asset again if it fails.
+		in.cur = nil
+		if verifyErr != nil {
+			return verifyErr
+		}
+
+		in.installed++
+		done += a.Size
+	}
+
+	r := in.ResultValue()
+	result, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	emit(&EventOK{Result: redactJSON(ctx, result), Value: r, Duration: time.Since(start)})
+	return nil
+}
+
+// State returns the marshaled state which contains the manifest, the number of the assets installed
+// and the state of the asset being installed.
+func (in *Installer) State() ([]byte, error) {
+	s := installerState{Version: StateVersion, Type: StateTypeInstaller, Dir: in.dir, BaseURL: in.baseURL, Manifest: in.m, Installed: in.installed}
+	if in.cur != nil {
+		state, err := in.cur.State()
+		if err != nil {
+			return nil, err
+		}
+		s.Current = state
+	}
+	return json.Marshal(s)
+}
+
+// ResultValue returns the [InstallResult] of the assets installed.
+func (in *Installer) ResultValue() any {
+	r := InstallResult{Dir: in.dir, Assets: in.installed}
+	for _, a := range in.m.Assets[:in.installed] {
+		r.Size += a.Size
+	}
+	return r
+}
@@ -0,0 +1,126 @@
+package iocopy_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing/fstest"
+
+	"github.com/northbright/iocopy"
+)
+
+// sha256Hex returns the hex encoded SHA-256 checksum of b.
+func sha256Hex(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+func ExampleInstaller() {
+	// This example installs the assets of an app from an embedded file system(emulated by fstest.MapFS).
+	// The installation is stopped and resumed.
+	src := fstest.MapFS{
+		"bin/app":          {Data: bytes.Repeat([]byte("app"), 128*1024), Mode: 0755},
+		"data/model.bin":   {Data: bytes.Repeat([]byte("0123456789abcdef"), 64*1024)},
+		"data/config.json": {Data: []byte(`{"debug":false}`)},
+	}
+
+	var m iocopy.Manifest
+	for _, name := range []string{"bin/app", "data/model.bin", "data/config.json"} {
+		data := src[name].Data
+		m.Assets = append(m.Assets, iocopy.Asset{Path: name, Size: int64(len(data)), Hashes: map[string]string{"sha256": sha256Hex(data)}})
+	}
+
+	dir, err := os.MkdirTemp("", "iocopy")
+	if err != nil {
+		log.Printf("os.MkdirTemp() error: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var state []byte
+	in := iocopy.NewInstaller(dir, m, src)
+	in.Run(ctx, make([]byte, 32*1024), func(e iocopy.Event) {
+		switch e := e.(type) {
+		case *iocopy.EventWritten:
+			// Emulate user cancelation when more than half of all assets are installed.
+			if e.Percent > 50 {
+				cancel()
+			}
+		case *iocopy.EventFileVerified:
+			fmt.Printf("verified %v: %v\n", e.File, e.OK)
+		case *iocopy.EventStop:
+			state = e.State
+		}
+	})
+
+	// The source file system is passed again to resume.
+	if in, err = iocopy.LoadInstaller(state, src); err != nil {
+		log.Printf("iocopy.LoadInstaller() error: %v", err)
+		return
+	}
+
+	fmt.Println("resume")
+	in.Run(context.Background(), nil, func(e iocopy.Event) {
+		switch e := e.(type) {
+		case *iocopy.EventFileVerified:
+			fmt.Printf("verified %v: %v\n", e.File, e.OK)
+		case *iocopy.EventOK:
+			r := e.Value.(iocopy.InstallResult)
+			fmt.Printf("assets: %v, size: %v\n", r.Assets, r.Size)
+		case *iocopy.EventError:
+			log.Printf("Run() error: %v", e.Err)
+		}
+	})
+
+	// Output:
+	// verified bin/app: true
+	// resume
+	// verified data/model.bin: true
+	// verified data/config.json: true
+	// assets: 3, size: 1441807
+}
+
+func ExampleNewURLInstaller() {
+	// This example downloads the assets from a base url.
+	// The checksum of an asset on the server doesn't match the manifest.
+	ts := httptest.NewServer(http.StripPrefix("/assets/", http.FileServerFS(fstest.MapFS{
+		"a.txt": {Data: []byte("hello")},
+		"b.txt": {Data: []byte("world")},
+	})))
+	defer ts.Close()
+
+	m := iocopy.Manifest{Assets: []iocopy.Asset{
+		{Path: "a.txt", Size: 5, Hashes: map[string]string{"sha256": sha256Hex([]byte("hello"))}},
+		{Path: "b.txt", Size: 5, Hashes: map[string]string{"sha256": sha256Hex([]byte("WORLD"))}},
+	}}
+
+	dir, err := os.MkdirTemp("", "iocopy")
+	if err != nil {
+		log.Printf("os.MkdirTemp() error: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	in := iocopy.NewURLInstaller(dir, m, ts.URL+"/assets/")
+	err = in.Run(context.Background(), nil, func(e iocopy.Event) {
+		if e, ok := e.(*iocopy.EventFileVerified); ok {
+			fmt.Printf("verified %v: %v\n", e.File, e.OK)
+		}
+	})
+	fmt.Printf("checksum mismatch: %v\n", errors.Is(err, iocopy.ErrChecksumMismatch))
+
+	// Output:
+	// verified a.txt: true
+	// verified b.txt: false
+	// checksum mismatch: true
+}
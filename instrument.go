@@ -0,0 +1,74 @@
+package iocopy
+
+import "context"
+
+// Endpointer is implemented by tasks which have a source and a destination,
+// e.g. [*CopyFileTask] and [*DownloadTask]. They're used as the attributes of [TaskSpan].
+type Endpointer interface {
+	// Endpoints returns the source(file or url) and the destination. An empty string means it's not available.
+	Endpoints() (src, dst string)
+}
+
+// TaskInfo is the information of a task passed to [Instrumenter] when [Do] starts to run it.
+type TaskInfo struct {
+	// ID is the deterministic ID of the task if it implements [Identifier].
+	// Spans of resume attempts of the same task can be linked by it.
+	ID string
+	// Src and Dst are the source and the destination if the task implements [Endpointer].
+	Src string
+	Dst string
+	// Prev is the number of bytes copied previously. It's greater than 0 if the task is resumed.
+	Prev int64
+}
+
+// TaskSpan is the span of running a task started by [Instrumenter].
+type TaskSpan interface {
+	// End is called when [Do] returns.
+	// total is the total number of bytes to copy(a negative value indicates it's unknown),
+	// written is the number of bytes written in this attempt and err is the error returned by Do.
+	End(total, written int64, err error)
+}
+
+// Instrumenter instruments the tasks run by [Do], e.g. by OpenTelemetry spans and metrics.
+// It's attached to the context by [ContextWithInstrumenter] so iocopy does not depend on any telemetry library.
+//
+// An OpenTelemetry implementation may start a span with the src and dst attributes in StartTask,
+// link it to the span of the previous attempt of the same ID if Prev > 0,
+// and record the size and the bytes written as metrics in End.
+type Instrumenter interface {
+	// StartTask is called when Do starts to run the task.
+	// The returned context is used to run the task, so the spans of HTTP requests can be the children of the task span.
+	StartTask(ctx context.Context, info TaskInfo) (context.Context, TaskSpan)
+}
+
+// instrumenterKey is the context key of [Instrumenter].
+type instrumenterKey struct{}
+
+// ContextWithInstrumenter returns a copy of ctx with ins attached.
+// [Do] instruments the tasks run with the returned context by ins.
+func ContextWithInstrumenter(ctx context.Context, ins Instrumenter) context.Context {
+	return context.WithValue(ctx, instrumenterKey{}, ins)
+}
+
+// noopSpan is the [TaskSpan] used when no [Instrumenter] is attached to the context.
+type noopSpan struct{}
+
+// End implements [TaskSpan] interface.
+func (noopSpan) End(total, written int64, err error) {}
+
+// startTask starts the span of t by the [Instrumenter] attached to ctx.
+func startTask(ctx context.Context, t Task) (context.Context, TaskSpan) {
+	ins, ok := ctx.Value(instrumenterKey{}).(Instrumenter)
+	if !ok || ins == nil {
+		return ctx, noopSpan{}
+	}
+
+	info := TaskInfo{Prev: t.Copied()}
+	if id, ok := t.(Identifier); ok {
+		info.ID = id.ID()
+	}
+	if e, ok := t.(Endpointer); ok {
+		info.Src, info.Dst = e.Endpoints()
+	}
+	return ins.StartTask(ctx, info)
+}
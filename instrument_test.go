@@ -0,0 +1,55 @@
+package iocopy_test
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/northbright/iocopy"
+)
+
+// printInstrumenter prints the spans of the tasks instead of exporting them.
+type printInstrumenter struct{}
+
+type printSpan struct {
+	info iocopy.TaskInfo
+}
+
+func (printInstrumenter) StartTask(ctx context.Context, info iocopy.TaskInfo) (context.Context, iocopy.TaskSpan) {
+	fmt.Printf("start: src: %v, dst: %v, resumed: %v\n", filepath.Base(info.Src), filepath.Base(info.Dst), info.Prev > 0)
+	return ctx, &printSpan{info: info}
+}
+
+func (s *printSpan) End(total, written int64, err error) {
+	fmt.Printf("end: size: %v, written: %v, err: %v\n", total, written, err)
+}
+
+func ExampleInstrumenter() {
+	// This example instruments a copy task by an Instrumenter attached to the context.
+	// An OpenTelemetry implementation starts spans and records metrics instead of printing them.
+	dir, err := os.MkdirTemp("", "iocopy")
+	if err != nil {
+		log.Printf("os.MkdirTemp() error: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	src := filepath.Join(dir, "src")
+	if err = os.WriteFile(src, []byte("Hello, World!"), 0644); err != nil {
+		log.Printf("os.WriteFile() error: %v", err)
+		return
+	}
+
+	ctx := iocopy.ContextWithInstrumenter(context.Background(), printInstrumenter{})
+	t := iocopy.NewCopyFileTask(filepath.Join(dir, "dst"), src, nil)
+	if err = iocopy.Do(ctx, t, nil, nil); err != nil {
+		log.Printf("iocopy.Do() error: %v", err)
+		return
+	}
+
+	// Output:
+	// start: src: src, dst: dst, resumed: false
+	// end: size: 13, written: 13, err: <nil>
+}
@@ -3,6 +3,8 @@ package iocopy
 import (
 	"context"
 	"io"
+	"math"
+	"time"
 )
 
 // readFunc is used to implement [io.Reader] interface and capture the [context.Context] parameter.
@@ -21,17 +23,44 @@ func (wf writeFunc) Write(p []byte) (n int, err error) {
 	return wf(p)
 }
 
+// ProgressInfo is the progress of IO copy reported by [OnWrittenFunc].
+// New fields may be added without breaking the callbacks.
+type ProgressInfo struct {
+	// Total is the total number of bytes to copy.
+	// A negative value indicates total size is unknown and Percent should be ignored(always 0).
+	Total int64
+	// Prev is the number of bytes copied previously.
+	Prev int64
+	// Current is the number of bytes copied in current copy.
+	Current int64
+	// Percent is the percent copied. It's always between 0 and 100.
+	Percent float32
+	// Indeterminate is true if total size is unknown.
+	// Percent is 0 and should not be shown. Show the number of bytes copied and Elapsed(e.g. a spinner) instead.
+	Indeterminate bool
+	// Elapsed is the time since the copy started(or resumed).
+	Elapsed time.Duration
+	// ReadTime and WriteTime are the time blocked in reading the source and writing the destination
+	// since the copy started(or resumed). They're 0 if the copy is done by splice(2).
+	ReadTime  time.Duration
+	WriteTime time.Duration
+}
+
+// Copied returns the number of bytes copied including the ones copied previously.
+func (p ProgressInfo) Copied() int64 {
+	return p.Prev + p.Current
+}
+
 // OnWrittenFunc is the callback function when bytes are copied successfully.
-// total: total number of bytes to copy.
-// A negative value indicates total size is unknown and percent should be ignored(always 0).
-// prev: number of bytes copied previously.
-// current: number of bytes copied in current copy.
-// percent: percent copied.
-type OnWrittenFunc func(total, prev, current int64, percent float32)
+// It's called when the percent changes.
+// If total size is unknown, it's called on every write.
+// It's always called once with the final counters when the copy succeeds, even if nothing is copied.
+type OnWrittenFunc func(p ProgressInfo)
 
 // computePercent returns the percentage.
 // total: total number of the bytes to copy.
 // A negative value indicates total size is unknown and it returns 0 as percent.
+// It never returns a value out of [0, 100], e.g. more bytes than total are copied.
 // prev: the number of the bytes copied previously.
 // current: the number of bytes written currently.
 func computePercent(total, prev, current int64) float32 {
@@ -47,11 +76,131 @@ func computePercent(total, prev, current int64) float32 {
 		return 0
 	}
 
-	if prev+current == total {
+	if prev+current >= total {
 		return 100
 	}
 
-	return float32(float64(prev+current) / (float64(total) / float64(100)))
+	percent := float32(float64(prev+current) / (float64(total) / float64(100)))
+	if percent >= 100 {
+		// Don't report 100% before all bytes are copied due to rounding.
+		return math.Nextafter32(100, 0)
+	}
+	return percent
+}
+
+// TotalUpdater is implemented by sources whose total size becomes known or changes after the copy starts,
+// e.g. chunked HTTP responses which reveal the size later or multi-file sources discovering entries lazily.
+// [CopyBufferWithProgress] calls Total after each write and reports the progress with the updated total.
+type TotalUpdater interface {
+	// Total returns the current total number of bytes to copy.
+	// A negative value indicates total size is unknown.
+	Total() int64
+}
+
+// progress reports the progress of IO copy by the callback.
+type progress struct {
+	total      int64
+	prev       int64
+	current    int64
+	oldPercent float32
+	fn         OnWrittenFunc
+	// totalFn returns the updated total if it's not nil.
+	totalFn func() int64
+	// start is the time when the copy starts.
+	start time.Time
+	// reported is true if the callback is called with the current counters.
+	reported bool
+	// readTime and writeTime are the time blocked in reading and writing.
+	readTime  time.Duration
+	writeTime time.Duration
+	// reads and writes are the numbers of Read and Write calls.
+	reads, writes int64
+	// bytesRead is the number of bytes read.
+	bytesRead int64
+	// onWrite is called with the number of bytes copied including prev on every write if it's not nil.
+	onWrite func(copied int64)
+}
+
+// stats returns the IO statistics. The bytes written are the ones reported.
+func (pr *progress) stats() IOStats {
+	return IOStats{
+		Reads:        pr.reads,
+		Writes:       pr.writes,
+		BytesRead:    pr.bytesRead,
+		BytesWritten: pr.current,
+		ReadTime:     pr.readTime,
+		WriteTime:    pr.writeTime,
+	}
+}
+
+// written updates the number of bytes copied and calls the callback when the percent changes.
+// The bytes are counted even if there's no callback, so the statistics are right.
+func (pr *progress) written(n int64) {
+	pr.current += n
+	if pr.onWrite != nil {
+		pr.onWrite(pr.prev + pr.current)
+	}
+
+	if pr.fn == nil {
+		return
+	}
+
+	pr.reported = false
+
+	if pr.totalFn != nil {
+		if total := pr.totalFn(); total != pr.total {
+			// Report the updated total.
+			pr.total = total
+			pr.oldPercent = -1
+		}
+	}
+
+	// Percent is always 0 if total size is unknown.
+	// Report on every write to make spinner-style progress possible.
+	if pr.total < 0 || computePercent(pr.total, pr.prev, pr.current) != pr.oldPercent {
+		pr.report()
+	}
+}
+
+// info returns the progress of the current counters.
+func (pr *progress) info() ProgressInfo {
+	p := ProgressInfo{Total: pr.total, Prev: pr.prev, Current: pr.current, Elapsed: time.Since(pr.start), ReadTime: pr.readTime, WriteTime: pr.writeTime}
+	if pr.total < 0 {
+		p.Indeterminate = true
+	} else {
+		p.Percent = computePercent(pr.total, pr.prev, pr.current)
+	}
+	return p
+}
+
+// report calls the callback with the current counters.
+func (pr *progress) report() {
+	p := pr.info()
+	if !p.Indeterminate {
+		pr.oldPercent = p.Percent
+	}
+
+	pr.reported = true
+	pr.fn(p)
+}
+
+// finish calls the callback with the final counters if they're not reported yet,
+// e.g. nothing is copied or the copy finishes before the percent changes.
+func (pr *progress) finish() {
+	if pr.fn == nil {
+		return
+	}
+
+	if pr.totalFn != nil {
+		if total := pr.totalFn(); total != pr.total {
+			pr.total = total
+			pr.reported = false
+		}
+	}
+
+	if !pr.reported {
+		pr.report()
+	}
 }
 
 // CopyBufferWithProgress wraps [io.CopyBuffer]. It accepts [context.Context] to make IO copy cancalable.
@@ -64,6 +213,9 @@ func computePercent(total, prev, current int64) float32 {
 // 3. Check if err == context.Canceled || err == context.DeadlineExceeded.
 // 4. Set prev to the "written" return value of previous CopyBufferWithProgress when make next call to resume the IO copy.
 // fn: callback on bytes written.
+// If src implements [TotalUpdater], the progress is reported with the updated total.
+// If ctx is returned by [ContextWithOnFinish], the callback is called once with the final counters when the copy ends.
+// On Linux, it uses splice(2) to avoid copying through user space if src or dst is a pipe or socket.
 func CopyBufferWithProgress(
 	ctx context.Context,
 	dst io.Writer,
@@ -73,30 +225,70 @@ func CopyBufferWithProgress(
 	prev int64,
 	fn OnWrittenFunc) (written int64, err error) {
 
-	var (
-		current    int64
-		percent    float32
-		oldPercent float32
-	)
+	pr := &progress{total: total, prev: prev, fn: fn, start: time.Now()}
+	if u, ok := src.(TotalUpdater); ok {
+		pr.totalFn = u.Total
+	}
+
+	written, err = copyBuffer(ctx, dst, src, buf, pr)
+	finishProgress(ctx, pr, err)
+	return written, err
+}
+
+// copyBuffer copies from src to dst and reports the progress by pr.
+// It always reports the final counters once when the copy succeeds.
+func copyBuffer(ctx context.Context, dst io.Writer, src io.Reader, buf []byte, pr *progress) (written int64, err error) {
+	end := statsStart()
+	defer func() { end(written, err) }()
+
+	written, err = copyBufferOnce(ctx, dst, src, buf, pr)
+	if err == nil {
+		pr.finish()
+	}
+	return written, err
+}
+
+// copyBufferOnce copies from src to dst and reports the progress by pr when the percent changes.
+func copyBufferOnce(ctx context.Context, dst io.Writer, src io.Reader, buf []byte, pr *progress) (written int64, err error) {
+	// Use an aligned buffer for direct IO to avoid extra copies.
+	if a, ok := src.(aligner); ok && !isAligned(buf, a.alignment()) {
+		buf = AlignedBuffer(max(len(buf), directBufSize))
+	}
+
+	// Keep writing the bytes read in the grace period after ctx is done if it's set.
+	wctx, stop := graceContext(ctx)
+	defer stop()
+
+	// Abandon the reads and writes which hang if the deadline is set.
+	if cd, ok := chunkDeadlineOf(ctx); ok {
+		src = &deadlineReader{ctx: ctx, r: src, d: cd.d}
+		dst = &deadlineWriter{ctx: wctx, w: dst, d: cd.d}
+	}
+
+	// Use splice(2) on Linux if src or dst is a pipe or socket.
+	if n, handled, err := spliceCopy(ctx, dst, src, pr); handled {
+		return n, err
+	}
+
+	// Keep the fast paths of io.ReaderFrom and io.WriterTo if they're requested.
+	if n, handled, err := fastCopy(ctx, dst, src, pr); handled {
+		return n, err
+	}
 
 	writeFn := writeFunc(func(p []byte) (n int, err error) {
 		select {
-		case <-ctx.Done():
+		case <-wctx.Done():
 			return 0, ctx.Err()
 		default:
+			start := time.Now()
 			n, err = dst.Write(p)
+			pr.writeTime += time.Since(start)
+			pr.writes++
 			if err != nil {
 				return n, err
 			}
 
-			if fn != nil {
-				current += int64(n)
-				percent = computePercent(total, prev, current)
-				if percent != oldPercent {
-					fn(total, prev, current, percent)
-					oldPercent = percent
-				}
-			}
+			pr.written(int64(n))
 			return n, nil
 		}
 	})
@@ -106,23 +298,31 @@ func CopyBufferWithProgress(
 		case <-ctx.Done():
 			return 0, ctx.Err()
 		default:
-			return src.Read(p)
+			start := time.Now()
+			n, err = src.Read(p)
+			pr.readTime += time.Since(start)
+			pr.reads++
+			pr.bytesRead += int64(n)
+			return n, err
 		}
 	})
 
-	if fn != nil {
+	if pr.fn != nil {
 		if buf != nil && len(buf) > 0 {
 			return io.CopyBuffer(writeFn, readFn, buf)
 		} else {
 			return io.Copy(writeFn, readFn)
 		}
+	}
+
+	// Write dst directly to keep its fast paths(e.g. io.ReaderFrom) and count the bytes written after the copy.
+	if buf != nil && len(buf) > 0 {
+		written, err = io.CopyBuffer(dst, readFn, buf)
 	} else {
-		if buf != nil && len(buf) > 0 {
-			return io.CopyBuffer(dst, readFn, buf)
-		} else {
-			return io.Copy(dst, readFn)
-		}
+		written, err = io.Copy(dst, readFn)
 	}
+	pr.written(written)
+	return written, err
 }
 
 // Copy wraps [io.Copy]. It accepts [context.Context] to make IO copy cancalable.
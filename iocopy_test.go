@@ -1,6 +1,7 @@
 package iocopy_test
 
 import (
+	"bytes"
 	"context"
 	"crypto/sha256"
 	"fmt"
@@ -237,8 +238,8 @@ func ExampleCopyWithProgress() {
 		resp.Body,
 		total,
 		0,
-		func(total, prev, current int64, percent float32) {
-			log.Printf("%v/%v(%.2f%%) bytes copied", prev+current, total, percent)
+		func(p iocopy.ProgressInfo) {
+			log.Printf("%v/%v(%.2f%%) bytes copied", p.Copied(), p.Total, p.Percent)
 		})
 	if err != nil {
 		if err != context.Canceled && err != context.DeadlineExceeded {
@@ -283,8 +284,8 @@ func ExampleCopyWithProgress() {
 		resp2.Body,
 		total,
 		n,
-		func(total, prev, current int64, percent float32) {
-			log.Printf("%v/%v(%.2f%%) bytes copied", prev+current, total, percent)
+		func(p iocopy.ProgressInfo) {
+			log.Printf("%v/%v(%.2f%%) bytes copied", p.Copied(), p.Total, p.Percent)
 		},
 	)
 	if err != nil {
@@ -359,8 +360,8 @@ func ExampleCopyBufferWithProgress() {
 		buf,
 		total,
 		0,
-		func(total, prev, current int64, percent float32) {
-			log.Printf("%v/%v(%.2f%%) bytes copied", prev+current, total, percent)
+		func(p iocopy.ProgressInfo) {
+			log.Printf("%v/%v(%.2f%%) bytes copied", p.Copied(), p.Total, p.Percent)
 		})
 	if err != nil {
 		if err != context.Canceled && err != context.DeadlineExceeded {
@@ -406,8 +407,8 @@ func ExampleCopyBufferWithProgress() {
 		buf,
 		total,
 		n,
-		func(total, prev, current int64, percent float32) {
-			log.Printf("%v/%v(%.2f%%) bytes copied", prev+current, total, percent)
+		func(p iocopy.ProgressInfo) {
+			log.Printf("%v/%v(%.2f%%) bytes copied", p.Copied(), p.Total, p.Percent)
 		},
 	)
 	if err != nil {
@@ -431,3 +432,90 @@ func ExampleCopyBufferWithProgress() {
 	// SHA-256:
 	// dd9e772686ed908bcff94b6144322d4e2473a7dcd7c696b7e8b6d12f23c887fd
 }
+
+// sizeRevealingReader reveals the total size after half of the bytes are read,
+// like a chunked HTTP response which reveals the size later.
+type sizeRevealingReader struct {
+	r *bytes.Reader
+}
+
+func (sr *sizeRevealingReader) Read(p []byte) (int, error) {
+	return sr.r.Read(p)
+}
+
+func (sr *sizeRevealingReader) Total() int64 {
+	if sr.r.Len() > int(sr.r.Size()/2) {
+		return -1
+	}
+	return sr.r.Size()
+}
+
+func ExampleTotalUpdater() {
+	// This example copies from a source whose total size becomes known after the copy starts.
+	src := &sizeRevealingReader{r: bytes.NewReader(bytes.Repeat([]byte("0123456789abcdef"), 256))}
+
+	var dst bytes.Buffer
+	iocopy.CopyBufferWithProgress(
+		context.Background(),
+		&dst,
+		src,
+		make([]byte, 1024),
+		-1,
+		0,
+		func(p iocopy.ProgressInfo) {
+			fmt.Printf("%v/%v bytes copied(%.2f%%)\n", p.Copied(), p.Total, p.Percent)
+		},
+	)
+
+	// Output:
+	// 1024/-1 bytes copied(0.00%)
+	// 2048/4096 bytes copied(50.00%)
+	// 3072/4096 bytes copied(75.00%)
+	// 4096/4096 bytes copied(100.00%)
+}
+
+func ExampleProgressInfo() {
+	// This example copies from a source whose total size is unknown.
+	// The progress is indeterminate, so it shows bytes copied and elapsed time like a spinner instead of the percent.
+	src := bytes.NewReader(bytes.Repeat([]byte("0123456789abcdef"), 192))
+
+	var dst bytes.Buffer
+	iocopy.CopyBufferWithProgress(
+		context.Background(),
+		&dst,
+		src,
+		make([]byte, 1024),
+		-1,
+		0,
+		func(p iocopy.ProgressInfo) {
+			if p.Indeterminate {
+				fmt.Printf("%v bytes copied, elapsed >= 0: %v\n", p.Copied(), p.Elapsed >= 0)
+			}
+		},
+	)
+
+	// Output:
+	// 1024 bytes copied, elapsed >= 0: true
+	// 2048 bytes copied, elapsed >= 0: true
+	// 3072 bytes copied, elapsed >= 0: true
+}
+
+func ExampleOnWrittenFunc() {
+	// This example copies an empty source.
+	// The callback is still called once with the final counters, so a progress bar always completes.
+	var dst bytes.Buffer
+	iocopy.CopyBufferWithProgress(
+		context.Background(),
+		&dst,
+		bytes.NewReader(nil),
+		nil,
+		0,
+		0,
+		func(p iocopy.ProgressInfo) {
+			fmt.Printf("%v/%v bytes copied(%.2f%%)\n", p.Copied(), p.Total, p.Percent)
+		},
+	)
+
+	// Output:
+	// 0/0 bytes copied(100.00%)
+}
@@ -0,0 +1,77 @@
+package iocopy
+
+import (
+	"archive/zip"
+	"fmt"
+	"io/fs"
+)
+
+// ExtractLimits are the limits of extracting untrusted archives, e.g. by [CopyFSTask] from a [zip.Reader] or [ExtractTarTask].
+// Zero values mean no limits.
+type ExtractLimits struct {
+	// MaxTotal is the max total size of the files.
+	MaxTotal int64
+	// MaxEntrySize is the max size of a file.
+	MaxEntrySize int64
+	// MaxEntries is the max number of the files and directories.
+	MaxEntries int
+	// MaxRatio is the max compression ratio(uncompressed size / compressed size) of a file to detect zip bombs.
+	// It's only checked for the sources which report the compressed sizes, e.g. [zip.Reader].
+	MaxRatio float64
+}
+
+// ExtractLimitError is returned by the tasks when the limits set by [WithExtractLimits] are exceeded.
+type ExtractLimitError struct {
+	// Limit is the limit exceeded: "total", "entry size", "entries" or "ratio".
+	Limit string
+	// Entry is the name of the entry which exceeds the limit. It's empty for "total" and "entries".
+	Entry string
+	// Value is the value which exceeds the limit.
+	Value float64
+	// Max is the limit.
+	Max float64
+}
+
+// Error implements error interface.
+func (e *ExtractLimitError) Error() string {
+	if e.Entry != "" {
+		return fmt.Sprintf("exceeded extract limit of %v: %v of %v > %v", e.Limit, e.Value, e.Entry, e.Max)
+	}
+	return fmt.Sprintf("exceeded extract limit of %v: %v > %v", e.Limit, e.Value, e.Max)
+}
+
+// check checks the files and directories to extract from src against the limits.
+// The sizes are the ones scanned which are enforced while extracting.
+func (l ExtractLimits) check(src fs.FS, dirs []string, files []CopyFSFile) error {
+	if l.MaxEntries > 0 && len(dirs)+len(files) > l.MaxEntries {
+		return &ExtractLimitError{Limit: "entries", Value: float64(len(dirs) + len(files)), Max: float64(l.MaxEntries)}
+	}
+
+	var total int64
+	for _, f := range files {
+		total += f.Size
+		if l.MaxEntrySize > 0 && f.Size > l.MaxEntrySize {
+			return &ExtractLimitError{Limit: "entry size", Entry: f.Name, Value: float64(f.Size), Max: float64(l.MaxEntrySize)}
+		}
+
+		if l.MaxRatio > 0 {
+			fi, err := fs.Stat(src, f.Name)
+			if err != nil {
+				return err
+			}
+
+			if h, ok := fi.Sys().(*zip.FileHeader); ok && h.UncompressedSize64 > 0 {
+				// An empty compressed entry with uncompressed bytes is malformed.
+				ratio := float64(h.UncompressedSize64) / float64(max(h.CompressedSize64, 1))
+				if ratio > l.MaxRatio {
+					return &ExtractLimitError{Limit: "ratio", Entry: f.Name, Value: ratio, Max: l.MaxRatio}
+				}
+			}
+		}
+	}
+
+	if l.MaxTotal > 0 && total > l.MaxTotal {
+		return &ExtractLimitError{Limit: "total", Value: float64(total), Max: float64(l.MaxTotal)}
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package iocopy_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/northbright/iocopy"
+)
+
+func ExampleWithExtractLimits() {
+	// This example extracts an untrusted zip archive with limits.
+	// The archive contains a highly compressed file like a zip bomb.
+	buf := &bytes.Buffer{}
+	zw := zip.NewWriter(buf)
+	for name, data := range map[string][]byte{
+		"readme.txt": []byte("hello"),
+		"zeros.bin":  make([]byte, 8*1024*1024),
+	} {
+		w, _ := zw.Create(name)
+		w.Write(data)
+	}
+	zw.Close()
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		log.Printf("zip.NewReader() error: %v", err)
+		return
+	}
+
+	dir, err := os.MkdirTemp("", "iocopy")
+	if err != nil {
+		log.Printf("os.MkdirTemp() error: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	for _, l := range []iocopy.ExtractLimits{
+		{MaxEntries: 2},
+		{MaxEntrySize: 1024 * 1024},
+		{MaxRatio: 100},
+		{MaxTotal: 16 * 1024 * 1024, MaxRatio: 10000},
+	} {
+		t := iocopy.NewCopyFSTask(dir, zr, nil, iocopy.WithExtractLimits(l))
+		err = iocopy.Do(context.Background(), t, nil, nil)
+
+		var limitErr *iocopy.ExtractLimitError
+		if errors.As(err, &limitErr) {
+			fmt.Printf("exceeded: %v, entry: %q\n", limitErr.Limit, limitErr.Entry)
+		} else {
+			fmt.Printf("extracted: %v, err: %v\n", t.Copied(), err)
+		}
+	}
+
+	// Output:
+	// exceeded: entries, entry: ""
+	// exceeded: entry size, entry: "zeros.bin"
+	// exceeded: ratio, entry: "zeros.bin"
+	// extracted: 8388613, err: <nil>
+}
@@ -0,0 +1,90 @@
+package iocopy
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+)
+
+// errTransport is an [http.RoundTripper] which always returns the error.
+type errTransport struct {
+	err error
+}
+
+// RoundTrip implements [http.RoundTripper] interface.
+func (et errTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return nil, et.err
+}
+
+// localIP returns local if it's an IP, or the first IP of the interface named local.
+// IPv4 addresses are preferred. Connections only dial the remote addresses of the same family.
+func localIP(local string) (net.IP, error) {
+	if ip := net.ParseIP(local); ip != nil {
+		return ip, nil
+	}
+
+	ifi, err := net.InterfaceByName(local)
+	if err != nil {
+		return nil, err
+	}
+
+	addrs, err := ifi.Addrs()
+	if err != nil {
+		return nil, err
+	}
+
+	var ip6 net.IP
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok {
+			continue
+		}
+
+		if ip4 := ipNet.IP.To4(); ip4 != nil {
+			return ip4, nil
+		}
+		if ip6 == nil && !ipNet.IP.IsLinkLocalUnicast() {
+			ip6 = ipNet.IP
+		}
+	}
+
+	if ip6 == nil {
+		return nil, fmt.Errorf("no address of interface %v", local)
+	}
+	return ip6, nil
+}
+
+// bindClient returns a copy of c(or [http.DefaultClient] if it's nil)
+// whose connections are dialed from the local address or interface.
+// The address of the interface is resolved when dialing, so it follows the changes of the interface.
+func bindClient(c *http.Client, local string) *http.Client {
+	if c == nil {
+		c = http.DefaultClient
+	}
+	bound := *c
+
+	var base *http.Transport
+	switch rt := c.Transport.(type) {
+	case nil:
+		base = http.DefaultTransport.(*http.Transport)
+	case *http.Transport:
+		base = rt
+	default:
+		bound.Transport = errTransport{fmt.Errorf("transport %T can't be bound to local address", rt)}
+		return &bound
+	}
+
+	tr := base.Clone()
+	tr.DialContext = func(ctx context.Context, network, address string) (net.Conn, error) {
+		ip, err := localIP(local)
+		if err != nil {
+			return nil, fmt.Errorf("local address %v: %w", local, err)
+		}
+
+		d := &net.Dialer{LocalAddr: &net.TCPAddr{IP: ip}}
+		return d.DialContext(ctx, network, address)
+	}
+	bound.Transport = tr
+	return &bound
+}
@@ -0,0 +1,34 @@
+package iocopy
+
+import (
+	"context"
+	"log/slog"
+)
+
+// loggerKey is the context key of the logger.
+type loggerKey struct{}
+
+// ContextWithLogger returns a copy of ctx with l attached.
+// [Do] and the tasks log their internal decisions(e.g. range fallbacks, skipped copies and mmap or splice(2) fallbacks)
+// by l at debug level when they run with the returned context.
+// Use a [slog.Handler] as the sink to capture the records without global state.
+func ContextWithLogger(ctx context.Context, l *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, l)
+}
+
+// logDebug logs the message by the logger attached to ctx if any.
+func logDebug(ctx context.Context, msg string, args ...any) {
+	if l, ok := ctx.Value(loggerKey{}).(*slog.Logger); ok && l != nil {
+		if r := redactor(ctx); r != nil {
+			// Mask the values of the key-value pairs.
+			for i := 0; i+1 < len(args); i += 2 {
+				if k, ok := args[i].(string); ok {
+					if v, ok := args[i+1].(string); ok {
+						args[i+1] = r(k, v)
+					}
+				}
+			}
+		}
+		l.DebugContext(ctx, msg, args...)
+	}
+}
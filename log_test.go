@@ -0,0 +1,56 @@
+package iocopy_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"log"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"github.com/northbright/iocopy"
+)
+
+func ExampleContextWithLogger() {
+	// This example captures the internal decisions of a copy task by a logger attached to the context.
+	dir, err := os.MkdirTemp("", "iocopy")
+	if err != nil {
+		log.Printf("os.MkdirTemp() error: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	src := filepath.Join(dir, "src")
+	dst := filepath.Join(dir, "dst")
+	data := bytes.Repeat([]byte("0123456789abcdef"), 256)
+	for _, name := range []string{src, dst} {
+		if err = os.WriteFile(name, data, 0644); err != nil {
+			log.Printf("os.WriteFile() error: %v", err)
+			return
+		}
+	}
+
+	l := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
+		Level: slog.LevelDebug,
+		// Remove the time and the temporary paths to make the output stable.
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			switch a.Key {
+			case slog.TimeKey, "src", "dst":
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+
+	ctx := iocopy.ContextWithLogger(context.Background(), l)
+	t := iocopy.NewCopyFileTask(dst, src, nil, iocopy.WithSkipIfMatch(sha256.New))
+	if err = iocopy.Do(ctx, t, nil, nil); err != nil {
+		log.Printf("iocopy.Do() error: %v", err)
+		return
+	}
+
+	// Output:
+	// level=DEBUG msg="iocopy: destination matches, skip copy"
+	// level=DEBUG msg="iocopy: task opened" total=4096 prev=4096
+}
@@ -0,0 +1,568 @@
+package iocopy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+)
+
+var (
+	// ErrShutdown is returned by [TaskManager.Submit] after the task manager is shut down.
+	ErrShutdown = errors.New("task manager is shut down")
+
+	// ErrTaskExists is returned by [TaskManager.Submit] when a task with the same id is running or pending.
+	ErrTaskExists = errors.New("task exists")
+
+	// ErrInvalidTaskID is returned by [TaskManager.Submit] when the id is empty or not a valid file name.
+	ErrInvalidTaskID = errors.New("invalid task id")
+)
+
+// TaskStateExt is the extension of the state files saved by [TaskManager].
+const TaskStateExt = ".json"
+
+// managedTask is a task submitted to [TaskManager].
+type managedTask struct {
+	id   string
+	kind string
+	// running, total and copied are updated by the events of the task.
+	running bool
+	total   int64
+	copied  int64
+}
+
+// TaskStatus is the status of a task run by [TaskManager] returned by [TaskManager.List].
+type TaskStatus struct {
+	// ID is the id of the task.
+	ID string `json:"id"`
+	// Kind is the kind of the task, e.g. "download".
+	Kind string `json:"kind"`
+	// Running is true if the task is running, or false if it's pending.
+	Running bool `json:"running"`
+	// Total is the total number of bytes to copy. A negative value indicates total size is unknown.
+	Total int64 `json:"total"`
+	// Copied is the number of bytes copied reported by the last [*EventWritten].
+	Copied int64 `json:"copied"`
+}
+
+// taskFile is the content of the state file saved by [TaskManager].
+type taskFile struct {
+	// Kind is the kind of the task, e.g. "download".
+	Kind string `json:"kind"`
+	// State is the marshaled state of the task. It's empty if the state is encrypted.
+	State json.RawMessage `json:"state,omitempty"`
+	// Encrypted is the state encrypted by [EncryptState] if a key is set by [TaskManager.SetStateKey].
+	Encrypted []byte `json:"encrypted,omitempty"`
+	// Codec is the name of the [StateCodec] set by [TaskManager.SetStateCodec] if it's not JSON
+	// and Encoded is the encoded state. The encoded state is encrypted if a key is set.
+	Codec   string `json:"codec,omitempty"`
+	Encoded []byte `json:"encoded,omitempty"`
+}
+
+// LoadTaskFunc loads a task from the state, e.g. by [LoadDownloadTask].
+type LoadTaskFunc func(state []byte) (Task, error)
+
+// CompletionFunc is called by [TaskManager] when a task is done or fails.
+// result is the marshaled result of the task if it's done, or err is the error if it fails.
+// The returned error is joined to the error returned by [TaskManager.Shutdown].
+type CompletionFunc func(id string, result []byte, err error) error
+
+// TaskManager runs tasks by a [Queue] and persists their states to a directory(the task store),
+// so daemons can stop cleanly and resume the tasks later.
+// The state file of a task is saved when it's submitted and stopped, and removed when it's done.
+// The state files of failed tasks are kept.
+type TaskManager struct {
+	dir    string
+	fn     OnTaskEventFunc
+	q      *Queue
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+
+	mu       sync.Mutex
+	tasks    map[Task]managedTask
+	ids      map[string]Task
+	subs     map[string][]*subscriber
+	loaders  map[string]LoadTaskFunc
+	complete []CompletionFunc
+	// key encrypts the states saved if it's not nil.
+	key      []byte
+	codec    StateCodec
+	shutdown bool
+	errs     []error
+	// submitting is used to wait for the calls of Submit in progress on shutdown.
+	submitting sync.WaitGroup
+	// completing is used to wait for the calls of the completion functions on shutdown.
+	completing sync.WaitGroup
+	// fair shares the bandwidth among the tasks if it's not nil.
+	fair *FairLimiter
+}
+
+// NewTaskManager returns a [*TaskManager] which saves the states of tasks to dir.
+// n: max number of running tasks. See [NewQueue].
+// fn: callback on events of tasks. It's called by one goroutine at a time.
+func NewTaskManager(dir string, n int, fn OnTaskEventFunc) *TaskManager {
+	m := &TaskManager{
+		dir:     dir,
+		fn:      fn,
+		tasks:   map[Task]managedTask{},
+		ids:     map[string]Task{},
+		subs:    map[string][]*subscriber{},
+		loaders: map[string]LoadTaskFunc{},
+	}
+	m.ctx, m.cancel = context.WithCancelCause(context.Background())
+	m.q = NewQueue(n, m.onEvent)
+	return m
+}
+
+// subscriber is a callback subscribed to the events of a task by [TaskManager.Subscribe].
+type subscriber struct {
+	fn OnEventFunc
+}
+
+// Submit saves the state of the task to the task store and submits it to run.
+// id is the unique id of the task and it's used as the name of the state file.
+// If id is empty, the ID of the task is used if it implements [Identifier].
+// kind is the kind of the task, e.g. "download". It's saved with the state to load the task.
+// It returns an error wrapping [ErrInvalidTaskID] if id is empty and the task doesn't implement [Identifier].
+// It returns an error wrapping [ErrTaskExists] if a task with the same id is running or pending,
+// e.g. the same download is submitted twice. Call [TaskManager.Task] to get the existing one to coalesce them.
+func (m *TaskManager) Submit(id, kind string, t Task) error {
+	if i, ok := t.(Identifier); ok && id == "" {
+		id = i.ID()
+	}
+
+	if id == "" || id != filepath.Base(id) {
+		return fmt.Errorf("%w: %q", ErrInvalidTaskID, id)
+	}
+
+	m.mu.Lock()
+	if m.shutdown {
+		m.mu.Unlock()
+		return ErrShutdown
+	}
+	if _, ok := m.ids[id]; ok {
+		m.mu.Unlock()
+		return fmt.Errorf("%w: %v", ErrTaskExists, id)
+	}
+	m.ids[id] = t
+	m.tasks[t] = managedTask{id: id, kind: kind, total: t.Total(), copied: t.Copied()}
+	m.submitting.Add(1)
+	m.mu.Unlock()
+
+	defer m.submitting.Done()
+
+	if err := m.save(id, kind, t); err != nil {
+		m.mu.Lock()
+		delete(m.ids, id)
+		delete(m.tasks, t)
+		m.mu.Unlock()
+		return err
+	}
+
+	m.q.Submit(m.ctx, t)
+	return nil
+}
+
+// Task returns the running or pending task with the id, or nil if it's not found.
+func (m *TaskManager) Task(id string) Task {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.ids[id]
+}
+
+// List returns the statuses of the running and pending tasks sorted by their ids.
+// The progress is the one reported by the events, so it's safe to call while the tasks are running.
+func (m *TaskManager) List() []TaskStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	statuses := make([]TaskStatus, 0, len(m.tasks))
+	for _, mt := range m.tasks {
+		statuses = append(statuses, TaskStatus{ID: mt.id, Kind: mt.kind, Running: mt.running, Total: mt.total, Copied: mt.copied})
+	}
+
+	slices.SortFunc(statuses, func(a, b TaskStatus) int {
+		return strings.Compare(a.ID, b.ID)
+	})
+	return statuses
+}
+
+// Subscribe subscribes fn to the events of the task with the id until it finishes.
+// fn is called after the callback of the task manager.
+// It returns a function to unsubscribe.
+func (m *TaskManager) Subscribe(id string, fn OnEventFunc) (unsubscribe func()) {
+	unsubscribe, _ = m.subscribe(id, fn, false)
+	return unsubscribe
+}
+
+// subscribe subscribes fn to the events of the task with the id.
+// If running is true, it only subscribes if the task is running or pending and returns false otherwise,
+// so fn is always called with the [*EventFinished] of the task.
+func (m *TaskManager) subscribe(id string, fn OnEventFunc, running bool) (unsubscribe func(), ok bool) {
+	s := &subscriber{fn: fn}
+
+	m.mu.Lock()
+	if _, ok := m.ids[id]; running && !ok {
+		m.mu.Unlock()
+		return func() {}, false
+	}
+	m.subs[id] = append(m.subs[id], s)
+	m.mu.Unlock()
+
+	return func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+
+		subs := m.subs[id]
+		for i := range subs {
+			if subs[i] == s {
+				m.subs[id] = append(subs[:i:i], subs[i+1:]...)
+				break
+			}
+		}
+		if len(m.subs[id]) == 0 {
+			delete(m.subs, id)
+		}
+	}, true
+}
+
+// OnComplete registers fn to be called when a task is done or fails, e.g. [Webhook].
+// It's not called for stopped tasks. It should be called before tasks are submitted.
+// The functions are called in a new goroutine after the state file is removed or saved,
+// so a slow one(e.g. a webhook to a slow server) doesn't block the events of other tasks.
+// [TaskManager.Shutdown] waits for the calls.
+func (m *TaskManager) OnComplete(fn CompletionFunc) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.complete = append(m.complete, fn)
+}
+
+// SetStateKey makes the task manager encrypt the states saved in the state files by [EncryptState] with key,
+// and decrypt them when they're recovered. The kinds of the tasks are not encrypted,
+// but they're authenticated with the ids of the tasks, so a state file can't be renamed or moved to another kind.
+// Plaintext state files(e.g. saved before the key is set) can still be recovered.
+// It should be called before tasks are submitted or recovered.
+func (m *TaskManager) SetStateKey(key []byte) error {
+	if _, err := newStateAEAD(key); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.key = key
+	return nil
+}
+
+// SetStateCodec makes the task manager encode the states saved in the state files by c, e.g. [CBORCodec],
+// and decode them when they're recovered. They're encoded before they're encrypted by [TaskManager.SetStateKey].
+// The state files saved by other built-in codecs(e.g. before the codec is set) can still be recovered.
+// It should be called before tasks are submitted or recovered.
+func (m *TaskManager) SetStateCodec(c StateCodec) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.codec = c
+}
+
+// SetFairLimiter makes the task manager adjust the weights of the tasks sharing fl by [TaskManager.SetWeight]
+// and remove their flows when they finish.
+// The tasks should be created with [WithFairShare] by fl and their ids of the task manager as the flow ids.
+// It should be called before tasks are submitted.
+func (m *TaskManager) SetFairLimiter(fl *FairLimiter) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.fair = fl
+}
+
+// SetWeight sets the weight of the task with the id in its fair share of the bandwidth at runtime,
+// e.g. boost a download the user is waiting for. See [FairLimiter.SetWeight].
+// It does nothing if no [FairLimiter] is set by [TaskManager.SetFairLimiter].
+func (m *TaskManager) SetWeight(id string, w float64) {
+	m.mu.Lock()
+	fl := m.fair
+	m.mu.Unlock()
+
+	if fl != nil {
+		fl.SetWeight(id, w)
+	}
+}
+
+// Register registers the function to load the tasks of the kind for [TaskManager.Recover].
+// The tasks of the kinds without registered functions are loaded by [LoadTask].
+func (m *TaskManager) Register(kind string, load LoadTaskFunc) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.loaders[kind] = load
+}
+
+// Recover scans the state files in dir, e.g. the task store of the previous run,
+// and loads the unfinished tasks by the registered functions.
+// It returns the loaded tasks by their ids.
+// If restart is true, the loaded tasks are submitted to run.
+// Files which fail to be loaded are skipped and the errors are joined and returned.
+func (m *TaskManager) Recover(dir string, restart bool) (map[string]Task, error) {
+	entries, err := os.ReadDir(longPath(dir))
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		tasks = map[string]Task{}
+		errs  []error
+	)
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, TaskStateExt) {
+			continue
+		}
+		id := strings.TrimSuffix(name, TaskStateExt)
+
+		kind, t, err := m.load(id, filepath.Join(dir, name))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("recover task %v: %w", id, err))
+			continue
+		}
+
+		if restart {
+			if err = m.Submit(id, kind, t); err != nil {
+				errs = append(errs, fmt.Errorf("recover task %v: %w", id, err))
+				continue
+			}
+		}
+		tasks[id] = t
+	}
+
+	return tasks, errors.Join(errs...)
+}
+
+// load loads the task with the id from the state file by the registered function of its kind.
+func (m *TaskManager) load(id, file string) (string, Task, error) {
+	buf, err := os.ReadFile(longPath(file))
+	if err != nil {
+		return "", nil, err
+	}
+
+	var f taskFile
+	if err = json.Unmarshal(buf, &f); err != nil {
+		return "", nil, err
+	}
+
+	m.mu.Lock()
+	load, ok := m.loaders[f.Kind]
+	key, codec := m.key, m.codec
+	m.mu.Unlock()
+	if !ok {
+		// Load the task by the type of its state.
+		load = LoadTask
+	}
+
+	state := []byte(f.State)
+	if f.Codec != "" && f.Encrypted == nil {
+		state = f.Encoded
+	}
+
+	if f.Encrypted != nil {
+		if key == nil {
+			return "", nil, errors.New("state is encrypted but no key is set")
+		}
+
+		if state, err = DecryptState(f.Encrypted, key, stateAdditionalData(f.Kind, id)); err != nil {
+			return "", nil, err
+		}
+	}
+
+	if f.Codec != "" {
+		if codec == nil || codec.Name() != f.Codec {
+			if codec, ok = stateCodecs[f.Codec]; !ok {
+				return "", nil, fmt.Errorf("unknown state codec %q", f.Codec)
+			}
+		}
+
+		if state, err = codec.Unmarshal(state); err != nil {
+			return "", nil, err
+		}
+	}
+
+	t, err := load(state)
+	if err != nil {
+		return "", nil, err
+	}
+	return f.Kind, t, nil
+}
+
+// onEvent persists the state of the task when it finishes and calls the callback and subscribers.
+func (m *TaskManager) onEvent(t Task, e Event) {
+	m.mu.Lock()
+	mt, ok := m.tasks[t]
+	switch e := e.(type) {
+	case *EventStarted:
+		mt.running = true
+	case *EventWritten:
+		mt.total, mt.copied = e.Total, e.Copied
+	}
+	if ok {
+		m.tasks[t] = mt
+	}
+	subs := m.subs[mt.id]
+	complete := m.complete
+	m.mu.Unlock()
+
+	if e, ok := e.(*EventFinished); ok {
+		var errs []error
+		if e.Err == nil {
+			errs = append(errs, m.remove(mt.id))
+		} else {
+			errs = append(errs, m.save(mt.id, mt.kind, t))
+		}
+
+		if !isStopped(e.Err) && len(complete) > 0 {
+			var result []byte
+			if e.Err == nil {
+				r, err := t.Result()
+				errs = append(errs, err)
+				result = r
+			}
+
+			m.completing.Add(1)
+			go m.callComplete(complete, mt.id, result, e.Err)
+		}
+
+		m.mu.Lock()
+		if err := errors.Join(errs...); err != nil {
+			m.errs = append(m.errs, err)
+		}
+		// Get the subscribers again to include the ones subscribed since the event.
+		subs = m.subs[mt.id]
+		delete(m.tasks, t)
+		delete(m.ids, mt.id)
+		delete(m.subs, mt.id)
+		fl := m.fair
+		m.mu.Unlock()
+
+		if fl != nil && !isStopped(e.Err) {
+			fl.Remove(mt.id)
+		}
+	}
+
+	if m.fn != nil {
+		m.fn(t, e)
+	}
+
+	for _, s := range subs {
+		s.fn(e)
+	}
+}
+
+// callComplete calls the completion functions and keeps the errors for [TaskManager.Shutdown].
+// It runs outside the callback of the queue, which is called by one goroutine at a time.
+func (m *TaskManager) callComplete(complete []CompletionFunc, id string, result []byte, err error) {
+	defer m.completing.Done()
+
+	var errs []error
+	for _, fn := range complete {
+		errs = append(errs, fn(id, result, err))
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		m.mu.Lock()
+		m.errs = append(m.errs, err)
+		m.mu.Unlock()
+	}
+}
+
+// path returns the path of the state file of the task.
+func (m *TaskManager) path(id string) string {
+	return filepath.Join(m.dir, id+TaskStateExt)
+}
+
+// save writes the state of the task to the state file atomically.
+func (m *TaskManager) save(id, kind string, t Task) error {
+	state, err := t.State()
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	key, codec := m.key, m.codec
+	m.mu.Unlock()
+
+	f := taskFile{Kind: kind, State: state}
+	if codec != nil && codec.Name() != JSONCodec.Name() {
+		if f.Encoded, err = codec.Marshal(state); err != nil {
+			return err
+		}
+		f.Codec, f.State, state = codec.Name(), nil, f.Encoded
+	}
+
+	if key != nil {
+		if f.Encrypted, err = EncryptState(state, key, stateAdditionalData(kind, id)); err != nil {
+			return err
+		}
+		f.State, f.Encoded = nil, nil
+	}
+
+	buf, err := json.Marshal(f)
+	if err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(longPath(m.dir), 0755); err != nil {
+		return err
+	}
+
+	tmp := m.path(id) + ".tmp"
+	if err = os.WriteFile(longPath(tmp), buf, 0644); err != nil {
+		return err
+	}
+	return os.Rename(longPath(tmp), longPath(m.path(id)))
+}
+
+// remove removes the state file of the task.
+func (m *TaskManager) remove(id string) error {
+	if err := os.Remove(longPath(m.path(id))); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// Shutdown stops the running and pending tasks, waits for them to finish
+// and returns after their states are saved to the task store, e.g. on SIGTERM.
+// It returns ctx.Err() if ctx is done before that,
+// or the errors occurred while saving the states and calling the functions registered by [TaskManager.OnComplete].
+// Tasks can't be submitted after Shutdown is called.
+func (m *TaskManager) Shutdown(ctx context.Context) error {
+	return m.shutdownCause(ctx, nil)
+}
+
+// shutdownCause shuts down the task manager like [TaskManager.Shutdown] and stops the tasks with the cause.
+func (m *TaskManager) shutdownCause(ctx context.Context, cause error) error {
+	m.mu.Lock()
+	m.shutdown = true
+	m.mu.Unlock()
+
+	m.submitting.Wait()
+	m.cancel(cause)
+
+	done := make(chan struct{})
+	go func() {
+		m.q.Wait()
+		m.completing.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-done:
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return errors.Join(m.errs...)
+}
@@ -0,0 +1,376 @@
+package iocopy_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/northbright/iocopy"
+)
+
+func ExampleTaskManager_Shutdown() {
+	// This example shuts down a task manager while a download task is running.
+	// The state of the task is saved to the task store to resume later.
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Write half of the content and wait for the client to stop.
+		w.Header().Set("Content-Length", "2000")
+		w.Write([]byte(strings.Repeat("a", 1000)))
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer ts.Close()
+
+	dir, err := os.MkdirTemp("", "iocopy")
+	if err != nil {
+		log.Printf("os.MkdirTemp() error: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	store := filepath.Join(dir, "tasks")
+	written := make(chan struct{})
+	m := iocopy.NewTaskManager(store, 2, func(t iocopy.Task, e iocopy.Event) {
+		if _, ok := e.(*iocopy.EventWritten); ok && t.Copied() == 1000 {
+			close(written)
+		}
+	})
+
+	t := iocopy.NewDownloadTask(filepath.Join(dir, "file"), ts.URL, nil)
+	if err = m.Submit("file", "download", t); err != nil {
+		log.Printf("m.Submit() error: %v", err)
+		return
+	}
+
+	// Shut down on the first bytes written, e.g. on SIGTERM.
+	<-written
+	if err = m.Shutdown(context.Background()); err != nil {
+		log.Printf("m.Shutdown() error: %v", err)
+		return
+	}
+
+	buf, err := os.ReadFile(filepath.Join(store, "file"+iocopy.TaskStateExt))
+	if err != nil {
+		log.Printf("os.ReadFile() error: %v", err)
+		return
+	}
+
+	var saved struct {
+		Kind  string          `json:"kind"`
+		State json.RawMessage `json:"state"`
+	}
+	if err = json.Unmarshal(buf, &saved); err != nil {
+		log.Printf("json.Unmarshal() error: %v", err)
+		return
+	}
+
+	t, err = iocopy.LoadDownloadTask(saved.State, nil)
+	if err != nil {
+		log.Printf("iocopy.LoadDownloadTask() error: %v", err)
+		return
+	}
+	fmt.Printf("kind: %v, saved: %v/%v\n", saved.Kind, t.Copied(), t.Total())
+
+	err = m.Submit("other", "download", t)
+	fmt.Printf("submit after shutdown: %v\n", err)
+
+	// Output:
+	// kind: download, saved: 1000/2000
+	// submit after shutdown: task manager is shut down
+}
+
+func ExampleTaskManager_Recover() {
+	// This example stops a download task by shutting down a task manager,
+	// then recovers and restarts the task by a new task manager, e.g. after the daemon restarts.
+	data := strings.Repeat("a", 2000)
+	var stopped atomic.Bool
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !stopped.Load() {
+			// Write half of the content and wait for the client to stop.
+			w.Header().Set("Content-Length", "2000")
+			w.Write([]byte(data[:1000]))
+			w.(http.Flusher).Flush()
+			<-r.Context().Done()
+			return
+		}
+		http.ServeContent(w, r, "file", time.Time{}, strings.NewReader(data))
+	}))
+	defer ts.Close()
+
+	dir, err := os.MkdirTemp("", "iocopy")
+	if err != nil {
+		log.Printf("os.MkdirTemp() error: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	store := filepath.Join(dir, "tasks")
+	file := filepath.Join(dir, "file")
+
+	written := make(chan struct{})
+	m := iocopy.NewTaskManager(store, 2, func(t iocopy.Task, e iocopy.Event) {
+		if _, ok := e.(*iocopy.EventWritten); ok && t.Copied() == 1000 {
+			close(written)
+		}
+	})
+
+	if err = m.Submit("file", "download", iocopy.NewDownloadTask(file, ts.URL, nil)); err != nil {
+		log.Printf("m.Submit() error: %v", err)
+		return
+	}
+
+	<-written
+	if err = m.Shutdown(context.Background()); err != nil {
+		log.Printf("m.Shutdown() error: %v", err)
+		return
+	}
+	stopped.Store(true)
+
+	// Restart.
+	var resumed int64
+	finished := make(chan error)
+	m = iocopy.NewTaskManager(store, 2, func(t iocopy.Task, e iocopy.Event) {
+		switch e := e.(type) {
+		case *iocopy.EventStarted:
+			resumed = t.Copied()
+		case *iocopy.EventFinished:
+			finished <- e.Err
+		}
+	})
+	m.Register("download", func(state []byte) (iocopy.Task, error) {
+		return iocopy.LoadDownloadTask(state, nil)
+	})
+
+	tasks, err := m.Recover(store, true)
+	if err != nil {
+		log.Printf("m.Recover() error: %v", err)
+		return
+	}
+	fmt.Printf("recovered: %v\n", len(tasks))
+
+	if err = <-finished; err != nil {
+		log.Printf("task error: %v", err)
+		return
+	}
+	fmt.Printf("resumed from: %v\n", resumed)
+
+	buf, _ := os.ReadFile(file)
+	entries, _ := os.ReadDir(store)
+	fmt.Printf("same content: %v, state files: %v\n", string(buf) == data, len(entries))
+
+	// Output:
+	// recovered: 1
+	// resumed from: 1000
+	// same content: true, state files: 0
+}
+
+func ExampleTaskManager_Submit() {
+	// This example submits the same download twice.
+	// Tasks are identified by their deterministic IDs, so the duplicate one is detected and coalesced.
+	data := strings.Repeat("Hello, World!\n", 1000)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "hello.txt", time.Time{}, strings.NewReader(data))
+	}))
+	defer ts.Close()
+
+	dir, err := os.MkdirTemp("", "iocopy")
+	if err != nil {
+		log.Printf("os.MkdirTemp() error: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	// Run at most 1 task, so the tasks submitted later are pending.
+	m := iocopy.NewTaskManager(filepath.Join(dir, "tasks"), 1, nil)
+	defer m.Shutdown(context.Background())
+
+	// Keep the task manager busy.
+	block := make(chan struct{})
+	blocker := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-block
+	}))
+	defer blocker.Close()
+	defer close(block)
+
+	if err = m.Submit("", "download", iocopy.NewDownloadTask(filepath.Join(dir, "blocker"), blocker.URL, nil)); err != nil {
+		log.Printf("m.Submit() error: %v", err)
+		return
+	}
+
+	file := filepath.Join(dir, "hello.txt")
+	t := iocopy.NewDownloadTask(file, ts.URL, nil)
+	if err = m.Submit("", "download", t); err != nil {
+		log.Printf("m.Submit() error: %v", err)
+		return
+	}
+
+	dup := iocopy.NewDownloadTask(file, ts.URL, nil)
+	fmt.Printf("same id: %v\n", dup.ID() == t.ID())
+
+	err = m.Submit("", "download", dup)
+	fmt.Printf("duplicate: %v\n", errors.Is(err, iocopy.ErrTaskExists))
+
+	// Coalesce: subscribe to the existing task by the ID.
+	fmt.Printf("existing task: %v\n", m.Task(dup.ID()) == iocopy.Task(t))
+
+	done := make(chan struct{})
+	m.Subscribe(dup.ID(), func(e iocopy.Event) {
+		switch e.(type) {
+		case *iocopy.EventOK:
+			fmt.Printf("downloaded: %v bytes\n", t.Copied())
+		case *iocopy.EventFinished:
+			close(done)
+		}
+	})
+
+	// Unblock.
+	block <- struct{}{}
+	<-done
+
+	// Output:
+	// same id: true
+	// duplicate: true
+	// existing task: true
+	// downloaded: 14000 bytes
+}
+
+func ExampleTaskManager_SetStateKey() {
+	// This example encrypts the states saved by a task manager, since the url contains an auth token.
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Write half of the content and wait for the client to stop.
+		w.Header().Set("Content-Length", "2000")
+		w.Write([]byte(strings.Repeat("a", 1000)))
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer ts.Close()
+
+	dir, err := os.MkdirTemp("", "iocopy")
+	if err != nil {
+		log.Printf("os.MkdirTemp() error: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	// Use a key from a secret store in practice.
+	key := []byte("0123456789abcdef0123456789abcdef")
+	store := filepath.Join(dir, "tasks")
+	written := make(chan struct{})
+	m := iocopy.NewTaskManager(store, 2, func(t iocopy.Task, e iocopy.Event) {
+		if _, ok := e.(*iocopy.EventWritten); ok && t.Copied() == 1000 {
+			close(written)
+		}
+	})
+	if err = m.SetStateKey(key); err != nil {
+		log.Printf("m.SetStateKey() error: %v", err)
+		return
+	}
+
+	t := iocopy.NewDownloadTask(filepath.Join(dir, "file"), ts.URL+"/file?token=secret", nil)
+	if err = m.Submit("file", "download", t); err != nil {
+		log.Printf("m.Submit() error: %v", err)
+		return
+	}
+
+	<-written
+	if err = m.Shutdown(context.Background()); err != nil {
+		log.Printf("m.Shutdown() error: %v", err)
+		return
+	}
+
+	buf, err := os.ReadFile(filepath.Join(store, "file"+iocopy.TaskStateExt))
+	if err != nil {
+		log.Printf("os.ReadFile() error: %v", err)
+		return
+	}
+	fmt.Printf("token in plaintext: %v\n", strings.Contains(string(buf), "secret"))
+
+	// A state file renamed to another task can't be decrypted.
+	if err = os.WriteFile(filepath.Join(store, "other"+iocopy.TaskStateExt), buf, 0644); err != nil {
+		log.Printf("os.WriteFile() error: %v", err)
+		return
+	}
+
+	// Recover the task by a new task manager with the same key.
+	m = iocopy.NewTaskManager(store, 2, nil)
+	if err = m.SetStateKey(key); err != nil {
+		log.Printf("m.SetStateKey() error: %v", err)
+		return
+	}
+	m.Register("download", func(state []byte) (iocopy.Task, error) {
+		return iocopy.LoadDownloadTask(state, nil)
+	})
+
+	tasks, err := m.Recover(store, false)
+	fmt.Printf("renamed state file recovered: %v\n", err == nil)
+	fmt.Printf("recovered: %v/%v\n", tasks["file"].Copied(), tasks["file"].Total())
+
+	// Output:
+	// token in plaintext: false
+	// renamed state file recovered: false
+	// recovered: 1000/2000
+}
+
+func ExampleTaskManager_SetStateCodec() {
+	// This example saves the states of a task manager as the protobuf encoding of google.protobuf.Struct,
+	// e.g. for a system which stores protobuf messages only.
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Write half of the content and wait for the client to stop.
+		w.Header().Set("Content-Length", "2000")
+		w.Write([]byte(strings.Repeat("a", 1000)))
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer ts.Close()
+
+	dir, err := os.MkdirTemp("", "iocopy")
+	if err != nil {
+		log.Printf("os.MkdirTemp() error: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	store := filepath.Join(dir, "tasks")
+	written := make(chan struct{})
+	m := iocopy.NewTaskManager(store, 2, func(t iocopy.Task, e iocopy.Event) {
+		if _, ok := e.(*iocopy.EventWritten); ok && t.Copied() == 1000 {
+			close(written)
+		}
+	})
+	m.SetStateCodec(iocopy.ProtoCodec)
+
+	t := iocopy.NewDownloadTask(filepath.Join(dir, "file"), ts.URL+"/file", nil)
+	if err = m.Submit("file", "download", t); err != nil {
+		log.Printf("m.Submit() error: %v", err)
+		return
+	}
+
+	<-written
+	if err = m.Shutdown(context.Background()); err != nil {
+		log.Printf("m.Shutdown() error: %v", err)
+		return
+	}
+
+	// Recover the task by a new task manager.
+	// It's loaded by the type of its state since no loader is registered for the kind.
+	m = iocopy.NewTaskManager(store, 2, nil)
+	m.SetStateCodec(iocopy.ProtoCodec)
+
+	tasks, err := m.Recover(store, false)
+	if err != nil {
+		log.Printf("m.Recover() error: %v", err)
+		return
+	}
+	fmt.Printf("recovered: %v/%v\n", tasks["file"].Copied(), tasks["file"].Total())
+
+	// Output:
+	// recovered: 1000/2000
+}
@@ -0,0 +1,51 @@
+package iocopy
+
+import (
+	"fmt"
+	"io"
+)
+
+// MaxBytesError is returned by tasks when the bytes to write exceed the limit set by [WithMaxBytes].
+type MaxBytesError struct {
+	// Limit is the max number of bytes to write.
+	Limit int64
+}
+
+// Error implements error interface.
+func (e *MaxBytesError) Error() string {
+	return fmt.Sprintf("exceeded max bytes: %v", e.Limit)
+}
+
+// maxBytesWriter writes to w until the limit is reached.
+// It forwards Commit to w if w implements [Committer].
+type maxBytesWriter struct {
+	w       io.Writer
+	limit   int64
+	written int64
+}
+
+// newMaxBytesWriter returns a [*maxBytesWriter] which writes to w.
+// written: number of bytes written previously.
+func newMaxBytesWriter(w io.Writer, limit, written int64) *maxBytesWriter {
+	return &maxBytesWriter{w: w, limit: limit, written: written}
+}
+
+// Write implements [io.Writer] interface.
+// It writes nothing and returns a [*MaxBytesError] if p would exceed the limit.
+func (mw *maxBytesWriter) Write(p []byte) (n int, err error) {
+	if mw.written+int64(len(p)) > mw.limit {
+		return 0, &MaxBytesError{Limit: mw.limit}
+	}
+
+	n, err = mw.w.Write(p)
+	mw.written += int64(n)
+	return n, err
+}
+
+// Commit implements [Committer] interface.
+func (mw *maxBytesWriter) Commit() error {
+	if c, ok := mw.w.(Committer); ok {
+		return c.Commit()
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package iocopy
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// MinisignVerifier implements [SignatureVerifier] interface to verify [minisign] signatures.
+// Both legacy("Ed") and pre-hashed("ED") signatures are supported.
+//
+// [minisign]: https://jedisct1.github.io/minisign/
+type MinisignVerifier struct {
+	keyID [8]byte
+	key   ed25519.PublicKey
+}
+
+// NewMinisignVerifier returns a [*MinisignVerifier] with the public key.
+// publicKey is the content of the public key file or the base64 encoded key line.
+func NewMinisignVerifier(publicKey string) (*MinisignVerifier, error) {
+	lines := minisignLines(publicKey)
+	if len(lines) == 0 {
+		return nil, errors.New("minisign: empty public key")
+	}
+
+	// Skip the untrusted comment line.
+	line := lines[0]
+	if strings.HasPrefix(line, "untrusted comment:") {
+		if len(lines) < 2 {
+			return nil, errors.New("minisign: missing public key")
+		}
+		line = lines[1]
+	}
+
+	buf, err := base64.StdEncoding.DecodeString(line)
+	if err != nil {
+		return nil, fmt.Errorf("minisign: invalid public key: %w", err)
+	}
+
+	if len(buf) != 2+8+ed25519.PublicKeySize || string(buf[:2]) != "Ed" {
+		return nil, errors.New("minisign: invalid public key")
+	}
+
+	v := &MinisignVerifier{key: ed25519.PublicKey(buf[10:])}
+	copy(v.keyID[:], buf[2:10])
+	return v, nil
+}
+
+// Verify implements [SignatureVerifier] interface.
+// It verifies the signature of the file and the trusted comment.
+func (v *MinisignVerifier) Verify(r io.Reader, sig []byte) error {
+	// Lines: untrusted comment, signature, trusted comment, global signature.
+	lines := minisignLines(string(sig))
+	if len(lines) < 4 || !strings.HasPrefix(lines[2], "trusted comment: ") {
+		return fmt.Errorf("%w: invalid minisign signature", ErrBadSignature)
+	}
+
+	buf, err := base64.StdEncoding.DecodeString(lines[1])
+	if err != nil || len(buf) != 2+8+ed25519.SignatureSize {
+		return fmt.Errorf("%w: invalid minisign signature", ErrBadSignature)
+	}
+
+	alg, keyID, s := string(buf[:2]), buf[2:10], buf[10:]
+	if !bytes.Equal(keyID, v.keyID[:]) {
+		return fmt.Errorf("%w: key id mismatch", ErrBadSignature)
+	}
+
+	var msg []byte
+	switch alg {
+	case "Ed":
+		if msg, err = io.ReadAll(r); err != nil {
+			return err
+		}
+	case "ED":
+		h, _ := blake2b.New512(nil)
+		if _, err = io.Copy(h, r); err != nil {
+			return err
+		}
+		msg = h.Sum(nil)
+	default:
+		return fmt.Errorf("%w: unsupported algorithm: %v", ErrBadSignature, alg)
+	}
+
+	if !ed25519.Verify(v.key, msg, s) {
+		return fmt.Errorf("%w: signature mismatch", ErrBadSignature)
+	}
+
+	// The global signature signs the signature and the trusted comment.
+	globalSig, err := base64.StdEncoding.DecodeString(lines[3])
+	if err != nil || len(globalSig) != ed25519.SignatureSize {
+		return fmt.Errorf("%w: invalid global signature", ErrBadSignature)
+	}
+
+	comment := strings.TrimPrefix(lines[2], "trusted comment: ")
+	if !ed25519.Verify(v.key, append(append([]byte{}, s...), comment...), globalSig) {
+		return fmt.Errorf("%w: trusted comment mismatch", ErrBadSignature)
+	}
+	return nil
+}
+
+// minisignLines returns the non-empty lines of s.
+func minisignLines(s string) []string {
+	var lines []string
+	for _, line := range strings.Split(s, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
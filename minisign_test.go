@@ -0,0 +1,87 @@
+package iocopy_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/northbright/iocopy"
+	"golang.org/x/crypto/blake2b"
+)
+
+// minisignSign returns a pre-hashed minisign public key and signature of data for testing.
+func minisignSign(seed byte, data []byte) (publicKey string, sig []byte) {
+	priv := ed25519.NewKeyFromSeed(bytes.Repeat([]byte{seed}, ed25519.SeedSize))
+	keyID := []byte("iocopyid")
+
+	pk := append(append([]byte("Ed"), keyID...), priv.Public().(ed25519.PublicKey)...)
+	publicKey = "untrusted comment: minisign public key\n" + base64.StdEncoding.EncodeToString(pk) + "\n"
+
+	sum := blake2b.Sum512(data)
+	s := ed25519.Sign(priv, sum[:])
+	comment := "timestamp:0\tfile:file"
+	global := ed25519.Sign(priv, append(append([]byte{}, s...), comment...))
+
+	sig = []byte("untrusted comment: signature from minisign secret key\n" +
+		base64.StdEncoding.EncodeToString(append(append([]byte("ED"), keyID...), s...)) + "\n" +
+		"trusted comment: " + comment + "\n" +
+		base64.StdEncoding.EncodeToString(global) + "\n")
+	return publicKey, sig
+}
+
+func ExampleWithSignature() {
+	// This example downloads a file and verifies it with the minisign signature fetched from the server.
+	data := bytes.Repeat([]byte("0123456789abcdef"), 1024)
+	publicKey, sig := minisignSign(1, data)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/file.minisig" {
+			w.Write(sig)
+			return
+		}
+		http.ServeContent(w, r, "file", time.Time{}, bytes.NewReader(data))
+	}))
+	defer ts.Close()
+
+	dir, err := os.MkdirTemp("", "iocopy")
+	if err != nil {
+		log.Printf("os.MkdirTemp() error: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	v, err := iocopy.NewMinisignVerifier(publicKey)
+	if err != nil {
+		log.Printf("iocopy.NewMinisignVerifier() error: %v", err)
+		return
+	}
+
+	dst := filepath.Join(dir, "file")
+	t := iocopy.NewDownloadTask(dst, ts.URL+"/file", nil, iocopy.WithSignature(v, nil, ts.URL+"/file.minisig"))
+	err = iocopy.Do(context.Background(), t, nil, nil)
+	fmt.Printf("verified: %v\n", err == nil)
+
+	// Use the public key of another key pair.
+	otherKey, _ := minisignSign(2, data)
+	if v, err = iocopy.NewMinisignVerifier(otherKey); err != nil {
+		log.Printf("iocopy.NewMinisignVerifier() error: %v", err)
+		return
+	}
+
+	t = iocopy.NewDownloadTask(dst, ts.URL+"/file", nil, iocopy.WithSignature(v, sig, ""))
+	err = iocopy.Do(context.Background(), t, nil, nil)
+	fmt.Printf("bad signature: %v\n", errors.Is(err, iocopy.ErrBadSignature))
+
+	// Output:
+	// verified: true
+	// bad signature: true
+}
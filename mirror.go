@@ -0,0 +1,124 @@
+package iocopy
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// DefaultMirrorCheckSize is the default number of the downloaded bytes to re-fetch and compare
+// when resuming a [DownloadTask] from a mirror.
+const DefaultMirrorCheckSize = 64 * 1024
+
+var (
+	// ErrMirrorMismatch is returned when the downloaded bytes or the size do not match the ones of the mirror.
+	ErrMirrorMismatch = errors.New("downloaded bytes do not match the mirror")
+
+	// ErrMirrorUnverified is returned when the downloaded bytes can't be compared with the mirror,
+	// e.g. the mirror does not support range or the destination file system is not [OSFS].
+	ErrMirrorUnverified = errors.New("downloaded bytes can't be verified with the mirror")
+)
+
+// verifyMirror re-fetches the last downloaded bytes from the mirror by a range request
+// and compares them with the ones in the destination file.
+func (t *DownloadTask) verifyMirror(ctx context.Context) error {
+	if t.fsys != OSFS {
+		return ErrMirrorUnverified
+	}
+
+	n := t.opts.mirrorCheckSize
+	if n <= 0 {
+		n = DefaultMirrorCheckSize
+	}
+
+	reason, err := t.compareTail(ctx, n)
+	switch {
+	case errors.Is(err, errNoPartialContent):
+		return fmt.Errorf("%w: %w", ErrMirrorUnverified, err)
+	case err != nil:
+		return err
+	case reason != "":
+		return fmt.Errorf("%w: %v", ErrMirrorMismatch, reason)
+	}
+	return nil
+}
+
+// errNoPartialContent is returned by compareTail if the server does not respond the range request with partial content.
+var errNoPartialContent = errors.New("no partial content")
+
+// compareTail re-fetches the last n downloaded bytes(of the last downloaded range if there're holes)
+// by a range request and compares them with the ones in the destination file.
+// It returns the reason if they don't match or the size of the remote file changed.
+// The destination file system should be [OSFS].
+func (t *DownloadTask) compareTail(ctx context.Context, n int64) (reason string, err error) {
+	end, size := t.copied, t.copied
+	if len(t.ranges) > 0 {
+		// Check the end of the last downloaded range.
+		r := t.ranges[len(t.ranges)-1]
+		end, size = r.End, r.Size()
+	}
+	n = min(n, size)
+	start := end - n
+
+	f, err := os.Open(longPath(t.dstName()))
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	local := make([]byte, n)
+	if _, err = f.ReadAt(local, start); err != nil {
+		return "", err
+	}
+
+	req, err := t.newRequest(ctx)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("range", rangeHeader(Range{Start: start, End: end}))
+
+	resp, err := t.do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusPartialContent {
+		return "", fmt.Errorf("%w: unexpected status: %v", errNoPartialContent, resp.Status)
+	}
+
+	if total := contentRangeTotal(resp.Header.Get("content-range")); total >= 0 && t.total >= 0 && total != t.total {
+		return fmt.Sprintf("size: %v, previous: %v", total, t.total), nil
+	}
+
+	remote, err := io.ReadAll(io.LimitReader(resp.Body, n+1))
+	if err != nil {
+		return "", err
+	}
+
+	if !bytes.Equal(local, remote) {
+		return "last downloaded bytes changed", nil
+	}
+	return "", nil
+}
+
+// contentRangeTotal returns the total size in the "content-range" header: "bytes <start>-<end>/<total>".
+// It returns -1 if it's unknown.
+func contentRangeTotal(s string) int64 {
+	i := strings.LastIndexByte(s, '/')
+	if i < 0 {
+		return -1
+	}
+
+	total, err := strconv.ParseInt(s[i+1:], 10, 64)
+	if err != nil {
+		return -1
+	}
+	return total
+}
@@ -0,0 +1,92 @@
+package iocopy
+
+import (
+	"errors"
+	"io"
+	"os"
+)
+
+// mmapReader reads a memory-mapped file to avoid the syscall overhead of reading large files.
+// It does not implement [io.WriterTo] to make the IO copy still go through the buffer,
+// so the copy can be canceled and the progress can be reported.
+type mmapReader struct {
+	f    *os.File
+	data []byte
+	off  int64
+}
+
+// newMmapReader maps the file with the size into memory.
+// The file is closed when the returned reader is closed.
+// It returns an error if the file can't be mapped, e.g. it's empty or mmap is not supported.
+func newMmapReader(f *os.File, size int64) (*mmapReader, error) {
+	if size <= 0 || int64(int(size)) != size {
+		return nil, errors.New("invalid size to mmap")
+	}
+
+	data, err := mmap(f, size)
+	if err != nil {
+		return nil, err
+	}
+
+	return &mmapReader{f: f, data: data}, nil
+}
+
+// Read implements [io.Reader] interface.
+func (r *mmapReader) Read(p []byte) (n int, err error) {
+	if r.off >= int64(len(r.data)) {
+		return 0, io.EOF
+	}
+
+	n = copy(p, r.data[r.off:])
+	r.off += int64(n)
+	return n, nil
+}
+
+// ReadAt implements [io.ReaderAt] interface.
+// It's safe to be called by multiple readers, e.g. [WithParallelReads] of [HashTask].
+func (r *mmapReader) ReadAt(p []byte, off int64) (n int, err error) {
+	if off < 0 {
+		return 0, errors.New("negative offset")
+	}
+
+	if off >= int64(len(r.data)) {
+		return 0, io.EOF
+	}
+
+	n = copy(p, r.data[off:])
+	if n < len(p) {
+		err = io.EOF
+	}
+	return n, err
+}
+
+// Seek implements [io.Seeker] interface.
+func (r *mmapReader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += r.off
+	case io.SeekEnd:
+		offset += int64(len(r.data))
+	default:
+		return 0, errors.New("invalid whence")
+	}
+
+	if offset < 0 {
+		return 0, errors.New("negative position")
+	}
+	r.off = offset
+	return offset, nil
+}
+
+// Close implements [io.Closer] interface.
+// It unmaps the memory and closes the file.
+func (r *mmapReader) Close() error {
+	err := munmap(r.data)
+	r.data = nil
+
+	if closeErr := r.f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
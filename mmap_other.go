@@ -0,0 +1,18 @@
+//go:build !unix && !windows
+
+package iocopy
+
+import (
+	"errors"
+	"os"
+)
+
+// mmap is not supported on this platform.
+func mmap(f *os.File, size int64) ([]byte, error) {
+	return nil, errors.ErrUnsupported
+}
+
+// munmap is not supported on this platform.
+func munmap(data []byte) error {
+	return errors.ErrUnsupported
+}
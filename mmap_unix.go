@@ -0,0 +1,18 @@
+//go:build unix
+
+package iocopy
+
+import (
+	"os"
+	"syscall"
+)
+
+// mmap maps the file with the size into memory for reading.
+func mmap(f *os.File, size int64) ([]byte, error) {
+	return syscall.Mmap(int(f.Fd()), 0, int(size), syscall.PROT_READ, syscall.MAP_SHARED)
+}
+
+// munmap unmaps the memory returned by mmap.
+func munmap(data []byte) error {
+	return syscall.Munmap(data)
+}
@@ -0,0 +1,31 @@
+//go:build windows
+
+package iocopy
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// mmap maps the file with the size into memory for reading.
+func mmap(f *os.File, size int64) ([]byte, error) {
+	h, err := syscall.CreateFileMapping(syscall.Handle(f.Fd()), nil, syscall.PAGE_READONLY, uint32(size>>32), uint32(size), nil)
+	if err != nil {
+		return nil, os.NewSyscallError("CreateFileMapping", err)
+	}
+	// The view keeps the mapping alive.
+	defer syscall.CloseHandle(h)
+
+	addr, err := syscall.MapViewOfFile(h, syscall.FILE_MAP_READ, 0, 0, uintptr(size))
+	if err != nil {
+		return nil, os.NewSyscallError("MapViewOfFile", err)
+	}
+
+	return unsafe.Slice(*(**byte)(unsafe.Pointer(&addr)), int(size)), nil
+}
+
+// munmap unmaps the memory returned by mmap.
+func munmap(data []byte) error {
+	return os.NewSyscallError("UnmapViewOfFile", syscall.UnmapViewOfFile(uintptr(unsafe.Pointer(unsafe.SliceData(data)))))
+}
@@ -0,0 +1,36 @@
+package iocopy
+
+import (
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+// multihashCodes are the multihash codes of the hash algorithms.
+// See https://github.com/multiformats/multicodec/blob/master/table.csv.
+var multihashCodes = map[string]uint64{
+	"md5":    0xd5,
+	"sha1":   0x11,
+	"sha256": 0x12,
+	"sha512": 0x13,
+}
+
+// multibaseBase32 is the lower case base32 encoding without padding used by multibase "b".
+var multibaseBase32 = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// Multihash returns the multihash of the digest computed by the hash algorithm, e.g. "sha256",
+// encoded as a multibase base32 string("b" prefix), which is used by IPFS and CID-based systems.
+// Supported algorithms are "md5", "sha1", "sha256" and "sha512".
+func Multihash(alg string, digest []byte) (string, error) {
+	code, ok := multihashCodes[alg]
+	if !ok {
+		return "", fmt.Errorf("no multihash code for hash algorithm: %v", alg)
+	}
+
+	buf := binary.AppendUvarint(nil, code)
+	buf = binary.AppendUvarint(buf, uint64(len(digest)))
+	buf = append(buf, digest...)
+
+	return "b" + strings.ToLower(multibaseBase32.EncodeToString(buf)), nil
+}
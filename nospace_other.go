@@ -0,0 +1,13 @@
+//go:build !windows && !plan9
+
+package iocopy
+
+import (
+	"errors"
+	"syscall"
+)
+
+// isNoSpace reports whether err is caused by a full disk.
+func isNoSpace(err error) bool {
+	return errors.Is(err, syscall.ENOSPC)
+}
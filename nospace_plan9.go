@@ -0,0 +1,9 @@
+//go:build plan9
+
+package iocopy
+
+// isNoSpace always returns false.
+// Errors are strings on Plan 9 and a full disk can't be detected reliably.
+func isNoSpace(err error) bool {
+	return false
+}
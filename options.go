@@ -0,0 +1,508 @@
+package iocopy
+
+import (
+	"context"
+	"hash"
+	"io"
+	"io/fs"
+	"net/http"
+	"runtime"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Option sets optional parameters of tasks.
+// Options which are not supported by a task are ignored.
+type Option func(o *options)
+
+// options contains the optional parameters of tasks.
+type options struct {
+	follow          bool
+	followInterval  time.Duration
+	followStopSize  int64
+	ads             bool
+	owner           bool
+	xattrs          XattrCategory
+	mmap            bool
+	directIO        bool
+	prefetch        bool
+	prefetchDepth   int
+	prefetchSize    int
+	skip            bool
+	skipHash        func() hash.Hash
+	maxBytes        int64
+	cleanup         CleanupPolicy
+	trashDir        string
+	partFile        bool
+	mirror          string
+	mirrorCheckSize int64
+	verifier        SignatureVerifier
+	sig             []byte
+	sigURL          string
+	limiter         *rate.Limiter
+	fair            *FairLimiter
+	fairID          string
+	writeLimiter    *rate.Limiter
+	multihash       bool
+	hashAlgs        []string
+	hashWorkers     int
+	parallelReads   int
+	readAtSize      int
+	method          string
+	body            []byte
+	contentType     string
+	requestHook     func(req *http.Request) error
+	client          *http.Client
+	localAddr       string
+	connections     int
+	workers         int
+	filter          func(name string, d fs.DirEntry) bool
+	storeExts       []string
+	extractLimits   ExtractLimits
+	collision       CollisionPolicy
+	dedupHash       func() hash.Hash
+	reconnect       reconnectPolicy
+	overlap         int64
+	smallSize       int64
+	smallWorkers    int
+	expected        *ExpectedDigest
+	hashCache       *HashCache
+}
+
+// newOptions returns the options with the default values and applies opts.
+func newOptions(opts []Option) options {
+	o := options{followStopSize: -1, maxBytes: -1}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if o.localAddr != "" {
+		o.client = bindClient(o.client, o.localAddr)
+	}
+
+	// The local engines not set by their own options run by the shared number of workers.
+	// The connections of DownloadTask are never set by it, since they load the server.
+	if o.workers > 0 && o.parallelReads == 0 {
+		o.parallelReads = o.workers
+	}
+	return o
+}
+
+// WithFollow makes [CopyFileTask] follow the growing source file like "tail -f".
+// Upon hitting EOF, it waits for more data by polling every interval instead of finishing,
+// until the context is done or stopSize bytes are copied.
+// A negative stopSize means no stop marker size.
+// It fails with [ErrFollowTruncated] or [ErrFollowRotated] when the source file is truncated or rotated.
+// See [FollowReader].
+func WithFollow(interval time.Duration, stopSize int64) Option {
+	return func(o *options) {
+		o.follow = true
+		o.followInterval = interval
+		o.followStopSize = stopSize
+	}
+}
+
+// WithADS makes [CopyFileTask] copy the NTFS alternate data streams of the source file
+// after the default data stream is copied.
+// It only works on Windows when the destination file system is [OSFS].
+func WithADS() Option {
+	return func(o *options) {
+		o.ads = true
+	}
+}
+
+// WithOwner makes [CopyFileTask] and [CopyFSTask] preserve the owners(uid and gid) of the copied files and directories
+// after the copy is done. It needs sufficient privileges(e.g. root or CAP_CHOWN) and it's only supported on unix.
+// Failures don't fail the tasks. They're reported by OwnerErr of [CopyFileResult] and [CopyFSResult] instead.
+// It's ignored if the destination file system is not [OSFS] or the source file system does not report the owners.
+func WithOwner() Option {
+	return func(o *options) {
+		o.owner = true
+	}
+}
+
+// WithXattrs makes [CopyFileTask] and [CopyFSTask] copy the extended attributes of the categories(e.g. [XattrACL])
+// of the copied files and directories after the copy is done. It's only supported on linux.
+// Like [WithOwner], failures are reported by XattrErr of [CopyFileResult] and [CopyFSResult] instead of failing the tasks.
+// It's ignored if the destination file system is not [OSFS] or the source of [CopyFSTask] is not an OS directory(e.g. [os.DirFS]).
+func WithXattrs(c XattrCategory) Option {
+	return func(o *options) {
+		o.xattrs = c
+	}
+}
+
+// WithMmap makes [CopyFileTask] and [HashTask] read the regular source file by memory mapping
+// to reduce the syscall overhead when copying or hashing very large files from fast local storage.
+// The parallel readers of [WithParallelReads] read the mapped memory too.
+// It falls back to read the file if it can't be mapped. It's ignored in follow mode([WithFollow]).
+// The source file must not be truncated while it's mapped.
+func WithMmap() Option {
+	return func(o *options) {
+		o.mmap = true
+	}
+}
+
+// WithDirectIO makes [CopyFileTask] read the regular source file with direct IO(O_DIRECT on Linux)
+// to bypass the page cache, e.g. copying disk images or backups which would evict the hot pages of other processes.
+// The IO copy allocates a buffer by [AlignedBuffer] if the buffer passed to [Do] is not aligned.
+// It falls back to read the file through the page cache if direct IO is not supported by the platform or the file system.
+// It's ignored in follow mode([WithFollow]) or with [WithMmap].
+func WithDirectIO() Option {
+	return func(o *options) {
+		o.directIO = true
+	}
+}
+
+// WithPrefetch makes [CopyFileTask], [DownloadTask] and [HashTask] read ahead of the writer by a [PrefetchReader].
+// It hides the latency of high-latency sources like NFS or HTTP.
+// depth: max number of chunks read ahead. chunkSize: size of each chunk.
+// Default values are used if they are not positive.
+// It's ignored if the source file is memory-mapped([WithMmap]).
+func WithPrefetch(depth, chunkSize int) Option {
+	return func(o *options) {
+		o.prefetch = true
+		o.prefetchDepth = depth
+		o.prefetchSize = chunkSize
+	}
+}
+
+// WithSkipIfMatch makes [CopyFileTask] skip the copy if the destination file exists and matches the source file.
+// Files match if they have the same size and the same checksum computed by newHash.
+// Only sizes are compared if newHash is nil.
+// The task is done without writing and the result has "skipped": true.
+// It only works when the destination file system is [OSFS] and it's ignored in follow mode([WithFollow]).
+func WithSkipIfMatch(newHash func() hash.Hash) Option {
+	return func(o *options) {
+		o.skip = true
+		o.skipHash = newHash
+	}
+}
+
+// WithMaxBytes makes [CopyFileTask] and [DownloadTask] abort with a [*MaxBytesError]
+// once they would exceed n written bytes(including the ones written previously).
+// It's a safety valve against servers lying about Content-Length or runaway responses filling the disk.
+// A negative n means no limit.
+func WithMaxBytes(n int64) Option {
+	return func(o *options) {
+		o.maxBytes = n
+	}
+}
+
+// WithCleanup sets the policy of [CopyFileTask] and [DownloadTask] to clean up the partial destination file
+// when the task fails or is stopped. Default policy is [CleanupKeep].
+// trashDir is the trash directory used by [CleanupTrash].
+// The task restarts from the beginning when it's resumed after the partial file is cleaned up.
+// It only works when the destination file system is [OSFS].
+func WithCleanup(policy CleanupPolicy, trashDir string) Option {
+	return func(o *options) {
+		o.cleanup = policy
+		o.trashDir = trashDir
+	}
+}
+
+// WithPartFile makes [DownloadTask] write to the part file "<dst>.iocopy-part"
+// and save the state to the sidecar state file "<dst>.iocopy-part.json" when it's stopped.
+// The part file is renamed to dst when the download is done.
+// It only works when the destination file system is [OSFS]. See [Download].
+func WithPartFile() Option {
+	return func(o *options) {
+		o.partFile = true
+	}
+}
+
+// WithMirror makes [LoadDownloadTask] resume the download from the mirror url
+// instead of the one in the state.
+// Before continuing, the last checkSize downloaded bytes are re-fetched from the mirror and compared
+// with the ones in the destination file. [DefaultMirrorCheckSize] is used if checkSize is not positive.
+// The task fails with [ErrMirrorMismatch] if they don't match, or [ErrMirrorUnverified] if they can't be compared.
+func WithMirror(url string, checkSize int64) Option {
+	return func(o *options) {
+		o.mirror = url
+		o.mirrorCheckSize = checkSize
+	}
+}
+
+// WithOverlapCheck makes [DownloadTask] and [CopyFileTask] compare the last size bytes of the destination file
+// with the ones re-read from the source before appending on resume,
+// which catches the servers and files changed in ways the size and ETag can't detect.
+// [DownloadTask] re-fetches them by a range request and restarts the download if they don't match([*EventRestarted]).
+// [CopyFileTask] fails with an error wrapping [ErrOverlapMismatch] if they don't match.
+// It only works when the destination file system is [OSFS].
+func WithOverlapCheck(size int64) Option {
+	return func(o *options) {
+		o.overlap = size
+	}
+}
+
+// WithSignature makes [DownloadTask] verify the downloaded file with a detached signature by v
+// after all bytes are written. The task fails with an error wrapping [ErrBadSignature] on mismatch.
+// sig is the signature. It's fetched from sigURL if it's nil.
+// It only works when the destination file system is [OSFS].
+func WithSignature(v SignatureVerifier, sig []byte, sigURL string) Option {
+	return func(o *options) {
+		o.verifier = v
+		o.sig = sig
+		o.sigURL = sigURL
+	}
+}
+
+// WithRateLimiter makes [CopyFileTask], [DownloadTask] and [HashTask] throttle reading the source by l.
+// Each token of l is a byte. l can be shared between tasks and other traffic of the application.
+// See [RateLimitReader]. Use [WithWriteRateLimiter] to throttle writing the destination.
+func WithRateLimiter(l *rate.Limiter) Option {
+	return func(o *options) {
+		o.limiter = l
+	}
+}
+
+// WithFairShare makes [CopyFileTask], [DownloadTask], [CopyFSTask], [HashTask] and [ZipDirTask] throttle reading the source
+// by the fair share of the flow id of fl. The weight of the flow is set by [FairLimiter.SetWeight].
+// It can be used with [WithRateLimiter] to limit the task further.
+func WithFairShare(fl *FairLimiter, id string) Option {
+	return func(o *options) {
+		o.fair = fl
+		o.fairID = id
+	}
+}
+
+// WithWriteRateLimiter makes [CopyFileTask], [DownloadTask], [CopyFSTask] and [ZipDirTask] throttle writing the destination by l
+// independently of reading the source([WithRateLimiter]), e.g. let network reads burst but cap the disk writes.
+// Each token of l is a byte. l can be shared between tasks. See [RateLimitWriter].
+func WithWriteRateLimiter(l *rate.Limiter) Option {
+	return func(o *options) {
+		o.writeLimiter = l
+	}
+}
+
+// limit wraps src by a [RateLimitReader] if [WithRateLimiter] is set and throttles it by the fair share if [WithFairShare] is set.
+func (o *options) limit(ctx context.Context, src io.Reader) io.Reader {
+	if o.limiter != nil {
+		src = NewRateLimitReader(ctx, src, o.limiter)
+	}
+
+	if o.fair != nil {
+		src = &fairReader{ctx: ctx, r: src, fl: o.fair, id: o.fairID}
+	}
+	return src
+}
+
+// throttle wraps dst by a [RateLimitWriter] if [WithWriteRateLimiter] is set.
+func (o *options) throttle(ctx context.Context, dst io.Writer) io.Writer {
+	if o.writeLimiter == nil {
+		return dst
+	}
+	return NewRateLimitWriter(ctx, dst, o.writeLimiter)
+}
+
+// WithMultihash makes [HashTask] add the checksums encoded as multibase multihash strings to the result.
+// It's useful to feed the output into IPFS or CID-based systems. See [Multihash].
+func WithMultihash() Option {
+	return func(o *options) {
+		o.multihash = true
+	}
+}
+
+// WithHash makes [DownloadTask] compute the checksums of the downloaded bytes by the hash algorithms in [HashFuncs]
+// as they stream to the destination, e.g. "sha256".
+// The hex encoded checksums are added to the result, which avoids a second full read of the destination file.
+// The states of the hashes are saved in the state of the task to resume.
+func WithHash(algs ...string) Option {
+	return func(o *options) {
+		o.hashAlgs = algs
+	}
+}
+
+// WithExpectedDigest makes [DownloadTask] and [HashTask] verify the checksum by the hash algorithm in [HashFuncs]
+// against the hex encoded digest when all bytes are copied, e.g. the published checksum of a release.
+// The task fails with an error wrapping [ErrChecksumMismatch] if it does not match.
+// The outcome is reported by Verification of the result, e.g. [DownloadResult].
+// The expected digest is saved in the state, so the tasks loaded from the state still verify it without this option.
+// The bytes are also hashed by alg if it's not set by [WithHash] or the algorithms of [HashTask].
+func WithExpectedDigest(alg, digest string) Option {
+	return func(o *options) {
+		o.expected = &ExpectedDigest{Alg: alg, Digest: digest}
+	}
+}
+
+// WithHashCache makes [HashTask] and [DirVerifier] reuse the checksums of the unchanged files in c
+// instead of reading them, and save the checksums computed to c.
+// The checksums of the standard input and the sources of [NewReaderAtHashTask] are not cached.
+func WithHashCache(c *HashCache) Option {
+	return func(o *options) {
+		o.hashCache = c
+	}
+}
+
+// WithHashWorkers makes [DownloadTask] compute the checksums of [WithHash] by at most n background workers
+// fed by the copies of the downloaded bytes, so the write throughput isn't gated by slow hashes,
+// e.g. SHA-512 on machines without SHA extensions.
+// Each hash is computed by a single worker in order, so more workers than the hash algorithms are not used.
+// Memory is bounded to [DefaultFanOutDepth] * [DefaultFanOutChunkSize].
+func WithHashWorkers(n int) Option {
+	return func(o *options) {
+		o.hashWorkers = n
+	}
+}
+
+// WithParallelReads makes [HashTask] read the source by n readers in parallel with ReadAt
+// and feed the hashes with the bytes in order, so hashing doesn't wait for a single stream of reads,
+// e.g. on network file systems or SSDs with deep queues.
+// chunkSize: size of each read. [DefaultParallelReadSize] is used if it's not positive.
+// Memory is bounded to 2 * n * chunkSize. It's only useful when the reads are slower than the hashes.
+func WithParallelReads(n, chunkSize int) Option {
+	return func(o *options) {
+		o.parallelReads = n
+		o.readAtSize = chunkSize
+	}
+}
+
+// WithMethod makes [DownloadTask] send the requests with the method and body instead of GET,
+// e.g. POST to export or report endpoints which return the file.
+// contentType is the Content-Type header of the body. It's not set if it's empty.
+// The download is resumed by sending the same request with the Range header,
+// and it restarts from the beginning if the server does not support range for the method.
+// The method and body are not saved in the state, so they should be passed to [LoadDownloadTask] again.
+func WithMethod(method string, body []byte, contentType string) Option {
+	return func(o *options) {
+		o.method = method
+		o.body = body
+		o.contentType = contentType
+	}
+}
+
+// WithRequestHook makes [DownloadTask] call fn with every request before it's sent,
+// including the initial request, the ones to resume and the ones to verify mirrors([WithMirror]) or fetch signatures([WithSignature]).
+// It's called after all headers(e.g. Range) are set, so it can sign the request(e.g. AWS SigV4),
+// add tracing headers or refresh tokens. The request is not sent and the task fails if fn returns an error.
+// fn is not saved in the state, so it should be passed to [LoadDownloadTask] again.
+func WithRequestHook(fn func(req *http.Request) error) Option {
+	return func(o *options) {
+		o.requestHook = fn
+	}
+}
+
+// WithHTTPClient makes [DownloadTask] send the requests by c instead of [http.DefaultClient].
+// It allows choosing the transport per task, e.g. an HTTP/3(QUIC) client of github.com/quic-go/quic-go/http3
+// which sustains throughput better on lossy networks:
+//
+//	c := &http.Client{Transport: &http3.Transport{}}
+//	t := iocopy.NewDownloadTask(dst, url, nil, iocopy.WithHTTPClient(c))
+//
+// Resuming works the same with any transport as long as the server supports range.
+// c is not saved in the state, so it should be passed to [LoadDownloadTask] again.
+func WithHTTPClient(c *http.Client) Option {
+	return func(o *options) {
+		o.client = c
+	}
+}
+
+// WithLocalAddr makes [DownloadTask] dial the connections from the local address or network interface,
+// e.g. "192.168.2.10" or "eth1" to route bulk traffic over a secondary NIC of a multi-homed host.
+// The first IPv4 address of the interface is used if any, or its first IPv6 one otherwise.
+// It works with the client set by [WithHTTPClient] if its transport is an [*http.Transport] or nil.
+// It's not saved in the state, so it should be passed to [LoadDownloadTask] again.
+func WithLocalAddr(addr string) Option {
+	return func(o *options) {
+		o.localAddr = addr
+	}
+}
+
+// WithConnections makes [DownloadTask] download the file by n connections in parallel
+// when the server supports range and the size is known.
+// The destination file is preallocated and the missing ranges are split into segments
+// which are written to their positions by WriteAt.
+// The downloaded ranges are saved in the state(Done of [DownloadState]) to resume.
+// The request hook set by [WithRequestHook] may be called concurrently.
+// It falls back to the sequential download if the server does not support range
+// or the destination file system is not [OSFS]. [WithPrefetch] is ignored in parallel mode.
+func WithConnections(n int) Option {
+	return func(o *options) {
+		o.connections = n
+	}
+}
+
+// WithWorkers sets the number of workers of the local parallel engines, e.g. the readers of [HashTask]([WithParallelReads]).
+// The options of the engines override it, e.g. WithWorkers(8) with WithParallelReads(2, 0) hashes by 2 readers.
+// It's independent of [WithConnections]: the connections of [DownloadTask] are set only by WithConnections,
+// since they change the load of the server. The progress of each worker is reported by Workers of [*EventWritten].
+func WithWorkers(n int) Option {
+	return func(o *options) {
+		o.workers = n
+	}
+}
+
+// WithReconnect makes [DownloadTask] re-establish the ranged request inside the same [Do] call
+// when the connection is lost by a network change, e.g. "connection reset" or "unexpected EOF" when Wi-Fi switches to LTE.
+// [Do] reports [*EventReconnect], waits for an exponential backoff from backoff up to maxBackoff(no limit if it's not positive)
+// and reopens the task to resume from the bytes downloaded. It gives up after retries attempts without progress.
+func WithReconnect(retries int, backoff, maxBackoff time.Duration) Option {
+	return func(o *options) {
+		o.reconnect = reconnectPolicy{retries: retries, backoff: backoff, maxBackoff: maxBackoff}
+	}
+}
+
+// WithFilter makes [CopyFSTask], [ZipDirTask] and [ExtractTarTask] copy only the entries for which fn returns true.
+// name is the slash-separated name of the entry in the source file system.
+// If fn returns false for a directory, the directory and its entries are skipped.
+func WithFilter(fn func(name string, d fs.DirEntry) bool) Option {
+	return func(o *options) {
+		o.filter = fn
+	}
+}
+
+// WithStoreExts makes [ZipDirTask] store the files with the extensions(e.g. ".jpg") without compression
+// instead of [DefaultStoreExts]. Other files are deflated. Extensions are case-insensitive.
+func WithStoreExts(exts ...string) Option {
+	return func(o *options) {
+		o.storeExts = exts
+	}
+}
+
+// WithCollisionPolicy sets the policy of [CopyFSTask] for the names which collide on case-insensitive
+// or Unicode-normalizing file systems, e.g. copying from linux to Windows or macOS.
+// The collisions are detected when the source is scanned and reported by [CopyFSResult].
+// Default policy is [CollisionIgnore].
+func WithCollisionPolicy(policy CollisionPolicy) Option {
+	return func(o *options) {
+		o.collision = policy
+	}
+}
+
+// WithHardlinkDedup makes [CopyFSTask] detect the identical files by the checksums computed by newHash while copying them,
+// and replace the duplicate copies with hardlinks to the first ones. The space saved is reported by [CopyFSResult].
+// Files are identical if they have the same size, permission bits and checksum. Empty files are not linked.
+// It only works when the destination file system is [OSFS]. The copies are kept if they fail to be linked,
+// e.g. the file system does not support hardlinks. A file resumed in the middle is not deduplicated.
+func WithHardlinkDedup(newHash func() hash.Hash) Option {
+	return func(o *options) {
+		o.dedupHash = newHash
+	}
+}
+
+// WithSmallFiles makes [CopyFSTask] batch the files not larger than size for trees with many tiny files,
+// where the per-file overhead(open, stat, seek, close...) dominates.
+// The consecutive small files are read by n workers in batches and each worker opens, reads and closes the files of its batch.
+// On [OSFS], they're also written by n workers with one open, write and close per file.
+// [runtime.NumCPU] is used if n is not positive.
+// The progress by file count is reported by Done of [FileProgress] as well as the bytes.
+func WithSmallFiles(size int64, n int) Option {
+	return func(o *options) {
+		if n <= 0 {
+			n = runtime.NumCPU()
+		}
+		o.smallSize = size
+		o.smallWorkers = n
+	}
+}
+
+// WithExtractLimits makes [CopyFSTask] check the files to extract against the limits before copying,
+// e.g. to extract untrusted archives by [zip.Reader].
+// [ExtractTarTask] checks the entries when they're read since the archive is not scanned.
+// The task fails with an [*ExtractLimitError] if a limit is exceeded.
+// Files can't be extracted beyond their sizes checked.
+func WithExtractLimits(l ExtractLimits) Option {
+	return func(o *options) {
+		o.extractLimits = l
+	}
+}
@@ -0,0 +1,68 @@
+package iocopy
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// ErrOverlapMismatch is returned by [CopyFileTask] when the last copied bytes of the destination file
+// do not match the ones of the source file on resume. See [WithOverlapCheck].
+var ErrOverlapMismatch = errors.New("overlapping bytes do not match")
+
+// checkOverlap reads the last copied bytes of the source and destination files and compares them.
+func (t *CopyFileTask) checkOverlap() error {
+	n := min(t.opts.overlap, t.copied)
+	start := t.copied - n
+
+	src, err := readFileAt(t.src, start, n)
+	if err != nil {
+		return err
+	}
+
+	dst, err := readFileAt(t.dst, start, n)
+	if err != nil {
+		return err
+	}
+
+	if !bytes.Equal(src, dst) {
+		return fmt.Errorf("%w: last %v copied bytes of %v changed", ErrOverlapMismatch, n, t.src)
+	}
+	return nil
+}
+
+// readFileAt reads n bytes of the file from the offset.
+func readFileAt(name string, off, n int64) ([]byte, error) {
+	f, err := os.Open(longPath(name))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	buf := make([]byte, n)
+	if _, err = f.ReadAt(buf, off); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return buf, nil
+}
+
+// checkOverlap re-fetches the last downloaded bytes and restarts the download if they don't match the ones in the destination file.
+func (t *DownloadTask) checkOverlap(ctx context.Context) error {
+	reason, err := t.compareTail(ctx, t.opts.overlap)
+	switch {
+	case errors.Is(err, errNoPartialContent):
+		// The download restarts if the server does not support range any more.
+		logDebug(ctx, "iocopy: overlap not checked", "url", t.url, "err", err)
+	case err != nil:
+		return err
+	case reason != "":
+		t.restart(ctx, "overlap mismatch: "+reason)
+	}
+	return nil
+}
@@ -0,0 +1,13 @@
+//go:build !unix
+
+package iocopy
+
+import (
+	"errors"
+	"io/fs"
+)
+
+// preserveOwner is only implemented on unix.
+func preserveOwner(dst string, fi fs.FileInfo) error {
+	return errors.ErrUnsupported
+}
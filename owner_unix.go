@@ -0,0 +1,20 @@
+//go:build unix
+
+package iocopy
+
+import (
+	"io/fs"
+	"os"
+	"syscall"
+)
+
+// preserveOwner sets the owner(uid and gid) of dst to the one of the source file info.
+// It does nothing if the source file system does not report the owner, e.g. [zip.Reader].
+// It does not follow symbolic links.
+func preserveOwner(dst string, fi fs.FileInfo) error {
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return nil
+	}
+	return os.Lchown(longPath(dst), int(st.Uid), int(st.Gid))
+}
@@ -0,0 +1,57 @@
+//go:build unix
+
+package iocopy_test
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"syscall"
+
+	"github.com/northbright/iocopy"
+)
+
+func ExampleWithOwner() {
+	// This example copies a directory and preserves the owners of the files and directories.
+	// Changing the owners to other users needs root, but it always succeeds for the current user.
+	dir, err := os.MkdirTemp("", "iocopy")
+	if err != nil {
+		log.Printf("os.MkdirTemp() error: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	src := filepath.Join(dir, "src")
+	if err = os.MkdirAll(filepath.Join(src, "sub"), 0755); err != nil {
+		log.Printf("os.MkdirAll() error: %v", err)
+		return
+	}
+	if err = os.WriteFile(filepath.Join(src, "sub", "a.txt"), []byte("hello"), 0644); err != nil {
+		log.Printf("os.WriteFile() error: %v", err)
+		return
+	}
+
+	dst := filepath.Join(dir, "dst")
+	t := iocopy.NewCopyFSTask(dst, os.DirFS(src), nil, iocopy.WithOwner())
+	if err = iocopy.Do(context.Background(), t, nil, nil); err != nil {
+		log.Printf("iocopy.Do() error: %v", err)
+		return
+	}
+
+	r := t.ResultValue().(iocopy.CopyFSResult)
+	fmt.Printf("files: %v, owner errors: %v\n", r.Files, r.OwnerErrs)
+
+	fi, err := os.Stat(filepath.Join(dst, "sub", "a.txt"))
+	if err != nil {
+		log.Printf("os.Stat() error: %v", err)
+		return
+	}
+	st := fi.Sys().(*syscall.Stat_t)
+	fmt.Printf("same owner: %v\n", int(st.Uid) == os.Getuid())
+
+	// Output:
+	// files: 1, owner errors: 0
+	// same owner: true
+}
@@ -0,0 +1,243 @@
+package iocopy
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+)
+
+const (
+	// minSegmentSize is the min size of the segments split by [WithConnections].
+	minSegmentSize = 64 * 1024
+	// chunkSize is the size of the chunks read by the connections.
+	chunkSize = 32 * 1024
+	// chunksPerConn is the number of chunks read ahead by each connection.
+	chunksPerConn = 4
+)
+
+// splitRanges splits the ranges into at least n segments if they are large enough.
+// The largest segment is split in half until there're n segments or they're smaller than 2 * minSize.
+func splitRanges(ranges RangeSet, n int, minSize int64) []Range {
+	segs := slices.Clone([]Range(ranges))
+	for len(segs) < n {
+		i := 0
+		for j := range segs {
+			if segs[j].Size() > segs[i].Size() {
+				i = j
+			}
+		}
+
+		r := segs[i]
+		if r.Size() < 2*minSize {
+			break
+		}
+
+		mid := r.Start + r.Size()/2
+		segs[i].End = mid
+		segs = slices.Insert(segs, i+1, Range{Start: mid, End: r.End})
+	}
+	return segs
+}
+
+// chunk is the bytes read at the offset of the file.
+// buf is the whole buffer of b to reuse after b is read.
+type chunk struct {
+	off int64
+	b   []byte
+	buf []byte
+}
+
+// parallelReader downloads the segments by multiple connections.
+// It reads the chunks in the order they arrive and records their offsets,
+// so [parallelWriter] writes them to the right positions.
+// The chunk buffers are reserved from the memory budget set by [SetMemoryBudget] and reused.
+type parallelReader struct {
+	pool   *workerPool
+	chunks chan chunk
+	free   chan []byte
+	// reserved is the memory of the chunk buffers reserved from the budget.
+	reserved int64
+	// cur is the chunk being read.
+	cur chunk
+	// spans are the ranges of the bytes read but not written yet.
+	spans []Range
+}
+
+// newParallelReader starts n connections to download the segments.
+// The response of the first segment should be t.resp.
+// The number of chunk buffers is reduced to fit the memory budget, but there's at least one.
+func newParallelReader(ctx context.Context, t *DownloadTask, segs []Range, n int) *parallelReader {
+	n = min(n, len(segs))
+
+	reserved := budget.tryAcquire(int64(n*chunksPerConn)*chunkSize, chunkSize)
+	depth := int(reserved / chunkSize)
+
+	pr := &parallelReader{
+		chunks:   make(chan chunk, depth),
+		free:     make(chan []byte, depth),
+		reserved: reserved,
+		pool:     newWorkerPool(ctx, n),
+	}
+
+	for i := 0; i < depth; i++ {
+		pr.free <- make([]byte, chunkSize)
+	}
+
+	queue := make(chan Range, len(segs))
+	for _, seg := range segs[1:] {
+		queue <- seg
+	}
+	close(queue)
+
+	pr.pool.start(func(ctx context.Context, worker int) error {
+		// The first connection reads the first segment from the response.
+		if worker == 0 {
+			if err := pr.fetch(ctx, worker, segs[0], t.resp.Body); err != nil {
+				return err
+			}
+		}
+		return pr.work(ctx, worker, t, queue)
+	})
+
+	go func() {
+		<-pr.pool.Done()
+		close(pr.chunks)
+	}()
+
+	return pr
+}
+
+// work downloads the segments in queue by the worker.
+func (pr *parallelReader) work(ctx context.Context, worker int, t *DownloadTask, queue <-chan Range) error {
+	for seg := range queue {
+		req, err := t.newRequest(ctx)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("range", rangeHeader(seg))
+
+		resp, err := t.do(req)
+		if err != nil {
+			return err
+		}
+
+		if resp.StatusCode != http.StatusPartialContent {
+			resp.Body.Close()
+			return fmt.Errorf("unexpected status of range %v: %v", rangeHeader(seg), resp.Status)
+		}
+
+		if err = pr.fetch(ctx, worker, seg, resp.Body); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fetch reads the segment from body by chunks and closes body.
+// It waits for a free chunk buffer before reading each chunk.
+func (pr *parallelReader) fetch(ctx context.Context, worker int, seg Range, body io.ReadCloser) error {
+	defer body.Close()
+
+	for off := seg.Start; off < seg.End; {
+		var buf []byte
+		select {
+		case buf = <-pr.free:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		n, err := io.ReadFull(body, buf[:min(chunkSize, seg.End-off)])
+		if n > 0 {
+			select {
+			case pr.chunks <- chunk{off: off, b: buf[:n], buf: buf}:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			pr.pool.add(worker, int64(n))
+			off += int64(n)
+		} else {
+			pr.free <- buf
+		}
+
+		if err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// Read implements [io.Reader] interface.
+// It returns the first error of the connections after the chunks which arrived are read.
+func (pr *parallelReader) Read(p []byte) (int, error) {
+	for len(pr.cur.b) == 0 {
+		c, ok := <-pr.chunks
+		if !ok {
+			if err := pr.pool.Err(); err != nil {
+				return 0, err
+			}
+			return 0, io.EOF
+		}
+		pr.cur = c
+	}
+
+	n := copy(p, pr.cur.b)
+	pr.spans = append(pr.spans, Range{Start: pr.cur.off, End: pr.cur.off + int64(n)})
+	pr.cur.off += int64(n)
+	pr.cur.b = pr.cur.b[n:]
+	if len(pr.cur.b) == 0 {
+		pr.free <- pr.cur.buf
+		pr.cur = chunk{}
+	}
+	return n, nil
+}
+
+// Close stops the connections, waits for them to exit and releases the chunk buffers.
+func (pr *parallelReader) Close() error {
+	pr.pool.Close()
+
+	if pr.reserved > 0 {
+		budget.release(pr.reserved)
+		pr.reserved = 0
+	}
+	return nil
+}
+
+// parallelWriter writes the bytes read from [parallelReader] to their offsets of f
+// and adds the written bytes to the downloaded ranges.
+// The digests of the segments are computed by sh.
+type parallelWriter struct {
+	pr     *parallelReader
+	f      io.WriterAt
+	ranges *RangeSet
+	sh     *segmentHashes
+}
+
+// Write implements [io.Writer] interface.
+func (pw *parallelWriter) Write(p []byte) (n int, err error) {
+	for len(p) > 0 {
+		if len(pw.pr.spans) == 0 {
+			return n, fmt.Errorf("bytes written are not read from the connections")
+		}
+
+		s := &pw.pr.spans[0]
+		m, err := pw.f.WriteAt(p[:min(int64(len(p)), s.Size())], s.Start)
+		pw.ranges.Add(s.Start, s.Start+int64(m))
+		pw.sh.write(p[:m], s.Start)
+		s.Start += int64(m)
+		n += m
+		p = p[m:]
+		if err != nil {
+			return n, err
+		}
+
+		if s.Size() == 0 {
+			pw.pr.spans = pw.pr.spans[1:]
+		}
+	}
+	return n, nil
+}
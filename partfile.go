@@ -0,0 +1,148 @@
+package iocopy
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+)
+
+const (
+	// PartFileExt is the extension of the part file written by [DownloadTask] with [WithPartFile].
+	PartFileExt = ".iocopy-part"
+	// PartStateExt is the extension of the sidecar state file of the part file.
+	PartStateExt = PartFileExt + ".json"
+)
+
+// commitWriter calls fn when it's committed by [Do].
+// It forwards Commit to the writer first if it implements [Committer].
+type commitWriter struct {
+	io.Writer
+	fn func() error
+}
+
+// Commit implements [Committer] interface.
+func (cw *commitWriter) Commit() error {
+	if c, ok := cw.Writer.(Committer); ok {
+		if err := c.Commit(); err != nil {
+			return err
+		}
+	}
+	return cw.fn()
+}
+
+// usePartFile reports whether the task writes to the part file.
+func (t *DownloadTask) usePartFile() bool {
+	return t.opts.partFile && t.fsys == OSFS && !isStdout(t.dst)
+}
+
+// dstName returns the name of the file to write.
+// It's the part file if [WithPartFile] is set.
+func (t *DownloadTask) dstName() string {
+	if t.usePartFile() {
+		return t.dst + PartFileExt
+	}
+	return t.dst
+}
+
+// closePartFile renames the part file to the destination and removes the sidecar state file if the download is done.
+// Otherwise, it saves the state to the sidecar state file to resume.
+func (t *DownloadTask) closePartFile() error {
+	return closePart(t.dst, t.done, t.State)
+}
+
+// closePart renames the part file of dst to dst and removes the sidecar state file if done is true.
+// Otherwise, it saves the state returned by state to the sidecar state file to resume.
+func closePart(dst string, done bool, state func() ([]byte, error)) error {
+	stateFile := longPath(dst + PartStateExt)
+
+	if !done {
+		buf, err := state()
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(stateFile, buf, 0644)
+	}
+
+	if err := os.Rename(longPath(dst+PartFileExt), longPath(dst)); err != nil {
+		return err
+	}
+
+	if err := os.Remove(stateFile); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// Download downloads url to dst on the OS file system and reports the events by fn like [Do].
+// It writes to the part file "<dst>.iocopy-part" with the sidecar state file "<dst>.iocopy-part.json"
+// and renames the part file to dst when it's done, like browsers and wget.
+// If the part file and its state file exist, it resumes the download automatically.
+// url can also be a file:// url of a local file(e.g. a local cache in a list of mirrors),
+// which is copied by [CopyFileTask] to the part file in the same way and [*EventOK] reports the [CopyFileResult].
+// It can also be a data: url(RFC 2397) of a small embedded asset or for testing, and the payload is decoded to the part file.
+// buf is the buffer used for IO copy. A default buffer is used if it's nil.
+// dst can be the standard output([Stdio]) which is streamed without the part file and can't be resumed.
+// opts: optional parameters of [DownloadTask] or [CopyFileTask]. [WithPartFile] is always set.
+func Download(ctx context.Context, dst, url string, buf []byte, fn OnEventFunc, opts ...Option) error {
+	dt, ok, err := newDataURLTask(dst, url, opts)
+	if err != nil {
+		return err
+	}
+	if ok {
+		return Do(ctx, dt, buf, fn)
+	}
+
+	src, ok, err := fileURLPath(url)
+	if err != nil {
+		return err
+	}
+	if ok {
+		return Do(ctx, newFileURLTask(dst, url, src, opts), buf, fn)
+	}
+
+	if isStdout(dst) {
+		// Stream to the standard output without the part file.
+		return Do(ctx, NewDownloadTask(dst, url, nil, opts...), buf, fn)
+	}
+
+	opts = append(opts, WithPartFile())
+
+	t, err := loadPartFile(dst, url, opts)
+	if err != nil {
+		return err
+	}
+
+	if t == nil {
+		t = NewDownloadTask(dst, url, nil, opts...)
+	}
+
+	return Do(ctx, t, buf, fn)
+}
+
+// loadPartFile loads the [*DownloadTask] from the sidecar state file of the part file to resume.
+// It returns nil if there's no valid part file to resume.
+func loadPartFile(dst, url string, opts []Option) (*DownloadTask, error) {
+	state, err := os.ReadFile(longPath(dst + PartStateExt))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	t, err := LoadDownloadTask(state, nil, opts...)
+	if err != nil || t.dst != dst || t.url != url {
+		// Invalid or stale state file.
+		return nil, nil
+	}
+
+	// The part file should contain the downloaded bytes.
+	fi, err := os.Stat(longPath(dst + PartFileExt))
+	if err != nil || fi.Size() < t.copied {
+		return nil, nil
+	}
+
+	return t, nil
+}
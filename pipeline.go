@@ -0,0 +1,348 @@
+package iocopy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Pipeline declares the steps of [Watcher] to process each file:
+// copy it to a staging directory, hash it, verify the checksum against a sidecar file and move it to the destination.
+// The steps run as chained tasks by one [PipelineTask] with a single progress stream.
+type Pipeline struct {
+	// Staging is the staging directory. It should be on the same file system as the destination to move the files by renaming.
+	Staging string
+	// Alg is the hash algorithm in [HashFuncs] to hash the staged files, e.g. "sha256". The files are not hashed if it's empty.
+	Alg string
+	// SidecarExt is the extension of the sidecar files which contain the expected hex encoded checksums, e.g. ".sha256".
+	// The sidecar of "a.txt" is "a.txt.sha256" in the same directory and it can be the output of sha256sum.
+	// The files are processed after their sidecars exist and the sidecars are not copied.
+	// The files are not verified if it's empty. It's ignored if Alg is empty.
+	SidecarExt string
+}
+
+// sidecar returns the sidecar of the file or an empty string if the files are not verified.
+func (p *Pipeline) sidecar(file string) string {
+	if p.Alg == "" || p.SidecarExt == "" {
+		return ""
+	}
+	return file + p.SidecarExt
+}
+
+// isSidecar reports whether the file is a sidecar.
+func (p *Pipeline) isSidecar(file string) bool {
+	return p.Alg != "" && p.SidecarExt != "" && strings.HasSuffix(file, p.SidecarExt)
+}
+
+// PipelineResult is the typed result of [PipelineTask].
+type PipelineResult struct {
+	// Src is the source file.
+	Src string `json:"src"`
+	// Dst is the destination file which the staged file is moved to.
+	Dst string `json:"dst"`
+	// Alg is the hash algorithm. It's empty if the file is not hashed.
+	Alg string `json:"alg,omitempty"`
+	// Checksum is the hex encoded checksum of the staged file.
+	Checksum string `json:"checksum,omitempty"`
+	// Verified is true if the checksum matches the sidecar.
+	Verified bool `json:"verified"`
+}
+
+// pipelineState is the state of [PipelineTask].
+type pipelineState struct {
+	Version    int               `json:"version"`
+	Type       string            `json:"type"`
+	Src        string            `json:"src"`
+	Dst        string            `json:"dst"`
+	Staged     string            `json:"staged"`
+	Staging    string            `json:"staging"`
+	Alg        string            `json:"alg,omitempty"`
+	SidecarExt string            `json:"sidecar_ext,omitempty"`
+	Total      int64             `json:"total"`
+	Step       int               `json:"step"`
+	Done       int64             `json:"done"`
+	Copied     int64             `json:"copied"`
+	Steps      []json.RawMessage `json:"steps"`
+}
+
+// PipelineTask runs the steps of a [Pipeline] for a file as chained tasks:
+// [CopyFileTask] copies the file to the staging directory and [HashTask] hashes the staged file.
+// Then the checksum is verified against the sidecar and the staged file is moved to the destination.
+// Total and the progress are the sums of the steps.
+// If the checksum does not match, it fails with an error wrapping [ErrChecksumMismatch] and the staged file is kept.
+type PipelineTask struct {
+	src, dst string
+	p        Pipeline
+	staged   string
+	total    int64
+	copied   int64
+	// steps are the chained tasks and cur is the index of the running one.
+	steps []Task
+	cur   int
+	// done is the sum of the bytes copied by the finished steps.
+	done int64
+	// ctx is used to open the next steps.
+	ctx context.Context
+	// stepDst and stepSrc are opened by the running step and written is the bytes written to stepDst.
+	stepDst  io.Writer
+	stepSrc  io.Reader
+	written  int64
+	checksum string
+	verified bool
+}
+
+// NewPipelineTask returns a [*PipelineTask] which processes the file src by the pipeline and moves it to dst.
+// name is the relative path of the staged file in the staging directory.
+// opts: optional parameters of the copy step, e.g. [WithRateLimiter].
+func NewPipelineTask(dst, src, name string, p Pipeline, opts ...Option) *PipelineTask {
+	t := &PipelineTask{src: src, dst: dst, p: p, staged: filepath.Join(p.Staging, name)}
+	t.steps = append(t.steps, NewCopyFileTask(t.staged, src, nil, opts...))
+	if p.Alg != "" {
+		t.steps = append(t.steps, NewHashTask(t.staged, []string{p.Alg}))
+	}
+	return t
+}
+
+// LoadPipelineTask loads a [*PipelineTask] from the state to resume the running step.
+// opts: optional parameters of the copy step which are not saved in the state.
+func LoadPipelineTask(state []byte, opts ...Option) (*PipelineTask, error) {
+	var s pipelineState
+	if err := json.Unmarshal(state, &s); err != nil {
+		return nil, err
+	}
+
+	if err := (stateHeader{s.Version, s.Type}).check(StateTypePipeline); err != nil {
+		return nil, err
+	}
+
+	p := Pipeline{Staging: s.Staging, Alg: s.Alg, SidecarExt: s.SidecarExt}
+	steps := 1
+	if p.Alg != "" {
+		steps++
+	}
+	if len(s.Steps) != steps {
+		return nil, fmt.Errorf("pipeline state has %v steps, expected: %v", len(s.Steps), steps)
+	}
+
+	t := &PipelineTask{src: s.Src, dst: s.Dst, p: p, staged: s.Staged, total: s.Total, copied: s.Copied, cur: s.Step, done: s.Done}
+	cp, err := LoadCopyFileTask(s.Steps[0], nil, opts...)
+	if err != nil {
+		return nil, err
+	}
+	t.steps = append(t.steps, cp)
+
+	if p.Alg != "" {
+		h, err := LoadHashTask(s.Steps[1])
+		if err != nil {
+			return nil, err
+		}
+		t.steps = append(t.steps, h)
+	}
+	return t, nil
+}
+
+// Endpoints implements [Endpointer] interface.
+func (t *PipelineTask) Endpoints() (src, dst string) {
+	return t.src, t.dst
+}
+
+// Open implements [Task] interface.
+// It opens the running step. The next steps are opened when the previous ones are done.
+func (t *PipelineTask) Open(ctx context.Context) (dst io.Writer, src io.Reader, err error) {
+	if t.cur == 0 && t.copied == 0 {
+		fi, err := os.Stat(longPath(t.src))
+		if err != nil {
+			return nil, nil, err
+		}
+		t.total = fi.Size() * int64(len(t.steps))
+
+		if err = os.MkdirAll(longPath(filepath.Dir(t.staged)), 0755); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	t.ctx = ctx
+	if err = t.openStep(); err != nil {
+		return nil, nil, err
+	}
+	return &pipelineWriter{t}, &pipelineReader{t}, nil
+}
+
+// openStep opens the running step if any.
+func (t *PipelineTask) openStep() (err error) {
+	if t.cur >= len(t.steps) {
+		return nil
+	}
+
+	step := t.steps[t.cur]
+	step.SetCopied(t.copied - t.done)
+	if t.stepDst, t.stepSrc, err = step.Open(t.ctx); err != nil {
+		return err
+	}
+	t.written = 0
+	return nil
+}
+
+// nextStep commits and closes the running step and opens the next one.
+func (t *PipelineTask) nextStep() error {
+	step := t.steps[t.cur]
+	step.SetCopied(step.Copied() + t.written)
+
+	var err error
+	if c, ok := t.stepDst.(Committer); ok {
+		err = c.Commit()
+	}
+
+	t.stepDst, t.stepSrc = nil, nil
+	if closeErr := step.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	t.done += step.Copied()
+	t.copied = t.done
+	t.cur++
+	return t.openStep()
+}
+
+// finish verifies the checksum of the staged file against the sidecar and moves it to the destination.
+func (t *PipelineTask) finish() error {
+	if t.p.Alg != "" {
+		checksums, _ := t.steps[len(t.steps)-1].(*HashTask).Checksums()
+		t.checksum = checksums[t.p.Alg]
+
+		if sidecar := t.p.sidecar(t.src); sidecar != "" {
+			buf, err := os.ReadFile(longPath(sidecar))
+			if err != nil {
+				return err
+			}
+
+			fields := strings.Fields(string(buf))
+			if len(fields) == 0 || !strings.EqualFold(fields[0], t.checksum) {
+				return fmt.Errorf("%w: %v of %v", ErrChecksumMismatch, t.p.Alg, t.src)
+			}
+			t.verified = true
+		}
+	}
+
+	if err := os.MkdirAll(longPath(filepath.Dir(t.dst)), 0755); err != nil {
+		return err
+	}
+	return os.Rename(longPath(t.staged), longPath(t.dst))
+}
+
+// pipelineReader reads the sources of the steps in order.
+type pipelineReader struct {
+	t *PipelineTask
+}
+
+// Read implements [io.Reader] interface.
+// The bytes read are written before the next Read, so the running step is done when its source reaches EOF.
+func (r *pipelineReader) Read(p []byte) (int, error) {
+	for r.t.stepSrc != nil {
+		n, err := r.t.stepSrc.Read(p)
+		if err != io.EOF {
+			return n, err
+		}
+		if n > 0 {
+			return n, nil
+		}
+
+		if err = r.t.nextStep(); err != nil {
+			return 0, err
+		}
+	}
+	return 0, io.EOF
+}
+
+// pipelineWriter writes the bytes to the destination of the running step.
+type pipelineWriter struct {
+	t *PipelineTask
+}
+
+// Write implements [io.Writer] interface.
+func (w *pipelineWriter) Write(p []byte) (int, error) {
+	if w.t.stepDst == nil {
+		return 0, fmt.Errorf("no running step of pipeline")
+	}
+
+	n, err := w.t.stepDst.Write(p)
+	w.t.written += int64(n)
+	return n, err
+}
+
+// Commit implements [Committer] interface.
+// It's called after all steps are done to verify and move the staged file.
+func (w *pipelineWriter) Commit() error {
+	return w.t.finish()
+}
+
+// Close implements [Task] interface. It closes the running step.
+func (t *PipelineTask) Close() error {
+	if t.cur >= len(t.steps) || t.stepSrc == nil {
+		return nil
+	}
+
+	step := t.steps[t.cur]
+	step.SetCopied(step.Copied() + t.written)
+	t.stepDst, t.stepSrc, t.written = nil, nil, 0
+	return step.Close()
+}
+
+// Total implements [Task] interface.
+func (t *PipelineTask) Total() int64 {
+	return t.total
+}
+
+// Copied implements [Task] interface.
+func (t *PipelineTask) Copied() int64 {
+	return t.copied
+}
+
+// SetCopied implements [Task] interface.
+func (t *PipelineTask) SetCopied(copied int64) {
+	t.copied = copied
+}
+
+// State implements [Task] interface.
+// It contains the states of the steps. The running step is resumed when the task is opened again.
+func (t *PipelineTask) State() ([]byte, error) {
+	s := pipelineState{
+		Version:    StateVersion,
+		Type:       StateTypePipeline,
+		Src:        t.src,
+		Dst:        t.dst,
+		Staged:     t.staged,
+		Staging:    t.p.Staging,
+		Alg:        t.p.Alg,
+		SidecarExt: t.p.SidecarExt,
+		Total:      t.total,
+		Step:       t.cur,
+		Done:       t.done,
+		Copied:     t.copied,
+	}
+	for _, step := range t.steps {
+		state, err := step.State()
+		if err != nil {
+			return nil, err
+		}
+		s.Steps = append(s.Steps, state)
+	}
+	return json.Marshal(s)
+}
+
+// Result implements [Task] interface.
+func (t *PipelineTask) Result() ([]byte, error) {
+	return json.Marshal(t.ResultValue())
+}
+
+// ResultValue implements [ResultValuer] interface. It returns the [PipelineResult].
+func (t *PipelineTask) ResultValue() any {
+	return PipelineResult{Src: t.src, Dst: t.dst, Alg: t.p.Alg, Checksum: t.checksum, Verified: t.verified}
+}
@@ -0,0 +1,145 @@
+package iocopy
+
+import (
+	"context"
+	"io"
+	"sync"
+)
+
+const (
+	// DefaultPrefetchDepth is the default number of chunks read ahead by [PrefetchReader].
+	DefaultPrefetchDepth = 4
+	// DefaultPrefetchChunkSize is the default size of chunks read by [PrefetchReader].
+	DefaultPrefetchChunkSize = 32 * 1024
+)
+
+// prefetchChunk is a chunk read by [PrefetchReader].
+type prefetchChunk struct {
+	buf []byte
+	n   int
+	err error
+}
+
+// PrefetchReader reads ahead of the consumer in a separate goroutine.
+// It hides the latency of high-latency sources like NFS or HTTP.
+// Memory is bounded to depth * chunk size.
+type PrefetchReader struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	chunks chan prefetchChunk
+	free   chan []byte
+	cur    prefetchChunk
+	off    int
+	// reserved is the memory reserved from the budget set by [SetMemoryBudget].
+	reserved int64
+	// done is closed when the goroutine exits.
+	done chan struct{}
+	once sync.Once
+}
+
+// NewPrefetchReader returns a [*PrefetchReader] which reads from r in a new goroutine.
+// ctx: the goroutine exits and Read returns ctx.Err() when ctx is done.
+// depth: max number of chunks read ahead. [DefaultPrefetchDepth] is used if it's not positive.
+// chunkSize: size of each chunk. [DefaultPrefetchChunkSize] is used if it's not positive.
+// The depth is reduced to fit the memory budget set by [SetMemoryBudget].
+// Call Close to stop the goroutine when it's not used.
+func NewPrefetchReader(ctx context.Context, r io.Reader, depth, chunkSize int) *PrefetchReader {
+	if depth <= 0 {
+		depth = DefaultPrefetchDepth
+	}
+
+	if chunkSize <= 0 {
+		chunkSize = DefaultPrefetchChunkSize
+	}
+
+	reserved := budget.tryAcquire(int64(depth)*int64(chunkSize), int64(chunkSize))
+	depth = int(reserved / int64(chunkSize))
+
+	ctx, cancel := context.WithCancel(ctx)
+	pr := &PrefetchReader{
+		ctx:      ctx,
+		cancel:   cancel,
+		chunks:   make(chan prefetchChunk, depth),
+		free:     make(chan []byte, depth),
+		reserved: reserved,
+		done:     make(chan struct{}),
+	}
+
+	for i := 0; i < depth; i++ {
+		pr.free <- make([]byte, chunkSize)
+	}
+
+	go pr.prefetch(r)
+	return pr
+}
+
+// prefetch reads chunks from r until an error occurs or ctx is done.
+func (pr *PrefetchReader) prefetch(r io.Reader) {
+	defer close(pr.done)
+
+	for {
+		var buf []byte
+		select {
+		case <-pr.ctx.Done():
+			return
+		case buf = <-pr.free:
+		}
+
+		n, err := r.Read(buf)
+		select {
+		case <-pr.ctx.Done():
+			return
+		case pr.chunks <- prefetchChunk{buf: buf, n: n, err: err}:
+		}
+
+		if err != nil {
+			return
+		}
+	}
+}
+
+// Read implements [io.Reader] interface.
+func (pr *PrefetchReader) Read(p []byte) (n int, err error) {
+	for pr.off >= pr.cur.n {
+		if pr.cur.err != nil {
+			return 0, pr.cur.err
+		}
+
+		// Recycle the consumed chunk.
+		if pr.cur.buf != nil {
+			pr.free <- pr.cur.buf
+		}
+
+		select {
+		case <-pr.ctx.Done():
+			return 0, pr.ctx.Err()
+		case pr.cur = <-pr.chunks:
+			pr.off = 0
+		}
+	}
+
+	n = copy(p, pr.cur.buf[pr.off:pr.cur.n])
+	pr.off += n
+	return n, nil
+}
+
+// Close implements [io.Closer] interface.
+// It stops the goroutine. It does not close the underlying reader.
+// The goroutine may still be blocked in reading the underlying reader until it returns,
+// so close the underlying reader after Close to unblock it.
+// The memory reserved from the budget is released after the goroutine exits since it may still hold a chunk.
+func (pr *PrefetchReader) Close() error {
+	pr.cancel()
+	pr.once.Do(func() {
+		select {
+		case <-pr.done:
+			budget.release(pr.reserved)
+		default:
+			go func() {
+				<-pr.done
+				budget.release(pr.reserved)
+			}()
+		}
+	})
+	return nil
+}
@@ -0,0 +1,45 @@
+package iocopy_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"time"
+
+	"github.com/northbright/iocopy"
+)
+
+// slowReader emulates a high-latency source.
+type slowReader struct {
+	r     io.Reader
+	delay time.Duration
+}
+
+func (sr *slowReader) Read(p []byte) (n int, err error) {
+	time.Sleep(sr.delay)
+	return sr.r.Read(p)
+}
+
+func ExamplePrefetchReader() {
+	// This example reads a high-latency source by a PrefetchReader.
+	// It reads ahead up to 4 chunks of 1024 bytes while the writer is busy.
+	data := bytes.Repeat([]byte("0123456789abcdef"), 1024)
+	src := &slowReader{r: bytes.NewReader(data), delay: time.Millisecond}
+
+	pr := iocopy.NewPrefetchReader(context.Background(), src, 4, 1024)
+	defer pr.Close()
+
+	var dst bytes.Buffer
+	n, err := iocopy.Copy(context.Background(), &dst, pr)
+	if err != nil {
+		log.Printf("iocopy.Copy() error: %v", err)
+		return
+	}
+
+	fmt.Printf("%v bytes copied, same content: %v\n", n, bytes.Equal(dst.Bytes(), data))
+
+	// Output:
+	// 16384 bytes copied, same content: true
+}
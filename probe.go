@@ -0,0 +1,158 @@
+package iocopy
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// EventRestarted is reported by [Do] when the task restarts from the beginning and discards the bytes copied,
+// e.g. [DownloadTask] restarts when the remote file changed. See [Restarter].
+type EventRestarted struct {
+	// Reason is why the task restarts.
+	Reason string
+	// Discarded is the number of the bytes copied previously which are discarded.
+	Discarded int64
+}
+
+func (e *EventRestarted) event() {}
+
+// Restarter is implemented by tasks which may restart from the beginning when they're opened,
+// e.g. [*DownloadTask]. Do reports [*EventRestarted] after Open if the task restarts.
+type Restarter interface {
+	// Restarted returns the reason why the task restarts when it's opened and the number of the bytes discarded.
+	// The reason is empty if it does not restart. It's reset when the task is closed.
+	Restarted() (reason string, discarded int64)
+}
+
+// remoteInfo is the information of the remote file detected by probing.
+type remoteInfo struct {
+	// size is the size of the remote file. It's -1 if it's unknown.
+	size int64
+	// noRanges is true if the server reports that it does not support range.
+	noRanges bool
+	// etag is the ETag of the remote file. It's empty if it's unknown.
+	etag string
+}
+
+// probe detects the size of the remote file and whether the server supports range by a HEAD request.
+// If the server rejects HEAD requests(403, 405 or 501), it probes by a GET request of the first byte instead.
+func (t *DownloadTask) probe(ctx context.Context) (remoteInfo, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, t.url, nil)
+	if err != nil {
+		return remoteInfo{}, err
+	}
+
+	resp, err := t.do(req)
+	if err != nil {
+		return remoteInfo{}, err
+	}
+	resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusForbidden, http.StatusMethodNotAllowed, http.StatusNotImplemented:
+		logDebug(ctx, "iocopy: HEAD rejected, probe by range request", "url", t.url, "status", resp.StatusCode)
+		return t.probeRange(ctx)
+	default:
+		return remoteInfo{}, fmt.Errorf("unexpected status of probe: %v", resp.Status)
+	}
+
+	return remoteInfo{
+		size:     resp.ContentLength,
+		noRanges: strings.EqualFold(resp.Header.Get("accept-ranges"), "none"),
+		etag:     resp.Header.Get("etag"),
+	}, nil
+}
+
+// probeRange probes the remote file by a GET request with "range: bytes=0-0".
+// If the server sends the whole file(200) instead, it does not support range and the body is closed without reading it.
+func (t *DownloadTask) probeRange(ctx context.Context) (remoteInfo, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, t.url, nil)
+	if err != nil {
+		return remoteInfo{}, err
+	}
+	req.Header.Set("range", "bytes=0-0")
+
+	resp, err := t.do(req)
+	if err != nil {
+		return remoteInfo{}, err
+	}
+	defer resp.Body.Close()
+
+	info := remoteInfo{size: -1, etag: resp.Header.Get("etag")}
+	switch resp.StatusCode {
+	case http.StatusPartialContent, http.StatusRequestedRangeNotSatisfiable:
+		// 416 is sent for empty files with "content-range: bytes */0".
+		info.size = contentRangeTotal(resp.Header.Get("content-range"))
+	case http.StatusOK:
+		info.size = resp.ContentLength
+		info.noRanges = true
+	default:
+		return remoteInfo{}, fmt.Errorf("unexpected status of probe: %v", resp.Status)
+	}
+	return info, nil
+}
+
+// reprobe probes the remote file before the loaded task resumes the download.
+// It restarts the download if the server does not support range any more or the size does not match the saved one,
+// instead of appending the bytes which may not match the downloaded ones.
+// The probe is skipped for the methods other than GET set by [WithMethod] and it's ignored if it fails,
+// since the response of the range request is checked again.
+func (t *DownloadTask) reprobe(ctx context.Context) {
+	if t.copied == 0 || t.opts.method != "" && t.opts.method != http.MethodGet {
+		return
+	}
+
+	info, err := t.probe(ctx)
+	if err != nil {
+		logDebug(ctx, "iocopy: probe failed", "url", t.url, "err", err)
+		return
+	}
+
+	switch {
+	case info.noRanges:
+		t.restart(ctx, "range not supported")
+	case info.size >= 0 && t.total >= 0 && info.size != t.total:
+		t.restart(ctx, fmt.Sprintf("size of the remote file changed: %v, previous: %v", info.size, t.total))
+	case info.etag != "" && t.etag != "" && info.etag != t.etag:
+		t.restart(ctx, fmt.Sprintf("etag of the remote file changed: %v, previous: %v", info.etag, t.etag))
+	}
+}
+
+// changed returns the reason why the partial content resumed does not match the downloaded bytes:
+// the total size in "content-range" or the ETag is different from the saved one.
+// It returns an empty string if it matches.
+func (t *DownloadTask) changed(resp *http.Response) string {
+	if total := contentRangeTotal(resp.Header.Get("content-range")); total >= 0 && t.total >= 0 && total != t.total {
+		return fmt.Sprintf("size of the remote file changed: %v, previous: %v", total, t.total)
+	}
+
+	if etag := resp.Header.Get("etag"); etag != "" && t.etag != "" && etag != t.etag {
+		return fmt.Sprintf("etag of the remote file changed: %v, previous: %v", etag, t.etag)
+	}
+	return ""
+}
+
+// restart resets the task to download from the beginning and records the reason reported by [*EventRestarted].
+func (t *DownloadTask) restart(ctx context.Context, reason string) {
+	if t.copied > 0 || t.ranges != nil {
+		logDebug(ctx, "iocopy: restart download", "url", t.url, "reason", reason, "copied", t.copied)
+		if t.restartReason == "" {
+			t.restartReason = reason
+		}
+		t.discarded += t.copied
+	}
+
+	t.copied = 0
+	t.ranges = nil
+	t.segments = nil
+	t.hs = nil
+	t.hashStates = nil
+}
+
+// Restarted implements [Restarter] interface.
+func (t *DownloadTask) Restarted() (reason string, discarded int64) {
+	return t.restartReason, t.discarded
+}
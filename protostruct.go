@@ -0,0 +1,289 @@
+package iocopy
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"unicode/utf8"
+)
+
+// Wire types of protobuf.
+const (
+	protoVarint  = 0
+	protoFixed64 = 1
+	protoBytes   = 2
+	protoFixed32 = 5
+)
+
+// Field numbers of the kinds of google.protobuf.Value.
+const (
+	protoNullValue   = 1
+	protoNumberValue = 2
+	protoStringValue = 3
+	protoBoolValue   = 4
+	protoStructValue = 5
+	protoListValue   = 6
+)
+
+// errProto is the error of malformed protobuf data.
+var errProto = errors.New("invalid protobuf state")
+
+// protoCodec implements [StateCodec] by the protobuf wire format of google.protobuf.Struct.
+type protoCodec struct{}
+
+// Name implements [StateCodec] interface.
+func (protoCodec) Name() string {
+	return "proto"
+}
+
+// Marshal implements [StateCodec] interface.
+// The state should be a JSON object.
+func (protoCodec) Marshal(state []byte) ([]byte, error) {
+	v, err := decodeJSONState(state)
+	if err != nil {
+		return nil, err
+	}
+
+	m, ok := v.(map[string]any)
+	if !ok {
+		return nil, errors.New("state is not a JSON object")
+	}
+	return appendProtoStruct(nil, m)
+}
+
+// Unmarshal implements [StateCodec] interface.
+func (protoCodec) Unmarshal(data []byte) ([]byte, error) {
+	m, err := decodeProtoStruct(data, 0)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(m)
+}
+
+// appendProtoTag appends the tag of the field.
+func appendProtoTag(b []byte, field int, wireType byte) []byte {
+	return binary.AppendUvarint(b, uint64(field)<<3|uint64(wireType))
+}
+
+// appendProtoBytes appends the length-delimited field.
+func appendProtoBytes(b []byte, field int, p []byte) []byte {
+	b = appendProtoTag(b, field, protoBytes)
+	b = binary.AppendUvarint(b, uint64(len(p)))
+	return append(b, p...)
+}
+
+// appendProtoStruct appends the fields of google.protobuf.Struct: map<string, Value> fields = 1.
+// The keys are sorted to make the encoding deterministic.
+func appendProtoStruct(b []byte, m map[string]any) ([]byte, error) {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+
+	for _, k := range keys {
+		value, err := appendProtoValue(nil, m[k])
+		if err != nil {
+			return nil, err
+		}
+
+		// Map entry: string key = 1, Value value = 2.
+		entry := appendProtoBytes(nil, 1, []byte(k))
+		entry = appendProtoBytes(entry, 2, value)
+		b = appendProtoBytes(b, 1, entry)
+	}
+	return b, nil
+}
+
+// appendProtoValue appends the fields of google.protobuf.Value.
+func appendProtoValue(b []byte, v any) ([]byte, error) {
+	switch v := v.(type) {
+	case nil:
+		return append(appendProtoTag(b, protoNullValue, protoVarint), 0), nil
+	case bool:
+		b = appendProtoTag(b, protoBoolValue, protoVarint)
+		if v {
+			return append(b, 1), nil
+		}
+		return append(b, 0), nil
+	case string:
+		return appendProtoBytes(b, protoStringValue, []byte(v)), nil
+	case json.Number:
+		f, err := v.Float64()
+		if err != nil {
+			return nil, err
+		}
+		b = appendProtoTag(b, protoNumberValue, protoFixed64)
+		return binary.LittleEndian.AppendUint64(b, math.Float64bits(f)), nil
+	case []any:
+		// ListValue: repeated Value values = 1.
+		var list []byte
+		for _, e := range v {
+			value, err := appendProtoValue(nil, e)
+			if err != nil {
+				return nil, err
+			}
+			list = appendProtoBytes(list, 1, value)
+		}
+		return appendProtoBytes(b, protoListValue, list), nil
+	case map[string]any:
+		s, err := appendProtoStruct(nil, v)
+		if err != nil {
+			return nil, err
+		}
+		return appendProtoBytes(b, protoStructValue, s), nil
+	default:
+		return nil, fmt.Errorf("unsupported JSON value: %T", v)
+	}
+}
+
+// protoField is a field decoded from the protobuf wire format.
+type protoField struct {
+	num      int
+	wireType byte
+	// n is the value of a varint or fixed field and p is the payload of a length-delimited one.
+	n uint64
+	p []byte
+}
+
+// decodeProtoFields decodes the fields of a message.
+func decodeProtoFields(b []byte) ([]protoField, error) {
+	var fields []protoField
+	for len(b) > 0 {
+		tag, k := binary.Uvarint(b)
+		if k <= 0 {
+			return nil, fmt.Errorf("%w: bad tag", errProto)
+		}
+		b = b[k:]
+
+		f := protoField{num: int(tag >> 3), wireType: byte(tag & 7)}
+		switch f.wireType {
+		case protoVarint:
+			if f.n, k = binary.Uvarint(b); k <= 0 {
+				return nil, fmt.Errorf("%w: bad varint", errProto)
+			}
+			b = b[k:]
+		case protoFixed64:
+			if len(b) < 8 {
+				return nil, fmt.Errorf("%w: unexpected end of data", errProto)
+			}
+			f.n, b = binary.LittleEndian.Uint64(b), b[8:]
+		case protoFixed32:
+			if len(b) < 4 {
+				return nil, fmt.Errorf("%w: unexpected end of data", errProto)
+			}
+			f.n, b = uint64(binary.LittleEndian.Uint32(b)), b[4:]
+		case protoBytes:
+			n, k := binary.Uvarint(b)
+			if k <= 0 || n > uint64(len(b)-k) {
+				return nil, fmt.Errorf("%w: bad length", errProto)
+			}
+			f.p, b = b[k:k+int(n)], b[k+int(n):]
+		default:
+			return nil, fmt.Errorf("%w: unsupported wire type %v", errProto, f.wireType)
+		}
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+// decodeProtoStruct decodes google.protobuf.Struct at the nesting depth. Unknown fields are skipped.
+func decodeProtoStruct(b []byte, depth int) (map[string]any, error) {
+	if depth > maxStateDepth {
+		return nil, fmt.Errorf("%w: too deep", errProto)
+	}
+
+	fields, err := decodeProtoFields(b)
+	if err != nil {
+		return nil, err
+	}
+
+	m := map[string]any{}
+	for _, f := range fields {
+		if f.num != 1 || f.wireType != protoBytes {
+			continue
+		}
+
+		entry, err := decodeProtoFields(f.p)
+		if err != nil {
+			return nil, err
+		}
+
+		var (
+			key   string
+			value any
+		)
+		for _, e := range entry {
+			switch {
+			case e.num == 1 && e.wireType == protoBytes:
+				if !utf8.Valid(e.p) {
+					return nil, fmt.Errorf("%w: invalid UTF-8 key", errProto)
+				}
+				key = string(e.p)
+			case e.num == 2 && e.wireType == protoBytes:
+				if value, err = decodeProtoValue(e.p, depth+1); err != nil {
+					return nil, err
+				}
+			}
+		}
+		m[key] = value
+	}
+	return m, nil
+}
+
+// decodeProtoValue decodes google.protobuf.Value at the nesting depth.
+// It's null if no kind is set. The last kind wins if there're more than one.
+func decodeProtoValue(b []byte, depth int) (any, error) {
+	fields, err := decodeProtoFields(b)
+	if err != nil {
+		return nil, err
+	}
+
+	var v any
+	for _, f := range fields {
+		switch {
+		case f.num == protoNullValue && f.wireType == protoVarint:
+			v = nil
+		case f.num == protoNumberValue && f.wireType == protoFixed64:
+			v = math.Float64frombits(f.n)
+		case f.num == protoStringValue && f.wireType == protoBytes:
+			if !utf8.Valid(f.p) {
+				return nil, fmt.Errorf("%w: invalid UTF-8 string", errProto)
+			}
+			v = string(f.p)
+		case f.num == protoBoolValue && f.wireType == protoVarint:
+			v = f.n != 0
+		case f.num == protoStructValue && f.wireType == protoBytes:
+			if v, err = decodeProtoStruct(f.p, depth+1); err != nil {
+				return nil, err
+			}
+		case f.num == protoListValue && f.wireType == protoBytes:
+			if depth > maxStateDepth {
+				return nil, fmt.Errorf("%w: too deep", errProto)
+			}
+
+			values, err := decodeProtoFields(f.p)
+			if err != nil {
+				return nil, err
+			}
+
+			list := []any{}
+			for _, e := range values {
+				if e.num != 1 || e.wireType != protoBytes {
+					continue
+				}
+
+				value, err := decodeProtoValue(e.p, depth+1)
+				if err != nil {
+					return nil, err
+				}
+				list = append(list, value)
+			}
+			v = list
+		}
+	}
+	return v, nil
+}
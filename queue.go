@@ -0,0 +1,116 @@
+package iocopy
+
+import (
+	"context"
+	"sync"
+)
+
+// EventQueued is reported by [Queue] when a task is submitted.
+type EventQueued struct{}
+
+// EventStarted is reported by [Queue] when a task starts to run.
+type EventStarted struct{}
+
+// EventFinished is reported by [Queue] when a task finishes.
+type EventFinished struct {
+	// Err is the error returned by [Do]. It's nil if the task is done.
+	// It's ctx.Err() if the context is done before the task starts.
+	Err error
+}
+
+func (e *EventQueued) event()   {}
+func (e *EventStarted) event()  {}
+func (e *EventFinished) event() {}
+
+// OnTaskEventFunc is the callback function on events of tasks run by [Queue].
+// e is one of [*EventQueued], [*EventStarted], [*EventFinished] and the events reported by [Do].
+type OnTaskEventFunc func(t Task, e Event)
+
+// queueItem is a task submitted to [Queue].
+type queueItem struct {
+	ctx context.Context
+	t   Task
+}
+
+// Queue runs submitted tasks in order with at most n tasks running simultaneously.
+type Queue struct {
+	n  int
+	fn OnTaskEventFunc
+
+	mu      sync.Mutex
+	running int
+	pending []queueItem
+
+	// emitMu makes fn called by one goroutine at a time.
+	emitMu sync.Mutex
+	wg     sync.WaitGroup
+}
+
+// NewQueue returns a [*Queue] which runs at most n tasks simultaneously.
+// n: max number of running tasks. It's 1 if n < 1.
+// fn: callback on events of tasks. It's called by one goroutine at a time.
+func NewQueue(n int, fn OnTaskEventFunc) *Queue {
+	return &Queue{n: max(n, 1), fn: fn}
+}
+
+// emit calls the callback with the event of the task.
+func (q *Queue) emit(t Task, e Event) {
+	if q.fn == nil {
+		return
+	}
+
+	q.emitMu.Lock()
+	defer q.emitMu.Unlock()
+	q.fn(t, e)
+}
+
+// Submit adds the task to the queue and reports [*EventQueued].
+// The task runs by [Do] with ctx when there's a free slot.
+func (q *Queue) Submit(ctx context.Context, t Task) {
+	q.wg.Add(1)
+	q.emit(t, &EventQueued{})
+
+	q.mu.Lock()
+	q.pending = append(q.pending, queueItem{ctx: ctx, t: t})
+	q.mu.Unlock()
+
+	q.schedule()
+}
+
+// schedule starts the pending tasks while there're free slots.
+func (q *Queue) schedule() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for q.running < q.n && len(q.pending) > 0 {
+		item := q.pending[0]
+		q.pending = q.pending[1:]
+		q.running++
+		go q.run(item)
+	}
+}
+
+// run runs the task and starts the next pending one after it finishes.
+func (q *Queue) run(item queueItem) {
+	defer q.wg.Done()
+
+	var err error
+	if err = withCause(item.ctx, item.ctx.Err()); err == nil {
+		q.emit(item.t, &EventStarted{})
+		err = Do(item.ctx, item.t, nil, func(e Event) {
+			q.emit(item.t, e)
+		})
+	}
+	q.emit(item.t, &EventFinished{Err: err})
+
+	q.mu.Lock()
+	q.running--
+	q.mu.Unlock()
+
+	q.schedule()
+}
+
+// Wait waits for all submitted tasks to finish.
+func (q *Queue) Wait() {
+	q.wg.Wait()
+}
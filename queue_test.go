@@ -0,0 +1,57 @@
+package iocopy_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/northbright/iocopy"
+)
+
+func ExampleQueue() {
+	// This example submits 5 copy tasks to a queue which runs at most 2 tasks simultaneously.
+	dir, err := os.MkdirTemp("", "iocopy")
+	if err != nil {
+		log.Printf("os.MkdirTemp() error: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	var (
+		queued, running, maxRunning, done int
+	)
+
+	// The callback is called by one goroutine at a time.
+	q := iocopy.NewQueue(2, func(t iocopy.Task, e iocopy.Event) {
+		switch e := e.(type) {
+		case *iocopy.EventQueued:
+			queued++
+		case *iocopy.EventStarted:
+			running++
+			maxRunning = max(maxRunning, running)
+		case *iocopy.EventFinished:
+			running--
+			if e.Err == nil {
+				done++
+			}
+		}
+	})
+
+	for i := 0; i < 5; i++ {
+		src := filepath.Join(dir, fmt.Sprintf("%d", i))
+		if err = os.WriteFile(src, bytes.Repeat([]byte("0123456789abcdef"), 4096), 0644); err != nil {
+			log.Printf("os.WriteFile() error: %v", err)
+			return
+		}
+		q.Submit(context.Background(), iocopy.NewCopyFileTask(src+".copy", src, nil))
+	}
+	q.Wait()
+
+	fmt.Printf("queued: %v, max running <= 2: %v, done: %v\n", queued, maxRunning <= 2, done)
+
+	// Output:
+	// queued: 5, max running <= 2: true, done: 5
+}
@@ -0,0 +1,107 @@
+package iocopy
+
+import "slices"
+
+// Range is a byte range [Start, End) of a file.
+type Range struct {
+	// Start is the offset of the first byte.
+	Start int64 `json:"start"`
+	// End is the offset after the last byte.
+	End int64 `json:"end"`
+}
+
+// Size returns the number of bytes in the range.
+func (r Range) Size() int64 {
+	return r.End - r.Start
+}
+
+// RangeSet is a set of byte ranges sorted by offsets.
+// Overlapping and adjacent ranges are merged.
+// It's used to track the downloaded ranges of a file which may be written out of order.
+type RangeSet []Range
+
+// Add adds the range [start, end) to the set.
+// Empty ranges are ignored.
+func (s *RangeSet) Add(start, end int64) {
+	if start >= end {
+		return
+	}
+
+	rs := *s
+	// i is the first range which ends at or after start.
+	i, _ := slices.BinarySearchFunc(rs, start, func(r Range, start int64) int {
+		switch {
+		case r.End < start:
+			return -1
+		case r.End > start:
+			return 1
+		default:
+			return 0
+		}
+	})
+
+	// j is the first range which starts after end.
+	j := i
+	for j < len(rs) && rs[j].Start <= end {
+		start = min(start, rs[j].Start)
+		end = max(end, rs[j].End)
+		j++
+	}
+
+	*s = slices.Replace(rs, i, j, Range{Start: start, End: end})
+}
+
+// Remove removes the range [start, end) from the set.
+func (s *RangeSet) Remove(start, end int64) {
+	if start >= end {
+		return
+	}
+
+	var rs RangeSet
+	for _, r := range *s {
+		if r.End <= start || r.Start >= end {
+			rs = append(rs, r)
+			continue
+		}
+
+		if r.Start < start {
+			rs = append(rs, Range{Start: r.Start, End: start})
+		}
+		if r.End > end {
+			rs = append(rs, Range{Start: end, End: r.End})
+		}
+	}
+	*s = rs
+}
+
+// Size returns the total number of bytes in the set.
+func (s RangeSet) Size() int64 {
+	var n int64
+	for _, r := range s {
+		n += r.Size()
+	}
+	return n
+}
+
+// Missing returns the ranges in [0, total) which are not in the set.
+func (s RangeSet) Missing(total int64) RangeSet {
+	var (
+		missing RangeSet
+		off     int64
+	)
+
+	for _, r := range s {
+		if r.Start >= total {
+			break
+		}
+		if r.Start > off {
+			missing = append(missing, Range{Start: off, End: r.Start})
+		}
+		off = max(off, r.End)
+	}
+
+	if off < total {
+		missing = append(missing, Range{Start: off, End: total})
+	}
+	return missing
+}
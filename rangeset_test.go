@@ -0,0 +1,30 @@
+package iocopy_test
+
+import (
+	"fmt"
+
+	"github.com/northbright/iocopy"
+)
+
+func ExampleRangeSet() {
+	var s iocopy.RangeSet
+
+	// Ranges written out of order.
+	s.Add(100, 200)
+	s.Add(0, 50)
+	s.Add(300, 400)
+	fmt.Printf("ranges: %v, size: %v\n", s, s.Size())
+	fmt.Printf("missing: %v\n", s.Missing(500))
+
+	// Fill the holes.
+	s.Add(50, 100)
+	s.Add(150, 350)
+	fmt.Printf("ranges: %v, size: %v\n", s, s.Size())
+	fmt.Printf("missing: %v\n", s.Missing(500))
+
+	// Output:
+	// ranges: [{0 50} {100 200} {300 400}], size: 250
+	// missing: [{50 100} {200 300} {400 500}]
+	// ranges: [{0 400}], size: 400
+	// missing: [{400 500}]
+}
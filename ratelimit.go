@@ -0,0 +1,88 @@
+package iocopy
+
+import (
+	"context"
+	"io"
+
+	"golang.org/x/time/rate"
+)
+
+// RateLimitReader throttles reading by a [*rate.Limiter].
+// The limiter can be shared between tasks and other traffic of the application,
+// so the total bandwidth is limited by the application.
+type RateLimitReader struct {
+	ctx context.Context
+	r   io.Reader
+	l   *rate.Limiter
+}
+
+// NewRateLimitReader returns a [*RateLimitReader] which reads from r and waits for l.
+// Each token of l is a byte. Reads are split into chunks not larger than the burst size of l.
+// ctx: Read returns ctx.Err() when ctx is done while waiting for l.
+func NewRateLimitReader(ctx context.Context, r io.Reader, l *rate.Limiter) *RateLimitReader {
+	return &RateLimitReader{ctx: ctx, r: r, l: l}
+}
+
+// Read implements [io.Reader] interface.
+func (rr *RateLimitReader) Read(p []byte) (n int, err error) {
+	if rr.l.Limit() != rate.Inf {
+		if burst := rr.l.Burst(); burst > 0 && len(p) > burst {
+			p = p[:burst]
+		}
+	}
+
+	n, err = rr.r.Read(p)
+	if n > 0 {
+		if waitErr := rr.l.WaitN(rr.ctx, n); waitErr != nil {
+			return n, waitErr
+		}
+	}
+	return n, err
+}
+
+// RateLimitWriter throttles writing by a [*rate.Limiter].
+// It forwards Commit to the underlying writer if it implements [Committer].
+type RateLimitWriter struct {
+	ctx context.Context
+	w   io.Writer
+	l   *rate.Limiter
+}
+
+// NewRateLimitWriter returns a [*RateLimitWriter] which waits for l and writes to w.
+// Each token of l is a byte. Writes are split into chunks not larger than the burst size of l.
+// ctx: Write returns ctx.Err() when ctx is done while waiting for l.
+func NewRateLimitWriter(ctx context.Context, w io.Writer, l *rate.Limiter) *RateLimitWriter {
+	return &RateLimitWriter{ctx: ctx, w: w, l: l}
+}
+
+// Write implements [io.Writer] interface.
+func (rw *RateLimitWriter) Write(p []byte) (n int, err error) {
+	for len(p) > 0 {
+		chunk := p
+		if rw.l.Limit() != rate.Inf {
+			if burst := rw.l.Burst(); burst > 0 && len(chunk) > burst {
+				chunk = chunk[:burst]
+			}
+		}
+
+		if err = rw.l.WaitN(rw.ctx, len(chunk)); err != nil {
+			return n, err
+		}
+
+		m, err := rw.w.Write(chunk)
+		n += m
+		if err != nil {
+			return n, err
+		}
+		p = p[m:]
+	}
+	return n, nil
+}
+
+// Commit implements [Committer] interface.
+func (rw *RateLimitWriter) Commit() error {
+	if c, ok := rw.w.(Committer); ok {
+		return c.Commit()
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package iocopy_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/northbright/iocopy"
+	"golang.org/x/time/rate"
+)
+
+func ExampleWithRateLimiter() {
+	// This example copies 2 files with one shared rate limiter: 64 KiB/s with 16 KiB burst.
+	dir, err := os.MkdirTemp("", "iocopy")
+	if err != nil {
+		log.Printf("os.MkdirTemp() error: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	l := rate.NewLimiter(64*1024, 16*1024)
+	start := time.Now()
+
+	for _, name := range []string{"a", "b"} {
+		src := filepath.Join(dir, name)
+		if err = os.WriteFile(src, bytes.Repeat([]byte("0123456789abcdef"), 1024), 0644); err != nil {
+			log.Printf("os.WriteFile() error: %v", err)
+			return
+		}
+
+		t := iocopy.NewCopyFileTask(src+".copy", src, nil, iocopy.WithRateLimiter(l))
+		if err = iocopy.Do(context.Background(), t, nil, nil); err != nil {
+			log.Printf("iocopy.Do() error: %v", err)
+			return
+		}
+	}
+
+	// 16 KiB burst + 16 KiB at 64 KiB/s takes about 250ms.
+	fmt.Printf("throttled: %v\n", time.Since(start) >= time.Millisecond*200)
+
+	// Output:
+	// throttled: true
+}
+
+func ExampleWithWriteRateLimiter() {
+	// This example throttles writing the destination at 64 KiB/s with 16 KiB burst while reading the source is not throttled.
+	dir, err := os.MkdirTemp("", "iocopy")
+	if err != nil {
+		log.Printf("os.MkdirTemp() error: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	src := filepath.Join(dir, "src")
+	if err = os.WriteFile(src, bytes.Repeat([]byte("0123456789abcdef"), 2048), 0644); err != nil {
+		log.Printf("os.WriteFile() error: %v", err)
+		return
+	}
+
+	l := rate.NewLimiter(64*1024, 16*1024)
+	start := time.Now()
+
+	t := iocopy.NewCopyFileTask(src+".copy", src, nil, iocopy.WithWriteRateLimiter(l))
+	if err = iocopy.Do(context.Background(), t, nil, nil); err != nil {
+		log.Printf("iocopy.Do() error: %v", err)
+		return
+	}
+
+	// 16 KiB burst + 16 KiB at 64 KiB/s takes about 250ms.
+	fmt.Printf("throttled: %v\n", time.Since(start) >= time.Millisecond*200)
+
+	// Output:
+	// throttled: true
+}
@@ -0,0 +1,123 @@
+package iocopy
+
+import (
+	"context"
+	"io"
+	"sync"
+)
+
+// DefaultParallelReadSize is the default size of the chunks read by [WithParallelReads].
+const DefaultParallelReadSize = 1024 * 1024
+
+// readAtChunk is a chunk read by [readAtReader].
+type readAtChunk struct {
+	off  int64
+	b    []byte
+	err  error
+	done chan struct{}
+}
+
+// readAtReader reads [off, size) of an [io.ReaderAt] by multiple readers in parallel
+// and returns the bytes in order, so a single sequenced writer(e.g. the hashes) consumes them.
+// At most 2 * n chunks are read ahead.
+type readAtReader struct {
+	pool *workerPool
+	// wg waits for the goroutine which queues the chunks.
+	wg    sync.WaitGroup
+	order chan *readAtChunk
+	// cur is the chunk being read.
+	cur *readAtChunk
+}
+
+// newReadAtReader starts n readers to read [off, size) of r by chunks of chunkSize.
+func newReadAtReader(ctx context.Context, r io.ReaderAt, off, size int64, n, chunkSize int) *readAtReader {
+	if chunkSize <= 0 {
+		chunkSize = DefaultParallelReadSize
+	}
+	n = max(n, 1)
+
+	rr := &readAtReader{pool: newWorkerPool(ctx, n), order: make(chan *readAtChunk, n)}
+	jobs := make(chan *readAtChunk, n)
+	ctx = rr.pool.ctx
+
+	rr.wg.Add(1)
+	go func() {
+		defer rr.wg.Done()
+		defer close(jobs)
+		defer close(rr.order)
+
+		for ; off < size; off += int64(chunkSize) {
+			c := &readAtChunk{off: off, b: make([]byte, min(int64(chunkSize), size-off)), done: make(chan struct{})}
+			// The order channel bounds the chunks read ahead.
+			select {
+			case rr.order <- c:
+			case <-ctx.Done():
+				return
+			}
+
+			select {
+			case jobs <- c:
+			case <-ctx.Done():
+				c.err = ctx.Err()
+				close(c.done)
+				return
+			}
+		}
+	}()
+
+	// The errors are reported by the chunks in order.
+	rr.pool.start(func(ctx context.Context, worker int) error {
+		for c := range jobs {
+			if err := ctx.Err(); err != nil {
+				c.err = err
+				close(c.done)
+				continue
+			}
+
+			m, err := r.ReadAt(c.b, c.off)
+			if err == io.EOF {
+				err = nil
+				if m < len(c.b) {
+					// The source is shorter than size.
+					err = io.ErrUnexpectedEOF
+				}
+			}
+			c.b, c.err = c.b[:m], err
+			rr.pool.add(worker, int64(m))
+			close(c.done)
+		}
+		return nil
+	})
+	return rr
+}
+
+// Read implements [io.Reader] interface.
+func (rr *readAtReader) Read(p []byte) (int, error) {
+	for rr.cur == nil || len(rr.cur.b) == 0 {
+		if rr.cur != nil && rr.cur.err != nil {
+			return 0, rr.cur.err
+		}
+
+		c, ok := <-rr.order
+		if !ok {
+			return 0, io.EOF
+		}
+		<-c.done
+		rr.cur = c
+	}
+
+	n := copy(p, rr.cur.b)
+	rr.cur.b = rr.cur.b[n:]
+	return n, nil
+}
+
+// Close stops the readers and waits for them to exit.
+func (rr *readAtReader) Close() error {
+	rr.pool.cancel()
+	// Unblock the producer and the readers.
+	for range rr.order {
+	}
+	rr.wg.Wait()
+	rr.pool.Close()
+	return nil
+}
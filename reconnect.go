@@ -0,0 +1,96 @@
+package iocopy
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"time"
+)
+
+// EventReconnect is reported by [Do] before the task is reopened to re-establish the connection
+// lost by a network change, e.g. Wi-Fi to LTE. See [WithReconnect].
+type EventReconnect struct {
+	// Attempt is the number of the attempts to reconnect since the last bytes were copied. It starts from 1.
+	Attempt int
+	// Delay is the backoff before reconnecting.
+	Delay time.Duration
+	// Copied is the number of bytes copied including the ones copied previously. The task resumes from it.
+	Copied int64
+	// Err is the error of the lost connection.
+	Err error
+}
+
+func (e *EventReconnect) event() {}
+
+// reconnectPolicy is the policy to reconnect set by [WithReconnect].
+type reconnectPolicy struct {
+	retries    int
+	backoff    time.Duration
+	maxBackoff time.Duration
+}
+
+// reconnecter is implemented by the tasks which can be reconnected by [Do], e.g. [*DownloadTask].
+type reconnecter interface {
+	reconnectPolicy() reconnectPolicy
+}
+
+// delay returns the exponential backoff of the attempt: backoff, 2 * backoff, 4 * backoff... up to maxBackoff.
+func (p reconnectPolicy) delay(attempt int) time.Duration {
+	d := p.backoff
+	for i := 1; i < attempt && (p.maxBackoff <= 0 || d < p.maxBackoff); i++ {
+		d *= 2
+	}
+
+	if p.maxBackoff > 0 && d > p.maxBackoff {
+		d = p.maxBackoff
+	}
+	return d
+}
+
+// networkChangeErrors are the messages of the errors typical of network changes.
+// They're matched as strings since the errors differ by platforms and HTTP versions.
+var networkChangeErrors = []string{
+	"connection reset",
+	"connection aborted",
+	"broken pipe",
+	"network is unreachable",
+	"network is down",
+	"no route to host",
+	"stream error",
+	"use of closed network connection",
+	"server closed idle connection",
+	"forcibly closed by the remote host",
+}
+
+// isNetworkChange reports whether err is likely caused by a network change, e.g. a connection reset or an unexpected EOF.
+func isNetworkChange(err error) bool {
+	if err == nil || isStopped(err) {
+		return false
+	}
+
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+
+	msg := strings.ToLower(err.Error())
+	for _, s := range networkChangeErrors {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// sleep waits for d or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,107 @@
+package iocopy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/url"
+	"strings"
+)
+
+// Redacted replaces the sensitive values masked by [RedactSecrets].
+const Redacted = "REDACTED"
+
+// Redactor masks the sensitive parts of a string value, e.g. the token in the query string of a url.
+// key is the JSON field name(or the one of the nearest object for the values in arrays) or the log attribute key of the value.
+// It returns the value as is if it's not sensitive.
+type Redactor func(key, value string) string
+
+// RedactSecrets is a [Redactor] which masks the query values and the passwords of urls,
+// and the values of the keys like "authorization", "token", "password" and "secret".
+func RedactSecrets(key, value string) string {
+	switch k := strings.ToLower(key); {
+	case strings.Contains(k, "authorization"), strings.Contains(k, "token"),
+		strings.Contains(k, "password"), strings.Contains(k, "secret"):
+		if value != "" {
+			return Redacted
+		}
+		return value
+	}
+
+	u, err := url.Parse(value)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return value
+	}
+
+	if _, ok := u.User.Password(); ok {
+		u.User = url.UserPassword(u.User.Username(), Redacted)
+	}
+
+	if u.RawQuery != "" {
+		q := u.Query()
+		for k := range q {
+			q[k] = []string{Redacted}
+		}
+		u.RawQuery = q.Encode()
+	}
+	return u.String()
+}
+
+// redactorKey is the context key of [Redactor].
+type redactorKey struct{}
+
+// ContextWithRedactor returns a copy of ctx with r attached.
+// [Do] and the runners([Installer], [DirVerifier] and [TaskGroup]) mask the states of [*EventStop]
+// and the results of [*EventOK] by r when they run with the returned context,
+// and so do the logs of [ContextWithLogger].
+// The tasks are not changed, so they can still be resumed from the full in-memory tasks(e.g. by calling [Do] again)
+// or their State methods. Value of [*EventOK] is not masked.
+func ContextWithRedactor(ctx context.Context, r Redactor) context.Context {
+	return context.WithValue(ctx, redactorKey{}, r)
+}
+
+// redactor returns the [Redactor] attached to ctx or nil.
+func redactor(ctx context.Context) Redactor {
+	r, _ := ctx.Value(redactorKey{}).(Redactor)
+	return r
+}
+
+// redactJSON masks the string values of the JSON payload by the [Redactor] attached to ctx.
+// It returns b as is if there's no redactor or b is not a JSON object or array.
+func redactJSON(ctx context.Context, b []byte) []byte {
+	r := redactor(ctx)
+	if r == nil || len(b) == 0 {
+		return b
+	}
+
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+
+	var v any
+	if err := d.Decode(&v); err != nil {
+		return b
+	}
+
+	redacted, err := json.Marshal(redactValue(r, "", v))
+	if err != nil {
+		return b
+	}
+	return redacted
+}
+
+// redactValue masks the string values of v decoded from JSON by r.
+func redactValue(r Redactor, key string, v any) any {
+	switch v := v.(type) {
+	case string:
+		return r(key, v)
+	case map[string]any:
+		for k, e := range v {
+			v[k] = redactValue(r, k, e)
+		}
+	case []any:
+		for i, e := range v {
+			v[i] = redactValue(r, key, e)
+		}
+	}
+	return v
+}
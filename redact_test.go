@@ -0,0 +1,76 @@
+package iocopy_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/northbright/iocopy"
+)
+
+func ExampleContextWithRedactor() {
+	// This example masks the token in the url of a download in the emitted state and result.
+	// The download is resumed from the in-memory task which still has the full url.
+	data := bytes.Repeat([]byte("0123456789abcdef"), 64*1024)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("token") != "secret" {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		http.ServeContent(w, r, "file", time.Time{}, bytes.NewReader(data))
+	}))
+	defer ts.Close()
+
+	dir, err := os.MkdirTemp("", "iocopy")
+	if err != nil {
+		log.Printf("os.MkdirTemp() error: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	// query returns the query string of the url field of the JSON payload.
+	query := func(payload []byte) string {
+		var v struct {
+			URL string `json:"url"`
+		}
+		json.Unmarshal(payload, &v)
+		u, _ := url.Parse(v.URL)
+		return u.RawQuery
+	}
+
+	ctx, cancel := context.WithCancel(iocopy.ContextWithRedactor(context.Background(), iocopy.RedactSecrets))
+	defer cancel()
+
+	t := iocopy.NewDownloadTask(filepath.Join(dir, "file"), ts.URL+"/file?token=secret", nil)
+	iocopy.Do(ctx, t, nil, func(e iocopy.Event) {
+		switch e := e.(type) {
+		case *iocopy.EventWritten:
+			// Emulate user cancelation.
+			cancel()
+		case *iocopy.EventStop:
+			fmt.Printf("query in state: %v\n", query(e.State))
+		}
+	})
+
+	ctx = iocopy.ContextWithRedactor(context.Background(), iocopy.RedactSecrets)
+	if err = iocopy.Do(ctx, t, nil, func(e iocopy.Event) {
+		if e, ok := e.(*iocopy.EventOK); ok {
+			fmt.Printf("query in result: %v\n", query(e.Result))
+		}
+	}); err != nil {
+		log.Printf("iocopy.Do() error: %v", err)
+		return
+	}
+
+	// Output:
+	// query in state: token=REDACTED
+	// query in result: token=REDACTED
+}
@@ -0,0 +1,96 @@
+package iocopy
+
+import (
+	"context"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// BandwidthRule is a rule of [BandwidthSchedule] which limits the bandwidth in a time range of the day.
+type BandwidthRule struct {
+	// Start and End are the offsets of the time range since midnight, e.g. 8 * time.Hour for 08:00.
+	// The range wraps around midnight if End <= Start, e.g. 20:00 - 08:00.
+	Start time.Duration
+	End   time.Duration
+	// Limit is the bandwidth in bytes per second. Use [rate.Inf] for unlimited.
+	Limit rate.Limit
+}
+
+// contains reports whether the offset since midnight is in the range of the rule.
+func (r BandwidthRule) contains(off time.Duration) bool {
+	if r.Start < r.End {
+		return off >= r.Start && off < r.End
+	}
+	return off >= r.Start || off < r.End
+}
+
+// BandwidthSchedule is a time-of-day bandwidth schedule,
+// e.g. 1 MiB/s from 08:00 to 20:00 and unlimited overnight, so long tasks don't compete with daytime traffic.
+// Run it with a [rate.Limiter] passed to [WithRateLimiter] or [WithWriteRateLimiter]
+// to change the limit while the tasks are running.
+type BandwidthSchedule struct {
+	// Rules are the rules of the schedule. The first rule which contains the time of the day applies.
+	Rules []BandwidthRule
+	// Default is the bandwidth when no rules apply. Zero value means no bytes are allowed, use [rate.Inf] for unlimited.
+	Default rate.Limit
+}
+
+// sinceMidnight returns the offset of t since the midnight of its day in its location.
+func sinceMidnight(t time.Time) time.Duration {
+	y, m, d := t.Date()
+	return t.Sub(time.Date(y, m, d, 0, 0, 0, 0, t.Location()))
+}
+
+// LimitAt returns the bandwidth of the schedule at t in its location.
+func (s BandwidthSchedule) LimitAt(t time.Time) rate.Limit {
+	off := sinceMidnight(t)
+	for _, r := range s.Rules {
+		if r.contains(off) {
+			return r.Limit
+		}
+	}
+	return s.Default
+}
+
+// next returns the next time after t when the rules may start or end.
+func (s BandwidthSchedule) next(t time.Time) time.Time {
+	y, m, d := t.Date()
+	var next time.Time
+	for _, r := range s.Rules {
+		for _, off := range []time.Duration{r.Start, r.End} {
+			at := time.Date(y, m, d, 0, 0, 0, 0, t.Location()).Add(off)
+			if !at.After(t) {
+				at = time.Date(y, m, d+1, 0, 0, 0, 0, t.Location()).Add(off)
+			}
+			if next.IsZero() || at.Before(next) {
+				next = at
+			}
+		}
+	}
+	return next
+}
+
+// Run sets the limit of l by the schedule now and at each start and end of the rules until ctx is done.
+// It blocks, so call it in a new goroutine.
+func (s BandwidthSchedule) Run(ctx context.Context, l *rate.Limiter) {
+	for {
+		now := time.Now()
+		l.SetLimitAt(now, s.LimitAt(now))
+
+		next := s.next(now)
+		if next.IsZero() {
+			// No rules. The default limit never changes.
+			<-ctx.Done()
+			return
+		}
+
+		timer := time.NewTimer(next.Sub(now))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package iocopy_test
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/northbright/iocopy"
+	"golang.org/x/time/rate"
+)
+
+func ExampleBandwidthSchedule() {
+	// This example limits the bandwidth to 1 MiB/s from 08:00 to 20:00 and 4 MiB/s from 20:00 to 23:00.
+	// It's unlimited overnight.
+	s := iocopy.BandwidthSchedule{
+		Rules: []iocopy.BandwidthRule{
+			{Start: 8 * time.Hour, End: 20 * time.Hour, Limit: 1024 * 1024},
+			{Start: 20 * time.Hour, End: 23 * time.Hour, Limit: 4 * 1024 * 1024},
+		},
+		Default: rate.Inf,
+	}
+
+	for _, hour := range []int{9, 21, 2} {
+		at := time.Date(2024, 1, 1, hour, 0, 0, 0, time.Local)
+		fmt.Printf("%02d:00: %v\n", hour, s.LimitAt(at))
+	}
+
+	// Change the limit of the limiter shared by the tasks while they're running, e.g. by iocopy.WithRateLimiter(l).
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	l := rate.NewLimiter(rate.Inf, 64*1024)
+	go s.Run(ctx, l)
+
+	// Output:
+	// 09:00: 1.048576e+06
+	// 21:00: 4.194304e+06
+	// 02:00: 1.7976931348623157e+308
+}
@@ -0,0 +1,135 @@
+package iocopy
+
+import (
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"io"
+	"os"
+	"slices"
+	"sync"
+)
+
+// SegmentDigest is the SHA-256 digest of the bytes of a segment downloaded by a connection of [WithConnections].
+type SegmentDigest struct {
+	// Start is the offset of the first byte.
+	Start int64 `json:"start"`
+	// End is the offset after the last byte downloaded.
+	// It's before the end of the segment if the download is stopped in the middle of it.
+	End int64 `json:"end"`
+	// SHA256 is the hex encoded SHA-256 digest of the bytes.
+	SHA256 string `json:"sha256"`
+}
+
+// segmentHashes computes the digests of the segments downloaded by the connections.
+// The bytes of a segment are written in order since each segment is read by a single connection.
+type segmentHashes struct {
+	mu     sync.Mutex
+	segs   []Range
+	hashes []hash.Hash
+	// ends are the offsets after the bytes written to the segments.
+	ends []int64
+}
+
+// newSegmentHashes returns a [*segmentHashes] of the segments sorted by offsets.
+func newSegmentHashes(segs []Range) *segmentHashes {
+	sh := &segmentHashes{segs: segs, hashes: make([]hash.Hash, len(segs)), ends: make([]int64, len(segs))}
+	for i, seg := range segs {
+		sh.hashes[i] = sha256.New()
+		sh.ends[i] = seg.Start
+	}
+	return sh
+}
+
+// write hashes p written at off of the segment.
+func (sh *segmentHashes) write(p []byte, off int64) {
+	i, ok := slices.BinarySearchFunc(sh.segs, off, func(r Range, off int64) int {
+		switch {
+		case r.End <= off:
+			return -1
+		case r.Start > off:
+			return 1
+		default:
+			return 0
+		}
+	})
+	if !ok {
+		return
+	}
+
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	sh.hashes[i].Write(p)
+	sh.ends[i] = off + int64(len(p))
+}
+
+// digests returns the digests of the segments which have bytes written.
+func (sh *segmentHashes) digests() []SegmentDigest {
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	var digests []SegmentDigest
+	for i, seg := range sh.segs {
+		if sh.ends[i] > seg.Start {
+			digests = append(digests, SegmentDigest{Start: seg.Start, End: sh.ends[i], SHA256: hex.EncodeToString(sh.hashes[i].Sum(nil))})
+		}
+	}
+	return digests
+}
+
+// segmentDigests returns the digests of the segments downloaded by the previous runs and the current one sorted by offsets.
+func (t *DownloadTask) segmentDigests() []SegmentDigest {
+	digests := slices.Clone(t.segments)
+	if t.sh != nil {
+		digests = append(digests, t.sh.digests()...)
+	}
+
+	slices.SortFunc(digests, func(a, b SegmentDigest) int {
+		return cmp.Compare(a.Start, b.Start)
+	})
+	return digests
+}
+
+// VerifySegments verifies the bytes of the segments downloaded by the connections of [WithConnections]
+// against their digests saved in the state, e.g. before resuming a download after a crash.
+// Only the downloaded segments are read instead of the whole file.
+// The segments which don't match are removed from the downloaded ranges, so they're downloaded again by [Do].
+// It returns the ranges of them.
+func (t *DownloadTask) VerifySegments() ([]Range, error) {
+	if len(t.segments) == 0 {
+		return nil, nil
+	}
+
+	f, err := os.Open(longPath(t.dstName()))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var (
+		bad      []Range
+		segments []SegmentDigest
+	)
+	for _, s := range t.segments {
+		h := sha256.New()
+		if _, err = io.Copy(h, io.NewSectionReader(f, s.Start, s.End-s.Start)); err != nil {
+			return nil, err
+		}
+
+		if hex.EncodeToString(h.Sum(nil)) == s.SHA256 {
+			segments = append(segments, s)
+			continue
+		}
+
+		bad = append(bad, Range{Start: s.Start, End: s.End})
+		t.ranges.Remove(s.Start, s.End)
+	}
+
+	t.segments = segments
+	if len(bad) > 0 {
+		t.copied = t.ranges.Size()
+	}
+	return bad, nil
+}
@@ -0,0 +1,61 @@
+package iocopy
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// SignalError is the cause of the tasks stopped by [TaskManager.HandleSignals].
+// It's wrapped by Err of [*EventStop] and [*EventFinished], so it can be checked by [errors.As].
+type SignalError struct {
+	// Signal is the signal received, e.g. [syscall.SIGTERM].
+	Signal os.Signal
+}
+
+// Error implements error interface.
+func (e *SignalError) Error() string {
+	return fmt.Sprintf("received signal %v", e.Signal)
+}
+
+// HandleSignals shuts down the task manager when one of the signals is received(SIGINT and SIGTERM if none),
+// the boilerplate of CLIs and daemons to persist the tasks on exit.
+// It stops the tasks with a [*SignalError] as the cause, waits at most timeout for their states to be saved
+// to the task store like [TaskManager.Shutdown], then sends the error returned by the shutdown to the returned channel.
+// A non-positive timeout means no timeout.
+// If ctx is done before a signal is received, it stops handling the signals and the channel is closed without a value.
+// The signals received after the first one are not handled, so the default behavior(e.g. exit) is restored.
+func (m *TaskManager) HandleSignals(ctx context.Context, timeout time.Duration, sigs ...os.Signal) <-chan error {
+	if len(sigs) == 0 {
+		sigs = []os.Signal{os.Interrupt, syscall.SIGTERM}
+	}
+
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, sigs...)
+
+	done := make(chan error, 1)
+	go func() {
+		defer close(done)
+
+		var sig os.Signal
+		select {
+		case <-ctx.Done():
+			signal.Stop(ch)
+			return
+		case sig = <-ch:
+			signal.Stop(ch)
+		}
+
+		sctx, cancel := context.WithoutCancel(ctx), context.CancelFunc(func() {})
+		if timeout > 0 {
+			sctx, cancel = context.WithTimeout(sctx, timeout)
+		}
+		defer cancel()
+
+		done <- m.shutdownCause(sctx, &SignalError{Signal: sig})
+	}()
+	return done
+}
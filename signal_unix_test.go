@@ -0,0 +1,80 @@
+//go:build unix
+
+package iocopy_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/northbright/iocopy"
+)
+
+func ExampleTaskManager_HandleSignals() {
+	// This example persists a running download task when SIGTERM is received.
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Write half of the content and wait for the client to stop.
+		w.Header().Set("Content-Length", "2000")
+		w.Write([]byte(strings.Repeat("a", 1000)))
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer ts.Close()
+
+	dir, err := os.MkdirTemp("", "iocopy")
+	if err != nil {
+		log.Printf("os.MkdirTemp() error: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	store := filepath.Join(dir, "tasks")
+	written := make(chan struct{})
+	m := iocopy.NewTaskManager(store, 2, func(t iocopy.Task, e iocopy.Event) {
+		switch e := e.(type) {
+		case *iocopy.EventWritten:
+			if t.Copied() == 1000 {
+				close(written)
+			}
+		case *iocopy.EventStop:
+			var se *iocopy.SignalError
+			fmt.Printf("stopped: %v, by signal: %v\n", e.Err, errors.As(e.Err, &se))
+		}
+	})
+
+	// Handle SIGINT and SIGTERM.
+	done := m.HandleSignals(context.Background(), 10*time.Second)
+
+	t := iocopy.NewDownloadTask(filepath.Join(dir, "file"), ts.URL, nil)
+	if err = m.Submit("file", "download", t); err != nil {
+		log.Printf("m.Submit() error: %v", err)
+		return
+	}
+
+	// Emulate SIGTERM sent by the service manager.
+	<-written
+	if err = syscall.Kill(os.Getpid(), syscall.SIGTERM); err != nil {
+		log.Printf("syscall.Kill() error: %v", err)
+		return
+	}
+
+	if err = <-done; err != nil {
+		log.Printf("shutdown error: %v", err)
+		return
+	}
+
+	_, err = os.Stat(filepath.Join(store, "file"+iocopy.TaskStateExt))
+	fmt.Printf("state saved: %v\n", err == nil)
+
+	// Output:
+	// stopped: context canceled: received signal terminated, by signal: true
+	// state saved: true
+}
@@ -0,0 +1,68 @@
+package iocopy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+)
+
+// ErrBadSignature is returned when the signature of the downloaded file is invalid.
+var ErrBadSignature = errors.New("bad signature")
+
+// SignatureVerifier verifies a detached signature of a file, e.g. [*MinisignVerifier].
+// Implementations of other formats(e.g. GPG) can be used by [WithSignature].
+type SignatureVerifier interface {
+	// Verify reads the file from r and verifies it with the detached signature sig.
+	// It returns an error wrapping [ErrBadSignature] if the signature does not match.
+	Verify(r io.Reader, sig []byte) error
+}
+
+// verifySignature verifies the downloaded file with the detached signature.
+// The signature is fetched from the signature url if it's not given.
+func (t *DownloadTask) verifySignature(ctx context.Context) error {
+	if t.fsys != OSFS {
+		return fmt.Errorf("%w: can't read the file to verify", ErrBadSignature)
+	}
+
+	sig := t.opts.sig
+	if sig == nil {
+		var err error
+		if sig, err = t.fetchSignature(ctx, t.opts.sigURL); err != nil {
+			return err
+		}
+	}
+
+	f, err := os.Open(longPath(t.dstName()))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return t.opts.verifier.Verify(f, sig)
+}
+
+// maxSignatureSize is the max size of detached signatures to fetch.
+const maxSignatureSize = 64 * 1024
+
+// fetchSignature fetches the detached signature from url.
+func (t *DownloadTask) fetchSignature(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := t.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch signature: unexpected status: %v", resp.Status)
+	}
+
+	return io.ReadAll(io.LimitReader(resp.Body, maxSignatureSize))
+}
@@ -0,0 +1,225 @@
+package iocopy
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"sync"
+)
+
+const (
+	// smallBatchFiles and smallBatchSize are the max number of the files and the max bytes read by a worker in a batch
+	// of [WithSmallFiles]. A file larger than smallBatchSize is read alone.
+	smallBatchFiles = 64
+	smallBatchSize  = 1024 * 1024
+)
+
+// smallBatch is the bytes of the consecutive small files read by the workers of [WithSmallFiles].
+type smallBatch struct {
+	buf []byte
+	// off is the offset of buf to read.
+	off int
+	// end is the index of the file after the batch.
+	end int
+}
+
+// smallRun is the consecutive files read by a worker in a batch.
+type smallRun struct {
+	start, end int
+	// off is the offset of the first file in the batch.
+	off int64
+}
+
+// readSmallFiles reads the consecutive files not larger than small from the file i in a batch.
+// The files are split into runs and each worker opens, reads and closes the files of its run in order.
+func readSmallFiles(src fs.FS, files []CopyFSFile, i int, small int64, workers int) (*smallBatch, error) {
+	var (
+		runs []smallRun
+		size int64
+	)
+
+	end := i
+	for len(runs) < workers && end < len(files) {
+		r := smallRun{start: end, end: end, off: size}
+		var n int64
+		for r.end < len(files) && files[r.end].Size <= small && r.end-r.start < smallBatchFiles {
+			if r.end > r.start && n+files[r.end].Size > smallBatchSize {
+				break
+			}
+			n += files[r.end].Size
+			r.end++
+		}
+
+		if r.end == r.start {
+			break
+		}
+		runs = append(runs, r)
+		size += n
+		end = r.end
+	}
+
+	buf := make([]byte, size)
+	errs := make([]error, len(runs))
+
+	var wg sync.WaitGroup
+	for k, r := range runs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			off := r.off
+			for _, f := range files[r.start:r.end] {
+				if f.Size == 0 {
+					continue
+				}
+
+				if err := readSmallFile(src, f, buf[off:off+f.Size]); err != nil {
+					errs[k] = err
+					return
+				}
+				off += f.Size
+			}
+		}()
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &smallBatch{buf: buf, end: end}, nil
+}
+
+// readSmallFile reads the whole file to p.
+func readSmallFile(src fs.FS, file CopyFSFile, p []byte) error {
+	f, err := src.Open(file.Name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if _, err = io.ReadFull(f, p); err == io.EOF || err == io.ErrUnexpectedEOF {
+		return fmt.Errorf("size of %v changed: %w", file.Name, io.ErrUnexpectedEOF)
+	}
+	return err
+}
+
+// smallFile is a small file to write by [smallWriter].
+type smallFile struct {
+	// i is the index of the file.
+	i    int
+	data []byte
+}
+
+// smallWriter writes the small files of [WithSmallFiles] by the workers.
+type smallWriter struct {
+	w     *copyFSWriter
+	files chan smallFile
+	wg    sync.WaitGroup
+
+	mu sync.Mutex
+	// err is the error of the first failed file and failed is its index.
+	err    error
+	failed int
+}
+
+// newSmallWriter returns a [*smallWriter] which writes the files of w by the workers.
+func newSmallWriter(w *copyFSWriter, workers int) *smallWriter {
+	sw := &smallWriter{w: w, files: make(chan smallFile, workers)}
+	for range workers {
+		sw.wg.Add(1)
+		go sw.work()
+	}
+	return sw
+}
+
+// work writes the files submitted.
+func (sw *smallWriter) work() {
+	defer sw.wg.Done()
+
+	for f := range sw.files {
+		if err := sw.w.writeSmall(f.i, f.data); err != nil {
+			sw.fail(f.i, err)
+		}
+	}
+}
+
+// fail records the error of the file i if it's the first failed one.
+func (sw *smallWriter) fail(i int, err error) {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+
+	if sw.err == nil || i < sw.failed {
+		sw.err, sw.failed = err, i
+	}
+}
+
+// error returns the error of the first failed file.
+func (sw *smallWriter) error() error {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	return sw.err
+}
+
+// wait waits for the files submitted to be written.
+// It returns the index and the error of the first failed file.
+func (sw *smallWriter) wait() (int, error) {
+	close(sw.files)
+	sw.wg.Wait()
+	return sw.failed, sw.err
+}
+
+// writeSmall writes the data of the file i by one open, write and close without seeking.
+// The directories are created when the task is opened.
+// The data is the beginning of the file if it's stopped in the middle of the file.
+func (w *copyFSWriter) writeSmall(i int, data []byte) error {
+	file := w.t.files[i]
+	name, err := w.t.dstPath(file.Name)
+	if err != nil {
+		return err
+	}
+
+	f, err := w.t.fsys.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+
+	_, err = f.Write(data)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil || int64(len(data)) < file.Size {
+		return err
+	}
+
+	if err = w.t.chmod(name, file.Mode); err != nil {
+		return err
+	}
+
+	if w.t.opts.dedupHash != nil {
+		h := w.t.opts.dedupHash()
+		h.Write(data)
+
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		return w.t.dedupe(file, name, h.Sum(nil))
+	}
+	return nil
+}
+
+// flush waits for the small files submitted to be written.
+// If any of them fails, the copied position is moved back to the first failed one to resume from it.
+func (w *copyFSWriter) flush() error {
+	if w.pool == nil {
+		return nil
+	}
+
+	i, err := w.pool.wait()
+	w.pool = nil
+	if err != nil {
+		w.t.copied = min(w.t.copied, w.t.offset(i))
+	}
+	return err
+}
@@ -0,0 +1,155 @@
+//go:build linux
+
+package iocopy
+
+import (
+	"context"
+	"errors"
+	"io"
+	"syscall"
+)
+
+// maxSpliceSize is the max number of bytes to splice at a time.
+// It's the default capacity of pipes on Linux.
+const maxSpliceSize = 64 * 1024
+
+// spliceFlags is the flags of splice(2).
+const spliceFlags = 0x1 | 0x2 // SPLICE_F_MOVE | SPLICE_F_NONBLOCK
+
+// rawConn returns the [syscall.RawConn] of v and the file type if v is a file or a connection.
+// It returns false if v is opened with O_APPEND which splice(2) does not support,
+// or v is a blocking pipe or socket(e.g. [os.Stdin] of a shell pipeline) which the runtime poller can't wait for.
+func rawConn(v any) (syscall.RawConn, uint32, bool) {
+	sc, ok := v.(syscall.Conn)
+	if !ok {
+		return nil, 0, false
+	}
+
+	rc, err := sc.SyscallConn()
+	if err != nil {
+		return nil, 0, false
+	}
+
+	var (
+		st      syscall.Stat_t
+		statErr error
+		flags   int
+	)
+	if err = rc.Control(func(fd uintptr) {
+		if statErr = syscall.Fstat(int(fd), &st); statErr != nil {
+			return
+		}
+
+		r, _, errno := syscall.Syscall(syscall.SYS_FCNTL, fd, syscall.F_GETFL, 0)
+		if errno != 0 {
+			statErr = errno
+			return
+		}
+		flags = int(r)
+	}); err != nil || statErr != nil || flags&syscall.O_APPEND != 0 {
+		return nil, 0, false
+	}
+
+	// The runtime poller only waits for the descriptors in non-blocking mode.
+	// splice(2) with SPLICE_F_NONBLOCK returns EAGAIN on the blocking ones and the wait fails.
+	typ := st.Mode & syscall.S_IFMT
+	if isPipeOrSocket(typ) && flags&syscall.O_NONBLOCK == 0 {
+		return nil, 0, false
+	}
+
+	return rc, typ, true
+}
+
+// isPipeOrSocket reports whether the file type is a pipe or a socket.
+func isPipeOrSocket(typ uint32) bool {
+	return typ == syscall.S_IFIFO || typ == syscall.S_IFSOCK
+}
+
+// spliceCopy copies from src to dst by splice(2) through a pipe to avoid copying through user space.
+// It's used when src and dst have file descriptors and one of them is a pipe or socket.
+// It reports the progress by the number of bytes returned by splice(2).
+// handled is false if splice(2) is not used and nothing is copied.
+func spliceCopy(ctx context.Context, dst io.Writer, src io.Reader, pr *progress) (written int64, handled bool, err error) {
+	srcRC, srcType, ok := rawConn(src)
+	if !ok {
+		return 0, false, nil
+	}
+
+	dstRC, dstType, ok := rawConn(dst)
+	if !ok {
+		return 0, false, nil
+	}
+
+	if !isPipeOrSocket(srcType) && !isPipeOrSocket(dstType) {
+		return 0, false, nil
+	}
+
+	var p [2]int
+	if err = syscall.Pipe2(p[:], syscall.O_CLOEXEC|syscall.O_NONBLOCK); err != nil {
+		return 0, false, nil
+	}
+	defer syscall.Close(p[0])
+	defer syscall.Close(p[1])
+
+	for {
+		select {
+		case <-ctx.Done():
+			return written, true, ctx.Err()
+		default:
+		}
+
+		// Move bytes from src to the pipe.
+		var (
+			n     int64
+			opErr error
+		)
+		err = srcRC.Read(func(fd uintptr) bool {
+			n, opErr = syscall.Splice(int(fd), nil, p[1], nil, maxSpliceSize, spliceFlags)
+			return opErr != syscall.EAGAIN
+		})
+		// Fall back to io.Copy if nothing is copied and the poller can't wait for src.
+		// Nothing is moved to the pipe when the wait fails.
+		if err != nil && written == 0 {
+			logDebug(ctx, "iocopy: can't wait for splice(2), fall back to io.Copy", "err", err)
+			return 0, false, nil
+		}
+		if err == nil {
+			err = opErr
+		}
+		if err != nil {
+			// Fall back to io.Copy if splice(2) is not supported and nothing is copied.
+			if written == 0 && (errors.Is(err, syscall.EINVAL) || errors.Is(err, syscall.ENOSYS)) {
+				logDebug(ctx, "iocopy: splice(2) not supported, fall back to io.Copy", "err", err)
+				return 0, false, nil
+			}
+			return written, true, err
+		}
+
+		if n == 0 {
+			// EOF.
+			return written, true, nil
+		}
+
+		// Move bytes from the pipe to dst.
+		for n > 0 {
+			var m int64
+			err = dstRC.Write(func(fd uintptr) bool {
+				m, opErr = syscall.Splice(p[0], nil, int(fd), nil, int(n), spliceFlags)
+				return opErr != syscall.EAGAIN
+			})
+			if err == nil {
+				err = opErr
+			}
+			if err != nil {
+				return written, true, err
+			}
+			if m == 0 {
+				return written, true, io.ErrShortWrite
+			}
+
+			n -= m
+			written += m
+			pr.written(m)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package iocopy_test
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/northbright/iocopy"
+)
+
+// writeSlowly writes the lines to w with a pause between them and closes it, like "(echo a; sleep 1; echo b)".
+func writeSlowly(w *os.File, lines ...string) {
+	defer w.Close()
+	for i, line := range lines {
+		if i > 0 {
+			time.Sleep(100 * time.Millisecond)
+		}
+		if _, err := w.WriteString(line); err != nil {
+			log.Printf("w.WriteString() error: %v", err)
+			return
+		}
+	}
+}
+
+func ExampleCopy_pipe() {
+	// This example copies from a pipe to a file by splice(2).
+	dir, err := os.MkdirTemp("", "iocopy")
+	if err != nil {
+		log.Printf("os.MkdirTemp() error: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		log.Printf("os.Pipe() error: %v", err)
+		return
+	}
+	defer r.Close()
+
+	dst := filepath.Join(dir, "dst")
+	f, err := os.Create(dst)
+	if err != nil {
+		log.Printf("os.Create() error: %v", err)
+		return
+	}
+	defer f.Close()
+
+	go writeSlowly(w, "a\n", "b\n")
+
+	n, err := iocopy.Copy(context.Background(), f, r)
+	if err != nil {
+		log.Printf("iocopy.Copy() error: %v", err)
+		return
+	}
+
+	buf, err := os.ReadFile(dst)
+	if err != nil {
+		log.Printf("os.ReadFile() error: %v", err)
+		return
+	}
+	fmt.Printf("written: %v, content: %q\n", n, buf)
+
+	// Output:
+	// written: 4, content: "a\nb\n"
+}
+
+func ExampleCopy_blockingPipe() {
+	// This example copies from a blocking pipe which the runtime poller can't wait for,
+	// e.g. the standard input of a shell pipeline like "(echo a; sleep 1; echo b) | prog".
+	dir, err := os.MkdirTemp("", "iocopy")
+	if err != nil {
+		log.Printf("os.MkdirTemp() error: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	var p [2]int
+	if err = syscall.Pipe2(p[:], syscall.O_CLOEXEC); err != nil {
+		log.Printf("syscall.Pipe2() error: %v", err)
+		return
+	}
+
+	// os.NewFile keeps the blocking descriptors out of the poller as [os.Stdin].
+	r := os.NewFile(uintptr(p[0]), "stdin")
+	defer r.Close()
+	w := os.NewFile(uintptr(p[1]), "stdout")
+
+	dst := filepath.Join(dir, "dst")
+	f, err := os.Create(dst)
+	if err != nil {
+		log.Printf("os.Create() error: %v", err)
+		return
+	}
+	defer f.Close()
+
+	go writeSlowly(w, "a\n", "b\n")
+
+	n, err := iocopy.Copy(context.Background(), f, r)
+	if err != nil {
+		log.Printf("iocopy.Copy() error: %v", err)
+		return
+	}
+
+	buf, err := os.ReadFile(dst)
+	if err != nil {
+		log.Printf("os.ReadFile() error: %v", err)
+		return
+	}
+	fmt.Printf("written: %v, content: %q\n", n, buf)
+
+	// Output:
+	// written: 4, content: "a\nb\n"
+}
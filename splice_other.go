@@ -0,0 +1,13 @@
+//go:build !linux
+
+package iocopy
+
+import (
+	"context"
+	"io"
+)
+
+// spliceCopy is only implemented on Linux.
+func spliceCopy(ctx context.Context, dst io.Writer, src io.Reader, pr *progress) (written int64, handled bool, err error) {
+	return 0, false, nil
+}
@@ -0,0 +1,113 @@
+package iocopy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// StateCodec encodes the marshaled JSON states of the tasks to another format and decodes them back,
+// e.g. to embed the states in systems with strict schemas or size constraints.
+// Tasks always marshal and load their states as JSON, so the codecs only change how the states are stored.
+// [JSONCodec] is the default. Use [ContextWithStateCodec] to select the codec of a task
+// and [TaskManager.SetStateCodec] to select the one of a task store.
+type StateCodec interface {
+	// Name returns the name of the codec which is saved with the encoded states, e.g. "cbor".
+	Name() string
+	// Marshal encodes the JSON state.
+	Marshal(state []byte) ([]byte, error)
+	// Unmarshal decodes the data encoded by Marshal to the JSON state.
+	Unmarshal(data []byte) ([]byte, error)
+}
+
+var (
+	// JSONCodec is the default [StateCodec] which keeps the states as compact JSON.
+	JSONCodec StateCodec = jsonCodec{}
+
+	// CBORCodec is the [StateCodec] which encodes the states as CBOR(RFC 8949).
+	// Objects are encoded as maps with text keys sorted, integers as the integer types and other numbers as float64.
+	// Byte strings(e.g. the states of the hashes) are base64 encoded strings as they're in JSON.
+	// Byte strings decoded from other encoders are converted to base64 encoded strings.
+	CBORCodec StateCodec = cborCodec{}
+
+	// ProtoCodec is the [StateCodec] which encodes the states as the protobuf wire format of google.protobuf.Struct,
+	// so they can be decoded by the protobuf well-known type in other systems.
+	// Numbers are doubles, so integers beyond 2^53 lose precision.
+	ProtoCodec StateCodec = protoCodec{}
+)
+
+// stateCodecs are the built-in codecs by their names.
+var stateCodecs = map[string]StateCodec{
+	JSONCodec.Name():  JSONCodec,
+	CBORCodec.Name():  CBORCodec,
+	ProtoCodec.Name(): ProtoCodec,
+}
+
+// jsonCodec implements [StateCodec] by JSON.
+type jsonCodec struct{}
+
+// Name implements [StateCodec] interface.
+func (jsonCodec) Name() string {
+	return "json"
+}
+
+// Marshal implements [StateCodec] interface. It compacts the state.
+func (jsonCodec) Marshal(state []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, state); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Unmarshal implements [StateCodec] interface.
+func (jsonCodec) Unmarshal(data []byte) ([]byte, error) {
+	if !json.Valid(data) {
+		return nil, errors.New("invalid JSON state")
+	}
+	return data, nil
+}
+
+// decodeJSONState decodes the JSON state with the numbers as [json.Number] to keep the integers.
+func decodeJSONState(state []byte) (any, error) {
+	d := json.NewDecoder(bytes.NewReader(state))
+	d.UseNumber()
+
+	var v any
+	if err := d.Decode(&v); err != nil {
+		return nil, err
+	}
+
+	if d.More() {
+		return nil, errors.New("invalid JSON state: trailing data")
+	}
+	return v, nil
+}
+
+// stateCodecKey is the context key of [StateCodec].
+type stateCodecKey struct{}
+
+// ContextWithStateCodec returns a copy of ctx with c attached.
+// [Do] and the runners([Installer], [DirVerifier] and [TaskGroup]) encode the states of [*EventStop] by c
+// when they run with the returned context. Decode them by c before loading the tasks, e.g. by [LoadTask].
+// The states are masked by the [Redactor] of [ContextWithRedactor](if any) before they're encoded.
+func ContextWithStateCodec(ctx context.Context, c StateCodec) context.Context {
+	return context.WithValue(ctx, stateCodecKey{}, c)
+}
+
+// stopState returns the state of [*EventStop] masked by the [Redactor] and encoded by the [StateCodec] attached to ctx.
+func stopState(ctx context.Context, state []byte) ([]byte, error) {
+	state = redactJSON(ctx, state)
+	c, _ := ctx.Value(stateCodecKey{}).(StateCodec)
+	if c == nil {
+		return state, nil
+	}
+
+	data, err := c.Marshal(state)
+	if err != nil {
+		return nil, fmt.Errorf("encode state by %v: %w", c.Name(), err)
+	}
+	return data, nil
+}
@@ -0,0 +1,301 @@
+package iocopy_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"time"
+
+	"github.com/northbright/iocopy"
+)
+
+func ExampleContextWithStateCodec() {
+	// This example stops a copy with the state encoded as CBOR to keep it small,
+	// then decodes the state and resumes the copy.
+	dir, err := os.MkdirTemp("", "iocopy")
+	if err != nil {
+		log.Printf("os.MkdirTemp() error: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	src := filepath.Join(dir, "src")
+	if err = os.WriteFile(src, bytes.Repeat([]byte("0123456789abcdef"), 4096), 0644); err != nil {
+		log.Printf("os.WriteFile() error: %v", err)
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var data []byte
+	t := iocopy.NewCopyFileTask(filepath.Join(dir, "dst"), src, nil)
+	iocopy.Do(iocopy.ContextWithStateCodec(ctx, iocopy.CBORCodec), t, make([]byte, 1024), func(e iocopy.Event) {
+		switch e := e.(type) {
+		case *iocopy.EventWritten:
+			// Emulate user cancelation.
+			cancel()
+		case *iocopy.EventStop:
+			data = e.State
+		}
+	})
+
+	state, err := iocopy.CBORCodec.Unmarshal(data)
+	if err != nil {
+		log.Printf("Unmarshal() error: %v", err)
+		return
+	}
+	fmt.Printf("CBOR is smaller than JSON: %v\n", len(data) < len(state))
+
+	resumed, err := iocopy.LoadTask(state)
+	if err != nil {
+		log.Printf("iocopy.LoadTask() error: %v", err)
+		return
+	}
+
+	if err = iocopy.Do(context.Background(), resumed, nil, nil); err != nil {
+		log.Printf("iocopy.Do() error: %v", err)
+		return
+	}
+	fmt.Printf("done: %v\n", resumed.Copied() == resumed.Total())
+
+	// Output:
+	// CBOR is smaller than JSON: true
+	// done: true
+}
+
+// stoppedStates returns the states of the tasks of all kinds stopped after the first bytes written,
+// and the states of the runners, keyed by their types.
+func stoppedStates(dir string) (map[string][]byte, error) {
+	data := bytes.Repeat([]byte("0123456789abcdef"), 64*1024)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "file", time.Time{}, bytes.NewReader(data))
+	}))
+	defer ts.Close()
+
+	srcDir := filepath.Join(dir, "src")
+	if err := os.MkdirAll(srcDir, 0755); err != nil {
+		return nil, err
+	}
+
+	src := filepath.Join(srcDir, "file")
+	if err := os.WriteFile(src, data, 0644); err != nil {
+		return nil, err
+	}
+
+	states := map[string][]byte{}
+	stop := func(cancel context.CancelFunc, typ string) iocopy.OnEventFunc {
+		return func(e iocopy.Event) {
+			switch e := e.(type) {
+			case *iocopy.EventWritten:
+				// Emulate user cancelation.
+				cancel()
+			case *iocopy.EventStop:
+				states[typ] = e.State
+			}
+		}
+	}
+
+	for typ, t := range map[string]iocopy.Task{
+		iocopy.StateTypeDownload: iocopy.NewDownloadTask(filepath.Join(dir, "download"), ts.URL, nil, iocopy.WithHash("sha256"), iocopy.WithConnections(2)),
+		iocopy.StateTypeCopyFile: iocopy.NewCopyFileTask(filepath.Join(dir, "copy"), src, nil),
+		iocopy.StateTypeHash:     iocopy.NewHashTask(src, []string{"md5", "sha256"}),
+		iocopy.StateTypeCopyFS:   iocopy.NewCopyFSTask(filepath.Join(dir, "copyfs"), os.DirFS(srcDir), nil),
+		iocopy.StateTypeZipDir:   iocopy.NewZipDirTask(filepath.Join(dir, "src.zip"), srcDir),
+		iocopy.StateTypePipeline: iocopy.NewPipelineTask(filepath.Join(dir, "dst", "file"), src, "file", iocopy.Pipeline{Staging: filepath.Join(dir, "staging"), Alg: "sha256"}),
+	} {
+		ctx, cancel := context.WithCancel(context.Background())
+		iocopy.Do(ctx, t, make([]byte, 1024), stop(cancel, typ))
+		cancel()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	iocopy.NewTaskGroup(iocopy.NewCopyFileTask(filepath.Join(dir, "group"), src, nil)).Run(ctx, 0, stop(cancel, iocopy.StateTypeTaskGroup))
+	cancel()
+
+	g := iocopy.NewDAG()
+	if err := g.Add("hash", iocopy.NewHashTask(src, []string{"sha256"})); err != nil {
+		return nil, err
+	}
+
+	in := iocopy.NewInstaller(filepath.Join(dir, "install"), iocopy.Manifest{Assets: []iocopy.Asset{{Path: "file", Size: int64(len(data))}}}, os.DirFS(srcDir))
+	v := iocopy.NewDirVerifier(srcDir, "sha256", map[string]string{"file": "0123"})
+
+	for typ, fn := range map[string]func() ([]byte, error){
+		iocopy.StateTypeDAG:         g.State,
+		iocopy.StateTypeInstaller:   in.State,
+		iocopy.StateTypeDirVerifier: v.State,
+	} {
+		state, err := fn()
+		if err != nil {
+			return nil, err
+		}
+		states[typ] = state
+	}
+	return states, nil
+}
+
+// jsonEqual reports whether the JSON values are equal.
+func jsonEqual(a, b []byte) bool {
+	var va, vb any
+	if json.Unmarshal(a, &va) != nil || json.Unmarshal(b, &vb) != nil {
+		return false
+	}
+	return reflect.DeepEqual(va, vb)
+}
+
+func ExampleStateCodec() {
+	// This example encodes the states of all kinds of tasks and runners by each codec,
+	// decodes them back and checks they're the same JSON values and they're loaded.
+	dir, err := os.MkdirTemp("", "iocopy")
+	if err != nil {
+		log.Printf("os.MkdirTemp() error: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	states, err := stoppedStates(dir)
+	if err != nil {
+		log.Printf("stoppedStates() error: %v", err)
+		return
+	}
+
+	iocopy.RegisterLoader(iocopy.StateTypeCopyFS, func(state []byte) (iocopy.Task, error) {
+		return iocopy.LoadCopyFSTask(state, os.DirFS(filepath.Join(dir, "src")), nil)
+	})
+
+	for _, typ := range []string{
+		iocopy.StateTypeDownload,
+		iocopy.StateTypeCopyFile,
+		iocopy.StateTypeHash,
+		iocopy.StateTypeCopyFS,
+		iocopy.StateTypeZipDir,
+		iocopy.StateTypePipeline,
+		iocopy.StateTypeTaskGroup,
+		iocopy.StateTypeDAG,
+		iocopy.StateTypeInstaller,
+		iocopy.StateTypeDirVerifier,
+	} {
+		fmt.Printf("%v:", typ)
+		for _, c := range []iocopy.StateCodec{iocopy.JSONCodec, iocopy.CBORCodec, iocopy.ProtoCodec} {
+			data, err := c.Marshal(states[typ])
+			if err != nil {
+				log.Printf("%v Marshal() error: %v", c.Name(), err)
+				return
+			}
+
+			state, err := c.Unmarshal(data)
+			if err != nil {
+				log.Printf("%v Unmarshal() error: %v", c.Name(), err)
+				return
+			}
+
+			// The states of the runners are loaded by their own functions.
+			if _, err = iocopy.LoadTask(state); err != nil && !errors.Is(err, iocopy.ErrNotTaskState) {
+				log.Printf("iocopy.LoadTask() error: %v", err)
+				return
+			}
+			fmt.Printf(" %v: %v", c.Name(), jsonEqual(states[typ], state))
+		}
+		fmt.Println()
+	}
+
+	// Output:
+	// download: json: true cbor: true proto: true
+	// copyfile: json: true cbor: true proto: true
+	// hash: json: true cbor: true proto: true
+	// copyfs: json: true cbor: true proto: true
+	// zipdir: json: true cbor: true proto: true
+	// pipeline: json: true cbor: true proto: true
+	// taskgroup: json: true cbor: true proto: true
+	// dag: json: true cbor: true proto: true
+	// installer: json: true cbor: true proto: true
+	// dirverifier: json: true cbor: true proto: true
+}
+
+func ExampleStateCodec_values() {
+	// This example shows how the codecs decode nested lists, nulls and large integers.
+	// Integers beyond 2^53 lose precision in ProtoCodec since the numbers are doubles.
+	state := []byte(`{"list":[[1,[2,null]],[],[{"a":null}]],"null":null,"max":9007199254740993,"min":-9007199254740993,"float":1.5,"str":"s","bool":true}`)
+
+	for _, c := range []iocopy.StateCodec{iocopy.JSONCodec, iocopy.CBORCodec, iocopy.ProtoCodec} {
+		data, err := c.Marshal(state)
+		if err != nil {
+			log.Printf("%v Marshal() error: %v", c.Name(), err)
+			return
+		}
+
+		decoded, err := c.Unmarshal(data)
+		if err != nil {
+			log.Printf("%v Unmarshal() error: %v", c.Name(), err)
+			return
+		}
+		fmt.Printf("%v: %s\n", c.Name(), decoded)
+	}
+
+	// Output:
+	// json: {"list":[[1,[2,null]],[],[{"a":null}]],"null":null,"max":9007199254740993,"min":-9007199254740993,"float":1.5,"str":"s","bool":true}
+	// cbor: {"bool":true,"float":1.5,"list":[[1,[2,null]],[],[{"a":null}]],"max":9007199254740993,"min":-9007199254740993,"null":null,"str":"s"}
+	// proto: {"bool":true,"float":1.5,"list":[[1,[2,null]],[],[{"a":null}]],"max":9007199254740992,"min":-9007199254740992,"null":null,"str":"s"}
+}
+
+func ExampleStateCodec_corrupt() {
+	// This example shows the codecs return errors for the truncated or corrupt data.
+	state := []byte(`{"type":"copyfile","list":[1,"a",{"b":null}]}`)
+
+	for _, c := range []iocopy.StateCodec{iocopy.JSONCodec, iocopy.CBORCodec, iocopy.ProtoCodec} {
+		data, err := c.Marshal(state)
+		if err != nil {
+			log.Printf("%v Marshal() error: %v", c.Name(), err)
+			return
+		}
+
+		_, err = c.Unmarshal(data[:len(data)-1])
+		fmt.Printf("%v: truncated: %v", c.Name(), err != nil)
+
+		_, err = c.Unmarshal([]byte{0xff, 0xff, 0xff})
+		fmt.Printf(", corrupt: %v", err != nil)
+
+		_, err = c.Marshal(state[:len(state)-1])
+		fmt.Printf(", invalid JSON: %v\n", err != nil)
+	}
+
+	// Only JSON objects are encoded by ProtoCodec.
+	_, err := iocopy.ProtoCodec.Marshal([]byte(`[1,2]`))
+	fmt.Printf("proto array: %v\n", err != nil)
+
+	// Output:
+	// json: truncated: true, corrupt: true, invalid JSON: true
+	// cbor: truncated: true, corrupt: true, invalid JSON: true
+	// proto: truncated: true, corrupt: true, invalid JSON: true
+	// proto array: true
+}
+
+func ExampleJSONCodec() {
+	// This example compacts a state by JSONCodec. The decoded state is the compact JSON.
+	data, err := iocopy.JSONCodec.Marshal([]byte("{\n    \"type\": \"copyfile\",\n    \"copied\": 1024\n}"))
+	if err != nil {
+		log.Printf("Marshal() error: %v", err)
+		return
+	}
+	fmt.Printf("%v: %s\n", iocopy.JSONCodec.Name(), data)
+
+	state, err := iocopy.JSONCodec.Unmarshal(data)
+	if err != nil {
+		log.Printf("Unmarshal() error: %v", err)
+		return
+	}
+	fmt.Printf("decoded: %s\n", state)
+
+	// Output:
+	// json: {"type":"copyfile","copied":1024}
+	// decoded: {"type":"copyfile","copied":1024}
+}
@@ -0,0 +1,64 @@
+package iocopy
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+)
+
+// EncryptState encrypts the marshaled state of a task by AES-GCM with key, which is 16, 24 or 32 bytes
+// to select AES-128, AES-192 or AES-256.
+// States may contain signed urls, auth tokens or private file paths which shouldn't be kept in plaintext on disk.
+// The random nonce is prepended to the ciphertext. Use [DecryptState] to decrypt it.
+// additionalData is authenticated but not encrypted, e.g. the type and the destination of the task,
+// so the encrypted state can't be moved to another task. It's nil if there's none.
+// The same additional data should be passed to [DecryptState].
+func EncryptState(state, key, additionalData []byte) ([]byte, error) {
+	aead, err := newStateAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(state)+aead.Overhead())
+	if _, err = rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, state, additionalData), nil
+}
+
+// DecryptState decrypts the state encrypted by [EncryptState] with the same key and additional data.
+// It returns an error if the key or the additional data is wrong, or the encrypted state is tampered.
+func DecryptState(encrypted, key, additionalData []byte) ([]byte, error) {
+	aead, err := newStateAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(encrypted) < aead.NonceSize() {
+		return nil, errors.New("encrypted state is too short")
+	}
+
+	nonce, ciphertext := encrypted[:aead.NonceSize()], encrypted[aead.NonceSize():]
+	state, err := aead.Open(nil, nonce, ciphertext, additionalData)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt state: %w", err)
+	}
+	return state, nil
+}
+
+// stateAdditionalData returns the additional data of the encrypted state of the task saved by [TaskManager].
+// It binds the state to the kind and the id of the task, which is the name of its state file.
+func stateAdditionalData(kind, id string) []byte {
+	return []byte(kind + "\x00" + id)
+}
+
+// newStateAEAD returns the AES-GCM AEAD of the key.
+func newStateAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
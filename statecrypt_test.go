@@ -0,0 +1,36 @@
+package iocopy_test
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/northbright/iocopy"
+)
+
+func ExampleEncryptState() {
+	// This example encrypts the state of a task bound to its type and destination.
+	// Use a key from a secret store in practice.
+	key := []byte("0123456789abcdef0123456789abcdef")
+	state := []byte(`{"version":1,"type":"download","url":"https://example.com/file?token=secret","dst":"file"}`)
+
+	encrypted, err := iocopy.EncryptState(state, key, []byte("download:file"))
+	if err != nil {
+		log.Printf("iocopy.EncryptState() error: %v", err)
+		return
+	}
+
+	decrypted, err := iocopy.DecryptState(encrypted, key, []byte("download:file"))
+	if err != nil {
+		log.Printf("iocopy.DecryptState() error: %v", err)
+		return
+	}
+	fmt.Printf("decrypted: %s\n", decrypted)
+
+	// The state can't be decrypted for another destination.
+	_, err = iocopy.DecryptState(encrypted, key, []byte("download:other"))
+	fmt.Printf("decrypted for another destination: %v\n", err == nil)
+
+	// Output:
+	// decrypted: {"version":1,"type":"download","url":"https://example.com/file?token=secret","dst":"file"}
+	// decrypted for another destination: false
+}
@@ -0,0 +1,128 @@
+package iocopy
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// StateVersion is the version of the states of the tasks.
+// It's increased when the states change incompatibly and [LoadTask] refuses the states of newer versions.
+const StateVersion = 1
+
+// Types of the states of the built-in tasks which are loaded by [LoadTask].
+const (
+	StateTypeDownload   = "download"
+	StateTypeCopyFile   = "copyfile"
+	StateTypeHash       = "hash"
+	StateTypeCopyFS     = "copyfs"
+	StateTypeZipDir     = "zipdir"
+	StateTypePipeline   = "pipeline"
+	StateTypeExtractTar = "extracttar"
+)
+
+// Types of the states of the runners which are not tasks.
+// They're loaded by their own functions, e.g. [LoadTaskGroup], instead of [LoadTask].
+const (
+	StateTypeTaskGroup   = "taskgroup"
+	StateTypeDAG         = "dag"
+	StateTypeInstaller   = "installer"
+	StateTypeDirVerifier = "dirverifier"
+)
+
+var (
+	// ErrUnknownStateType is returned by [LoadTask] when the state has no type or no loader is registered for its type.
+	ErrUnknownStateType = errors.New("unknown state type")
+
+	// ErrNotTaskState is returned by [LoadTask] when the state is the one of a runner which is not a task,
+	// e.g. [TaskGroup]. Load it by its own function, e.g. [LoadTaskGroup].
+	ErrNotTaskState = errors.New("state is not a task")
+)
+
+// stateHeader is the type tag and the version embedded in the states.
+type stateHeader struct {
+	Version int    `json:"version"`
+	Type    string `json:"type"`
+}
+
+// check returns an error if the version is newer than [StateVersion] or the type is not typ.
+// The states without the type saved by the previous versions are accepted.
+func (h stateHeader) check(typ string) error {
+	if h.Type != "" && h.Type != typ {
+		return fmt.Errorf("state type %q is not %q", h.Type, typ)
+	}
+
+	if h.Version > StateVersion {
+		return fmt.Errorf("unsupported version of %v state: %v", typ, h.Version)
+	}
+	return nil
+}
+
+// runnerLoaders are the names of the functions to load the states of the runners by their types.
+var runnerLoaders = map[string]string{
+	StateTypeTaskGroup:   "LoadTaskGroup",
+	StateTypeDAG:         "LoadDAG",
+	StateTypeInstaller:   "LoadInstaller",
+	StateTypeDirVerifier: "LoadDirVerifier",
+}
+
+var (
+	loadersMu sync.RWMutex
+	// loaders are the functions to load the tasks by the types of their states.
+	loaders = map[string]LoadTaskFunc{
+		StateTypeDownload:   func(state []byte) (Task, error) { return LoadDownloadTask(state, nil) },
+		StateTypeCopyFile:   func(state []byte) (Task, error) { return LoadCopyFileTask(state, nil) },
+		StateTypeHash:       func(state []byte) (Task, error) { return LoadHashTask(state) },
+		StateTypeZipDir:     func(state []byte) (Task, error) { return LoadZipDirTask(state) },
+		StateTypePipeline:   func(state []byte) (Task, error) { return LoadPipelineTask(state) },
+		StateTypeExtractTar: func(state []byte) (Task, error) { return LoadExtractTarTask(state, nil) },
+		StateTypeCopyFS: func(state []byte) (Task, error) {
+			return nil, fmt.Errorf("%w: %q needs the source file system which is not saved, register a loader by RegisterLoader", ErrUnknownStateType, StateTypeCopyFS)
+		},
+	}
+)
+
+// RegisterLoader registers the function to load the tasks whose states have the type by [LoadTask],
+// e.g. custom tasks which embed "type" in their states, or built-in ones loaded with options.
+// It replaces the function registered for the type.
+func RegisterLoader(typ string, load LoadTaskFunc) {
+	loadersMu.Lock()
+	defer loadersMu.Unlock()
+	loaders[typ] = load
+}
+
+// LoadTask loads a task from the state by the function registered for the "type" of the state,
+// so a generic resume manager doesn't need to know which constructor to call.
+// The states of [DownloadTask], [CopyFileTask], [HashTask], [ZipDirTask], [PipelineTask] and [ExtractTarTask]
+// are loaded without options and the default file system.
+// The source file system of [CopyFSTask] is not saved, so register a loader of [StateTypeCopyFS] to load it with the source.
+// Register other loaders by [RegisterLoader] to load them with options.
+// It returns an error wrapping [ErrUnknownStateType] if the type is unknown,
+// an error wrapping [ErrNotTaskState] if it's the state of a runner(e.g. [TaskGroup]),
+// or an error if the version is newer than [StateVersion].
+func LoadTask(state []byte) (Task, error) {
+	var h stateHeader
+	if err := json.Unmarshal(state, &h); err != nil {
+		return nil, err
+	}
+
+	if h.Type == "" {
+		return nil, fmt.Errorf("%w: no type in state", ErrUnknownStateType)
+	}
+
+	if h.Version > StateVersion {
+		return nil, fmt.Errorf("unsupported version of %v state: %v", h.Type, h.Version)
+	}
+
+	loadersMu.RLock()
+	load, ok := loaders[h.Type]
+	loadersMu.RUnlock()
+	if !ok {
+		if fn, ok := runnerLoaders[h.Type]; ok {
+			return nil, fmt.Errorf("%w: load %v state by %v", ErrNotTaskState, h.Type, fn)
+		}
+		return nil, fmt.Errorf("%w: %q", ErrUnknownStateType, h.Type)
+	}
+	return load(state)
+}
@@ -0,0 +1,148 @@
+package iocopy_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/northbright/iocopy"
+)
+
+func ExampleLoadTask() {
+	// This example stops a copy and a hash task and resumes them from their states
+	// without knowing which constructor to call, like a generic resume manager.
+	dir, err := os.MkdirTemp("", "iocopy")
+	if err != nil {
+		log.Printf("os.MkdirTemp() error: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	src := filepath.Join(dir, "src")
+	if err = os.WriteFile(src, bytes.Repeat([]byte("0123456789abcdef"), 4096), 0644); err != nil {
+		log.Printf("os.WriteFile() error: %v", err)
+		return
+	}
+
+	var states [][]byte
+	for _, t := range []iocopy.Task{
+		iocopy.NewCopyFileTask(filepath.Join(dir, "dst"), src, nil),
+		iocopy.NewHashTask(src, []string{"sha256"}),
+	} {
+		ctx, cancel := context.WithCancel(context.Background())
+		iocopy.Do(ctx, t, make([]byte, 1024), func(e iocopy.Event) {
+			switch e := e.(type) {
+			case *iocopy.EventWritten:
+				// Emulate user cancelation.
+				cancel()
+			case *iocopy.EventStop:
+				states = append(states, e.State)
+			}
+		})
+		cancel()
+	}
+
+	for _, state := range states {
+		t, err := iocopy.LoadTask(state)
+		if err != nil {
+			log.Printf("iocopy.LoadTask() error: %v", err)
+			return
+		}
+
+		resumed := t.Copied() > 0
+		if err = iocopy.Do(context.Background(), t, nil, nil); err != nil {
+			log.Printf("iocopy.Do() error: %v", err)
+			return
+		}
+		fmt.Printf("%T resumed: %v, done: %v\n", t, resumed, t.Copied() == t.Total())
+	}
+
+	_, err = iocopy.LoadTask([]byte(`{"dst":"a","src":"b"}`))
+	fmt.Printf("no type: %v\n", errors.Is(err, iocopy.ErrUnknownStateType))
+
+	// Output:
+	// *iocopy.CopyFileTask resumed: true, done: true
+	// *iocopy.HashTask resumed: true, done: true
+	// no type: true
+}
+
+func ExampleRegisterLoader() {
+	// This example stops the tasks of the other kinds and resumes them by LoadTask.
+	// The source file system of CopyFSTask is not saved in the state, so its loader is registered.
+	dir, err := os.MkdirTemp("", "iocopy")
+	if err != nil {
+		log.Printf("os.MkdirTemp() error: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	srcDir := filepath.Join(dir, "src")
+	if err = os.MkdirAll(srcDir, 0755); err != nil {
+		log.Printf("os.MkdirAll() error: %v", err)
+		return
+	}
+
+	src := filepath.Join(srcDir, "file")
+	if err = os.WriteFile(src, bytes.Repeat([]byte("0123456789abcdef"), 4096), 0644); err != nil {
+		log.Printf("os.WriteFile() error: %v", err)
+		return
+	}
+
+	var states [][]byte
+	for _, t := range []iocopy.Task{
+		iocopy.NewZipDirTask(filepath.Join(dir, "src.zip"), srcDir),
+		iocopy.NewPipelineTask(filepath.Join(dir, "dst", "file"), src, "file", iocopy.Pipeline{Staging: filepath.Join(dir, "staging"), Alg: "sha256"}),
+		iocopy.NewCopyFSTask(filepath.Join(dir, "copy"), os.DirFS(srcDir), nil),
+	} {
+		ctx, cancel := context.WithCancel(context.Background())
+		iocopy.Do(ctx, t, make([]byte, 1024), func(e iocopy.Event) {
+			switch e := e.(type) {
+			case *iocopy.EventWritten:
+				// Emulate user cancelation.
+				cancel()
+			case *iocopy.EventStop:
+				states = append(states, e.State)
+			}
+		})
+		cancel()
+	}
+
+	// The state of CopyFSTask can't be loaded until a loader with the source is registered.
+	iocopy.RegisterLoader(iocopy.StateTypeCopyFS, func(state []byte) (iocopy.Task, error) {
+		return iocopy.LoadCopyFSTask(state, os.DirFS(srcDir), nil)
+	})
+
+	for _, state := range states {
+		t, err := iocopy.LoadTask(state)
+		if err != nil {
+			log.Printf("iocopy.LoadTask() error: %v", err)
+			return
+		}
+
+		if err = iocopy.Do(context.Background(), t, nil, nil); err != nil {
+			log.Printf("iocopy.Do() error: %v", err)
+			return
+		}
+		fmt.Printf("%T done: %v\n", t, t.Copied() == t.Total())
+	}
+
+	for _, file := range []string{filepath.Join(dir, "dst", "file"), filepath.Join(dir, "copy", "file")} {
+		fi, err := os.Stat(file)
+		if err != nil {
+			log.Printf("os.Stat() error: %v", err)
+			return
+		}
+		fmt.Printf("%v: %v bytes\n", filepath.Base(filepath.Dir(file)), fi.Size())
+	}
+
+	// Output:
+	// *iocopy.ZipDirTask done: true
+	// *iocopy.PipelineTask done: true
+	// *iocopy.CopyFSTask done: true
+	// dst: 65536 bytes
+	// copy: 65536 bytes
+}
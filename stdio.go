@@ -0,0 +1,28 @@
+package iocopy
+
+import (
+	"fmt"
+	"os"
+)
+
+// Stdio is the name of the standard input as the source or the standard output as the destination,
+// e.g. of [CopyFileTask], [DownloadTask], [HashTask] and [Download], like "-" of many CLIs.
+// The names of [os.Stdin] and [os.Stdout] are also accepted.
+// They're streamed with unknown total size(of the standard input) and can't be resumed,
+// so the tools based on iocopy compose in shell pipelines while still reporting the progress, e.g. on the standard error.
+const Stdio = "-"
+
+// isStdin reports whether the source name is the standard input.
+func isStdin(name string) bool {
+	return name == Stdio || name == os.Stdin.Name()
+}
+
+// isStdout reports whether the destination name is the standard output.
+func isStdout(name string) bool {
+	return name == Stdio || name == os.Stdout.Name()
+}
+
+// errStdioResume is returned when a task streaming the standard input or output is resumed.
+func errStdioResume(name string) error {
+	return fmt.Errorf("%v is the standard input or output and can't be resumed", name)
+}
@@ -0,0 +1,66 @@
+package iocopy_test
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/northbright/iocopy"
+)
+
+func ExampleStdio() {
+	// This example copies a file to the standard output like "cat" and hashes the standard input like "sha256sum -".
+	dir, err := os.MkdirTemp("", "iocopy")
+	if err != nil {
+		log.Printf("os.MkdirTemp() error: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	src := filepath.Join(dir, "src")
+	if err = os.WriteFile(src, []byte("Hello, World!\n"), 0644); err != nil {
+		log.Printf("os.WriteFile() error: %v", err)
+		return
+	}
+
+	t := iocopy.NewCopyFileTask(iocopy.Stdio, src, nil)
+	if err = iocopy.Do(context.Background(), t, nil, nil); err != nil {
+		log.Printf("iocopy.Do() error: %v", err)
+		return
+	}
+
+	// Redirect the standard input from the file.
+	f, err := os.Open(src)
+	if err != nil {
+		log.Printf("os.Open() error: %v", err)
+		return
+	}
+	defer f.Close()
+
+	stdin := os.Stdin
+	os.Stdin = f
+	defer func() { os.Stdin = stdin }()
+
+	// Total size of the standard input is unknown.
+	indeterminate := false
+	ht := iocopy.NewHashTask(iocopy.Stdio, []string{"sha256"})
+	err = iocopy.Do(context.Background(), ht, nil, func(e iocopy.Event) {
+		switch e := e.(type) {
+		case *iocopy.EventWritten:
+			indeterminate = e.Indeterminate
+		case *iocopy.EventOK:
+			r := e.Value.(iocopy.HashResult)
+			fmt.Printf("indeterminate: %v, size: %v, sha256: %v\n", indeterminate, r.Size, r.Checksums["sha256"])
+		}
+	})
+	if err != nil {
+		log.Printf("iocopy.Do() error: %v", err)
+		return
+	}
+
+	// Output:
+	// Hello, World!
+	// indeterminate: true, size: 14, sha256: c98c24b677eff44860afea6f493bbaec5bb1c4cbb209c6fc2bbb47f66ff2ad31
+}
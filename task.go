@@ -0,0 +1,487 @@
+package iocopy
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Task represents an IO copy task which can be stopped and resumed.
+//
+// Do calls Open to get the source and destination,
+// copies bytes and updates the number of bytes copied by SetCopied,
+// then calls Close.
+// State is used to save the task when it's stopped and
+// Result is used to get the output when it's done.
+type Task interface {
+	// Open prepares the source and destination to copy from the copied position.
+	// It's called by Do before the IO copy starts.
+	// Total should be valid after Open returns.
+	// It can be updated during the copy if the size becomes known later,
+	// and Do reports the progress with the updated total.
+	Open(ctx context.Context) (dst io.Writer, src io.Reader, err error)
+	// Close releases the resources allocated by Open.
+	// It's called by Do after the IO copy ends.
+	Close() error
+	// Total returns the total number of bytes to copy.
+	// A negative value indicates total size is unknown.
+	Total() int64
+	// Copied returns the number of bytes copied.
+	Copied() int64
+	// SetCopied sets the number of bytes copied.
+	// It's called by Do to update the progress.
+	SetCopied(copied int64)
+	// State returns the marshaled state which is used to resume the task.
+	State() ([]byte, error)
+	// Result returns the marshaled result after the task is done.
+	Result() ([]byte, error)
+}
+
+// ErrNoSpace is the cause of [*EventStop] when the destination file system is full.
+// The task can be resumed after space is freed.
+var ErrNoSpace = errors.New("no space left on destination")
+
+// Committer is implemented by destinations which need to be committed after all bytes are written,
+// e.g. files of [CASFS].
+// Do calls Commit before Close when the IO copy is done.
+// Destinations which are closed without Commit should keep the written bytes to resume.
+type Committer interface {
+	Commit() error
+}
+
+// Identifier is implemented by tasks which have a deterministic ID, e.g. [*CopyFileTask] and [*DownloadTask].
+// Tasks with the same ID do the same work, so duplicate ones can be detected and coalesced.
+type Identifier interface {
+	ID() string
+}
+
+// taskID returns a deterministic ID computed from the kind of the task and the parts, e.g. dst and src.
+// It's the hex encoded first 16 bytes of the SHA-256 digest and it's safe to be used as a file name.
+func taskID(kind string, parts ...string) string {
+	h := sha256.New()
+	io.WriteString(h, kind)
+	for _, part := range parts {
+		h.Write([]byte{0})
+		io.WriteString(h, part)
+	}
+	return hex.EncodeToString(h.Sum(nil)[:16])
+}
+
+// ResultValuer is implemented by tasks which return typed results,
+// e.g. [CopyFileResult] of [*CopyFileTask] and [DownloadResult] of [*DownloadTask].
+// Do reports the typed result by Value of [*EventOK], so consumers don't need to decode the JSON result.
+type ResultValuer interface {
+	// ResultValue returns the typed result after the task is done.
+	ResultValue() any
+}
+
+// Cleaner is implemented by tasks which clean up the partial destination when they fail or are stopped.
+// Do calls Cleanup after Close with the error. The state should still be valid to resume after Cleanup.
+type Cleaner interface {
+	Cleanup(cause error) error
+}
+
+// FileProgresser is implemented by tasks which copy multiple files, e.g. [*CopyFSTask] and [*ZipDirTask].
+// Do reports the progress of the current file by File of [*EventWritten].
+type FileProgresser interface {
+	// FileProgress returns the progress of the current file. ok is false if there's no file.
+	FileProgress() (p FileProgress, ok bool)
+}
+
+// FileProgress is the progress of the current file of a multi-file task,
+// e.g. "copying 37/120: photos/IMG_2041.jpg (63%)".
+type FileProgress struct {
+	// Index is the index of the file starting from 0.
+	Index int `json:"index"`
+	// Count is the number of the files.
+	Count int `json:"count"`
+	// Done is the number of the files done.
+	Done int `json:"done"`
+	// Name is the slash-separated name of the file.
+	Name string `json:"name"`
+	// Total is the size of the file.
+	Total int64 `json:"total"`
+	// Copied is the number of bytes of the file copied.
+	Copied int64 `json:"copied"`
+	// Percent is the percent of the file copied. It's always between 0 and 100.
+	Percent float32 `json:"percent"`
+}
+
+// Event is the interface of events reported by Do.
+// It's one of [*EventWritten], [*EventStop], [*EventOK] and [*EventError].
+// It also reports [*EventRestarted] if the task restarts from the beginning when it's opened,
+// [*EventReconnect] if the task reconnects after a network change([WithReconnect])
+// and [*EventHeartbeat] periodically if it's requested by [ContextWithHeartbeat].
+// [Queue] also reports [*EventQueued], [*EventStarted] and [*EventFinished].
+type Event interface {
+	event()
+}
+
+// EventWritten is reported when bytes are written and the percent changes.
+// If total size is unknown, it's reported on every write with Indeterminate set and Percent is always 0.
+// When the task succeeds, the last one before [*EventOK] always has the final counters, even if nothing is copied.
+type EventWritten struct {
+	// Total is the total number of bytes to copy.
+	// A negative value indicates total size is unknown.
+	Total int64
+	// Copied is the number of bytes copied including the ones copied previously.
+	Copied int64
+	// Percent is the percent copied. It's always between 0 and 100.
+	Percent float32
+	// Indeterminate is true if total size is unknown.
+	// Percent is 0 and should not be shown. Show Copied, Elapsed and Speed(e.g. a spinner) instead.
+	Indeterminate bool
+	// Elapsed is the time since the copy started(or resumed).
+	Elapsed time.Duration
+	// Speed is the speed in bytes per second over the last interval,
+	// which is the time since the previous EventWritten(or the start of the copy for the first one).
+	Speed float64
+	// AvgSpeed is the average speed in bytes per second since the copy started(or resumed).
+	AvgSpeed float64
+	// ReadTime and WriteTime are the time blocked in reading the source and writing the destination
+	// since the copy started(or resumed). See [EventWritten.WriteRatio].
+	ReadTime  time.Duration
+	WriteTime time.Duration
+	// File is the progress of the current file of the tasks which copy multiple files.
+	// It's nil for other tasks. See [FileProgresser].
+	File *FileProgress
+	// Workers are the numbers of bytes read by the workers of the parallel engines in the order of the workers,
+	// e.g. the connections of [WithConnections]. It's nil for other tasks. See [WorkerProgresser].
+	Workers []int64
+}
+
+// WriteRatio returns the ratio of the time blocked in writing to the time blocked in reading and writing.
+// A ratio close to 1 means the destination(e.g. the disk) is the bottleneck,
+// and a ratio close to 0 means the source(e.g. the network) is. It's 0 if no time is recorded.
+func (e *EventWritten) WriteRatio() float64 {
+	return writeRatio(e.ReadTime, e.WriteTime)
+}
+
+// EventStop is reported when the task is stopped by the context
+// or the destination file system is full.
+type EventStop struct {
+	// Err is the cause: context.Canceled, context.DeadlineExceeded or an error wrapping [ErrNoSpace].
+	// If the context is canceled with a cause(e.g. by [context.WithCancelCause]), it also wraps the cause.
+	Err error
+	// State is the marshaled state which is used to resume the task.
+	State []byte
+}
+
+// EventOK is reported when the task is done.
+type EventOK struct {
+	// Result is the marshaled result of the task.
+	Result []byte
+	// Value is the typed result of the task if it implements [ResultValuer], e.g. [CopyFileResult].
+	// It's nil otherwise.
+	Value any
+	// Duration is the time spent on the copy since it started(or resumed).
+	Duration time.Duration
+	// ReadTime and WriteTime are the time blocked in reading the source and writing the destination
+	// since the copy started(or resumed). See [EventOK.WriteRatio].
+	ReadTime  time.Duration
+	WriteTime time.Duration
+	// Stats are the detailed IO statistics since the copy started(or resumed).
+	// They're zero for the events not reported by [Do], e.g. the ones of [TaskGroup].
+	Stats IOStats
+}
+
+// WriteRatio returns the ratio of the time blocked in writing to the time blocked in reading and writing.
+// See [EventWritten.WriteRatio].
+func (e *EventOK) WriteRatio() float64 {
+	return writeRatio(e.ReadTime, e.WriteTime)
+}
+
+// IOStats are the IO statistics of a task to tune the buffer size and diagnose slow transfers.
+// Small average sizes mean the source returns short reads or the buffer is too small.
+type IOStats struct {
+	// Reads and Writes are the numbers of Read calls of the source and Write calls of the destination.
+	// They're 0 if the bytes are copied in the kernel, e.g. by splice(2).
+	Reads  int64 `json:"reads"`
+	Writes int64 `json:"writes"`
+	// BytesRead and BytesWritten are the numbers of bytes read and written.
+	BytesRead    int64 `json:"bytes_read"`
+	BytesWritten int64 `json:"bytes_written"`
+	// ReadTime and WriteTime are the time blocked in reading and writing.
+	ReadTime  time.Duration `json:"read_time"`
+	WriteTime time.Duration `json:"write_time"`
+	// Retries is the number of times the task is reopened by [Do] to retry from the bytes copied,
+	// after a read or write is abandoned by [ContextWithChunkDeadline] or the connection is lost([WithReconnect]).
+	// Failed reopens are counted too. The restarts from the beginning reported by [*EventRestarted] are not retries.
+	Retries int `json:"retries"`
+}
+
+// AvgReadSize returns the average number of bytes per Read call or 0 if there's no call.
+func (s IOStats) AvgReadSize() float64 {
+	if s.Reads == 0 {
+		return 0
+	}
+	return float64(s.BytesRead) / float64(s.Reads)
+}
+
+// AvgWriteSize returns the average number of bytes per Write call or 0 if there's no call.
+func (s IOStats) AvgWriteSize() float64 {
+	if s.Writes == 0 {
+		return 0
+	}
+	return float64(s.BytesWritten) / float64(s.Writes)
+}
+
+// writeRatio returns writeTime / (readTime + writeTime) or 0 if both are 0.
+func writeRatio(readTime, writeTime time.Duration) float64 {
+	if readTime+writeTime <= 0 {
+		return 0
+	}
+	return float64(writeTime) / float64(readTime+writeTime)
+}
+
+// EventError is reported when an error occurs.
+type EventError struct {
+	Err error
+}
+
+func (e *EventWritten) event() {}
+func (e *EventStop) event()    {}
+func (e *EventOK) event()      {}
+func (e *EventError) event()   {}
+
+// OnEventFunc is the callback function on events reported by Do.
+type OnEventFunc func(e Event)
+
+// Do runs the task and reports the events by fn.
+// It accepts [context.Context] to make the task cancalable.
+// buf is the buffer used for IO copy. A default buffer is used if it's nil.
+// It's shrunk to fit the memory budget set by [SetMemoryBudget].
+// It returns nil when the task is done.
+// If the task is stopped by ctx, it reports [*EventStop] with the state and returns ctx.Err().
+// If the destination file system is full, it also reports [*EventStop] and returns an error wrapping [ErrNoSpace].
+// The task can be resumed by calling Do again or loading the state later.
+// Otherwise, it reports [*EventError] and returns the error.
+// If an [Instrumenter] is attached to ctx by [ContextWithInstrumenter], the task is instrumented by it.
+// fn is never called concurrently, even if [*EventHeartbeat] is reported by another goroutine.
+func Do(ctx context.Context, t Task, buf []byte, fn OnEventFunc) (err error) {
+	// Report heartbeats if they're requested by ContextWithHeartbeat.
+	hb := startHeartbeat(ctx, fn)
+	defer hb.stop()
+	emit := hb.emit
+
+	defer func() {
+		if err != nil && !isStopped(err) {
+			emit(&EventError{Err: err})
+		}
+	}()
+
+	ctx, span := startTask(ctx, t)
+	var written int64
+	defer func() {
+		span.End(t.Total(), written, err)
+	}()
+
+	// Take the buffer before the task is opened,
+	// so the memory reserved by the task(e.g. its prefetch queue) doesn't keep it waiting for itself.
+	buf, release, err := budgetBuffer(ctx, buf)
+	if err != nil {
+		return withCause(ctx, err)
+	}
+
+	dst, src, err := t.Open(ctx)
+	if err != nil {
+		release()
+		return withCause(ctx, err)
+	}
+
+	if r, ok := t.(Restarter); ok {
+		if reason, discarded := r.Restarted(); reason != "" {
+			emit(&EventRestarted{Reason: reason, Discarded: discarded})
+		}
+	}
+
+	prev := t.Copied()
+	hb.setOpened(prev)
+	logDebug(ctx, "iocopy: task opened", "total", t.Total(), "prev", prev)
+	start := time.Now()
+	last, lastCopied := start, prev
+
+	pr := &progress{total: t.Total(), prev: prev, totalFn: t.Total, start: start, onWrite: hb.written}
+	pr.fn = func(p ProgressInfo) {
+		copied := p.Copied()
+		t.SetCopied(copied)
+
+		now := time.Now()
+		e := &EventWritten{Total: p.Total, Copied: copied, Percent: p.Percent, Indeterminate: p.Indeterminate, Elapsed: p.Elapsed, ReadTime: p.ReadTime, WriteTime: p.WriteTime}
+		if d := now.Sub(last).Seconds(); d > 0 {
+			e.Speed = float64(copied-lastCopied) / d
+		}
+		if d := now.Sub(start).Seconds(); d > 0 {
+			e.AvgSpeed = float64(p.Current) / d
+		}
+		last, lastCopied = now, copied
+
+		if fp, ok := t.(FileProgresser); ok {
+			if f, ok := fp.FileProgress(); ok {
+				e.File = &f
+			}
+		}
+
+		if wp, ok := t.(WorkerProgresser); ok {
+			e.Workers = wp.WorkerProgress()
+		}
+
+		emit(e)
+	}
+
+	written, err = copyBuffer(ctx, dst, src, buf, pr)
+
+	// Reopen the task to retry from the bytes copied if a read or write is abandoned by the chunk deadline
+	// or the connection is lost by a network change.
+	cd, _ := chunkDeadlineOf(ctx)
+	var rp reconnectPolicy
+	if r, ok := t.(reconnecter); ok {
+		rp = r.reconnectPolicy()
+	}
+
+	retries, timeouts, attempts, lastWritten := 0, 0, 0, written
+	// opened is false if the task fails to be reopened, so it's not closed again.
+	opened := true
+	for err != nil {
+		if errors.Is(err, ErrChunkTimeout) && timeouts < cd.retries {
+			logDebug(ctx, "iocopy: chunk deadline exceeded, reopen task to retry", "err", err, "copied", prev+written)
+			timeouts++
+		} else if rp.retries > 0 && isNetworkChange(err) {
+			// Reset the attempts if there's progress since the last reconnect.
+			if written != lastWritten {
+				attempts = 0
+			}
+			if attempts >= rp.retries {
+				break
+			}
+			attempts++
+
+			delay := rp.delay(attempts)
+			logDebug(ctx, "iocopy: connection lost, reconnect", "err", err, "copied", prev+written, "attempt", attempts, "delay", delay)
+			emit(&EventReconnect{Attempt: attempts, Delay: delay, Copied: prev + written, Err: err})
+			if err = sleep(ctx, delay); err != nil {
+				break
+			}
+		} else {
+			break
+		}
+
+		retries++
+		lastWritten = written
+		t.SetCopied(prev + written)
+		if opened {
+			t.Close()
+			opened = false
+		}
+
+		if dst, src, err = t.Open(ctx); err != nil {
+			// Reconnect again if the network is still down.
+			continue
+		}
+		opened = true
+
+		// The bytes copied go back if the task restarts from the beginning, e.g. the remote file changed.
+		// Count the bytes written from them, so written is never negative.
+		copied := t.Copied()
+		if copied < prev {
+			prev, pr.prev = copied, copied
+		}
+		lastCopied = min(lastCopied, copied)
+		written = copied - prev
+		lastWritten = written
+		pr.current = written
+
+		if r, ok := t.(Restarter); ok {
+			if reason, discarded := r.Restarted(); reason != "" {
+				emit(&EventRestarted{Reason: reason, Discarded: discarded})
+			}
+		}
+
+		var n int64
+		n, err = copyBuffer(ctx, dst, src, buf, pr)
+		written += n
+	}
+	release()
+	t.SetCopied(prev + written)
+
+	if c, ok := dst.(Committer); ok && err == nil {
+		err = c.Commit()
+	}
+
+	if opened {
+		if closeErr := t.Close(); err == nil {
+			err = closeErr
+		}
+	}
+
+	if isNoSpace(err) {
+		// Let the application free space and resume.
+		logDebug(ctx, "iocopy: no space left, stop task to resume later", "copied", t.Copied())
+		err = fmt.Errorf("%w: %w", ErrNoSpace, err)
+	}
+
+	if err != nil {
+		err = withCause(ctx, err)
+		if c, ok := t.(Cleaner); ok {
+			if cleanupErr := c.Cleanup(err); cleanupErr != nil {
+				err = errors.Join(err, cleanupErr)
+			}
+		}
+
+		if !isStopped(err) {
+			return err
+		}
+
+		state, stateErr := t.State()
+		if stateErr != nil {
+			return stateErr
+		}
+
+		if state, stateErr = stopState(ctx, state); stateErr != nil {
+			return stateErr
+		}
+		emit(&EventStop{Err: err, State: state})
+		return err
+	}
+
+	result, err := t.Result()
+	if err != nil {
+		return err
+	}
+
+	e := &EventOK{Result: redactJSON(ctx, result), Duration: time.Since(start), ReadTime: pr.readTime, WriteTime: pr.writeTime}
+	e.Stats = pr.stats()
+	e.Stats.Retries = retries
+	if v, ok := t.(ResultValuer); ok {
+		e.Value = v.ResultValue()
+	}
+	emit(e)
+	return nil
+}
+
+// withCause wraps err with both ctx.Err() and the cause of ctx if ctx is canceled with a cause by [context.WithCancelCause],
+// e.g. [*SignalError] of [TaskManager.HandleSignals].
+// Some sources(e.g. the bodies of HTTP responses) return the cause instead of ctx.Err() and they should still be stopped.
+func withCause(ctx context.Context, err error) error {
+	if err == nil || ctx.Err() == nil {
+		return err
+	}
+
+	cause := context.Cause(ctx)
+	switch stopped, caused := errors.Is(err, ctx.Err()), errors.Is(err, cause); {
+	case stopped && !caused:
+		return fmt.Errorf("%w: %w", err, cause)
+	case !stopped && caused:
+		return fmt.Errorf("%w: %w", ctx.Err(), err)
+	}
+	return err
+}
+
+// isStopped reports whether err is caused by the context or no space.
+func isStopped(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, ErrNoSpace)
+}
@@ -0,0 +1,233 @@
+// Package torrent provides an experimental task which downloads a torrent by a BitTorrent client,
+// so download managers run HTTP and BitTorrent downloads by the same [iocopy.Do] and progress events.
+//
+// The package does not depend on a BitTorrent library.
+// Implement [Client] and [Torrent] by an adapter of the library, e.g. github.com/anacrolix/torrent.
+// The API may change.
+package torrent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/northbright/iocopy"
+)
+
+// StateType is the type of the states of [Task]. Register the loader of it by [Register].
+const StateType = "torrent"
+
+// pollInterval is the interval to poll the bytes verified when no new pieces are verified.
+const pollInterval = 200 * time.Millisecond
+
+// Client adds torrents to download. It's implemented by the adapters of BitTorrent libraries.
+type Client interface {
+	// Add adds the torrent of uri(e.g. a magnet link) to download its files to dir and starts the download.
+	// resume is the fast-resume data returned by ResumeData of [Torrent] to resume the download. It's nil for a new one.
+	Add(ctx context.Context, uri, dir string, resume []byte) (Torrent, error)
+}
+
+// Torrent is a torrent added to a [Client].
+type Torrent interface {
+	// Info blocks until the metadata is available and returns the name and the total length of the files.
+	Info(ctx context.Context) (name string, length int64, err error)
+	// BytesVerified returns the number of bytes of the pieces downloaded and verified.
+	BytesVerified() int64
+	// ResumeData returns the fast-resume data to resume the download, e.g. the bitfield of the pieces verified.
+	ResumeData() ([]byte, error)
+	// Close stops the download and removes the torrent from the client. The files downloaded are kept.
+	Close() error
+}
+
+// Task implements [iocopy.Task] interface to download a torrent.
+// The total is the length of the files in the metadata and the progress is the bytes of the pieces verified.
+// The fast-resume data is saved in the state when it's stopped.
+type Task struct {
+	c      Client
+	uri    string
+	dir    string
+	name   string
+	total  int64
+	copied int64
+	resume []byte
+	tor    Torrent
+}
+
+// State is the typed state of [Task].
+type State struct {
+	// Version is the version of the state. See [iocopy.StateVersion].
+	Version int `json:"version"`
+	// Type is the type of the state to load it by [iocopy.LoadTask]: [StateType].
+	Type string `json:"type"`
+	// URI is the uri of the torrent, e.g. a magnet link.
+	URI string `json:"uri"`
+	// Dir is the directory to download the files.
+	Dir string `json:"dir"`
+	// Name is the name of the torrent in the metadata. It's empty if the metadata is not available yet.
+	Name string `json:"name"`
+	// Total is the length of the files. A negative value indicates the metadata is not available yet.
+	Total int64 `json:"total"`
+	// Copied is the number of bytes verified.
+	Copied int64 `json:"copied"`
+	// Resume is the fast-resume data of the client.
+	Resume []byte `json:"resume,omitempty"`
+}
+
+// Result is the typed result of [Task].
+type Result struct {
+	// URI is the uri of the torrent.
+	URI string `json:"uri"`
+	// Dir is the directory of the files downloaded.
+	Dir string `json:"dir"`
+	// Name is the name of the torrent in the metadata.
+	Name string `json:"name"`
+	// Size is the length of the files downloaded.
+	Size int64 `json:"size"`
+}
+
+// New returns a [*Task] which downloads the torrent of uri to dir by c.
+func New(c Client, uri, dir string) *Task {
+	return &Task{c: c, uri: uri, dir: dir, total: -1}
+}
+
+// Load loads a [*Task] from the state to resume the download by c.
+func Load(c Client, state []byte) (*Task, error) {
+	var s State
+	if err := json.Unmarshal(state, &s); err != nil {
+		return nil, err
+	}
+
+	if s.Type != StateType {
+		return nil, fmt.Errorf("state type %q is not %q", s.Type, StateType)
+	}
+
+	if s.Version > iocopy.StateVersion {
+		return nil, fmt.Errorf("unsupported version of %v state: %v", StateType, s.Version)
+	}
+
+	t := New(c, s.URI, s.Dir)
+	t.name = s.Name
+	t.total = s.Total
+	t.copied = s.Copied
+	t.resume = s.Resume
+	return t, nil
+}
+
+// Register registers the loader of [StateType] by [iocopy.RegisterLoader] to load the tasks by [iocopy.LoadTask] with c.
+func Register(c Client) {
+	iocopy.RegisterLoader(StateType, func(state []byte) (iocopy.Task, error) {
+		return Load(c, state)
+	})
+}
+
+// Open implements [iocopy.Task] interface.
+// It adds the torrent to the client with the fast-resume data and waits for the metadata.
+func (t *Task) Open(ctx context.Context) (dst io.Writer, src io.Reader, err error) {
+	if t.tor, err = t.c.Add(ctx, t.uri, t.dir, t.resume); err != nil {
+		return nil, nil, err
+	}
+
+	if t.name, t.total, err = t.tor.Info(ctx); err != nil {
+		t.Close()
+		return nil, nil, err
+	}
+	t.copied = t.tor.BytesVerified()
+
+	// The client writes the files, so the destination only counts the bytes.
+	return io.Discard, &verifiedReader{ctx: ctx, t: t.tor, total: t.total, n: t.copied}, nil
+}
+
+// Close implements [iocopy.Task] interface.
+// It saves the fast-resume data and removes the torrent from the client.
+func (t *Task) Close() error {
+	if t.tor == nil {
+		return nil
+	}
+
+	resume, err := t.tor.ResumeData()
+	if err == nil {
+		t.resume = resume
+	}
+
+	if closeErr := t.tor.Close(); err == nil {
+		err = closeErr
+	}
+	t.tor = nil
+	return err
+}
+
+// Endpoints implements [iocopy.Endpointer] interface.
+func (t *Task) Endpoints() (src, dst string) {
+	return t.uri, t.dir
+}
+
+// Total implements [iocopy.Task] interface.
+func (t *Task) Total() int64 {
+	return t.total
+}
+
+// Copied implements [iocopy.Task] interface.
+func (t *Task) Copied() int64 {
+	return t.copied
+}
+
+// SetCopied implements [iocopy.Task] interface.
+func (t *Task) SetCopied(copied int64) {
+	t.copied = copied
+}
+
+// State implements [iocopy.Task] interface.
+func (t *Task) State() ([]byte, error) {
+	return json.Marshal(t.StateValue())
+}
+
+// StateValue implements [iocopy.StateValuer] interface.
+// It returns the [State].
+func (t *Task) StateValue() any {
+	return State{Version: iocopy.StateVersion, Type: StateType, URI: t.uri, Dir: t.dir, Name: t.name, Total: t.total, Copied: t.copied, Resume: t.resume}
+}
+
+// Result implements [iocopy.Task] interface.
+func (t *Task) Result() ([]byte, error) {
+	return json.Marshal(t.ResultValue())
+}
+
+// ResultValue implements [iocopy.ResultValuer] interface.
+// It returns the [Result].
+func (t *Task) ResultValue() any {
+	return Result{URI: t.uri, Dir: t.dir, Name: t.name, Size: t.copied}
+}
+
+// verifiedReader returns the number of bytes of the pieces verified since the last read as zeros,
+// so [iocopy.Do] reports the progress of the torrent.
+type verifiedReader struct {
+	ctx   context.Context
+	t     Torrent
+	total int64
+	// n is the number of bytes returned.
+	n int64
+}
+
+// Read implements [io.Reader] interface. It blocks until new pieces are verified.
+func (r *verifiedReader) Read(p []byte) (int, error) {
+	for {
+		if r.n >= r.total {
+			return 0, io.EOF
+		}
+
+		if v := min(r.t.BytesVerified(), r.total); v > r.n {
+			n := int(min(int64(len(p)), v-r.n))
+			clear(p[:n])
+			r.n += int64(n)
+			return n, nil
+		}
+
+		select {
+		case <-r.ctx.Done():
+			return 0, r.ctx.Err()
+		case <-time.After(pollInterval):
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package torrent_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/northbright/iocopy"
+	"github.com/northbright/iocopy/task/torrent"
+)
+
+// pieceSize is the piece size of the fake torrents.
+const pieceSize = 16 * 1024
+
+// fakeClient is a [torrent.Client] which verifies a piece of a fake torrent on each call of BytesVerified.
+type fakeClient struct {
+	length int64
+}
+
+// Add implements [torrent.Client] interface.
+// The fast-resume data is the number of the bytes verified as JSON.
+func (c *fakeClient) Add(ctx context.Context, uri, dir string, resume []byte) (torrent.Torrent, error) {
+	t := &fakeTorrent{length: c.length}
+	if resume != nil {
+		if err := json.Unmarshal(resume, &t.verified); err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+// fakeTorrent is a [torrent.Torrent] of [fakeClient].
+type fakeTorrent struct {
+	length   int64
+	mu       sync.Mutex
+	verified int64
+}
+
+// Info implements [torrent.Torrent] interface.
+func (t *fakeTorrent) Info(ctx context.Context) (string, int64, error) {
+	return "fake", t.length, nil
+}
+
+// BytesVerified implements [torrent.Torrent] interface.
+func (t *fakeTorrent) BytesVerified() int64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.verified = min(t.verified+pieceSize, t.length)
+	return t.verified
+}
+
+// ResumeData implements [torrent.Torrent] interface.
+func (t *fakeTorrent) ResumeData() ([]byte, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return json.Marshal(t.verified)
+}
+
+// Close implements [torrent.Torrent] interface.
+func (t *fakeTorrent) Close() error {
+	return nil
+}
+
+func ExampleRegister() {
+	// This example downloads a fake torrent. It's stopped and resumed by iocopy.LoadTask
+	// from the state which contains the fast-resume data.
+	c := &fakeClient{length: 64 * pieceSize}
+	torrent.Register(c)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var state []byte
+	t := torrent.New(c, "magnet:?xt=urn:btih:0123456789abcdef0123456789abcdef01234567", "downloads")
+	iocopy.Do(ctx, t, nil, func(e iocopy.Event) {
+		switch e := e.(type) {
+		case *iocopy.EventWritten:
+			// Emulate user cancelation.
+			cancel()
+		case *iocopy.EventStop:
+			state = e.State
+		}
+	})
+
+	resumed, err := iocopy.LoadTask(state)
+	if err != nil {
+		log.Printf("iocopy.LoadTask() error: %v", err)
+		return
+	}
+
+	prev := resumed.Copied()
+	err = iocopy.Do(context.Background(), resumed, nil, func(e iocopy.Event) {
+		if e, ok := e.(*iocopy.EventOK); ok {
+			r := e.Value.(torrent.Result)
+			fmt.Printf("name: %v, size: %v\n", r.Name, r.Size)
+		}
+	})
+	if err != nil {
+		log.Printf("iocopy.Do() error: %v", err)
+		return
+	}
+	fmt.Printf("resumed: %v, total: %v\n", prev > 0, resumed.Total())
+
+	// Output:
+	// name: fake, size: 1048576
+	// resumed: true, total: 1048576
+}
@@ -0,0 +1,131 @@
+package iocopy_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/northbright/iocopy"
+)
+
+func ExampleEventWritten() {
+	// This example shows the speed over the last interval and the average speed reported by EventWritten.
+	// The instantaneous speed makes smooth per-second graphs possible.
+	dir, err := os.MkdirTemp("", "iocopy")
+	if err != nil {
+		log.Printf("os.MkdirTemp() error: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	src := filepath.Join(dir, "src")
+	if err = os.WriteFile(src, bytes.Repeat([]byte("0123456789abcdef"), 256), 0644); err != nil {
+		log.Printf("os.WriteFile() error: %v", err)
+		return
+	}
+
+	var speeds, avgSpeeds int
+	t := iocopy.NewCopyFileTask(filepath.Join(dir, "dst"), src, nil)
+	err = iocopy.Do(context.Background(), t, make([]byte, 1024), func(e iocopy.Event) {
+		if e, ok := e.(*iocopy.EventWritten); ok {
+			if e.Speed > 0 {
+				speeds++
+			}
+			if e.AvgSpeed > 0 {
+				avgSpeeds++
+			}
+			// Emulate a slow destination.
+			time.Sleep(time.Millisecond)
+		}
+	})
+	if err != nil {
+		log.Printf("iocopy.Do() error: %v", err)
+		return
+	}
+
+	fmt.Printf("events with speed: %v, with average speed: %v\n", speeds, avgSpeeds)
+
+	// Output:
+	// events with speed: 4, with average speed: 4
+}
+
+func ExampleEventOK_WriteRatio() {
+	// This example tells whether the bottleneck is the source or the destination by the time blocked in them.
+	// The server sends the file slowly, so the source is the bottleneck.
+	data := bytes.Repeat([]byte("0123456789abcdef"), 1024)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("content-length", fmt.Sprint(len(data)))
+		for i := 0; i < 4; i++ {
+			time.Sleep(time.Millisecond * 20)
+			w.Write(data[i*4096 : (i+1)*4096])
+			w.(http.Flusher).Flush()
+		}
+	}))
+	defer ts.Close()
+
+	dir, err := os.MkdirTemp("", "iocopy")
+	if err != nil {
+		log.Printf("os.MkdirTemp() error: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	t := iocopy.NewDownloadTask(filepath.Join(dir, "file"), ts.URL, nil)
+	if err = iocopy.Do(context.Background(), t, nil, func(e iocopy.Event) {
+		if e, ok := e.(*iocopy.EventOK); ok {
+			bottleneck := "destination"
+			if e.WriteRatio() < 0.5 {
+				bottleneck = "source"
+			}
+			fmt.Printf("bottleneck: %v\n", bottleneck)
+		}
+	}); err != nil {
+		log.Printf("iocopy.Do() error: %v", err)
+		return
+	}
+
+	// Output:
+	// bottleneck: source
+}
+
+func ExampleIOStats() {
+	// This example shows the IO statistics reported by EventOK to tune the buffer size.
+	dir, err := os.MkdirTemp("", "iocopy")
+	if err != nil {
+		log.Printf("os.MkdirTemp() error: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	src := filepath.Join(dir, "src")
+	if err = os.WriteFile(src, bytes.Repeat([]byte("a"), 4096), 0644); err != nil {
+		log.Printf("os.WriteFile() error: %v", err)
+		return
+	}
+
+	t := iocopy.NewCopyFileTask(filepath.Join(dir, "dst"), src, nil)
+	err = iocopy.Do(context.Background(), t, make([]byte, 1024), func(e iocopy.Event) {
+		if e, ok := e.(*iocopy.EventOK); ok {
+			s := e.Stats
+			// The last read returns io.EOF.
+			fmt.Printf("reads: %v, writes: %v, retries: %v\n", s.Reads, s.Writes, s.Retries)
+			fmt.Printf("bytes read: %v, bytes written: %v\n", s.BytesRead, s.BytesWritten)
+			fmt.Printf("avg read size: %v, avg write size: %v\n", s.AvgReadSize(), s.AvgWriteSize())
+		}
+	})
+	if err != nil {
+		log.Printf("iocopy.Do() error: %v", err)
+		return
+	}
+
+	// Output:
+	// reads: 5, writes: 4, retries: 0
+	// bytes read: 4096, bytes written: 4096
+	// avg read size: 819.2, avg write size: 1024
+}
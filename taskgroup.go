@@ -0,0 +1,240 @@
+package iocopy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// TaskGroupError is reported by [TaskGroup] when some tasks fail.
+type TaskGroupError struct {
+	// Errs contains the errors of the tasks in the order they're added.
+	// It's nil for the tasks which are done.
+	Errs []error
+}
+
+// Error implements error interface.
+func (e *TaskGroupError) Error() string {
+	var (
+		n     int
+		first error
+	)
+	for _, err := range e.Errs {
+		if err != nil {
+			if n++; first == nil {
+				first = err
+			}
+		}
+	}
+	return fmt.Sprintf("%v of %v tasks failed, first error: %v", n, len(e.Errs), first)
+}
+
+// Unwrap returns the errors of the failed tasks.
+func (e *TaskGroupError) Unwrap() []error {
+	var errs []error
+	for _, err := range e.Errs {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// TaskGroup runs tasks as a group with one progress surface,
+// e.g. "install these 12 components".
+// It reports [*EventWritten] with the combined totals,
+// a single [*EventOK] when all tasks are done,
+// [*EventStop] with the states of all tasks when it's stopped,
+// or [*EventError] with a [*TaskGroupError] when some tasks fail.
+type TaskGroup struct {
+	tasks []Task
+}
+
+// NewTaskGroup returns a [*TaskGroup] of the tasks.
+func NewTaskGroup(tasks ...Task) *TaskGroup {
+	return &TaskGroup{tasks: tasks}
+}
+
+// taskGroupState is the state of [TaskGroup].
+type taskGroupState struct {
+	Version int               `json:"version"`
+	Type    string            `json:"type"`
+	Tasks   []json.RawMessage `json:"tasks"`
+}
+
+// LoadTaskGroup loads a [*TaskGroup] from the state reported by [*EventStop] to resume.
+// load is called to load each task from its index and state, e.g. by [LoadDownloadTask].
+// [LoadTask] is used if load is nil.
+// The JSON array of the states of the tasks saved by previous versions is also accepted.
+func LoadTaskGroup(state []byte, load func(i int, state []byte) (Task, error)) (*TaskGroup, error) {
+	var s taskGroupState
+	if trimmed := bytes.TrimSpace(state); len(trimmed) > 0 && trimmed[0] == '[' {
+		if err := json.Unmarshal(trimmed, &s.Tasks); err != nil {
+			return nil, err
+		}
+	} else {
+		if err := json.Unmarshal(state, &s); err != nil {
+			return nil, err
+		}
+
+		if err := (stateHeader{s.Version, s.Type}).check(StateTypeTaskGroup); err != nil {
+			return nil, err
+		}
+	}
+
+	if load == nil {
+		load = func(_ int, state []byte) (Task, error) {
+			return LoadTask(state)
+		}
+	}
+
+	g := &TaskGroup{}
+	for i, ts := range s.Tasks {
+		t, err := load(i, ts)
+		if err != nil {
+			return nil, err
+		}
+		g.tasks = append(g.tasks, t)
+	}
+	return g, nil
+}
+
+// Tasks returns the tasks of the group.
+func (g *TaskGroup) Tasks() []Task {
+	return g.tasks
+}
+
+// Run runs the tasks simultaneously by a [Queue] and reports the combined events by fn.
+// n: max number of running tasks. All tasks run simultaneously if n < 1.
+// Total of [*EventWritten] is unknown(-1) until the totals of all tasks are known.
+// Result of [*EventOK] is the JSON array of the results of the tasks
+// and State of [*EventStop] is the state of the group which contains the states of the tasks.
+// It returns nil when all tasks are done, the cause of [*EventStop] if it's stopped, or a [*TaskGroupError].
+func (g *TaskGroup) Run(ctx context.Context, n int, fn OnEventFunc) (err error) {
+	emit := func(e Event) {
+		if fn != nil {
+			fn(e)
+		}
+	}
+
+	defer func() {
+		if err != nil && !isStopped(err) {
+			emit(&EventError{Err: err})
+		}
+	}()
+
+	var (
+		index   = map[Task]int{}
+		totals  = make([]int64, len(g.tasks))
+		copied  = make([]int64, len(g.tasks))
+		errs    = make([]error, len(g.tasks))
+		prev    int64
+		start   = time.Now()
+		last    = start
+		lastSum int64
+	)
+
+	for i, t := range g.tasks {
+		index[t] = i
+		totals[i] = t.Total()
+		copied[i] = t.Copied()
+		prev += copied[i]
+	}
+	lastSum = prev
+
+	if n < 1 {
+		n = len(g.tasks)
+	}
+
+	// The callback is called by one goroutine at a time
+	// and the goroutine which runs t, so it's safe to call methods of t.
+	q := NewQueue(n, func(t Task, e Event) {
+		i := index[t]
+		switch e := e.(type) {
+		case *EventWritten:
+			totals[i], copied[i] = t.Total(), t.Copied()
+
+			var total, sum int64
+			for j := range g.tasks {
+				sum += copied[j]
+				if total >= 0 {
+					if totals[j] < 0 {
+						total = -1
+					} else {
+						total += totals[j]
+					}
+				}
+			}
+
+			now := time.Now()
+			w := &EventWritten{Total: total, Copied: sum, Percent: computePercent(total, 0, sum), Indeterminate: total < 0, Elapsed: now.Sub(start)}
+			if d := now.Sub(last).Seconds(); d > 0 {
+				w.Speed = float64(sum-lastSum) / d
+			}
+			if d := now.Sub(start).Seconds(); d > 0 {
+				w.AvgSpeed = float64(sum-prev) / d
+			}
+			last, lastSum = now, sum
+
+			emit(w)
+		case *EventFinished:
+			totals[i], copied[i] = t.Total(), t.Copied()
+			errs[i] = e.Err
+		}
+	})
+
+	for _, t := range g.tasks {
+		q.Submit(ctx, t)
+	}
+	q.Wait()
+
+	var stopErr error
+	for _, e := range errs {
+		if e == nil {
+			continue
+		}
+
+		if !isStopped(e) {
+			return &TaskGroupError{Errs: errs}
+		}
+		if stopErr == nil {
+			stopErr = e
+		}
+	}
+
+	if stopErr != nil {
+		s := taskGroupState{Version: StateVersion, Type: StateTypeTaskGroup, Tasks: make([]json.RawMessage, len(g.tasks))}
+		for i, t := range g.tasks {
+			if s.Tasks[i], err = t.State(); err != nil {
+				return err
+			}
+		}
+
+		state, err := json.Marshal(s)
+		if err != nil {
+			return err
+		}
+
+		if state, err = stopState(ctx, state); err != nil {
+			return err
+		}
+		emit(&EventStop{Err: stopErr, State: state})
+		return stopErr
+	}
+
+	results := make([]json.RawMessage, len(g.tasks))
+	for i, t := range g.tasks {
+		if results[i], err = t.Result(); err != nil {
+			return err
+		}
+	}
+
+	result, err := json.Marshal(results)
+	if err != nil {
+		return err
+	}
+	emit(&EventOK{Result: redactJSON(ctx, result), Duration: time.Since(start)})
+	return nil
+}
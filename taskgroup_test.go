@@ -0,0 +1,168 @@
+package iocopy_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/northbright/iocopy"
+)
+
+func ExampleTaskGroup() {
+	// This example copies 3 files as a group with one progress surface.
+	// One of the sources does not exist, so the group reports partial failure.
+	dir, err := os.MkdirTemp("", "iocopy")
+	if err != nil {
+		log.Printf("os.MkdirTemp() error: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	data := bytes.Repeat([]byte("0123456789abcdef"), 1024)
+	var tasks []iocopy.Task
+	for i := 0; i < 3; i++ {
+		src := filepath.Join(dir, fmt.Sprintf("%d", i))
+		if i < 2 {
+			if err = os.WriteFile(src, data, 0644); err != nil {
+				log.Printf("os.WriteFile() error: %v", err)
+				return
+			}
+		}
+		tasks = append(tasks, iocopy.NewCopyFileTask(src+".copy", src, nil))
+	}
+
+	g := iocopy.NewTaskGroup(tasks...)
+	err = g.Run(context.Background(), 0, func(e iocopy.Event) {
+		if e, ok := e.(*iocopy.EventError); ok {
+			fmt.Printf("error: %v\n", errors.Is(e.Err, os.ErrNotExist))
+		}
+	})
+
+	var groupErr *iocopy.TaskGroupError
+	if errors.As(err, &groupErr) {
+		for i, err := range groupErr.Errs {
+			fmt.Printf("task %v: failed: %v, copied: %v\n", i, err != nil, g.Tasks()[i].Copied())
+		}
+	}
+
+	// Create the missing source and copy all files again.
+	if err = os.WriteFile(filepath.Join(dir, "2"), data, 0644); err != nil {
+		log.Printf("os.WriteFile() error: %v", err)
+		return
+	}
+
+	tasks = nil
+	for i := 0; i < 3; i++ {
+		src := filepath.Join(dir, fmt.Sprintf("%d", i))
+		tasks = append(tasks, iocopy.NewCopyFileTask(src+".copy2", src, nil))
+	}
+
+	g = iocopy.NewTaskGroup(tasks...)
+	err = g.Run(context.Background(), 0, func(e iocopy.Event) {
+		switch e := e.(type) {
+		case *iocopy.EventWritten:
+			if e.Percent == 100 {
+				fmt.Printf("written: %v/%v\n", e.Copied, e.Total)
+			}
+		case *iocopy.EventOK:
+			fmt.Printf("all done\n")
+		}
+	})
+	if err != nil {
+		log.Printf("g.Run() error: %v", err)
+		return
+	}
+
+	// Output:
+	// error: true
+	// task 0: failed: false, copied: 16384
+	// task 1: failed: false, copied: 16384
+	// task 2: failed: true, copied: 0
+	// written: 49152/49152
+	// all done
+}
+
+func ExampleLoadTaskGroup() {
+	// This example stops a group and resumes it from the state reported by EventStop.
+	dir, err := os.MkdirTemp("", "iocopy")
+	if err != nil {
+		log.Printf("os.MkdirTemp() error: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	data := bytes.Repeat([]byte("0123456789abcdef"), 4096)
+	var tasks []iocopy.Task
+	for i := 0; i < 2; i++ {
+		src := filepath.Join(dir, fmt.Sprintf("%d", i))
+		if err = os.WriteFile(src, data, 0644); err != nil {
+			log.Printf("os.WriteFile() error: %v", err)
+			return
+		}
+		tasks = append(tasks, iocopy.NewCopyFileTask(src+".copy", src, nil))
+	}
+
+	var state []byte
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	iocopy.NewTaskGroup(tasks...).Run(ctx, 0, func(e iocopy.Event) {
+		switch e := e.(type) {
+		case *iocopy.EventWritten:
+			// Emulate user cancelation.
+			cancel()
+		case *iocopy.EventStop:
+			state = e.State
+		}
+	})
+
+	// The state of the group is not the state of a task.
+	_, err = iocopy.LoadTask(state)
+	fmt.Printf("not task state: %v\n", errors.Is(err, iocopy.ErrNotTaskState))
+
+	// The tasks are loaded by LoadTask if load is nil.
+	g, err := iocopy.LoadTaskGroup(state, nil)
+	if err != nil {
+		log.Printf("iocopy.LoadTaskGroup() error: %v", err)
+		return
+	}
+
+	if err = g.Run(context.Background(), 0, nil); err != nil {
+		log.Printf("g.Run() error: %v", err)
+		return
+	}
+
+	for i, t := range g.Tasks() {
+		fmt.Printf("task %v: %T, done: %v\n", i, t, t.Copied() == t.Total())
+	}
+
+	// The JSON array of the states of the tasks saved by previous versions is also accepted.
+	taskState, err := g.Tasks()[0].State()
+	if err != nil {
+		log.Printf("State() error: %v", err)
+		return
+	}
+
+	legacy, err := json.Marshal([]json.RawMessage{taskState})
+	if err != nil {
+		log.Printf("json.Marshal() error: %v", err)
+		return
+	}
+
+	if g, err = iocopy.LoadTaskGroup(legacy, nil); err != nil {
+		log.Printf("iocopy.LoadTaskGroup() error: %v", err)
+		return
+	}
+	fmt.Printf("legacy tasks: %v\n", len(g.Tasks()))
+
+	// Output:
+	// not task state: true
+	// task 0: *iocopy.CopyFileTask, done: true
+	// task 1: *iocopy.CopyFileTask, done: true
+	// legacy tasks: 1
+}
@@ -0,0 +1,121 @@
+package iocopy
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+)
+
+// maxTokenStateSize is the max size of the state decoded from a token to avoid decompression bombs.
+const maxTokenStateSize = 1024 * 1024
+
+// Tokener is implemented by the tasks which return their resume state as a compact token,
+// e.g. [*DownloadTask], [*CopyFileTask] and [*HashTask].
+type Tokener interface {
+	// Token returns the token of the state without the states of the hashes. See [Token].
+	Token() (string, error)
+}
+
+// Token returns a compact URL-safe token of the state of t to resume it later,
+// which is convenient to keep in mobile apps or URLs.
+// It's the raw URL-safe base64 encoding of the deflated CBOR([CBORCodec]) state.
+// The states of the hashes(the "hashes" field of the states, e.g. [DownloadState] and [HashState]) are binary blobs
+// which make the token long. They're omitted unless withHashes is true, and the tasks loaded from the token
+// compute the hashes again by reading the bytes copied previously([DownloadTask]) or restart the hashing([HashTask]).
+// Use [LoadFromToken] to load the task.
+func Token(t Task, withHashes bool) (string, error) {
+	state, err := t.State()
+	if err != nil {
+		return "", err
+	}
+
+	if !withHashes {
+		var m map[string]json.RawMessage
+		if err = json.Unmarshal(state, &m); err != nil {
+			return "", err
+		}
+
+		delete(m, "hashes")
+		if state, err = json.Marshal(m); err != nil {
+			return "", err
+		}
+	}
+
+	if state, err = CBORCodec.Marshal(state); err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	w, err := flate.NewWriter(&buf, flate.BestCompression)
+	if err != nil {
+		return "", err
+	}
+
+	if _, err = w.Write(state); err != nil {
+		return "", err
+	}
+
+	if err = w.Close(); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// StateFromToken decodes the JSON state from the token returned by [Token].
+// The tokens of the deflated JSON states returned by the previous versions are also decoded.
+func StateFromToken(token string) ([]byte, error) {
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, err
+	}
+
+	r := flate.NewReader(bytes.NewReader(b))
+	defer r.Close()
+
+	state, err := io.ReadAll(io.LimitReader(r, maxTokenStateSize+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(state) > maxTokenStateSize {
+		return nil, errors.New("state of token is too large")
+	}
+
+	if len(state) > 0 && state[0] == '{' {
+		// The JSON state of the previous versions. A CBOR state starts with a map.
+		return state, nil
+	}
+	return CBORCodec.Unmarshal(state)
+}
+
+// Token implements [Tokener] interface.
+func (t *DownloadTask) Token() (string, error) {
+	return Token(t, false)
+}
+
+// Token implements [Tokener] interface.
+func (t *CopyFileTask) Token() (string, error) {
+	return Token(t, false)
+}
+
+// Token implements [Tokener] interface.
+func (t *HashTask) Token() (string, error) {
+	return Token(t, false)
+}
+
+// LoadFromToken loads the task from the token returned by [Token] by load, e.g. a wrapper of [LoadDownloadTask].
+// [LoadTask] is used if load is nil.
+func LoadFromToken(token string, load LoadTaskFunc) (Task, error) {
+	state, err := StateFromToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	if load == nil {
+		load = LoadTask
+	}
+	return load(state)
+}
@@ -0,0 +1,136 @@
+package iocopy_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/northbright/iocopy"
+)
+
+func ExampleToken() {
+	// This example stops a download, saves the resume state as a compact token and resumes it from the token.
+	// The states of the hashes are omitted from the token and the hashes are computed again from the downloaded bytes.
+	data := bytes.Repeat([]byte("0123456789abcdef"), 64*1024)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "file", time.Time{}, bytes.NewReader(data))
+	}))
+	defer ts.Close()
+
+	dir, err := os.MkdirTemp("", "iocopy")
+	if err != nil {
+		log.Printf("os.MkdirTemp() error: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var state []byte
+	t := iocopy.NewDownloadTask(filepath.Join(dir, "file"), ts.URL, nil, iocopy.WithHash("sha256"))
+	iocopy.Do(ctx, t, nil, func(e iocopy.Event) {
+		switch e := e.(type) {
+		case *iocopy.EventWritten:
+			// Emulate user cancelation.
+			cancel()
+		case *iocopy.EventStop:
+			state = e.State
+		}
+	})
+
+	token, err := t.Token()
+	if err != nil {
+		log.Printf("Token() error: %v", err)
+		return
+	}
+	fmt.Printf("token is shorter than the state: %v\n", len(token) < len(state))
+
+	loaded, err := iocopy.LoadFromToken(token, func(state []byte) (iocopy.Task, error) {
+		return iocopy.LoadDownloadTask(state, nil, iocopy.WithHash("sha256"))
+	})
+	if err != nil {
+		log.Printf("iocopy.LoadFromToken() error: %v", err)
+		return
+	}
+	fmt.Printf("resume: %v\n", loaded.Copied() > 0)
+
+	iocopy.Do(context.Background(), loaded, nil, func(e iocopy.Event) {
+		if e, ok := e.(*iocopy.EventOK); ok {
+			sum := sha256.Sum256(data)
+			r := e.Value.(iocopy.DownloadResult)
+			fmt.Printf("SHA-256 matches: %v\n", r.Checksums["sha256"] == hex.EncodeToString(sum[:]))
+		}
+	})
+
+	// Output:
+	// token is shorter than the state: true
+	// resume: true
+	// SHA-256 matches: true
+}
+
+func ExampleStateFromToken() {
+	// This example decodes the states from the tokens of a hash task with and without the states of the hashes,
+	// and a token of the deflated JSON state returned by the previous versions.
+	dir, err := os.MkdirTemp("", "iocopy")
+	if err != nil {
+		log.Printf("os.MkdirTemp() error: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "file")
+	if err = os.WriteFile(file, []byte("Hello, World!"), 0644); err != nil {
+		log.Printf("os.WriteFile() error: %v", err)
+		return
+	}
+
+	t := iocopy.NewHashTask(file, []string{"sha256"})
+	if err = iocopy.Do(context.Background(), t, nil, nil); err != nil {
+		log.Printf("iocopy.Do() error: %v", err)
+		return
+	}
+
+	for _, withHashes := range []bool{false, true} {
+		token, err := iocopy.Token(t, withHashes)
+		if err != nil {
+			log.Printf("iocopy.Token() error: %v", err)
+			return
+		}
+
+		state, err := iocopy.StateFromToken(token)
+		if err != nil {
+			log.Printf("iocopy.StateFromToken() error: %v", err)
+			return
+		}
+
+		var s iocopy.HashState
+		if err = json.Unmarshal(state, &s); err != nil {
+			log.Printf("json.Unmarshal() error: %v", err)
+			return
+		}
+		fmt.Printf("with hashes: %v, copied: %v, hashes: %v\n", withHashes, s.Copied, len(s.Hashes))
+	}
+
+	// The deflated JSON state: {"version":1,"type":"hash","file":"file","algs":["md5"],"total":13,"copied":13}.
+	state, err := iocopy.StateFromToken("HMdBCoAgFADRu8z6byTa_KtEC0lLwVJSgojuHraaeQ-XP2vMB2qEdhePEmwNCGtMXX8Em7aKTuxuZBZabjahZhCWXKJ3_d9vAA")
+	if err != nil {
+		log.Printf("iocopy.StateFromToken() error: %v", err)
+		return
+	}
+	fmt.Printf("%s\n", state)
+
+	// Output:
+	// with hashes: false, copied: 13, hashes: 0
+	// with hashes: true, copied: 13, hashes: 1
+	// {"version":1,"type":"hash","file":"file","algs":["md5"],"total":13,"copied":13}
+}
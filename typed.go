@@ -0,0 +1,51 @@
+package iocopy
+
+import (
+	"encoding/json"
+)
+
+// StateValuer is implemented by tasks which return typed states,
+// e.g. [CopyFileState] of [*CopyFileTask] and [DownloadState] of [*DownloadTask].
+type StateValuer interface {
+	// StateValue returns the typed state which is used to resume the task.
+	StateValue() any
+}
+
+// StateAs returns the typed state of the task as S, e.g. [CopyFileState].
+// It returns the value of [StateValuer] if it's an S, otherwise it decodes the marshaled state.
+func StateAs[S any](t Task) (S, error) {
+	if v, ok := t.(StateValuer); ok {
+		if s, ok := v.StateValue().(S); ok {
+			return s, nil
+		}
+	}
+
+	var s S
+	state, err := t.State()
+	if err != nil {
+		return s, err
+	}
+
+	err = json.Unmarshal(state, &s)
+	return s, err
+}
+
+// ResultAs returns the typed result of the task as R, e.g. [DownloadResult].
+// It returns the value of [ResultValuer] if it's an R, otherwise it decodes the marshaled result.
+// It should be called after the task is done.
+func ResultAs[R any](t Task) (R, error) {
+	if v, ok := t.(ResultValuer); ok {
+		if r, ok := v.ResultValue().(R); ok {
+			return r, nil
+		}
+	}
+
+	var r R
+	result, err := t.Result()
+	if err != nil {
+		return r, err
+	}
+
+	err = json.Unmarshal(result, &r)
+	return r, err
+}
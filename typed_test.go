@@ -0,0 +1,63 @@
+package iocopy_test
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/northbright/iocopy"
+)
+
+func ExampleResultAs() {
+	// This example reads the typed state and result of a copy task without round-tripping through JSON.
+	dir, err := os.MkdirTemp("", "iocopy")
+	if err != nil {
+		log.Printf("os.MkdirTemp() error: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	src := filepath.Join(dir, "src")
+	if err = os.WriteFile(src, []byte("Hello, World!"), 0644); err != nil {
+		log.Printf("os.WriteFile() error: %v", err)
+		return
+	}
+
+	t := iocopy.NewCopyFileTask(filepath.Join(dir, "dst"), src, nil)
+
+	s, err := iocopy.StateAs[iocopy.CopyFileState](t)
+	if err != nil {
+		log.Printf("iocopy.StateAs() error: %v", err)
+		return
+	}
+	fmt.Printf("state: src: %v, total: %v\n", filepath.Base(s.Src), s.Total)
+
+	if err = iocopy.Do(context.Background(), t, nil, nil); err != nil {
+		log.Printf("iocopy.Do() error: %v", err)
+		return
+	}
+
+	r, err := iocopy.ResultAs[iocopy.CopyFileResult](t)
+	if err != nil {
+		log.Printf("iocopy.ResultAs() error: %v", err)
+		return
+	}
+	fmt.Printf("result: dst: %v, size: %v\n", filepath.Base(r.Dst), r.Size)
+
+	// Other types are decoded from the JSON result.
+	size, err := iocopy.ResultAs[struct {
+		Size int64 `json:"size"`
+	}](t)
+	if err != nil {
+		log.Printf("iocopy.ResultAs() error: %v", err)
+		return
+	}
+	fmt.Printf("size: %v\n", size.Size)
+
+	// Output:
+	// state: src: src, total: -1
+	// result: dst: dst, size: 13
+	// size: 13
+}
@@ -0,0 +1,243 @@
+package iocopy
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// EventFileVerified is reported by [DirVerifier] and [Installer] when a file is verified.
+type EventFileVerified struct {
+	// File is the slash-separated name of the file relative to the directory(or the path of the asset).
+	File string
+	// OK is true if the checksum matches.
+	OK bool
+	// Expected is the expected checksum.
+	Expected string
+	// Actual is the actual checksum. It's empty if Err is not nil.
+	Actual string
+	// Err is the error occurred while hashing the file, e.g. the file does not exist.
+	Err error
+}
+
+func (e *EventFileVerified) event() {}
+
+// VerifyFileResult is the result of a file verified by [DirVerifier].
+type VerifyFileResult struct {
+	File     string `json:"file"`
+	OK       bool   `json:"ok"`
+	Expected string `json:"expected"`
+	Actual   string `json:"actual,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// VerifyResult is the typed result of [DirVerifier].
+type VerifyResult struct {
+	// Dir is the directory.
+	Dir string `json:"dir"`
+	// Alg is the name of the hash algorithm.
+	Alg string `json:"alg"`
+	// Passed is the number of files which match the checksums.
+	Passed int `json:"passed"`
+	// Failed is the number of files which don't match or fail to be hashed.
+	Failed int `json:"failed"`
+	// Files are the results of the files sorted by names.
+	Files []VerifyFileResult `json:"files"`
+}
+
+// dirVerifierState is the state of [DirVerifier].
+type dirVerifierState struct {
+	Version int                `json:"version"`
+	Type    string             `json:"type"`
+	Dir     string             `json:"dir"`
+	Alg     string             `json:"alg"`
+	Sums    map[string]string  `json:"sums"`
+	Results []VerifyFileResult `json:"results,omitempty"`
+	Current json.RawMessage    `json:"current,omitempty"`
+}
+
+// DirVerifier verifies the files in a directory against the expected checksums.
+// It hashes the files one by one by [HashTask] and it can be stopped and resumed across files.
+type DirVerifier struct {
+	dir     string
+	alg     string
+	sums    map[string]string
+	files   []string
+	results []VerifyFileResult
+	cur     *HashTask
+	opts    []Option
+}
+
+// ParseSums parses the checksums in the format of the output of sha256sum, md5sum...
+// Each line is "<hex checksum>  <file>" or "<hex checksum> *<file>". Empty lines are ignored.
+// It returns the checksums by the slash-separated file names.
+func ParseSums(r io.Reader) (map[string]string, error) {
+	sums := map[string]string{}
+	s := bufio.NewScanner(r)
+	for n := 1; s.Scan(); n++ {
+		line := strings.TrimSpace(s.Text())
+		if line == "" {
+			continue
+		}
+
+		sum, name, ok := strings.Cut(line, " ")
+		if !ok {
+			return nil, fmt.Errorf("invalid checksum line %v: %q", n, line)
+		}
+
+		name = strings.TrimPrefix(name, " ")
+		name = strings.TrimPrefix(name, "*")
+		sums[filepath.ToSlash(name)] = sum
+	}
+
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return sums, nil
+}
+
+// NewDirVerifier returns a [*DirVerifier] which verifies the files in dir.
+// alg: name of the hash algorithm in [HashFuncs], e.g. "sha256".
+// sums: expected hex encoded checksums by the slash-separated file names relative to dir.
+// See [ParseSums] to read them from a sums file.
+// opts: optional parameters of the [HashTask] of each file. e.g. [WithHashCache], [WithPrefetch].
+func NewDirVerifier(dir, alg string, sums map[string]string, opts ...Option) *DirVerifier {
+	v := &DirVerifier{dir: dir, alg: alg, sums: sums, opts: opts}
+	for name := range sums {
+		v.files = append(v.files, name)
+	}
+	slices.Sort(v.files)
+	return v
+}
+
+// LoadDirVerifier loads a [*DirVerifier] from the state reported by [*EventStop] to resume.
+// opts: optional parameters which are not saved in the state.
+func LoadDirVerifier(state []byte, opts ...Option) (*DirVerifier, error) {
+	var s dirVerifierState
+	if err := json.Unmarshal(state, &s); err != nil {
+		return nil, err
+	}
+
+	if err := (stateHeader{s.Version, s.Type}).check(StateTypeDirVerifier); err != nil {
+		return nil, err
+	}
+
+	v := NewDirVerifier(s.Dir, s.Alg, s.Sums, opts...)
+	v.results = s.Results
+	if len(s.Current) > 0 {
+		cur, err := LoadHashTask(s.Current, opts...)
+		if err != nil {
+			return nil, err
+		}
+		v.cur = cur
+	}
+	return v, nil
+}
+
+// Run verifies the files which are not verified yet by name order and reports the events by fn.
+// It reports [*EventWritten] of the file being hashed(with its index and name by File) and [*EventFileVerified] for each file.
+// When all files are verified, it reports [*EventOK] with the [VerifyResult] and returns nil even if some files fail.
+// If it's stopped by ctx, it reports [*EventStop] with the state and returns ctx.Err().
+func (v *DirVerifier) Run(ctx context.Context, buf []byte, fn OnEventFunc) (err error) {
+	emit := func(e Event) {
+		if fn != nil {
+			fn(e)
+		}
+	}
+
+	defer func() {
+		if err != nil && !isStopped(err) {
+			emit(&EventError{Err: err})
+		}
+	}()
+
+	if _, ok := HashFuncs[v.alg]; !ok {
+		return fmt.Errorf("unsupported hash algorithm: %v", v.alg)
+	}
+
+	for len(v.results) < len(v.files) {
+		name := v.files[len(v.results)]
+		r := VerifyFileResult{File: name, Expected: v.sums[name]}
+
+		var hashErr error
+		if !filepath.IsLocal(filepath.FromSlash(name)) {
+			hashErr = fmt.Errorf("invalid file name: %v", name)
+		} else {
+			if v.cur == nil {
+				v.cur = NewHashTask(filepath.Join(v.dir, filepath.FromSlash(name)), []string{v.alg}, v.opts...)
+			}
+
+			hashErr = Do(ctx, v.cur, buf, func(e Event) {
+				if e, ok := e.(*EventWritten); ok {
+					e.File = &FileProgress{Index: len(v.results), Count: len(v.files), Done: len(v.results), Name: name, Total: e.Total, Copied: e.Copied, Percent: e.Percent}
+					emit(e)
+				}
+			})
+
+			if isStopped(hashErr) {
+				state, err := v.State()
+				if err != nil {
+					return err
+				}
+
+				if state, err = stopState(ctx, state); err != nil {
+					return err
+				}
+				emit(&EventStop{Err: hashErr, State: state})
+				return hashErr
+			}
+		}
+
+		if hashErr != nil {
+			r.Error = hashErr.Error()
+		} else {
+			checksums, _ := v.cur.Checksums()
+			r.Actual = checksums[v.alg]
+			r.OK = strings.EqualFold(r.Actual, r.Expected)
+		}
+
+		v.results = append(v.results, r)
+		v.cur = nil
+		emit(&EventFileVerified{File: name, OK: r.OK, Expected: r.Expected, Actual: r.Actual, Err: hashErr})
+	}
+
+	r := v.ResultValue()
+	result, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	emit(&EventOK{Result: redactJSON(ctx, result), Value: r})
+	return nil
+}
+
+// State returns the marshaled state which contains the results of the verified files
+// and the state of the file being hashed.
+func (v *DirVerifier) State() ([]byte, error) {
+	s := dirVerifierState{Version: StateVersion, Type: StateTypeDirVerifier, Dir: v.dir, Alg: v.alg, Sums: v.sums, Results: v.results}
+	if v.cur != nil {
+		state, err := v.cur.State()
+		if err != nil {
+			return nil, err
+		}
+		s.Current = state
+	}
+	return json.Marshal(s)
+}
+
+// ResultValue returns the [VerifyResult] of the verified files.
+func (v *DirVerifier) ResultValue() any {
+	r := VerifyResult{Dir: v.dir, Alg: v.alg, Files: v.results}
+	for _, f := range v.results {
+		if f.OK {
+			r.Passed++
+		} else {
+			r.Failed++
+		}
+	}
+	return r
+}
@@ -0,0 +1,142 @@
+package iocopy_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/northbright/iocopy"
+)
+
+func ExampleDirVerifier() {
+	// This example verifies the files in a directory against a sums file.
+	// It stops after the first file is verified and resumes from the saved state.
+	dir, err := os.MkdirTemp("", "iocopy")
+	if err != nil {
+		log.Printf("os.MkdirTemp() error: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	var sums strings.Builder
+	for i, name := range []string{"a", "b", "sub/c"} {
+		data := bytes.Repeat([]byte{byte('a' + i)}, 4096)
+		file := filepath.Join(dir, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(file), 0755)
+		if err = os.WriteFile(file, data, 0644); err != nil {
+			log.Printf("os.WriteFile() error: %v", err)
+			return
+		}
+
+		if name == "b" {
+			// Corrupt the file.
+			os.WriteFile(file, data[:100], 0644)
+		}
+		fmt.Fprintf(&sums, "%x  %v\n", sha256.Sum256(data), name)
+	}
+	fmt.Fprintf(&sums, "%x  %v\n", sha256.Sum256(nil), "missing")
+
+	m, err := iocopy.ParseSums(strings.NewReader(sums.String()))
+	if err != nil {
+		log.Printf("iocopy.ParseSums() error: %v", err)
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var state []byte
+	v := iocopy.NewDirVerifier(dir, "sha256", m)
+	v.Run(ctx, nil, func(e iocopy.Event) {
+		switch e := e.(type) {
+		case *iocopy.EventFileVerified:
+			fmt.Printf("%v: ok: %v\n", e.File, e.OK)
+			// Emulate user cancelation.
+			cancel()
+		case *iocopy.EventStop:
+			state = e.State
+		}
+	})
+
+	if v, err = iocopy.LoadDirVerifier(state); err != nil {
+		log.Printf("iocopy.LoadDirVerifier() error: %v", err)
+		return
+	}
+
+	v.Run(context.Background(), nil, func(e iocopy.Event) {
+		switch e := e.(type) {
+		case *iocopy.EventFileVerified:
+			fmt.Printf("%v: ok: %v, error: %v\n", e.File, e.OK, e.Err != nil)
+		case *iocopy.EventOK:
+			r := e.Value.(iocopy.VerifyResult)
+			fmt.Printf("passed: %v, failed: %v\n", r.Passed, r.Failed)
+		}
+	})
+
+	// Output:
+	// a: ok: true
+	// b: ok: false, error: false
+	// missing: ok: false, error: true
+	// sub/c: ok: true, error: false
+	// passed: 2, failed: 2
+}
+
+func ExampleDirVerifier_hashCache() {
+	// This example verifies a directory twice with a hash cache.
+	// The second verification reuses the cached checksums of the unchanged files instead of reading them.
+	dir, err := os.MkdirTemp("", "iocopy")
+	if err != nil {
+		log.Printf("os.MkdirTemp() error: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	sums := map[string]string{}
+	for _, name := range []string{"a", "b"} {
+		data := []byte(strings.Repeat(name, 4096))
+		if err = os.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+			log.Printf("os.WriteFile() error: %v", err)
+			return
+		}
+		sums[name] = fmt.Sprintf("%x", sha256.Sum256(data))
+	}
+
+	c, err := iocopy.LoadHashCache(filepath.Join(dir, "cache.json"))
+	if err != nil {
+		log.Printf("iocopy.LoadHashCache() error: %v", err)
+		return
+	}
+
+	verify := func() {
+		v := iocopy.NewDirVerifier(dir, "sha256", sums, iocopy.WithHashCache(c))
+		v.Run(context.Background(), nil, func(e iocopy.Event) {
+			if e, ok := e.(*iocopy.EventOK); ok {
+				r := e.Value.(iocopy.VerifyResult)
+				fmt.Printf("passed: %v, failed: %v\n", r.Passed, r.Failed)
+			}
+		})
+	}
+	verify()
+
+	// Corrupt "b" without changing its size and modification time.
+	// It still passes since the cached checksum is reused.
+	b := filepath.Join(dir, "b")
+	fi, err := os.Stat(b)
+	if err != nil {
+		log.Printf("os.Stat() error: %v", err)
+		return
+	}
+	os.WriteFile(b, []byte(strings.Repeat("c", 4096)), 0644)
+	os.Chtimes(b, time.Time{}, fi.ModTime())
+	verify()
+
+	// Output:
+	// passed: 2, failed: 0
+	// passed: 2, failed: 0
+}
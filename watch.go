@@ -0,0 +1,299 @@
+package iocopy
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Watcher watches a source directory and copies the new and modified files to a destination directory
+// by [CopyFileTask], e.g. a hot folder or a camera upload directory.
+// The subdirectories are watched recursively and the relative paths are kept in the destination.
+// A file is copied after it's stable: there're no events of it in the debounce interval
+// and its size and modification time don't change in another interval, so files being written(e.g. growing) are not copied.
+// The files which exist when it starts are not copied.
+// Call [Watcher.SetPipeline] to stage, hash and verify the files before moving them to the destination.
+type Watcher struct {
+	src      string
+	dst      string
+	debounce time.Duration
+	fn       OnTaskEventFunc
+	opts     []Option
+	filter   func(name string, d fs.DirEntry) bool
+	q        *Queue
+	pipeline *Pipeline
+
+	mu     sync.Mutex
+	files  map[string]*watchedFile
+	tasks  map[Task]string
+	closed bool
+}
+
+// watchedFile is a file which is changed and waits to be copied by [Watcher].
+type watchedFile struct {
+	timer *time.Timer
+	// fi is the file info of the last check. It's nil if the file is changed after that.
+	fi fs.FileInfo
+	// running is true if the file is being copied.
+	running bool
+	// dirty is true if the file is changed while it's being copied.
+	dirty bool
+}
+
+// NewWatcher returns a [*Watcher] which copies the new and modified files in src to dst.
+// n: max number of the files copied simultaneously. See [NewQueue].
+// debounce: the interval to wait for a file to be stable.
+// fn: callback on events of the copy tasks. It's called by one goroutine at a time.
+// opts: optional parameters of the tasks, e.g. [WithRateLimiter].
+// The files(and directories) for which the function set by [WithFilter] returns false are ignored.
+func NewWatcher(src, dst string, n int, debounce time.Duration, fn OnTaskEventFunc, opts ...Option) *Watcher {
+	w := &Watcher{
+		src:      src,
+		dst:      dst,
+		debounce: debounce,
+		fn:       fn,
+		opts:     opts,
+		filter:   newOptions(opts).filter,
+		files:    map[string]*watchedFile{},
+		tasks:    map[Task]string{},
+	}
+	w.q = NewQueue(n, w.onEvent)
+	return w
+}
+
+// SetPipeline makes the watcher process the files by the pipeline with [PipelineTask] instead of copying them directly.
+// It should be called before Run.
+func (w *Watcher) SetPipeline(p Pipeline) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.pipeline = &p
+}
+
+// Run watches the source directory until ctx is done.
+// The copy tasks are run with ctx, so they're stopped when ctx is done.
+// It waits for them to finish and returns ctx.Err(), or an error if the directory can't be watched.
+func (w *Watcher) Run(ctx context.Context) error {
+	fw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer fw.Close()
+
+	if err = w.addDir(ctx, fw, w.src, false); err != nil {
+		return err
+	}
+
+	defer func() {
+		w.mu.Lock()
+		w.closed = true
+		for _, f := range w.files {
+			if f.timer != nil {
+				f.timer.Stop()
+			}
+		}
+		w.mu.Unlock()
+
+		w.q.Wait()
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-fw.Errors:
+			// Events may be lost(e.g. overflow), but the watcher still works.
+			logDebug(ctx, "iocopy: watcher error", "err", err)
+		case e := <-fw.Events:
+			w.handle(ctx, fw, e)
+		}
+	}
+}
+
+// handle handles the event of the source directory.
+func (w *Watcher) handle(ctx context.Context, fw *fsnotify.Watcher, e fsnotify.Event) {
+	name, err := filepath.Rel(w.src, e.Name)
+	if err != nil {
+		return
+	}
+
+	switch {
+	case e.Has(fsnotify.Create) || e.Has(fsnotify.Write):
+		fi, err := os.Lstat(e.Name)
+		if err != nil || !w.match(name, fi) {
+			return
+		}
+
+		if fi.IsDir() {
+			// Watch the new directory and copy the files created before it's watched.
+			if e.Has(fsnotify.Create) {
+				if err = w.addDir(ctx, fw, e.Name, true); err != nil {
+					logDebug(ctx, "iocopy: failed to watch directory", "dir", e.Name, "err", err)
+				}
+			}
+			return
+		}
+
+		if fi.Mode().IsRegular() {
+			w.touch(ctx, name)
+		}
+	case e.Has(fsnotify.Remove) || e.Has(fsnotify.Rename):
+		// The copied files are kept. Stop waiting for the file if it's not being copied.
+		w.mu.Lock()
+		if f, ok := w.files[name]; ok && !f.running {
+			f.timer.Stop()
+			delete(w.files, name)
+		}
+		w.mu.Unlock()
+	}
+}
+
+// match reports whether the file should be copied by the filter set by [WithFilter].
+func (w *Watcher) match(name string, fi fs.FileInfo) bool {
+	return w.filter == nil || w.filter(filepath.ToSlash(name), fs.FileInfoToDirEntry(fi))
+}
+
+// addDir watches dir and its subdirectories.
+// If touch is true, the files in them are copied when they're stable.
+func (w *Watcher) addDir(ctx context.Context, fw *fsnotify.Watcher, dir string, touch bool) error {
+	return filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		name, err := filepath.Rel(w.src, p)
+		if err != nil {
+			return err
+		}
+
+		if name != "." && w.filter != nil && !w.filter(filepath.ToSlash(name), d) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if d.IsDir() {
+			return fw.Add(p)
+		}
+
+		if touch && d.Type().IsRegular() {
+			w.touch(ctx, name)
+		}
+		return nil
+	})
+}
+
+// touch marks the file changed and checks it after the debounce interval.
+// The file of a sidecar of the pipeline is checked instead of the sidecar.
+func (w *Watcher) touch(ctx context.Context, name string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return
+	}
+
+	if w.pipeline != nil && w.pipeline.isSidecar(name) {
+		name = strings.TrimSuffix(name, w.pipeline.SidecarExt)
+	}
+
+	f, ok := w.files[name]
+	if !ok {
+		f = &watchedFile{}
+		w.files[name] = f
+	}
+	f.fi = nil
+
+	if f.timer == nil {
+		f.timer = time.AfterFunc(w.debounce, func() { w.check(ctx, name) })
+	} else {
+		f.timer.Reset(w.debounce)
+	}
+}
+
+// check copies the file if it's stable, or checks it again after the debounce interval.
+func (w *Watcher) check(ctx context.Context, name string) {
+	fi, err := os.Stat(filepath.Join(w.src, name))
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	f, ok := w.files[name]
+	if !ok || w.closed {
+		return
+	}
+
+	if err != nil {
+		// The file is removed.
+		if !f.running {
+			delete(w.files, name)
+		}
+		return
+	}
+
+	if f.running {
+		// Copy it again after the running task finishes.
+		f.dirty = true
+		return
+	}
+
+	if f.fi == nil || f.fi.Size() != fi.Size() || !f.fi.ModTime().Equal(fi.ModTime()) {
+		// The file is still changing.
+		f.fi = fi
+		f.timer.Reset(w.debounce)
+		return
+	}
+
+	src, dst := filepath.Join(w.src, name), filepath.Join(w.dst, name)
+	var t Task
+	if w.pipeline != nil {
+		if sidecar := w.pipeline.sidecar(src); sidecar != "" {
+			if _, err = os.Stat(longPath(sidecar)); err != nil {
+				// Wait for the sidecar. It's checked again when the sidecar is changed.
+				return
+			}
+		}
+		t = NewPipelineTask(dst, src, name, *w.pipeline, w.opts...)
+	} else {
+		if err = os.MkdirAll(longPath(filepath.Dir(dst)), 0755); err != nil {
+			logDebug(ctx, "iocopy: failed to create destination directory", "dir", filepath.Dir(dst), "err", err)
+			delete(w.files, name)
+			return
+		}
+		t = NewCopyFileTask(dst, src, nil, w.opts...)
+	}
+
+	f.running = true
+	w.tasks[t] = name
+	w.q.Submit(ctx, t)
+}
+
+// onEvent copies the file again if it's changed while it's being copied and calls the callback.
+func (w *Watcher) onEvent(t Task, e Event) {
+	if _, ok := e.(*EventFinished); ok {
+		w.mu.Lock()
+		name := w.tasks[t]
+		delete(w.tasks, t)
+
+		if f, ok := w.files[name]; ok {
+			f.running = false
+			if f.dirty && !w.closed {
+				f.dirty, f.fi = false, nil
+				f.timer.Reset(w.debounce)
+			} else {
+				delete(w.files, name)
+			}
+		}
+		w.mu.Unlock()
+	}
+
+	if w.fn != nil {
+		w.fn(t, e)
+	}
+}
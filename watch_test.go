@@ -0,0 +1,166 @@
+package iocopy_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/northbright/iocopy"
+)
+
+func ExampleWatcher() {
+	// This example watches a directory and copies the new files to another one when they're stable.
+	dir, err := os.MkdirTemp("", "iocopy")
+	if err != nil {
+		log.Printf("os.MkdirTemp() error: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	src, dst := filepath.Join(dir, "src"), filepath.Join(dir, "dst")
+	if err = os.Mkdir(src, 0755); err != nil {
+		log.Printf("os.Mkdir() error: %v", err)
+		return
+	}
+
+	finished := make(chan string, 8)
+	w := iocopy.NewWatcher(src, dst, 2, 50*time.Millisecond, func(t iocopy.Task, e iocopy.Event) {
+		if e, ok := e.(*iocopy.EventFinished); ok && e.Err == nil {
+			finished <- t.(*iocopy.CopyFileTask).ResultValue().(iocopy.CopyFileResult).Dst
+		}
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error)
+	go func() {
+		done <- w.Run(ctx)
+	}()
+	// Wait for the watcher to start.
+	time.Sleep(100 * time.Millisecond)
+
+	// Write a file in two parts. It's copied after it stops growing.
+	f, err := os.Create(filepath.Join(src, "a.txt"))
+	if err != nil {
+		log.Printf("os.Create() error: %v", err)
+		return
+	}
+	f.WriteString("Hello, ")
+	time.Sleep(30 * time.Millisecond)
+	f.WriteString("World!")
+	f.Close()
+
+	// Create a file in a new subdirectory.
+	if err = os.MkdirAll(filepath.Join(src, "sub"), 0755); err != nil {
+		log.Printf("os.MkdirAll() error: %v", err)
+		return
+	}
+	if err = os.WriteFile(filepath.Join(src, "sub", "b.txt"), []byte("b"), 0644); err != nil {
+		log.Printf("os.WriteFile() error: %v", err)
+		return
+	}
+
+	var copied []string
+	for range 2 {
+		name, err := filepath.Rel(dst, <-finished)
+		if err != nil {
+			log.Printf("filepath.Rel() error: %v", err)
+			return
+		}
+		copied = append(copied, filepath.ToSlash(name))
+	}
+	slices.Sort(copied)
+
+	cancel()
+	<-done
+
+	buf, err := os.ReadFile(filepath.Join(dst, "a.txt"))
+	if err != nil {
+		log.Printf("os.ReadFile() error: %v", err)
+		return
+	}
+	fmt.Printf("copied: %q\n", copied)
+	fmt.Printf("a.txt: %s\n", buf)
+
+	// Output:
+	// copied: ["a.txt" "sub/b.txt"]
+	// a.txt: Hello, World!
+}
+
+func ExampleWatcher_SetPipeline() {
+	// This example watches a hot folder. Each new file is copied to a staging directory, hashed,
+	// verified against its sidecar("*.sha256") and moved to the destination.
+	dir, err := os.MkdirTemp("", "iocopy")
+	if err != nil {
+		log.Printf("os.MkdirTemp() error: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	src, dst := filepath.Join(dir, "src"), filepath.Join(dir, "dst")
+	if err = os.Mkdir(src, 0755); err != nil {
+		log.Printf("os.Mkdir() error: %v", err)
+		return
+	}
+
+	results := make(chan string, 8)
+	w := iocopy.NewWatcher(src, dst, 2, 50*time.Millisecond, func(t iocopy.Task, e iocopy.Event) {
+		switch e := e.(type) {
+		case *iocopy.EventOK:
+			r := e.Value.(iocopy.PipelineResult)
+			results <- fmt.Sprintf("%v: verified: %v, progress: %v/%v", filepath.Base(r.Dst), r.Verified, t.Copied(), t.Total())
+		case *iocopy.EventError:
+			results <- fmt.Sprintf("%v: checksum mismatch: %v", filepath.Base(t.(*iocopy.PipelineTask).ResultValue().(iocopy.PipelineResult).Src), errors.Is(e.Err, iocopy.ErrChecksumMismatch))
+		}
+	})
+	w.SetPipeline(iocopy.Pipeline{Staging: filepath.Join(dir, "staging"), Alg: "sha256", SidecarExt: ".sha256"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error)
+	go func() {
+		done <- w.Run(ctx)
+	}()
+	// Wait for the watcher to start.
+	time.Sleep(100 * time.Millisecond)
+
+	// The data files are processed after their sidecars exist.
+	files := map[string]string{
+		"a.txt":        "Hello, World!",
+		"a.txt.sha256": "dffd6021bb2bd5b0af676290809ec3a53191dd81c7f70a4b28688a362182986f  a.txt\n",
+		"b.txt":        "corrupted",
+		"b.txt.sha256": "dffd6021bb2bd5b0af676290809ec3a53191dd81c7f70a4b28688a362182986f  b.txt\n",
+	}
+	for _, name := range []string{"a.txt", "b.txt", "a.txt.sha256", "b.txt.sha256"} {
+		if err = os.WriteFile(filepath.Join(src, name), []byte(files[name]), 0644); err != nil {
+			log.Printf("os.WriteFile() error: %v", err)
+			return
+		}
+	}
+
+	var lines []string
+	for range 2 {
+		lines = append(lines, <-results)
+	}
+	slices.Sort(lines)
+
+	cancel()
+	<-done
+
+	for _, line := range lines {
+		fmt.Println(line)
+	}
+
+	_, err = os.Stat(filepath.Join(dst, "b.txt"))
+	fmt.Printf("b.txt moved: %v\n", err == nil)
+
+	// Output:
+	// a.txt: verified: true, progress: 26/26
+	// b.txt: checksum mismatch: true
+	// b.txt moved: false
+}
@@ -0,0 +1,66 @@
+package iocopy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// DefaultWebhookTimeout is the default timeout of the requests sent by [Webhook].
+const DefaultWebhookTimeout = time.Second * 10
+
+// WebhookPayload is the JSON body posted by [Webhook].
+type WebhookPayload struct {
+	// ID is the id of the task.
+	ID string `json:"id"`
+	// OK is true if the task is done.
+	OK bool `json:"ok"`
+	// Result is the result of the task if it's done.
+	Result json.RawMessage `json:"result,omitempty"`
+	// Error is the error message if the task fails.
+	Error string `json:"error,omitempty"`
+}
+
+// Webhook returns a [CompletionFunc] which posts a [WebhookPayload] as JSON to url
+// when a task of [TaskManager] is done or fails, e.g. to trigger serverless-style pipelines.
+// timeout is the timeout of each request. [DefaultWebhookTimeout] is used if it's 0.
+func Webhook(url string, timeout time.Duration) CompletionFunc {
+	if timeout == 0 {
+		timeout = DefaultWebhookTimeout
+	}
+
+	return func(id string, result []byte, err error) error {
+		p := WebhookPayload{ID: id, OK: err == nil, Result: result}
+		if err != nil {
+			p.Error = err.Error()
+		}
+
+		buf, err := json.Marshal(p)
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(buf))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return fmt.Errorf("webhook %v: unexpected status: %v", id, resp.Status)
+		}
+		return nil
+	}
+}
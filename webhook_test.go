@@ -0,0 +1,129 @@
+package iocopy_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/northbright/iocopy"
+)
+
+func ExampleWebhook() {
+	// This example posts the results of the tasks of a task manager to a webhook.
+	payloads := make(chan iocopy.WebhookPayload)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var p iocopy.WebhookPayload
+		if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		payloads <- p
+	}))
+	defer ts.Close()
+
+	dir, err := os.MkdirTemp("", "iocopy")
+	if err != nil {
+		log.Printf("os.MkdirTemp() error: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	src := filepath.Join(dir, "src")
+	if err = os.WriteFile(src, []byte("Hello, World!"), 0644); err != nil {
+		log.Printf("os.WriteFile() error: %v", err)
+		return
+	}
+
+	m := iocopy.NewTaskManager(filepath.Join(dir, "tasks"), 2, nil)
+	m.OnComplete(iocopy.Webhook(ts.URL, time.Second*5))
+
+	m.Submit("ok", "copy", iocopy.NewCopyFileTask(filepath.Join(dir, "dst"), src, nil))
+	m.Submit("fail", "copy", iocopy.NewCopyFileTask(filepath.Join(dir, "dst2"), filepath.Join(dir, "not-exist"), nil))
+
+	var received []iocopy.WebhookPayload
+	for i := 0; i < 2; i++ {
+		received = append(received, <-payloads)
+	}
+	sort.Slice(received, func(i, j int) bool { return received[i].ID < received[j].ID })
+
+	for _, p := range received {
+		var r struct {
+			Size int64 `json:"size"`
+		}
+		json.Unmarshal(p.Result, &r)
+		fmt.Printf("id: %v, ok: %v, size: %v, has error: %v\n", p.ID, p.OK, r.Size, p.Error != "")
+	}
+
+	if err = m.Shutdown(context.Background()); err != nil {
+		log.Printf("m.Shutdown() error: %v", err)
+		return
+	}
+
+	// Output:
+	// id: fail, ok: false, size: 0, has error: true
+	// id: ok, ok: true, size: 13, has error: false
+}
+
+func ExampleWebhook_slow() {
+	// This example posts the result of a task to a slow webhook.
+	// Other tasks keep running while the request is pending.
+	var once sync.Once
+	pending := make(chan struct{})
+	release := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		once.Do(func() { close(pending) })
+		<-release
+	}))
+	defer ts.Close()
+
+	dir, err := os.MkdirTemp("", "iocopy")
+	if err != nil {
+		log.Printf("os.MkdirTemp() error: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	src := filepath.Join(dir, "src")
+	if err = os.WriteFile(src, []byte("Hello, World!"), 0644); err != nil {
+		log.Printf("os.WriteFile() error: %v", err)
+		return
+	}
+
+	second := iocopy.NewCopyFileTask(filepath.Join(dir, "dst2"), src, nil)
+	secondDone := make(chan struct{})
+	m := iocopy.NewTaskManager(filepath.Join(dir, "tasks"), 1, func(t iocopy.Task, e iocopy.Event) {
+		if _, ok := e.(*iocopy.EventFinished); ok && t == second {
+			close(secondDone)
+		}
+	})
+	m.OnComplete(iocopy.Webhook(ts.URL, time.Second*5))
+
+	m.Submit("first", "copy", iocopy.NewCopyFileTask(filepath.Join(dir, "dst"), src, nil))
+	<-pending
+
+	m.Submit("second", "copy", second)
+
+	select {
+	case <-secondDone:
+		fmt.Println("second task is done while the webhook is pending")
+	case <-time.After(time.Second * 3):
+		fmt.Println("second task is blocked by the webhook")
+	}
+	close(release)
+
+	if err = m.Shutdown(context.Background()); err != nil {
+		log.Printf("m.Shutdown() error: %v", err)
+		return
+	}
+
+	// Output:
+	// second task is done while the webhook is pending
+}
@@ -0,0 +1,108 @@
+package iocopy
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// WorkerProgresser is implemented by tasks which copy by multiple workers in parallel,
+// e.g. [*DownloadTask] with [WithConnections] and [*HashTask] with [WithParallelReads].
+// Do reports the progress of the workers by Workers of [*EventWritten].
+type WorkerProgresser interface {
+	// WorkerProgress returns the number of bytes read by each worker in the order of the workers.
+	// It's nil if the task does not run by multiple workers.
+	WorkerProgress() []int64
+}
+
+// workerPool runs the workers of the parallel engines, e.g. the connections of [WithConnections]
+// and the readers of [WithParallelReads]. The first error of the workers stops the others.
+// Each worker records its progress by its index, so the progress is merged in the order of the workers
+// no matter how they're scheduled.
+type workerPool struct {
+	// parent is the context of the task. The workers run with a context canceled by cancel.
+	parent context.Context
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	// done is closed when all workers exit.
+	done     chan struct{}
+	progress []atomic.Int64
+
+	errOnce sync.Once
+	err     error
+}
+
+// newWorkerPool returns a [*workerPool] of n workers.
+func newWorkerPool(ctx context.Context, n int) *workerPool {
+	n = max(n, 1)
+	p := &workerPool{parent: ctx, done: make(chan struct{}), progress: make([]atomic.Int64, n)}
+	p.ctx, p.cancel = context.WithCancel(ctx)
+	return p
+}
+
+// size returns the number of workers.
+func (p *workerPool) size() int {
+	return len(p.progress)
+}
+
+// start starts the workers which run fn with their indexes.
+// If fn returns an error, the other workers are stopped and the error is reported by Err.
+func (p *workerPool) start(fn func(ctx context.Context, worker int) error) {
+	p.wg.Add(p.size())
+	for i := range p.size() {
+		go func() {
+			defer p.wg.Done()
+			if err := fn(p.ctx, i); err != nil {
+				p.fail(err)
+			}
+		}()
+	}
+
+	go func() {
+		p.wg.Wait()
+		close(p.done)
+	}()
+}
+
+// fail records the first error and stops the workers.
+// The error of the task context is recorded instead if it's done, e.g. the task is stopped.
+func (p *workerPool) fail(err error) {
+	p.errOnce.Do(func() {
+		if p.parent.Err() != nil {
+			err = p.parent.Err()
+		}
+		p.err = err
+		p.cancel()
+	})
+}
+
+// Err returns the first error of the workers. It should be called after Done is closed.
+func (p *workerPool) Err() error {
+	return p.err
+}
+
+// Done returns a channel which is closed when all workers exit.
+func (p *workerPool) Done() <-chan struct{} {
+	return p.done
+}
+
+// add adds n bytes to the progress of the worker.
+func (p *workerPool) add(worker int, n int64) {
+	p.progress[worker].Add(n)
+}
+
+// Progress returns the number of bytes read by each worker in the order of the workers.
+func (p *workerPool) Progress() []int64 {
+	progress := make([]int64, p.size())
+	for i := range p.progress {
+		progress[i] = p.progress[i].Load()
+	}
+	return progress
+}
+
+// Close stops the workers and waits for them to exit.
+func (p *workerPool) Close() {
+	p.cancel()
+	p.wg.Wait()
+}
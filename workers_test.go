@@ -0,0 +1,91 @@
+package iocopy_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/northbright/iocopy"
+)
+
+func ExampleWithWorkers() {
+	// This example downloads a file by 4 connections and hashes it by 4 workers.
+	// WithWorkers doesn't set the connections of downloads, so a download with it only runs by 1 connection.
+	// The bytes read by each worker are reported by EventWritten.
+	data := bytes.Repeat([]byte("0123456789abcdef"), 256*1024)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "file", time.Time{}, bytes.NewReader(data))
+	}))
+	defer ts.Close()
+
+	dir, err := os.MkdirTemp("", "iocopy")
+	if err != nil {
+		log.Printf("os.MkdirTemp() error: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	// sum returns the number of workers and the bytes read by them.
+	sum := func(workers []int64) (int, int64) {
+		total := int64(0)
+		for _, n := range workers {
+			total += n
+		}
+		return len(workers), total
+	}
+
+	var workers []int64
+	onEvent := func(e iocopy.Event) {
+		if e, ok := e.(*iocopy.EventWritten); ok {
+			workers = e.Workers
+		}
+	}
+
+	dst := filepath.Join(dir, "file")
+	t := iocopy.NewDownloadTask(dst, ts.URL, nil, iocopy.WithConnections(4))
+	if err = iocopy.Do(context.Background(), t, nil, onEvent); err != nil {
+		log.Printf("iocopy.Do() error: %v", err)
+		return
+	}
+	n, total := sum(workers)
+	fmt.Printf("download: workers: %v, bytes: %v\n", n, total)
+
+	h := iocopy.NewHashTask(dst, []string{"sha256"}, iocopy.WithWorkers(4))
+	if err = iocopy.Do(context.Background(), h, nil, onEvent); err != nil {
+		log.Printf("iocopy.Do() error: %v", err)
+		return
+	}
+	n, total = sum(workers)
+	fmt.Printf("hash: workers: %v, bytes: %v\n", n, total)
+
+	// The options of the engines override the shared number of workers.
+	h = iocopy.NewHashTask(dst, []string{"sha256"}, iocopy.WithWorkers(4), iocopy.WithParallelReads(2, 0))
+	if err = iocopy.Do(context.Background(), h, nil, onEvent); err != nil {
+		log.Printf("iocopy.Do() error: %v", err)
+		return
+	}
+	n, total = sum(workers)
+	fmt.Printf("hash: workers: %v, bytes: %v\n", n, total)
+
+	// The connections are not set by WithWorkers.
+	workers = nil
+	t = iocopy.NewDownloadTask(dst, ts.URL, nil, iocopy.WithWorkers(4))
+	if err = iocopy.Do(context.Background(), t, nil, onEvent); err != nil {
+		log.Printf("iocopy.Do() error: %v", err)
+		return
+	}
+	n, total = sum(workers)
+	fmt.Printf("download: workers: %v, bytes: %v\n", n, total)
+
+	// Output:
+	// download: workers: 4, bytes: 4194304
+	// hash: workers: 4, bytes: 4194304
+	// hash: workers: 2, bytes: 4194304
+	// download: workers: 0, bytes: 0
+}
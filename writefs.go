@@ -0,0 +1,139 @@
+package iocopy
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// WriteFile is a file opened by [WriteFS] for writing.
+type WriteFile interface {
+	io.Writer
+	io.Seeker
+	io.Closer
+	// Truncate changes the size of the file.
+	Truncate(size int64) error
+}
+
+// Digester is implemented by a [WriteFile] which computes the digest of the written bytes,
+// e.g. files of [CASFS].
+// Tasks add the digest to their results if it's not empty.
+type Digester interface {
+	// Digest returns the hex encoded digest. It's empty if it's not available.
+	Digest() string
+}
+
+// WriteFS is a minimal writable file system used as destinations of the tasks.
+// It makes destinations not only the OS file system but memory file systems,
+// object-store adapters or test fakes.
+// Implementations of other file system packages(e.g. afero) can be adapted by a small wrapper.
+type WriteFS interface {
+	// Create creates or truncates the named file.
+	Create(name string) (WriteFile, error)
+	// OpenFile opens the named file with specified flag(os.O_WRONLY, os.O_CREATE...) and perm.
+	OpenFile(name string, flag int, perm fs.FileMode) (WriteFile, error)
+	// Mkdir creates a directory with the name and perm.
+	// It returns an error wrapping [fs.ErrExist] if the directory exists.
+	Mkdir(name string, perm fs.FileMode) error
+}
+
+// SizedWriteFS is implemented by a [WriteFS] which needs the size of a file before writing it, e.g. [TarFS].
+// Tasks call CreateSize instead of OpenFile to create new destination files when the size is known.
+type SizedWriteFS interface {
+	WriteFS
+	// CreateSize creates the named file with the size and perm.
+	CreateSize(name string, size int64, perm fs.FileMode) (WriteFile, error)
+}
+
+// osFS implements [WriteFS] by the os package.
+type osFS struct{}
+
+// OSFS is the [WriteFS] of the OS file system.
+// It's used when the file system of a task is nil.
+// Long paths are converted to extended-length paths(`\\?\`) on Windows.
+var OSFS WriteFS = osFS{}
+
+// Create implements [WriteFS] interface.
+func (osFS) Create(name string) (WriteFile, error) {
+	return os.Create(longPath(name))
+}
+
+// OpenFile implements [WriteFS] interface.
+func (osFS) OpenFile(name string, flag int, perm fs.FileMode) (WriteFile, error) {
+	return os.OpenFile(longPath(name), flag, perm)
+}
+
+// Mkdir implements [WriteFS] interface.
+func (osFS) Mkdir(name string, perm fs.FileMode) error {
+	return os.Mkdir(longPath(name), perm)
+}
+
+// mkdirAll creates the directory and all its parents by fsys.Mkdir.
+func mkdirAll(fsys WriteFS, dir string, perm fs.FileMode) error {
+	dir = filepath.Clean(dir)
+	if dir == "." || dir == string(filepath.Separator) || dir == filepath.VolumeName(dir)+string(filepath.Separator) {
+		return nil
+	}
+
+	err := fsys.Mkdir(dir, perm)
+	if err == nil || errors.Is(err, fs.ErrExist) {
+		return nil
+	}
+
+	if !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	// Parent does not exist.
+	if err = mkdirAll(fsys, filepath.Dir(dir), perm); err != nil {
+		return err
+	}
+
+	err = fsys.Mkdir(dir, perm)
+	if err != nil && !errors.Is(err, fs.ErrExist) {
+		return err
+	}
+	return nil
+}
+
+// openDst creates the parent directory of the destination file if need,
+// opens it and truncates it to the copied size to append bytes.
+// total is the size of the file. A negative value indicates it's unknown.
+func openDst(fsys WriteFS, name string, total, copied int64) (WriteFile, error) {
+	if fsys == nil {
+		fsys = OSFS
+	}
+
+	if err := mkdirAll(fsys, filepath.Dir(name), 0755); err != nil {
+		return nil, err
+	}
+
+	var (
+		f   WriteFile
+		err error
+	)
+
+	if sfs, ok := fsys.(SizedWriteFS); ok && copied == 0 && total >= 0 {
+		f, err = sfs.CreateSize(name, total, 0644)
+	} else {
+		f, err = fsys.OpenFile(name, os.O_WRONLY|os.O_CREATE, 0644)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Drop the bytes after the copied ones(if any).
+	if err = f.Truncate(copied); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	if _, err = f.Seek(copied, io.SeekStart); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return f, nil
+}
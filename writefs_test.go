@@ -0,0 +1,190 @@
+package iocopy_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/northbright/iocopy"
+)
+
+// memFile is a file of memFS.
+type memFile struct {
+	fsys *memFS
+	data *[]byte
+	off  int64
+}
+
+func (f *memFile) Write(p []byte) (int, error) {
+	var err error
+	if c := f.fsys.capacity; c > 0 && f.off+int64(len(p)) > c {
+		// Emulate a full disk.
+		p = p[:max(c-f.off, 0)]
+		err = syscall.ENOSPC
+	}
+
+	if end := f.off + int64(len(p)); end > int64(len(*f.data)) {
+		*f.data = append(*f.data, make([]byte, end-int64(len(*f.data)))...)
+	}
+	n := copy((*f.data)[f.off:], p)
+	f.off += int64(n)
+	return n, err
+}
+
+func (f *memFile) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekCurrent:
+		offset += f.off
+	case io.SeekEnd:
+		offset += int64(len(*f.data))
+	}
+	f.off = offset
+	return offset, nil
+}
+
+func (f *memFile) Truncate(size int64) error {
+	if size > int64(len(*f.data)) {
+		*f.data = append(*f.data, make([]byte, size-int64(len(*f.data)))...)
+	}
+	*f.data = (*f.data)[:size]
+	return nil
+}
+
+func (f *memFile) Close() error {
+	return nil
+}
+
+// memFS is a memory file system which implements iocopy.WriteFS.
+type memFS struct {
+	files map[string]*[]byte
+	dirs  map[string]bool
+	// capacity is the max size of files. 0 means no limit.
+	capacity int64
+}
+
+func newMemFS() *memFS {
+	return &memFS{files: map[string]*[]byte{}, dirs: map[string]bool{}}
+}
+
+func (m *memFS) Create(name string) (iocopy.WriteFile, error) {
+	return m.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666)
+}
+
+func (m *memFS) OpenFile(name string, flag int, perm fs.FileMode) (iocopy.WriteFile, error) {
+	if dir := filepath.Dir(name); dir != "." && !m.dirs[dir] {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+
+	data, ok := m.files[name]
+	if !ok {
+		if flag&os.O_CREATE == 0 {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+		}
+		data = &[]byte{}
+		m.files[name] = data
+	}
+
+	if flag&os.O_TRUNC != 0 {
+		*data = (*data)[:0]
+	}
+	return &memFile{fsys: m, data: data}, nil
+}
+
+func (m *memFS) Mkdir(name string, perm fs.FileMode) error {
+	if m.dirs[name] {
+		return &fs.PathError{Op: "mkdir", Path: name, Err: fs.ErrExist}
+	}
+	if dir := filepath.Dir(name); dir != "." && !m.dirs[dir] {
+		return &fs.PathError{Op: "mkdir", Path: name, Err: fs.ErrNotExist}
+	}
+	m.dirs[name] = true
+	return nil
+}
+
+func ExampleWriteFS() {
+	// This example downloads a file to a memory file system which implements iocopy.WriteFS.
+	data := strings.Repeat("Hello, World!\n", 1000)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "hello.txt", time.Time{}, strings.NewReader(data))
+	}))
+	defer ts.Close()
+
+	fsys := newMemFS()
+	t := iocopy.NewDownloadTask(filepath.Join("downloads", "hello.txt"), ts.URL, fsys)
+
+	err := iocopy.Do(context.Background(), t, nil, func(e iocopy.Event) {
+		if _, ok := e.(*iocopy.EventOK); ok {
+			fmt.Printf("%v bytes downloaded\n", t.Copied())
+		}
+	})
+	if err != nil {
+		log.Printf("iocopy.Do() error: %v", err)
+		return
+	}
+
+	fmt.Printf("same content: %v\n", bytes.Equal(*fsys.files[filepath.Join("downloads", "hello.txt")], []byte(data)))
+
+	// Output:
+	// 14000 bytes downloaded
+	// same content: true
+}
+
+func ExampleErrNoSpace() {
+	// This example copies a file to a memory file system which becomes full.
+	// The task is stopped with iocopy.ErrNoSpace and resumed after space is freed.
+	dir, err := os.MkdirTemp("", "iocopy")
+	if err != nil {
+		log.Printf("os.MkdirTemp() error: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	src := filepath.Join(dir, "src")
+	data := bytes.Repeat([]byte("0123456789abcdef"), 512)
+	if err = os.WriteFile(src, data, 0644); err != nil {
+		log.Printf("os.WriteFile() error: %v", err)
+		return
+	}
+
+	fsys := newMemFS()
+	fsys.capacity = 5000
+
+	var state []byte
+	t := iocopy.NewCopyFileTask("dst", src, fsys)
+	err = iocopy.Do(context.Background(), t, make([]byte, 1024), func(e iocopy.Event) {
+		if e, ok := e.(*iocopy.EventStop); ok {
+			state = e.State
+		}
+	})
+	if errors.Is(err, iocopy.ErrNoSpace) {
+		fmt.Printf("stopped: no space, %v/%v bytes copied\n", t.Copied(), t.Total())
+	}
+
+	// Free space and resume.
+	fsys.capacity = 0
+	if t, err = iocopy.LoadCopyFileTask(state, fsys); err != nil {
+		log.Printf("iocopy.LoadCopyFileTask() error: %v", err)
+		return
+	}
+
+	if err = iocopy.Do(context.Background(), t, nil, nil); err != nil {
+		log.Printf("iocopy.Do() error: %v", err)
+		return
+	}
+	fmt.Printf("done, same content: %v\n", bytes.Equal(*fsys.files["dst"], data))
+
+	// Output:
+	// stopped: no space, 5000/8192 bytes copied
+	// done, same content: true
+}
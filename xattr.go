@@ -0,0 +1,34 @@
+package iocopy
+
+import "strings"
+
+// XattrCategory is a set of the categories of extended attributes to copy by [WithXattrs].
+type XattrCategory uint
+
+const (
+	// XattrUser is the user attributes("user.*").
+	XattrUser XattrCategory = 1 << iota
+	// XattrSecurity is the security attributes("security.*"), e.g. SELinux labels and file capabilities.
+	XattrSecurity
+	// XattrACL is the POSIX ACLs("system.posix_acl_access" and "system.posix_acl_default").
+	XattrACL
+	// XattrTrusted is the trusted attributes("trusted.*") which need CAP_SYS_ADMIN to read and write.
+	XattrTrusted
+	// XattrAll is all categories above.
+	XattrAll = XattrUser | XattrSecurity | XattrACL | XattrTrusted
+)
+
+// match reports whether the attribute of the name is in the categories.
+func (c XattrCategory) match(name string) bool {
+	switch {
+	case strings.HasPrefix(name, "user."):
+		return c&XattrUser != 0
+	case strings.HasPrefix(name, "security."):
+		return c&XattrSecurity != 0
+	case name == "system.posix_acl_access" || name == "system.posix_acl_default":
+		return c&XattrACL != 0
+	case strings.HasPrefix(name, "trusted."):
+		return c&XattrTrusted != 0
+	}
+	return false
+}
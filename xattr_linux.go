@@ -0,0 +1,84 @@
+package iocopy
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"syscall"
+)
+
+// listXattrs returns the names of the extended attributes of the file.
+func listXattrs(path string) ([]string, error) {
+	for {
+		n, err := syscall.Listxattr(path, nil)
+		if err != nil {
+			return nil, err
+		}
+		if n == 0 {
+			return nil, nil
+		}
+
+		buf := make([]byte, n)
+		n, err = syscall.Listxattr(path, buf)
+		if err == syscall.ERANGE {
+			// The attributes changed in between. Try again.
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var names []string
+		for _, b := range bytes.Split(buf[:n], []byte{0}) {
+			if len(b) > 0 {
+				names = append(names, string(b))
+			}
+		}
+		return names, nil
+	}
+}
+
+// getXattr returns the value of the extended attribute of the file.
+func getXattr(path, name string) ([]byte, error) {
+	for {
+		n, err := syscall.Getxattr(path, name, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		buf := make([]byte, n)
+		n, err = syscall.Getxattr(path, name, buf)
+		if err == syscall.ERANGE {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return buf[:n], nil
+	}
+}
+
+// copyXattrs copies the extended attributes of the categories from src to dst.
+// It copies as many attributes as possible and returns the errors joined.
+func copyXattrs(dst, src string, c XattrCategory) error {
+	names, err := listXattrs(src)
+	if err != nil {
+		return fmt.Errorf("list xattrs of %v: %w", src, err)
+	}
+
+	var errs []error
+	for _, name := range names {
+		if !c.match(name) {
+			continue
+		}
+
+		v, err := getXattr(src, name)
+		if err == nil {
+			err = syscall.Setxattr(dst, name, v, 0)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("copy xattr %v: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,56 @@
+package iocopy_test
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"syscall"
+
+	"github.com/northbright/iocopy"
+)
+
+func ExampleWithXattrs() {
+	// This example copies a file with its user extended attributes.
+	// The file system of the temporary directory should support user attributes(e.g. ext4).
+	dir, err := os.MkdirTemp("", "iocopy")
+	if err != nil {
+		log.Printf("os.MkdirTemp() error: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	src := filepath.Join(dir, "src")
+	if err = os.WriteFile(src, []byte("hello"), 0644); err != nil {
+		log.Printf("os.WriteFile() error: %v", err)
+		return
+	}
+
+	if err = syscall.Setxattr(src, "user.comment", []byte("greeting"), 0); err != nil {
+		log.Printf("syscall.Setxattr() error: %v", err)
+		return
+	}
+
+	dst := filepath.Join(dir, "dst")
+	t := iocopy.NewCopyFileTask(dst, src, nil, iocopy.WithXattrs(iocopy.XattrUser|iocopy.XattrACL))
+	if err = iocopy.Do(context.Background(), t, nil, nil); err != nil {
+		log.Printf("iocopy.Do() error: %v", err)
+		return
+	}
+
+	r := t.ResultValue().(iocopy.CopyFileResult)
+	fmt.Printf("xattr error: %q\n", r.XattrErr)
+
+	buf := make([]byte, 64)
+	n, err := syscall.Getxattr(dst, "user.comment", buf)
+	if err != nil {
+		log.Printf("syscall.Getxattr() error: %v", err)
+		return
+	}
+	fmt.Printf("user.comment: %s\n", buf[:n])
+
+	// Output:
+	// xattr error: ""
+	// user.comment: greeting
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package iocopy
+
+import "errors"
+
+// copyXattrs is only implemented on linux.
+func copyXattrs(dst, src string, c XattrCategory) error {
+	return errors.ErrUnsupported
+}
@@ -0,0 +1,455 @@
+package iocopy
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// DefaultStoreExts are the extensions of the files which are stored without compression by [ZipDirTask]
+// since they're compressed already.
+var DefaultStoreExts = []string{
+	".7z", ".avif", ".br", ".bz2", ".docx", ".flac", ".gif", ".gz", ".heic", ".jar", ".jpeg", ".jpg",
+	".m4a", ".mkv", ".mov", ".mp3", ".mp4", ".ogg", ".png", ".pptx", ".rar", ".webm", ".webp", ".xlsx",
+	".xz", ".zip", ".zst",
+}
+
+// ZipPrevExt is the extension of the zip file renamed by [ZipDirTask] to copy the entries when it's resumed.
+const ZipPrevExt = ".iocopy-prev"
+
+// ZipDirTask implements [Task] interface to zip a directory recursively.
+// The files are scanned when it's opened for the first time and the total is the sum of their sizes.
+// Files are deflated except the ones with the extensions in [DefaultStoreExts](or the ones set by [WithStoreExts]).
+//
+// When it's stopped, the zip file is closed and it's a valid zip of the entries written.
+// It resumes at entry granularity: the entries of the files done are copied without recompression
+// to a new zip file and the file being written is zipped again.
+type ZipDirTask struct {
+	dst    string
+	dir    string
+	src    fs.FS
+	dirs   []string
+	files  []CopyFSFile
+	total  int64
+	copied int64
+	// done is the number of the files zipped.
+	done int
+	// opened is true if the entries of the directories are written.
+	opened bool
+	f      *os.File
+	zw     *zip.Writer
+	r      *fsReader
+	w      *zipWriter
+	pf     *PrefetchReader
+	opts   options
+}
+
+// ZipDirState is the typed state of [ZipDirTask].
+type ZipDirState struct {
+	// Version is the version of the state. See [StateVersion].
+	Version int `json:"version"`
+	// Type is the type of the state to load it by [LoadTask]: [StateTypeZipDir].
+	Type string `json:"type"`
+	// Dst is the zip file.
+	Dst string `json:"dst"`
+	// Dir is the directory to zip.
+	Dir string `json:"dir"`
+	// Dirs are the slash-separated names of the directories.
+	// They're nil if the directory is not scanned yet.
+	Dirs []string `json:"dirs"`
+	// Files are the files to zip in order.
+	// They're nil if the directory is not scanned yet.
+	Files []CopyFSFile `json:"files"`
+	// Total is the total size of the files.
+	// A negative value indicates the directory is not scanned yet.
+	Total int64 `json:"total"`
+	// Copied is the total size of the files zipped.
+	Copied int64 `json:"copied"`
+	// Done is the number of the files zipped.
+	Done int `json:"done"`
+	// Opened is true if the zip file is created.
+	Opened bool `json:"opened"`
+}
+
+// ZipDirResult is the typed result of [ZipDirTask].
+type ZipDirResult struct {
+	// Dst is the zip file.
+	Dst string `json:"dst"`
+	// Dir is the directory to zip.
+	Dir string `json:"dir"`
+	// Files is the number of the files zipped.
+	Files int `json:"files"`
+	// Size is the total size of the files zipped.
+	Size int64 `json:"size"`
+	// ZipSize is the size of the zip file.
+	ZipSize int64 `json:"zip_size"`
+}
+
+// NewZipDirTask returns a [*ZipDirTask] which zips dir to dst.
+// The names of the entries are relative to dir.
+// opts: optional parameters. e.g. [WithFilter], [WithStoreExts], [WithPrefetch], [WithRateLimiter].
+func NewZipDirTask(dst, dir string, opts ...Option) *ZipDirTask {
+	return &ZipDirTask{dst: dst, dir: dir, src: os.DirFS(dir), total: -1, opts: newOptions(opts)}
+}
+
+// LoadZipDirTask loads a [*ZipDirTask] from the state to resume.
+// opts: optional parameters which are not saved in the state.
+func LoadZipDirTask(state []byte, opts ...Option) (*ZipDirTask, error) {
+	var s ZipDirState
+	if err := json.Unmarshal(state, &s); err != nil {
+		return nil, err
+	}
+
+	if err := (stateHeader{s.Version, s.Type}).check(StateTypeZipDir); err != nil {
+		return nil, err
+	}
+
+	t := NewZipDirTask(s.Dst, s.Dir, opts...)
+	t.dirs = s.Dirs
+	t.files = s.Files
+	t.total = s.Total
+	t.copied = s.Copied
+	t.done = s.Done
+	t.opened = s.Opened
+	return t, nil
+}
+
+// method returns the compression method of the file by its extension.
+func (t *ZipDirTask) method(name string) uint16 {
+	exts := DefaultStoreExts
+	if t.opts.storeExts != nil {
+		exts = t.opts.storeExts
+	}
+
+	ext := strings.ToLower(filepath.Ext(name))
+	if slices.ContainsFunc(exts, func(e string) bool { return strings.ToLower(e) == ext }) {
+		return zip.Store
+	}
+	return zip.Deflate
+}
+
+// Open implements [Task] interface.
+// It scans the directory if it's not scanned yet and creates the zip file.
+// If it's resumed, the entries of the files done are copied from the previous zip file.
+func (t *ZipDirTask) Open(ctx context.Context) (dst io.Writer, src io.Reader, err error) {
+	if t.files == nil {
+		if t.dirs, t.files, t.total, err = scanFS(ctx, t.src, t.opts.filter); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if err = os.MkdirAll(longPath(filepath.Dir(t.dst)), 0755); err != nil {
+		return nil, nil, err
+	}
+
+	defer func() {
+		if err != nil {
+			t.Close()
+		}
+	}()
+
+	if t.opened {
+		err = t.reopen(ctx)
+	} else {
+		err = t.create()
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	t.copied = 0
+	for _, f := range t.files[:t.done] {
+		t.copied += f.Size
+	}
+
+	t.r = &fsReader{src: t.src, files: t.files, i: t.done}
+	t.w = &zipWriter{t: t, i: t.done}
+
+	src = t.r
+	src = t.opts.limit(ctx, src)
+
+	if t.opts.prefetch {
+		t.pf = NewPrefetchReader(ctx, src, t.opts.prefetchDepth, t.opts.prefetchSize)
+		src = t.pf
+	}
+
+	return t.opts.throttle(ctx, t.w), src, nil
+}
+
+// create creates the zip file and writes the entries of the directories.
+func (t *ZipDirTask) create() error {
+	f, err := os.Create(longPath(t.dst))
+	if err != nil {
+		return err
+	}
+	t.f = f
+	t.zw = zip.NewWriter(f)
+
+	for _, name := range t.dirs {
+		if name == "." {
+			continue
+		}
+
+		fi, err := fs.Stat(t.src, name)
+		if err != nil {
+			return err
+		}
+
+		h, err := zip.FileInfoHeader(fi)
+		if err != nil {
+			return err
+		}
+		h.Name = name + "/"
+
+		if _, err = t.zw.CreateHeader(h); err != nil {
+			return err
+		}
+	}
+
+	t.opened = true
+	t.done = 0
+	return nil
+}
+
+// reopen copies the entries of the directories and the files done from the previous zip file to a new one.
+// The previous zip file is renamed to "<dst>.iocopy-prev" and removed after the entries are copied.
+func (t *ZipDirTask) reopen(ctx context.Context) error {
+	prev := t.dst + ZipPrevExt
+	// The previous zip file exists if it failed to copy the entries last time.
+	if _, err := os.Stat(longPath(prev)); errors.Is(err, fs.ErrNotExist) {
+		if err = os.Rename(longPath(t.dst), longPath(prev)); err != nil {
+			return err
+		}
+	}
+
+	zr, err := zip.OpenReader(longPath(prev))
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+
+	n := len(t.dirs) + t.done
+	if slices.Contains(t.dirs, ".") {
+		n--
+	}
+	if len(zr.File) < n {
+		return fmt.Errorf("%v has %v entries, expected at least %v", prev, len(zr.File), n)
+	}
+
+	f, err := os.Create(longPath(t.dst))
+	if err != nil {
+		return err
+	}
+	t.f = f
+	t.zw = zip.NewWriter(f)
+
+	for _, zf := range zr.File[:n] {
+		if err = ctx.Err(); err != nil {
+			return err
+		}
+
+		if err = t.zw.Copy(zf); err != nil {
+			return err
+		}
+	}
+
+	zr.Close()
+	return os.Remove(longPath(prev))
+}
+
+// Close implements [Task] interface.
+// It closes the zip file, so it's valid even if the task is stopped.
+func (t *ZipDirTask) Close() error {
+	if t.pf != nil {
+		t.pf.Close()
+		t.pf = nil
+	}
+
+	if t.r != nil {
+		t.r.close()
+		t.r = nil
+	}
+	t.w = nil
+
+	var err error
+	if t.zw != nil {
+		err = t.zw.Close()
+		t.zw = nil
+	}
+
+	if t.f != nil {
+		if closeErr := t.f.Close(); err == nil {
+			err = closeErr
+		}
+		t.f = nil
+	}
+	return err
+}
+
+// Current returns the slash-separated name of the file being zipped,
+// the number of bytes of it written and its size.
+// It's safe to be called in the callback of [Do] to report the progress of the entry.
+func (t *ZipDirTask) Current() (name string, written, size int64) {
+	w := t.w
+	if w == nil || w.i >= len(t.files) {
+		return "", 0, 0
+	}
+	f := t.files[w.i]
+	return f.Name, w.off, f.Size
+}
+
+// Endpoints implements [Endpointer] interface.
+func (t *ZipDirTask) Endpoints() (src, dst string) {
+	return t.dir, t.dst
+}
+
+// Total implements [Task] interface.
+func (t *ZipDirTask) Total() int64 {
+	return t.total
+}
+
+// FileProgress implements [FileProgresser] interface.
+func (t *ZipDirTask) FileProgress() (FileProgress, bool) {
+	return filesProgress(t.files, t.copied)
+}
+
+// Copied implements [Task] interface.
+func (t *ZipDirTask) Copied() int64 {
+	return t.copied
+}
+
+// SetCopied implements [Task] interface.
+func (t *ZipDirTask) SetCopied(copied int64) {
+	t.copied = copied
+}
+
+// State implements [Task] interface.
+func (t *ZipDirTask) State() ([]byte, error) {
+	return json.Marshal(t.StateValue())
+}
+
+// StateValue implements [StateValuer] interface.
+// It returns the [ZipDirState].
+// Copied is the total size of the files done since the file being written is zipped again when it's resumed.
+func (t *ZipDirTask) StateValue() any {
+	s := ZipDirState{Version: StateVersion, Type: StateTypeZipDir, Dst: t.dst, Dir: t.dir, Dirs: t.dirs, Files: t.files, Total: t.total, Done: t.done, Opened: t.opened}
+	for _, f := range t.files[:t.done] {
+		s.Copied += f.Size
+	}
+	return s
+}
+
+// Result implements [Task] interface.
+func (t *ZipDirTask) Result() ([]byte, error) {
+	return json.Marshal(t.ResultValue())
+}
+
+// ResultValue implements [ResultValuer] interface.
+// It returns the [ZipDirResult].
+func (t *ZipDirTask) ResultValue() any {
+	r := ZipDirResult{Dst: t.dst, Dir: t.dir, Files: t.done}
+	for _, f := range t.files[:t.done] {
+		r.Size += f.Size
+	}
+	if fi, err := os.Stat(longPath(t.dst)); err == nil {
+		r.ZipSize = fi.Size()
+	}
+	return r
+}
+
+// zipWriter writes the bytes read by [fsReader] to the entries of the files one by one.
+type zipWriter struct {
+	t *ZipDirTask
+	// i is the index of the file to write.
+	i int
+	// off is the offset of the file to write.
+	off int64
+	w   io.Writer
+}
+
+// Write implements [io.Writer] interface.
+func (w *zipWriter) Write(p []byte) (n int, err error) {
+	for len(p) > 0 {
+		if err = w.next(); err != nil {
+			return n, err
+		}
+
+		if w.i >= len(w.t.files) {
+			return n, fmt.Errorf("bytes written beyond the files")
+		}
+
+		file := w.t.files[w.i]
+		m, err := w.w.Write(p[:min(int64(len(p)), file.Size-w.off)])
+		w.off += int64(m)
+		n += m
+		p = p[m:]
+		if err != nil {
+			return n, err
+		}
+
+		if w.off == file.Size {
+			w.finish()
+		}
+	}
+	return n, nil
+}
+
+// finish moves to the next file after the entry of the current one is written.
+// The entry is done since it's closed by the next one or the zip writer.
+func (w *zipWriter) finish() {
+	w.w = nil
+	w.i++
+	w.off = 0
+	w.t.done = w.i
+}
+
+// next creates the entry of the file to write if it's not created.
+// Entries of empty files are created when they're reached.
+func (w *zipWriter) next() error {
+	for w.i < len(w.t.files) {
+		file := w.t.files[w.i]
+		if w.w == nil {
+			if err := w.create(file); err != nil {
+				return err
+			}
+		}
+
+		if w.off < file.Size {
+			return nil
+		}
+		w.finish()
+	}
+	return nil
+}
+
+// create creates the entry of the file.
+func (w *zipWriter) create(file CopyFSFile) error {
+	fi, err := fs.Stat(w.t.src, file.Name)
+	if err != nil {
+		return err
+	}
+
+	h, err := zip.FileInfoHeader(fi)
+	if err != nil {
+		return err
+	}
+	h.Name = file.Name
+	h.Method = w.t.method(file.Name)
+
+	w.w, err = w.t.zw.CreateHeader(h)
+	return err
+}
+
+// Commit implements [Committer] interface.
+// It creates the entries of the remaining empty files.
+func (w *zipWriter) Commit() error {
+	return w.next()
+}
@@ -0,0 +1,113 @@
+package iocopy_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/northbright/iocopy"
+)
+
+func ExampleZipDirTask() {
+	// This example zips a directory. It's stopped when the large file is being zipped and resumed.
+	dir, err := os.MkdirTemp("", "iocopy")
+	if err != nil {
+		log.Printf("os.MkdirTemp() error: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	src := filepath.Join(dir, "src")
+	large := bytes.Repeat([]byte("0123456789abcdef"), 256*1024)
+	files := map[string][]byte{
+		"a.txt":         []byte("hello"),
+		"photo.jpg":     bytes.Repeat([]byte{0xff}, 1024),
+		"data/large":    large,
+		"data/empty":    nil,
+		"data/sub/b.md": []byte("# b"),
+	}
+	for name, data := range files {
+		file := filepath.Join(src, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(file), 0755)
+		os.WriteFile(file, data, 0644)
+	}
+
+	dst := filepath.Join(dir, "out", "src.zip")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var state []byte
+	t := iocopy.NewZipDirTask(dst, src)
+	iocopy.Do(ctx, t, make([]byte, 32*1024), func(e iocopy.Event) {
+		switch e := e.(type) {
+		case *iocopy.EventWritten:
+			// Emulate user cancelation when the large file is being zipped.
+			if name, written, size := t.Current(); name == "data/large" && written > size/2 {
+				cancel()
+			}
+		case *iocopy.EventStop:
+			state = e.State
+		}
+	})
+
+	// The zip file is valid after it's stopped.
+	if zr, err := zip.OpenReader(dst); err == nil {
+		fmt.Printf("valid zip after stopped: %v\n", len(zr.File) > 0)
+		zr.Close()
+	}
+
+	if t, err = iocopy.LoadZipDirTask(state); err != nil {
+		log.Printf("iocopy.LoadZipDirTask() error: %v", err)
+		return
+	}
+
+	iocopy.Do(context.Background(), t, nil, func(e iocopy.Event) {
+		switch e := e.(type) {
+		case *iocopy.EventOK:
+			r := e.Value.(iocopy.ZipDirResult)
+			fmt.Printf("files: %v, size: %v, compressed: %v\n", r.Files, r.Size, r.ZipSize < r.Size)
+		case *iocopy.EventError:
+			log.Printf("iocopy.Do() error: %v", e.Err)
+		}
+	})
+
+	zr, err := zip.OpenReader(dst)
+	if err != nil {
+		log.Printf("zip.OpenReader() error: %v", err)
+		return
+	}
+	defer zr.Close()
+
+	for _, f := range zr.File {
+		method := "deflate"
+		if f.Method == zip.Store {
+			method = "store"
+		}
+
+		same := true
+		if !f.FileInfo().IsDir() {
+			rc, _ := f.Open()
+			data, _ := io.ReadAll(rc)
+			rc.Close()
+			same = bytes.Equal(data, files[f.Name])
+		}
+		fmt.Printf("%v: %v, same content: %v\n", f.Name, method, same)
+	}
+
+	// Output:
+	// valid zip after stopped: true
+	// files: 5, size: 4195336, compressed: true
+	// data/: store, same content: true
+	// data/sub/: store, same content: true
+	// a.txt: deflate, same content: true
+	// data/empty: deflate, same content: true
+	// data/large: deflate, same content: true
+	// data/sub/b.md: deflate, same content: true
+	// photo.jpg: store, same content: true
+}